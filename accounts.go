@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// accountsSheet lists the accounts expenses are paid from, named as in
+// their Payment column, with the balance each one starts from. Like the
+// Budgets sheet it is edited in the workbook.
+const accountsSheet = "Accounts"
+
+var accountsHeaders = []string{"Account", "Opening Balance", "As Of", "Currency", "Statement Day", "Due Day"}
+
+// account is a row of the Accounts sheet.
+type account struct {
+	Name    string
+	Opening float64
+	// AsOf is the day Opening was the balance; expenses dated before it
+	// are already in it. Zero counts every expense.
+	AsOf time.Time
+	// Currency is what the account is held in, blank for the configured
+	// currency. Its balance is kept in it, and expenses in others are
+	// converted at the rate of their date.
+	Currency string
+	// StatementDay makes the account a credit card whose statements are
+	// drawn up on that day of the month, and DueDay is the day each one
+	// is to be paid by; see cardCycles.
+	StatementDay, DueDay int
+}
+
+// amount is x in the account's currency as shown.
+func (a account) amount(x float64) string {
+	if a.Currency != "" {
+		return money(x) + " " + a.Currency
+	}
+	return money(x)
+}
+
+// readAccounts reads the Accounts sheet, skipping rows without a name. A
+// workbook without one has no accounts.
+func readAccounts(f *excelize.File) []account {
+	if idx, _ := f.GetSheetIndex(accountsSheet); idx == -1 {
+		return nil
+	}
+	rows, err := f.GetRows(accountsSheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil
+	}
+	var accounts []account
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		a := account{Name: strings.TrimSpace(row[0])}
+		if len(row) > 1 {
+			opening, _ := strconv.ParseFloat(strings.TrimSpace(row[1]), 64)
+			a.Opening = cfg.Rounding.round(opening)
+		}
+		if len(row) > 2 {
+			a.AsOf = parseExpenseDate(row[2])
+		}
+		if len(row) > 3 {
+			a.Currency = baseOrCurrency(row[3])
+		}
+		if len(row) > 4 {
+			a.StatementDay = dayOfMonth(row[4])
+		}
+		if len(row) > 5 {
+			a.DueDay = dayOfMonth(row[5])
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts
+}
+
+// accountOf returns the index in accounts of the account e was paid from,
+// matched case-insensitively, or -1.
+func accountOf(accounts []account, e Expense) int {
+	if e.Payment == "" {
+		return -1
+	}
+	for i, a := range accounts {
+		if payeeKey(a.Name) == payeeKey(e.Payment) {
+			return i
+		}
+	}
+	return -1
+}
+
+// runningBalance is an account's balance just after an expense.
+type runningBalance struct {
+	// account indexes accounts, -1 when the expense moves none.
+	account int
+	balance float64
+}
+
+// runningBalances works out, for each of rows, the balance of its account
+// once it is paid, as a bank statement would: in date order, rows dated
+// the same day in the order they are listed, after that day's transfers.
+// Pending and undated rows, and rows dated before the account's As Of
+// day, move no account.
+func runningBalances(accounts []account, transfers []accountTransfer, rows []Expense, rates fxRates) []runningBalance {
+	return bookAccounts(accounts, transfers, rows, rates).rows
+}
+
+// balancesLine lists the balance of every account, for below the expenses
+// table, or is empty without accounts. With accounts in other currencies
+// it adds their value at the latest rates and the net worth they make.
+func balancesLine(accounts []account, transfers []accountTransfer, rows []Expense, rates fxRates) string {
+	if len(accounts) == 0 {
+		return ""
+	}
+	book := bookAccounts(accounts, transfers, rows, rates)
+	values := book.values(accounts, rates)
+	parts := make([]string, len(accounts))
+	foreign := false
+	var worth float64
+	for i, a := range accounts {
+		worth += values[i]
+		parts[i] = a.Name + " " + a.amount(book.balances[i])
+		if a.Currency != "" {
+			parts[i] += " ≈ " + money(values[i])
+			foreign = true
+		}
+	}
+	line := "Balances: " + strings.Join(parts, " · ") + "\n"
+	if foreign || book.realized() != 0 {
+		line += fmt.Sprintf("Net worth %s at the latest rates · unrealized FX gain %s · realized FX gain %s\n",
+			money(cfg.Rounding.round(worth)), money(book.unrealized(values)), money(book.realized()))
+	}
+	return line
+}
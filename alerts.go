@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// alertConfig is the hook run when a watchlist price reaches a target, or
+// a wishlist item drops to its target price.
+type alertConfig struct {
+	// Command runs through the shell with $TET_SYMBOL, $TET_SIDE (buy,
+	// sell, or wish for a wishlist item), $TET_PRICE and $TET_TARGET set,
+	// e.g.
+	// `notify-send "$TET_SYMBOL hit its $TET_SIDE target"`.
+	Command string `json:"command,omitempty"`
+}
+
+func (w WatchItem) buyHit() bool {
+	return w.Price > 0 && w.TargetBuy > 0 && w.Price <= w.TargetBuy
+}
+
+func (w WatchItem) sellHit() bool {
+	return w.Price > 0 && w.TargetSell > 0 && w.Price >= w.TargetSell
+}
+
+// targetAlert is a watchlist price at or past one of its targets, or a
+// wishlist item's price at or below its target price.
+type targetAlert struct {
+	symbol        string
+	side          string
+	price, target float64
+}
+
+func (a targetAlert) key() string { return a.symbol + "/" + a.side }
+
+func (a targetAlert) String() string {
+	switch a.side {
+	case "buy":
+		return fmt.Sprintf("%s at %g is at or below its buy target %g", a.symbol, a.price, a.target)
+	case "wish":
+		return fmt.Sprintf("%s at %g is at or below its target price %g", a.symbol, a.price, a.target)
+	}
+	return fmt.Sprintf("%s at %g is at or above its sell target %g", a.symbol, a.price, a.target)
+}
+
+func targetAlerts(items []WatchItem) []targetAlert {
+	var alerts []targetAlert
+	for _, w := range items {
+		if w.buyHit() {
+			alerts = append(alerts, targetAlert{w.Symbol, "buy", w.Price, w.TargetBuy})
+		}
+		if w.sellHit() {
+			alerts = append(alerts, targetAlert{w.Symbol, "sell", w.Price, w.TargetSell})
+		}
+	}
+	return alerts
+}
+
+// checkAlerts reports targets hit since the last check in the status line,
+// those of the watchlist and the wishlist alike, and runs the configured
+// hook for each. A target only alerts again after
+// the price has moved away from it.
+func (m *model) checkAlerts() tea.Cmd {
+	current := make(map[string]bool)
+	var fresh []targetAlert
+	for _, a := range append(targetAlerts(m.watchList), wishAlerts(m.wishlist)...) {
+		current[a.key()] = true
+		if !m.alerted[a.key()] {
+			fresh = append(fresh, a)
+		}
+	}
+	m.alerted = current
+	if len(fresh) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(fresh))
+	for i, a := range fresh {
+		msgs[i] = a.String()
+	}
+	m.status = "Target reached: " + strings.Join(msgs, "; ")
+	if cfg.Alerts.Command == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		for _, a := range fresh {
+			if err := runAlertHook(cfg.Alerts.Command, a); err != nil {
+				return alertHookFailedMsg{fmt.Errorf("alert hook for %s: %w", a.symbol, err)}
+			}
+		}
+		return nil
+	}
+}
+
+// alertHookFailedMsg is shown in the status line; unlike errMsg it leaves
+// the file watcher alone.
+type alertHookFailedMsg struct{ err error }
+
+func (e alertHookFailedMsg) Error() string { return e.err.Error() }
+
+// shellCommand runs command through the system's shell.
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+func runAlertHook(command string, a targetAlert) error {
+	cmd := shellCommand(command)
+	cmd.Env = append(os.Environ(),
+		"TET_SYMBOL="+a.symbol,
+		"TET_SIDE="+a.side,
+		"TET_PRICE="+strconv.FormatFloat(a.price, 'f', -1, 64),
+		"TET_TARGET="+strconv.FormatFloat(a.target, 'f', -1, 64),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
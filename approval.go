@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Expenses added by someone other than the book's owner are pending until
+// the owner approves them on the Review screen. Their Pending field says
+// what they propose: pendingNew for a new expense, else the edit of the
+// approved row whose expenseSummary follows pendingEditPrefix, or its
+// deletion after pendingDeletePrefix, the row staying as it is in the
+// meantime. Pending rows count towards no totals.
+const (
+	pendingNew          = "New"
+	pendingEditPrefix   = "Replaces "
+	pendingDeletePrefix = "Deletes "
+)
+
+// needsApproval reports whether changes by the current user wait for the
+// owner's approval.
+func needsApproval() bool {
+	return cfg.Owner != "" && currentUser() != cfg.Owner
+}
+
+// applyEdit saves e to expenses at index, or adds it when index is -1.
+// Without the owner's say-so, a new expense is added pending and an edit
+// to an approved row is added as a pending row proposing it. Editing a
+// row that is still pending keeps it pending, as the same proposal.
+func applyEdit(expenses []Expense, index int, e Expense) []Expense {
+	if index == -1 {
+		if needsApproval() {
+			e.Pending = pendingNew
+		}
+		return append(expenses, e)
+	}
+	original := expenses[index]
+	switch {
+	case original.Pending != "":
+		e.Pending = original.Pending
+	case needsApproval():
+		e.Pending = pendingEditPrefix + expenseSummary(original)
+		return append(expenses, e)
+	}
+	expenses[index] = e
+	return expenses
+}
+
+// applyDelete removes the expense at index. Without the owner's say-so,
+// deleting an approved row adds a pending row proposing it instead;
+// deleting a pending row withdraws what it proposed.
+func applyDelete(expenses []Expense, index int) []Expense {
+	original := expenses[index]
+	if original.Pending == "" && needsApproval() {
+		proposal := original
+		proposal.Pending = pendingDeletePrefix + expenseSummary(original)
+		return append(expenses, proposal)
+	}
+	return slices.Delete(expenses, index, index+1)
+}
+
+// pendingRows returns the indexes of the pending expenses.
+func pendingRows(expenses []Expense) []int {
+	var rows []int
+	for i, e := range expenses {
+		if e.Pending != "" {
+			rows = append(rows, i)
+		}
+	}
+	return rows
+}
+
+// approve applies the pending expense at index: a new one simply stops
+// being pending, an edit replaces the row it was made to and a deletion
+// removes it. The edited or deleted row must not have changed since.
+func approve(expenses []Expense, index int) ([]Expense, error) {
+	e := expenses[index]
+	if target, ok := strings.CutPrefix(e.Pending, pendingDeletePrefix); ok {
+		for i, original := range expenses {
+			if original.Pending == "" && expenseSummary(original) == target {
+				// The later row goes first, so the other keeps its index.
+				lo, hi := min(i, index), max(i, index)
+				expenses = slices.Delete(expenses, hi, hi+1)
+				return slices.Delete(expenses, lo, lo+1), nil
+			}
+		}
+		return expenses, fmt.Errorf("review: %s has changed since its deletion was asked for; reject it and delete the row again", target)
+	}
+	target, isEdit := strings.CutPrefix(e.Pending, pendingEditPrefix)
+	e.Pending = ""
+	if !isEdit {
+		expenses[index] = e
+		return expenses, nil
+	}
+	for i, original := range expenses {
+		if original.Pending == "" && expenseSummary(original) == target {
+			expenses[i] = e
+			return slices.Delete(expenses, index, index+1), nil
+		}
+	}
+	return expenses, fmt.Errorf("review: %s has changed since the edit was made; reject it and edit the row again", target)
+}
+
+// reject drops the pending expense at index.
+func reject(expenses []Expense, index int) []Expense {
+	return slices.Delete(expenses, index, index+1)
+}
+
+// describePending says what the pending expense e would change.
+func describePending(e Expense) string {
+	proposed := e
+	proposed.Pending = ""
+	if target, ok := strings.CutPrefix(e.Pending, pendingEditPrefix); ok {
+		return target + " → " + expenseSummary(proposed)
+	}
+	if target, ok := strings.CutPrefix(e.Pending, pendingDeletePrefix); ok {
+		return "Delete: " + target
+	}
+	if receipt, ok := strings.CutPrefix(e.Pending, pendingReceiptPrefix); ok {
+		return "Receipt " + receipt + ": " + expenseSummary(proposed)
+	}
+	if subject, ok := strings.CutPrefix(e.Pending, pendingEmailPrefix); ok {
+		return fmt.Sprintf("Email %q: %s", subject, expenseSummary(proposed))
+	}
+	return "New: " + expenseSummary(proposed)
+}
+
+func (m *model) updateReview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rows := pendingRows(m.expenses)
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "b":
+		m.currentScreen = screenMenu
+	case "up":
+		if m.reviewRow > 0 {
+			m.reviewRow--
+		}
+	case "down":
+		if m.reviewRow < len(rows)-1 {
+			m.reviewRow++
+		}
+	case "y", "r":
+		if m.reviewRow >= len(rows) {
+			return m, nil
+		}
+		if m.readOnly || needsApproval() {
+			m.status = "Only the owner of the book can approve or reject changes."
+			return m, nil
+		}
+		index := rows[m.reviewRow]
+		if msg.String() == "y" {
+			expenses, err := approve(m.expenses, index)
+			if err != nil {
+				m.status = err.Error()
+				return m, nil
+			}
+			m.expenses = expenses
+			m.status = "Approved."
+		} else {
+			m.expenses = reject(m.expenses, index)
+			m.status = "Rejected."
+		}
+		m.reviewRow = max(min(m.reviewRow, len(rows)-2), 0)
+		m.updateExpensesTable()
+		return m, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil)
+	}
+	return m, nil
+}
+
+func (m *model) viewReview() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	buffer.WriteString(screenTitleStyle.Render("Review"))
+	buffer.WriteString("\n\n")
+	rows := pendingRows(m.expenses)
+	switch {
+	case len(rows) > 0:
+	case cfg.Owner == "":
+		buffer.WriteString("Changes apply straight away, and only expenses read from receipts and e-receipts wait here. Set \"owner\" in the config file to have the owner approve everyone else's.\n")
+	default:
+		buffer.WriteString("  No changes are waiting for approval.\n")
+	}
+	for i, index := range rows {
+		line := describePending(m.expenses[index])
+		if i == m.reviewRow {
+			buffer.WriteString(selectedItemStyle.Render("> "+line) + "\n")
+		} else {
+			buffer.WriteString(itemStyle.Render(line) + "\n")
+		}
+	}
+	if needsApproval() {
+		buffer.WriteString(fmt.Sprintf("\nYour changes wait here until %s approves them.\n", cfg.Owner))
+		buffer.WriteString("\nUse ↑/↓ to move, 'b' to go back.\n")
+	} else {
+		buffer.WriteString("\nUse ↑/↓ to move, 'y' to approve, 'r' to reject, 'b' to go back.\n")
+	}
+	buffer.WriteString(m.statusLine())
+	return buffer.String()
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xuri/excelize/v2"
+)
+
+// archiveSheetPrefix names the sheets archived expenses are moved to, one
+// per year, e.g. "Archive 2024". They are laid out like Expenses but never
+// read by the everyday load.
+const archiveSheetPrefix = "Archive "
+
+// archiveCutoff is the first day of the month months ago; expenses dated
+// before it are archived.
+func archiveCutoff(now time.Time, months int) time.Time {
+	return time.Date(now.Year(), now.Month()-time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func archiveSheets(f *excelize.File) []string {
+	var sheets []string
+	for _, name := range f.GetSheetList() {
+		if strings.HasPrefix(name, archiveSheetPrefix) {
+			sheets = append(sheets, name)
+		}
+	}
+	sort.Strings(sheets)
+	return sheets
+}
+
+// archiveExpenses moves the expenses dated before cutoff out of the
+// Expenses sheet into the archive sheet of their year, returning how many
+// were moved. Undated expenses are never archived.
+func archiveExpenses(filename string, cutoff time.Time) (int, error) {
+	if _, err := upgradeWorkbook(filename); err != nil {
+		return 0, err
+	}
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows("Expenses")
+	if err != nil {
+		return 0, err
+	}
+	expenses, err := readExpenses(f)
+	if err != nil {
+		return 0, err
+	}
+	keep, audit, err := moveToArchive(f, expenses, len(rows), cutoff)
+	if err != nil || len(audit) == 0 {
+		return 0, err
+	}
+	if err := writeExpenseTotal(f, keep); err != nil {
+		return 0, err
+	}
+	if err := appendAudit(f, audit); err != nil {
+		return 0, err
+	}
+
+	// Sessions holding the old rows must reload before saving over them.
+	current, _ := strconv.Atoi(getMeta(f, "revision"))
+	if err := setMeta(f, "revision", strconv.Itoa(current+1)); err != nil {
+		return 0, err
+	}
+	return len(audit), f.Save()
+}
+
+// moveToArchive moves the expenses of the open workbook f dated before
+// cutoff into the archive sheet of their year, compacting the Expenses
+// sheet, whose rows run to last. It returns the expenses left there and a
+// change for each one moved, leaving the total and audit log to the
+// caller.
+func moveToArchive(f *excelize.File, expenses []Expense, last int, cutoff time.Time) (keep []Expense, audit []rowChange, err error) {
+	var old []Expense
+	for _, e := range expenses {
+		if !e.Date.IsZero() && e.Date.Before(cutoff) {
+			old = append(old, e)
+		} else {
+			keep = append(keep, e)
+		}
+	}
+	if len(old) == 0 {
+		return expenses, nil, nil
+	}
+
+	next := make(map[string]int)
+	for _, e := range old {
+		sheet := archiveSheetPrefix + strconv.Itoa(e.Date.Year())
+		if _, ok := next[sheet]; !ok {
+			if next[sheet], err = openArchiveSheet(f, sheet); err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := writeExpenseRow(f, sheet, next[sheet], e); err != nil {
+			return nil, nil, err
+		}
+		next[sheet]++
+		audit = append(audit, rowChange{kind: rowChanged, sheet: "Expenses", key: expenseKey(e), before: expenseSummary(e), after: "moved to " + sheet})
+	}
+
+	if err := compactExpenses(f, keep, last); err != nil {
+		return nil, nil, err
+	}
+	return keep, audit, nil
+}
+
+// compactExpenses writes keep to the top of the Expenses sheet of f and
+// blanks the rows after them, up to last.
+func compactExpenses(f *excelize.File, keep []Expense, last int) error {
+	for i, e := range keep {
+		if err := writeExpenseRow(f, "Expenses", i+2, e); err != nil {
+			return err
+		}
+	}
+	blank := make([]any, len(sheetHeaders["Expenses"]))
+	for row := len(keep) + 2; row <= last; row++ {
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		if err := f.SetSheetRow("Expenses", cell, &blank); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openArchiveSheet creates sheet if needed and returns its first free row.
+func openArchiveSheet(f *excelize.File, sheet string) (int, error) {
+	if idx, _ := f.GetSheetIndex(sheet); idx == -1 {
+		if _, err := f.NewSheet(sheet); err != nil {
+			return 0, err
+		}
+		header := sheetHeaders["Expenses"]
+		if err := f.SetSheetRow(sheet, "A1", &header); err != nil {
+			return 0, err
+		}
+		return 2, nil
+	}
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return 0, err
+	}
+	return len(rows) + 1, nil
+}
+
+// readArchive reads every archived expense of filename, oldest year first.
+func readArchive(filename string) ([]Expense, error) {
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var archived []Expense
+	for _, sheet := range archiveSheets(f) {
+		rows, err := readExpenseRows(f, sheet)
+		if err != nil {
+			return nil, fmt.Errorf("%s sheet: %w", sheet, err)
+		}
+		archived = append(archived, rows...)
+	}
+	return archived, nil
+}
+
+type archiveMsg struct {
+	expenses []Expense
+}
+
+func loadArchiveCmd(filename string) tea.Cmd {
+	return func() tea.Msg {
+		archived, err := readArchive(filename)
+		if err != nil {
+			return errMsg{err}
+		}
+		return archiveMsg{expenses: archived}
+	}
+}
+
+// runArchive moves expenses older than --months into the archive sheets.
+func runArchive(args []string) error {
+	flags := flag.NewFlagSet("archive", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "workbook to archive")
+	months := flags.Int("months", 12, "keep expenses from this many months back, plus the current month")
+	flags.Parse(args)
+	if *months < 0 {
+		return errors.New("archive: --months must not be negative")
+	}
+
+	cutoff := archiveCutoff(now(), *months)
+	moved, err := archiveExpenses(*file, cutoff)
+	if err != nil {
+		return err
+	}
+	if moved == 0 {
+		fmt.Printf("No expenses dated before %s.\n", formatExpenseDate(cutoff))
+		return nil
+	}
+	fmt.Printf("Archived %d expenses dated before %s.\n", moved, formatExpenseDate(cutoff))
+	return nil
+}
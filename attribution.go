@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"strings"
+)
+
+// currentUser is who new expenses are entered by: the configured user, or
+// else the login name of whoever runs tet.
+func currentUser() string {
+	if cfg.User != "" {
+		return cfg.User
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		// Windows usernames come as DOMAIN\name.
+		name := u.Username
+		if i := strings.LastIndex(name, `\`); i >= 0 {
+			name = name[i+1:]
+		}
+		return name
+	}
+	return os.Getenv("USER")
+}
+
+// enteredByNames lists who entered expenses, sorted, leaving out rows
+// entered before attribution existed.
+func enteredByNames(expenses []Expense) []string {
+	var names []string
+	for _, e := range expenses {
+		if e.EnteredBy != "" && !slices.Contains(names, e.EnteredBy) {
+			names = append(names, e.EnteredBy)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// nextEnteredBy is the filter after current: each person in turn, then
+// everyone again.
+func nextEnteredBy(current string, names []string) string {
+	i := slices.Index(names, current)
+	if i+1 < len(names) {
+		return names[i+1]
+	}
+	return ""
+}
+
+// transfer is a payment settling up a shared book.
+type transfer struct {
+	from, to string
+	amount   float64
+}
+
+// settlement works out who owes whom for the net spending of expenses,
+// split equally between everyone who entered any: whoever paid more than
+// their share is paid back by those who paid less. Unattributed rows are
+// left out.
+func settlement(expenses []Expense) []transfer {
+	names := enteredByNames(expenses)
+	if len(names) < 2 {
+		return nil
+	}
+	paid := make(map[string]float64, len(names))
+	var total float64
+	for _, name := range names {
+		var rows []Expense
+		for _, e := range expenses {
+			if e.EnteredBy == name {
+				rows = append(rows, e)
+			}
+		}
+		paid[name] = -totalsOf(rows).netSpend()
+		total += paid[name]
+	}
+	share := total / float64(len(names))
+
+	// Pay the largest debts to the largest credits first, which keeps the
+	// number of transfers down.
+	balance := make(map[string]float64, len(names))
+	for _, name := range names {
+		balance[name] = cfg.Rounding.round(paid[name] - share)
+	}
+	var transfers []transfer
+	for {
+		debtor, creditor := names[0], names[0]
+		for _, name := range names {
+			if balance[name] < balance[debtor] {
+				debtor = name
+			}
+			if balance[name] > balance[creditor] {
+				creditor = name
+			}
+		}
+		amount := cfg.Rounding.round(min(-balance[debtor], balance[creditor]))
+		if amount <= 0 {
+			return transfers
+		}
+		transfers = append(transfers, transfer{from: debtor, to: creditor, amount: amount})
+		balance[debtor] = cfg.Rounding.round(balance[debtor] + amount)
+		balance[creditor] = cfg.Rounding.round(balance[creditor] - amount)
+	}
+}
+
+// settlementLine sums up settlement for the Expenses screen.
+func settlementLine(expenses []Expense) string {
+	transfers := settlement(expenses)
+	if len(transfers) == 0 {
+		if len(enteredByNames(expenses)) > 1 {
+			return "Settle up: everyone has paid their share.\n"
+		}
+		return ""
+	}
+	parts := make([]string, len(transfers))
+	for i, t := range transfers {
+		parts[i] = fmt.Sprintf("%s owes %s %s", t.from, t.to, money(t.amount))
+	}
+	return "Settle up: " + strings.Join(parts, ", ") + ".\n"
+}
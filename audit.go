@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xuri/excelize/v2"
+)
+
+// auditSheet is a hidden log of the changes written to the workbook, one
+// row per row changed, oldest first. It is only ever appended to.
+const auditSheet = "Audit"
+
+var auditHeaders = []string{"Time", "Source", "User", "Sheet", "Change", "Row", "Old Value", "New Value"}
+
+// auditSource is where this process writes changes from, recorded with
+// each of them: a command unless main or `tet serve` say otherwise.
+var auditSource = "CLI"
+
+// auditEntry is a row of the Audit sheet.
+type auditEntry struct {
+	Time   time.Time
+	Source string
+	User   string
+	Sheet  string
+	Change string
+	// Row names the row changed, an expense's name or a symbol.
+	Row      string
+	Old, New string
+}
+
+func (k changeKind) auditName() string {
+	switch k {
+	case rowAdded:
+		return "added"
+	case rowRemoved:
+		return "removed"
+	default:
+		return "changed"
+	}
+}
+
+// withoutQuote leaves out what quote refreshes change, so that they are
+// not logged as edits.
+func withoutQuote(s Stonk) Stonk {
+	s.Price, s.Open, s.DayChange = 0, 0, 0
+	return s
+}
+
+// holdingSummary is stonkSummary without the prices quote refreshes keep
+// changing.
+func holdingSummary(s Stonk) string {
+	str := fmt.Sprintf("%s qty %g avg %g", s.Symbol, s.Quantity, s.AvgPrice)
+	if s.Currency != "" {
+		str += " " + s.Currency
+	}
+	if s.Comment != "" {
+		str += fmt.Sprintf(" %q", s.Comment)
+	}
+	return str
+}
+
+// auditChanges lists what writing expenses, stonks and watchList over the
+// rows read from f changes, for the sheets being written (those not nil).
+// Prices are left out: they change with every quote refresh.
+func auditChanges(f *excelize.File, expenses []Expense, stonks []Stonk, watchList []WatchItem) ([]rowChange, error) {
+	var changes []rowChange
+	if expenses != nil {
+		old, err := readExpenses(f)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, diffRows("Expenses", old, expenses, expenseKey, expenseSummary)...)
+	}
+	if stonks != nil {
+		old, err := readStonks(f)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, diffRows("Stonks", mapSlice(old, withoutQuote), mapSlice(stonks, withoutQuote), stonkKey, holdingSummary)...)
+	}
+	if watchList != nil {
+		old, err := readWatchList(f)
+		if err != nil {
+			return nil, err
+		}
+		unpriced := func(w WatchItem) WatchItem {
+			w.Price, w.legacyOwned = 0, false
+			return w
+		}
+		changes = append(changes, diffRows("WatchList", mapSlice(old, unpriced), mapSlice(watchList, unpriced), watchKey, watchSummary)...)
+	}
+	return changes, nil
+}
+
+func mapSlice[T any](s []T, fn func(T) T) []T {
+	out := make([]T, len(s))
+	for i, v := range s {
+		out[i] = fn(v)
+	}
+	return out
+}
+
+// appendAudit logs changes in the Audit sheet of f, creating it hidden
+// the first time.
+func appendAudit(f *excelize.File, changes []rowChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	created := false
+	if idx, _ := f.GetSheetIndex(auditSheet); idx == -1 {
+		created = true
+	}
+	row, err := openSheet(f, auditSheet, auditHeaders)
+	if err != nil {
+		return err
+	}
+	if created {
+		if err := f.SetSheetVisible(auditSheet, false); err != nil {
+			return err
+		}
+	}
+	stamp := now().Format(time.RFC3339)
+	user := currentUser()
+	for _, c := range changes {
+		values := []any{stamp, auditSource, user, c.sheet, c.kind.auditName(), c.key, c.before, c.after}
+		if err := f.SetSheetRow(auditSheet, fmt.Sprintf("A%d", row), &values); err != nil {
+			return err
+		}
+		row++
+	}
+	return nil
+}
+
+// readAudit reads the Audit sheet, newest first.
+func readAudit(f *excelize.File) ([]auditEntry, error) {
+	if idx, _ := f.GetSheetIndex(auditSheet); idx == -1 {
+		return nil, nil
+	}
+	rows, err := f.GetRows(auditSheet)
+	if err != nil {
+		return nil, err
+	}
+	var entries []auditEntry
+	for i := len(rows) - 1; i >= 1; i-- {
+		row := append(rows[i], make([]string, len(auditHeaders))...)
+		t, err := time.Parse(time.RFC3339, row[0])
+		if err != nil {
+			continue
+		}
+		entries = append(entries, auditEntry{
+			Time: t, Source: row[1], User: row[2], Sheet: row[3], Change: row[4], Row: row[5], Old: row[6], New: row[7],
+		})
+	}
+	return entries, nil
+}
+
+type auditMsg struct {
+	entries []auditEntry
+	err     error
+}
+
+func loadAuditCmd(filename string) tea.Cmd {
+	return func() tea.Msg {
+		if !isWorkbook(filename) {
+			return auditMsg{err: fmt.Errorf("the audit log is kept in .xlsx workbooks only")}
+		}
+		f, err := openWorkbook(filename)
+		if err != nil {
+			return auditMsg{err: err}
+		}
+		defer f.Close()
+		entries, err := readAudit(f)
+		if err != nil {
+			err = fmt.Errorf("%s sheet: %w", auditSheet, err)
+		}
+		return auditMsg{entries: entries, err: err}
+	}
+}
+
+// auditPageSize is how many entries the Audit Log screen shows at once.
+const auditPageSize = 15
+
+func (m *model) updateAudit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "b", "esc":
+		m.currentScreen = screenMenu
+	case "up":
+		if m.auditRow > 0 {
+			m.auditRow--
+		}
+	case "down":
+		if m.auditRow < len(m.auditEntries)-1 {
+			m.auditRow++
+		}
+	case "pgup":
+		m.auditRow = max(m.auditRow-auditPageSize, 0)
+	case "pgdown":
+		m.auditRow = max(min(m.auditRow+auditPageSize, len(m.auditEntries)-1), 0)
+	}
+	return m, nil
+}
+
+func (m *model) viewAudit() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	buffer.WriteString(screenTitleStyle.Render("Audit Log"))
+	buffer.WriteString("\n\n")
+	switch {
+	case m.auditErr != nil:
+		buffer.WriteString(errorBannerStyle.Render("⚠ "+m.auditErr.Error()) + "\n")
+	case len(m.auditEntries) == 0:
+		buffer.WriteString("No changes have been logged yet. Every change saved to the workbook from now on is listed here, newest first.\n")
+	default:
+		first := max(min(m.auditRow-auditPageSize/2, len(m.auditEntries)-auditPageSize), 0)
+		last := min(first+auditPageSize, len(m.auditEntries))
+		for i := first; i < last; i++ {
+			line := auditLine(m.auditEntries[i])
+			if i == m.auditRow {
+				buffer.WriteString(selectedItemStyle.Render("> "+line) + "\n")
+			} else {
+				buffer.WriteString(itemStyle.Render(line) + "\n")
+			}
+		}
+		buffer.WriteString(fmt.Sprintf("\n%d of %d changes.\n", m.auditRow+1, len(m.auditEntries)))
+	}
+	buffer.WriteString("\nUse ↑/↓ or pgup/pgdown to move, 'b' to go back.\n")
+	return buffer.String()
+}
+
+// auditLine is an entry on one line: when, from where and by whom, and
+// what changed.
+func auditLine(e auditEntry) string {
+	var change string
+	switch e.Change {
+	case "added":
+		change = "+ " + e.New
+	case "removed":
+		change = "- " + e.Old
+	default:
+		change = fmt.Sprintf("~ %s → %s", e.Old, e.New)
+	}
+	return strings.Join([]string{e.Time.Local().Format("2006-01-02 15:04"), e.Source, e.User, e.Sheet, change}, "  ")
+}
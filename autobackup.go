@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupsConfig sets how many copies of the workbook are kept in the
+// backups directory beside it, one taken before every save that changes
+// more than prices.
+type backupsConfig struct {
+	// Keep is the number of copies kept, the oldest being deleted; 0 turns
+	// the copies off.
+	Keep int `json:"keep"`
+}
+
+func (c backupsConfig) validate() error {
+	if c.Keep < 0 {
+		return errors.New("backups.keep must not be negative")
+	}
+	return nil
+}
+
+// autoBackupDir is the directory, beside the data file, the copies go in.
+const autoBackupDir = "backups"
+
+// autoBackupLayout stamps each copy with the time it was taken, to the
+// millisecond so that quick saves in a row do not collide.
+const autoBackupLayout = "20060102-150405.000"
+
+// autoBackupPath is the copy of path taken at t: data.xlsx is copied to
+// backups/data-20250131-093000.000.xlsx. It keeps the extension, so the
+// copy opens with the backend of the original.
+func autoBackupPath(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	return filepath.Join(filepath.Dir(path), autoBackupDir, base+"-"+t.Format(autoBackupLayout)+ext)
+}
+
+// listAutoBackups returns the copies of path, newest first.
+func listAutoBackups(path string) []string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	matches, _ := files.Glob(filepath.Join(filepath.Dir(path), autoBackupDir, base+"-*"+ext))
+	var backups []string
+	for _, m := range matches {
+		// data-2.xlsx's copies match data-*.xlsx too.
+		stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), base+"-"), ext)
+		if _, err := time.Parse(autoBackupLayout, stamp); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// backupBeforeWrite copies path into the backups directory before it is
+// saved over, deleting the oldest copies beyond the configured number.
+func backupBeforeWrite(path string) error {
+	keep := cfg.Backups.Keep
+	if keep == 0 {
+		return nil
+	}
+	if err := files.MkdirAll(filepath.Join(filepath.Dir(path), autoBackupDir), 0o755); err != nil {
+		return err
+	}
+	if err := copyFile(path, autoBackupPath(path, now())); err != nil {
+		return err
+	}
+	backups := listAutoBackups(path)
+	for _, old := range backups[min(keep, len(backups)):] {
+		if err := files.Remove(old); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// backup is the document `tet export --format json` writes and `tet import
+// --format json` restores: the whole Dataset in the model's own types, so
+// fields added to them are carried along, with the expense totals for
+// reference. Restoring ignores the totals and the revision.
+type backup struct {
+	Exported time.Time `json:"exported"`
+	Dataset
+	Totals backupTotals `json:"totals"`
+}
+
+type backupTotals struct {
+	Income   float64 `json:"income"`
+	Spent    float64 `json:"spent"`
+	Refunds  float64 `json:"refunds"`
+	NetSpend float64 `json:"netSpend"`
+}
+
+func newBackup(data Dataset) backup {
+	t := totalsOf(data.Expenses)
+	return backup{
+		Exported: now().UTC().Truncate(time.Second),
+		Dataset:  data,
+		Totals:   backupTotals{Income: t.income, Spent: t.spent, Refunds: t.refunds, NetSpend: t.netSpend()},
+	}
+}
+
+// backupPath is the default backup of the data file at path, named for
+// today: data.xlsx is backed up to data-backup-2025-01-31.json beside it.
+func backupPath(path string) string {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	return base + "-backup-" + today().Format(dateLayout) + ".json"
+}
+
+// runExportJSON writes a backup of file to out, refusing to overwrite an
+// existing one.
+func runExportJSON(file, out string) error {
+	s, err := readOnlyStoreForPath(file)
+	if err != nil {
+		return err
+	}
+	data, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		out = backupPath(file)
+	}
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf("export: %s already exists, refusing to overwrite it", out)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	b, err := json.MarshalIndent(newBackup(data), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(out, append(b, '\n')); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %s to %s.\n", backupSummary(data), out)
+	return nil
+}
+
+func readBackup(path string) (Dataset, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Dataset{}, err
+	}
+	var bk backup
+	if err := json.Unmarshal(b, &bk); err != nil {
+		return Dataset{}, fmt.Errorf("import: %s: %w", filepath.Base(path), err)
+	}
+	linkLegacyOwned(&bk.Dataset)
+	return bk.Dataset, nil
+}
+
+// backupSummary says what data holds, for the messages of export and
+// import.
+func backupSummary(data Dataset) string {
+	summary := fmt.Sprintf("%d expenses, %d positions and %d watchlist items", len(data.Expenses), len(data.Stonks), len(data.WatchList))
+	if n := len(data.Sheets); n == 1 {
+		summary += " with 1 other sheet"
+	} else if n > 1 {
+		summary += fmt.Sprintf(" with %d other sheets", n)
+	}
+	return summary
+}
+
+// restoreBackup replaces the expenses, positions and watchlist of file, and
+// every other sheet the backup has, with those of the backup at path. The file is copied aside first, where the
+// Snapshots screen lists it.
+func restoreBackup(file, path string, dryRun bool) error {
+	restored, err := readBackup(path)
+	if err != nil {
+		return err
+	}
+	summary := backupSummary(restored)
+	if dryRun {
+		fmt.Printf("Dry run, nothing written. Would restore %s from %s.\n", summary, path)
+		return nil
+	}
+	s, err := storeForPath(file)
+	if err != nil {
+		return err
+	}
+	var current Dataset
+	if _, err := os.Stat(file); err == nil {
+		if current, err = s.Load(); err != nil {
+			return err
+		}
+		saved := fmt.Sprintf("%s.restore-%s.bak", file, now().Format("20060102-150405"))
+		if err := copyFile(file, saved); err != nil {
+			return fmt.Errorf("import: backing up before restoring: %w", err)
+		}
+		fmt.Printf("Saved the current data as %s.\n", saved)
+	}
+	// Saving skips sheets passed as nil, but an empty list in a backup
+	// means the sheet is to be emptied.
+	if restored.Expenses == nil {
+		restored.Expenses = []Expense{}
+	}
+	if restored.Stonks == nil {
+		restored.Stonks = []Stonk{}
+	}
+	if restored.WatchList == nil {
+		restored.WatchList = []WatchItem{}
+	}
+	restored.Revision = current.Revision
+	if err := s.Save(restored); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s from %s.\n", summary, path)
+	return nil
+}
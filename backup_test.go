@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportJSONRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	file, out := filepath.Join(dir, "data.xlsx"), filepath.Join(dir, "backup.json")
+	if err := generateWorkbook(file, fixtureOptions{rows: 60, seed: 5, months: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runExportJSON(file, out); err != nil {
+		t.Fatal(err)
+	}
+	want, err := (&excelStore{path: file}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readBackup(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := fixtureRows(got.Expenses), fixtureRows(want.Expenses); !reflect.DeepEqual(g, w) {
+		t.Errorf("the backup's expenses differ from the workbook's:\n got %q\nwant %q", g, w)
+	}
+}
+
+// TestExportJSONKeepsEverySheet restores a backup into a new workbook and
+// expects every sheet with rows, the tracker's own and any added after the
+// backup was written, to come back as it was.
+func TestExportJSONKeepsEverySheet(t *testing.T) {
+	dir := t.TempDir()
+	file, out, restored := filepath.Join(dir, "data.xlsx"), filepath.Join(dir, "backup.json"), filepath.Join(dir, "restored.xlsx")
+	if err := generateWorkbook(file, fixtureOptions{rows: 10, seed: 5, months: 1}); err != nil {
+		t.Fatal(err)
+	}
+	sheets := []string{budgetsSheet, historySheet, accountsSheet, transfersSheet, goalsSheet,
+		wishlistSheet, fxSheet, auditSheet, recurringSheet, pricesSheet, wishPricesSheet, "Sheet Added Later"}
+	for _, sheet := range sheets {
+		addSheetRow(t, file, sheet)
+	}
+	if err := runExportJSON(file, out); err != nil {
+		t.Fatal(err)
+	}
+	bk, err := readBackup(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sheet := range sheets {
+		if _, ok := bk.Sheets[sheet]; !ok {
+			t.Errorf("the backup has no %s sheet", sheet)
+		}
+	}
+
+	if err := restoreBackup(restored, out, false); err != nil {
+		t.Fatal(err)
+	}
+	want, err := readOtherSheets(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readOtherSheets(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sheet := range sheets {
+		rows := got[sheet]
+		// The restore itself is audited below the restored rows.
+		if sheet == auditSheet && len(rows) > len(want[sheet]) {
+			rows = rows[:len(want[sheet])]
+		}
+		if !reflect.DeepEqual(rows, want[sheet]) {
+			t.Errorf("%s was restored as\n%q\nwant\n%q", sheet, rows, want[sheet])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runBench generates workbooks of increasing size and times loading,
+// saving and rendering them, failing if the largest one loads slower than
+// the budget.
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	sizes := flags.String("rows", "1000,10000,50000", "comma-separated expense row counts to generate")
+	runs := flags.Int("runs", 3, "timed runs per size; the fastest is reported")
+	budget := flags.Duration("budget", time.Second, "maximum load time for the largest workbook")
+	keep := flags.Bool("keep", false, "keep the generated workbooks")
+	cpuProfile := flags.String("cpuprofile", "", "write a CPU profile of the timed runs to this file")
+	flags.Parse(args)
+
+	if *cpuProfile != "" {
+		out, err := os.Create(*cpuProfile)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		if err := pprof.StartCPUProfile(out); err != nil {
+			return err
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	dir, err := os.MkdirTemp("", "tet-bench")
+	if err != nil {
+		return err
+	}
+	if *keep {
+		fmt.Printf("Workbooks are kept in %s\n", dir)
+	} else {
+		defer os.RemoveAll(dir)
+	}
+
+	var counts []int
+	for _, s := range strings.Split(*sizes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("bench: invalid row count %q", s)
+		}
+		counts = append(counts, n)
+	}
+
+	fmt.Printf("%8s %10s %10s %10s\n", "rows", "load", "save", "render")
+	var lastLoad time.Duration
+	for _, n := range counts {
+		path := filepath.Join(dir, fmt.Sprintf("bench-%d.xlsx", n))
+		if err := generateWorkbook(path, fixtureOptions{rows: n, seed: 1}); err != nil {
+			return err
+		}
+		var load, save, render time.Duration
+		for i := 0; i < *runs; i++ {
+			l, s, r, err := benchOnce(path)
+			if err != nil {
+				return err
+			}
+			if i == 0 || l < load {
+				load = l
+			}
+			if i == 0 || s < save {
+				save = s
+			}
+			if i == 0 || r < render {
+				render = r
+			}
+		}
+		fmt.Printf("%8d %10s %10s %10s\n", n, ms(load), ms(save), ms(render))
+		lastLoad = load
+	}
+
+	if lastLoad > *budget {
+		return fmt.Errorf("bench: loading %d rows took %s, over the %s budget", counts[len(counts)-1], ms(lastLoad), budget)
+	}
+	fmt.Printf("Largest workbook loaded within the %s budget.\n", budget)
+	return nil
+}
+
+// benchOnce times one load, save and render cycle of the workbook at path.
+func benchOnce(path string) (load, save, render time.Duration, err error) {
+	start := time.Now()
+	data, err := readExcelData(path)
+	if err != nil {
+		return
+	}
+	load = time.Since(start)
+
+	start = time.Now()
+	if err = writeExcelData(path, data.revision, data.expenses, data.stonks, data.watchList, nil); err != nil {
+		return
+	}
+	save = time.Since(start)
+
+	m := &model{
+		currentScreen: screenExpenses,
+		expenses:      data.expenses,
+		stonks:        data.stonks,
+		watchList:     data.watchList,
+	}
+	start = time.Now()
+	m.updateExpensesTable()
+	m.View()
+	render = time.Since(start)
+	return
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+	"github.com/xuri/excelize/v2"
+)
+
+// budgetsSheet holds a monthly budget per category. It is edited in the
+// workbook and, like the Prices sheet, only read by the screen using it.
+const budgetsSheet = "Budgets"
+
+var budgetsHeaders = []string{"Category", "Budget", "Rollover", "Icon"}
+
+// budget is a row of the Budgets sheet.
+type budget struct {
+	Category string
+	// Limit is what may be spent each month, a positive amount.
+	Limit float64
+	// Rollover carries what is left of a month's budget into the next
+	// one, and takes overspending off it.
+	Rollover bool
+	// Icon is shown beside the category, such as an emoji; optional.
+	Icon string
+}
+
+// categoryOf is the budget category an expense counts towards: its
+// Category or else its name, matched case-insensitively.
+func categoryOf(e Expense) string {
+	if e.Category != "" {
+		return categoryKey(e.Category)
+	}
+	return categoryKey(e.Name)
+}
+
+// budgetCategories are the categories of budgets, in order.
+func budgetCategories(budgets []budget) []string {
+	categories := make([]string, len(budgets))
+	for i, b := range budgets {
+		categories[i] = b.Category
+	}
+	return categories
+}
+
+// parseRollover reads the Rollover column: "Yes", "Y", "True", "X" or "1"
+// in any case turn it on.
+func parseRollover(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "y", "true", "x", "1":
+		return true
+	}
+	return false
+}
+
+// readBudgets reads the Budgets sheet, skipping rows without a category.
+// A workbook without one has no budgets.
+func readBudgets(f *excelize.File) ([]budget, bool, error) {
+	return readBudgetSheet(f, budgetsSheet)
+}
+
+// readBudgetSheet reads sheet laid out like the Budgets sheet, such as a
+// scenario's.
+func readBudgetSheet(f *excelize.File, sheet string) ([]budget, bool, error) {
+	if idx, _ := f.GetSheetIndex(sheet); idx == -1 {
+		return nil, false, nil
+	}
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, true, err
+	}
+	var budgets []budget
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		b := budget{Category: strings.TrimSpace(row[0])}
+		if len(row) > 1 {
+			limit, _ := strconv.ParseFloat(row[1], 64)
+			b.Limit = cfg.Rounding.round(limit)
+		}
+		if len(row) > 2 {
+			b.Rollover = parseRollover(row[2])
+		}
+		if len(row) > 3 {
+			b.Icon = strings.TrimSpace(row[3])
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, true, nil
+}
+
+type budgetsMsg struct {
+	budgets []budget
+	// found is false when the workbook has no Budgets sheet.
+	found     bool
+	scenarios []budgetScenario
+	err       error
+}
+
+func loadBudgetsCmd(filename string) tea.Cmd {
+	return func() tea.Msg {
+		f, err := openWorkbook(filename)
+		if err != nil {
+			return budgetsMsg{err: err}
+		}
+		defer f.Close()
+		budgets, found, err := readBudgets(f)
+		if err != nil {
+			return budgetsMsg{err: fmt.Errorf("%s sheet: %w", budgetsSheet, err)}
+		}
+		scenarios, err := readScenarios(f)
+		return budgetsMsg{budgets: budgets, found: found, scenarios: scenarios, err: err}
+	}
+}
+
+// monthOf is the month t counts towards, identified by its first day: its
+// calendar month or, with period.start_day set, the month its budgeting
+// period is named after.
+func monthOf(t time.Time) time.Time {
+	month := calendarMonth(t)
+	if !cfg.Period.custom() {
+		return month
+	}
+	if t.Day() >= cfg.Period.StartDay {
+		month = month.AddDate(0, 1, 0)
+	}
+	if cfg.Period.StartDay <= 15 {
+		month = month.AddDate(0, -1, 0)
+	}
+	return month
+}
+
+// monthlySpending sums the net spending of each category per month.
+// Refunds take off what was spent, and income is no spending; undated and
+// pending rows count towards no month.
+func monthlySpending(expenses []Expense) map[string]map[time.Time]float64 {
+	spending := make(map[string]map[time.Time]float64)
+	for _, e := range expenses {
+		if e.Date.IsZero() || e.Pending != "" {
+			continue
+		}
+		var spent float64
+		switch {
+		case e.Kind == kindRefund:
+			// Refunds are written with either sign.
+			spent = -math.Abs(e.Amount)
+		case e.Amount < 0:
+			spent = -e.Amount
+		default:
+			// Income to a category does not make up for its spending.
+			continue
+		}
+		key := categoryOf(e)
+		if spending[key] == nil {
+			spending[key] = make(map[time.Time]float64)
+		}
+		spending[key][monthOf(e.Date)] += spent
+	}
+	return spending
+}
+
+// spendingWithin sums the monthly spending of the category with key and
+// its subcategories.
+func spendingWithin(spending map[string]map[time.Time]float64, key string) map[time.Time]float64 {
+	within := make(map[time.Time]float64)
+	for category, byMonth := range spending {
+		if !withinCategory(category, key) {
+			continue
+		}
+		for m, spent := range byMonth {
+			within[m] += spent
+		}
+	}
+	return within
+}
+
+// budgetStatus is how a category stands in one month.
+type budgetStatus struct {
+	budget
+	// carried is what rollover brought from earlier months: unspent
+	// budget, or overspending as a negative amount.
+	carried float64
+	spent   float64
+}
+
+func (s budgetStatus) available() float64 {
+	return cfg.Rounding.round(s.Limit + s.carried)
+}
+
+func (s budgetStatus) left() float64 {
+	return cfg.Rounding.round(s.available() - s.spent)
+}
+
+// budgetStatuses works out each budget in month. A rollover category
+// carries its balance from the first month it has spending in, so a
+// budget added later does not bank the months before it was used.
+func budgetStatuses(budgets []budget, expenses []Expense, month time.Time) []budgetStatus {
+	spending := monthlySpending(expenses)
+	month = calendarMonth(month)
+	statuses := make([]budgetStatus, 0, len(budgets))
+	for _, b := range budgets {
+		s := budgetStatus{budget: b}
+		byMonth := spendingWithin(spending, categoryKey(b.Category))
+		if b.Rollover {
+			first := month
+			for m := range byMonth {
+				if m.Before(first) {
+					first = m
+				}
+			}
+			for m := first; m.Before(month); m = m.AddDate(0, 1, 0) {
+				s.carried += b.Limit - byMonth[m]
+			}
+			s.carried = cfg.Rounding.round(s.carried)
+		}
+		s.spent = cfg.Rounding.round(byMonth[month])
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+func (m *model) updateBudgets(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.monthPicker.open {
+		if month := m.monthPicker.update(msg.String()); !month.IsZero() {
+			m.budgetMonth = month
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "b":
+		m.currentScreen = screenMenu
+	case "left":
+		m.budgetMonth = m.budgetMonth.AddDate(0, -1, 0)
+	case "right":
+		m.budgetMonth = m.budgetMonth.AddDate(0, 1, 0)
+	case "m":
+		m.monthPicker.show(m.budgetMonth)
+	case "v":
+		m.budgetVariance, m.budgetCompare = !m.budgetVariance, false
+	case "s":
+		m.budgetCompare, m.budgetVariance = !m.budgetCompare, false
+	case "E":
+		if m.budgetVariance {
+			return m, m.exportVarianceCmd()
+		}
+	}
+	return m, nil
+}
+
+func (m *model) viewBudgets() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	title := "Budgets — "
+	switch {
+	case m.budgetVariance:
+		title = "Budget Variance — "
+	case m.budgetCompare:
+		title = "Budget Scenarios — from "
+	}
+	buffer.WriteString(screenTitleStyle.Render(title + periodName(m.budgetMonth)))
+	buffer.WriteString("\n\n")
+	buffer.WriteString(m.sheetErrorBanner(sheetExpenses))
+	if m.monthPicker.open {
+		buffer.WriteString(m.monthPicker.view(m.budgetMonth))
+		return buffer.String()
+	}
+	switch {
+	case m.budgetsErr != nil:
+		buffer.WriteString(errorBannerStyle.Render("⚠ "+m.budgetsErr.Error()) + "\n")
+	case m.budgetCompare:
+		buffer.WriteString(m.viewScenarios())
+	case !m.budgetsFound:
+		buffer.WriteString(fmt.Sprintf("No %s sheet in %s yet. Add one with %s columns: a category per row, matching expense names, its monthly budget, Yes under Rollover to carry unspent budget into the next month, and an optional icon.\n",
+			budgetsSheet, m.path, strings.Join(budgetsHeaders, ", ")))
+	case len(m.budgets) == 0:
+		buffer.WriteString(fmt.Sprintf("The %s sheet has no categories.\n", budgetsSheet))
+	case m.budgetVariance:
+		buffer.WriteString(varianceTable(budgetVariance(m.budgets, m.inBase(m.expenses), m.budgetMonth)))
+		buffer.WriteString("\n" + varianceLegend())
+	default:
+		statuses := budgetStatuses(m.budgets, m.inBase(m.expenses), m.budgetMonth)
+		buffer.WriteString(budgetTable(statuses))
+		// A subcategory's budget is part of its parent's, when that has
+		// one too, and so is its spending.
+		categories := budgetCategories(m.budgets)
+		var available, spent float64
+		for _, s := range statuses {
+			if nestedCategory(s.Category, categories) {
+				continue
+			}
+			available += s.available()
+			spent += s.spent
+		}
+		buffer.WriteString(fmt.Sprintf("\nAvailable %s · Spent %s · Left %s\n",
+			money(available), money(spent), money(cfg.Rounding.round(available-spent))))
+		buffer.WriteString("↻ rolls over: unspent budget carries into the next month and overspending reduces it.\n")
+	}
+	switch {
+	case m.budgetVariance:
+		buffer.WriteString("\nUse ←/→ to change month, 'm' to pick one, 'v' to show the budgets, 'E' to export the report, 'b' to go back.\n")
+	case m.budgetCompare:
+		buffer.WriteString("\nUse ←/→ to change the month projected from, 'm' to pick one, 's' to show the budgets, 'b' to go back.\n")
+	default:
+		buffer.WriteString("\nUse ←/→ to change month, 'm' to pick one, 'v' to compare planned and actual spending, 's' to compare budget scenarios, 'b' to go back.\n")
+	}
+	buffer.WriteString(m.statusLine())
+	return buffer.String()
+}
+
+func budgetTable(statuses []budgetStatus) string {
+	headers := []string{"Category", "Budget", "Carried", "Available", "Spent", "Left"}
+	var data [][]string
+	for _, s := range statuses {
+		category := withIcon(s.icon(), s.Category)
+		carried := ""
+		if s.Rollover {
+			category += " ↻"
+			carried = money(s.carried)
+		}
+		data = append(data, []string{category, money(s.Limit), carried, money(s.available()), money(s.spent), money(s.left())})
+	}
+
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	overStyle := baseStyle.Foreground(lipgloss.Color("203"))
+
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			if col == len(headers)-1 && statuses[row].left() < 0 {
+				return overStyle
+			}
+			return rowStyle
+		})
+	return t.String()
+}
+
+// overBudgetStyle marks categories over budget on the expenses screen.
+var overBudgetStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("203"))
+
+// budgetBarWidth is the width of the bars showing spending against each
+// budget on the expenses screen.
+const budgetBarWidth = 20
+
+// budgetBar draws spent against available, full once it is all spent.
+func budgetBar(spent, available float64) string {
+	filled := budgetBarWidth
+	if available > 0 && spent < available {
+		filled = max(0, int(spent/available*budgetBarWidth))
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", budgetBarWidth-filled)
+}
+
+// budgetProgress renders the spending against each budget in month for the
+// expenses screen, the categories over budget in red, along with the
+// warning naming them. Both are empty without budgets.
+func budgetProgress(budgets []budget, expenses []Expense, month time.Time) (progress, warning string) {
+	statuses := budgetStatuses(budgets, expenses, month)
+	if len(statuses) == 0 {
+		return "", ""
+	}
+	labels := make([]string, len(statuses))
+	width, spentWidth, availableWidth := 0, 0, 0
+	for i, s := range statuses {
+		labels[i] = withIcon(s.icon(), s.Category)
+		width = max(width, lipgloss.Width(labels[i]))
+		spentWidth = max(spentWidth, len(money(s.spent)))
+		availableWidth = max(availableWidth, len(money(s.available())))
+	}
+	var b strings.Builder
+	var over []string
+	b.WriteString("Budgets for " + periodName(month) + ":\n")
+	for i, s := range statuses {
+		percent := "—"
+		if s.available() > 0 {
+			percent = fmt.Sprintf("%.0f%%", s.spent/s.available()*100)
+		}
+		line := fmt.Sprintf("  %s%s  %s  %*s of %-*s  %4s", labels[i], strings.Repeat(" ", width-lipgloss.Width(labels[i])),
+			budgetBar(s.spent, s.available()), spentWidth, money(s.spent), availableWidth, money(s.available()), percent)
+		if s.left() < 0 {
+			line = overBudgetStyle.Render(line)
+			over = append(over, s.Category+" by "+money(-s.left()))
+		}
+		b.WriteString(line + "\n")
+	}
+	if len(over) > 0 {
+		warning = overBudgetStyle.Bold(true).Render("⚠ Over budget in "+month.Format("January 2006")+": "+strings.Join(over, ", ")+".") + "\n"
+	}
+	return b.String(), warning
+}
+
+// overBudget marks, by categoryOf and month, the budgets spending went
+// over in each month of rows.
+func overBudget(budgets []budget, expenses, rows []Expense) map[string]map[time.Time]bool {
+	over := make(map[string]map[time.Time]bool)
+	if len(budgets) == 0 {
+		return over
+	}
+	for _, month := range expenseMonths(rows) {
+		for _, s := range budgetStatuses(budgets, expenses, month) {
+			if s.left() < 0 {
+				key := categoryKey(s.Category)
+				if over[key] == nil {
+					over[key] = make(map[time.Time]bool)
+				}
+				over[key][month] = true
+			}
+		}
+	}
+	return over
+}
+
+// overIn reports whether over marks the category with key, or its
+// parent, as over budget in month.
+func overIn(over map[string]map[time.Time]bool, key string, month time.Time) bool {
+	return over[key][month] || over[categoryKey(parentCategory(key))][month]
+}
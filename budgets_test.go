@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMonthlySpendingNetsRefundsNotIncome(t *testing.T) {
+	month := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	day := parseExpenseDate("2026-09-10")
+	expenses := []Expense{
+		{Name: "Groceries", Amount: -120, Date: day, Category: "Food"},
+		{Name: "Shop refund", Amount: 20, Kind: kindRefund, Date: day, Category: "Food"},
+		{Name: "Returned basket", Amount: -5, Kind: kindRefund, Date: day, Category: "Food"},
+		{Name: "Sold leftovers", Amount: 300, Date: day, Category: "Food"},
+		{Name: "Pending", Amount: -50, Pending: "me", Date: day, Category: "Food"},
+	}
+	got := monthlySpending(expenses)[categoryKey("Food")][monthOf(month)]
+	if got != 95 {
+		t.Errorf("Food spending is %v, want 95: the refunds taken off the groceries, the income left out", got)
+	}
+	for _, s := range budgetStatuses([]budget{{Category: "Food", Limit: 100}}, expenses, month) {
+		if s.left() != 5 {
+			t.Errorf("%s has %v left, want 5", s.Category, s.left())
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// bulkOp is a change made to every marked row of the expenses table at
+// once, saved in a single write.
+type bulkOp int
+
+const (
+	bulkDelete bulkOp = iota
+	bulkCategory
+	bulkMove
+)
+
+// bulkMsg answers bulkForm. rows are the indexes in m.expenses the change
+// applies to, with their expenseSummary when the form was shown; rows is
+// nil when the user backed out.
+type bulkMsg struct {
+	op        bulkOp
+	rows      []int
+	summaries []string
+	category  string
+	month     time.Time
+}
+
+// toggleMark marks or unmarks the selected row and moves to the next one.
+// Archived rows cannot be marked.
+func (m *model) toggleMark() {
+	_, index := m.visibleRows()
+	if m.selectedRow >= len(index) || index[m.selectedRow] < 0 {
+		return
+	}
+	i := index[m.selectedRow]
+	if m.marked[i] {
+		delete(m.marked, i)
+	} else {
+		if m.marked == nil {
+			m.marked = make(map[int]bool)
+		}
+		m.marked[i] = true
+	}
+	if m.selectedRow < len(index)-1 {
+		m.selectedRow++
+	}
+	m.updateExpensesTable()
+}
+
+// markedRows returns the indexes of the marked expenses in order.
+func (m *model) markedRows() []int {
+	rows := make([]int, 0, len(m.marked))
+	for i := range m.marked {
+		rows = append(rows, i)
+	}
+	slices.Sort(rows)
+	return rows
+}
+
+// startBulk asks how to apply op to the marked rows.
+func (m *model) startBulk(op bulkOp) (tea.Model, tea.Cmd) {
+	if m.readOnly {
+		m.status = "Read-only session: editing is disabled."
+		return m, nil
+	}
+	rows := m.markedRows()
+	if len(rows) == 0 {
+		m.status = "Mark rows with 'space' first."
+		return m, nil
+	}
+	m.editing = true
+	return m, m.bulkForm(op, rows)
+}
+
+func (m *model) bulkForm(op bulkOp, rows []int) tea.Cmd {
+	msg := bulkMsg{op: op, rows: rows}
+	var total float64
+	for _, i := range rows {
+		msg.summaries = append(msg.summaries, expenseSummary(m.expenses[i]))
+		total += m.expenses[i].Amount
+	}
+	count := fmt.Sprintf("%d expenses", len(rows))
+
+	var confirmed bool
+	var month string
+	var field huh.Field
+	switch op {
+	case bulkDelete:
+		description := fmt.Sprintf("They total %s and are removed from the workbook, which is backed up before saving.", money(total))
+		if needsApproval() {
+			description = fmt.Sprintf("They total %s and stay until %s approves their deletion.", money(total), cfg.Owner)
+		}
+		field = huh.NewConfirm().
+			Title(fmt.Sprintf("Delete %s?", count)).
+			Description(description).
+			Affirmative("Delete").
+			Negative("Keep").
+			Value(&confirmed)
+	case bulkCategory:
+		confirmed = true
+		field = huh.NewSelect[string]().
+			Title("Category of " + count).
+			Options(categoryChoices("", m.expenses, m.budgets)...).
+			Value(&msg.category)
+	case bulkMove:
+		confirmed = true
+		month = monthOf(today()).Format(monthLayout)
+		field = huh.NewInput().
+			Title(fmt.Sprintf("Move %s to the month (YYYY-MM)", count)).
+			Description("They keep their day of the month, or take the month's last day when it has fewer.").
+			Value(&month).
+			Validate(func(s string) error {
+				if _, err := time.Parse(monthLayout, s); err != nil {
+					return fmt.Errorf("%q is not a month; use YYYY-MM", s)
+				}
+				return nil
+			})
+	}
+	form := huh.NewForm(huh.NewGroup(field))
+	return func() tea.Msg {
+		if err := form.Run(); err != nil || !confirmed {
+			return bulkMsg{op: op}
+		}
+		msg.month, _ = time.Parse(monthLayout, month)
+		return msg
+	}
+}
+
+// moveToMonth dates t in month instead, keeping its day when the month
+// has it. An undated expense goes to the month's first day.
+func moveToMonth(t, month time.Time) time.Time {
+	if t.IsZero() {
+		return month
+	}
+	last := month.AddDate(0, 1, -1).Day()
+	return time.Date(month.Year(), month.Month(), min(t.Day(), last), 0, 0, 0, 0, t.Location())
+}
+
+// applyBulk makes the change the user confirmed, unless a row changed
+// while they were asked, unlocking the closed months it touches first.
+func (m *model) applyBulk(msg bulkMsg) (tea.Model, tea.Cmd) {
+	if msg.rows == nil {
+		m.editing = false
+		m.status = "Nothing was changed."
+		return m, nil
+	}
+	for n, i := range msg.rows {
+		if i >= len(m.expenses) || expenseSummary(m.expenses[i]) != msg.summaries[n] {
+			m.editing = false
+			m.status = msg.summaries[n] + " changed in the meantime; nothing was changed."
+			return m, nil
+		}
+	}
+	for _, i := range msg.rows {
+		e := m.expenses[i]
+		locked := m.isLocked(e)
+		if !locked && msg.op == bulkMove {
+			e.Date = moveToMonth(e.Date, msg.month)
+			locked = m.isLocked(e)
+		}
+		if locked {
+			again := msg
+			return m, unlockForm(monthOf(e.Date), func() tea.Msg { return again })
+		}
+	}
+	m.editing = false
+
+	// From the last row back, so that deleting one leaves the indexes of
+	// the others as they were.
+	for _, i := range slices.Backward(msg.rows) {
+		e := m.expenses[i]
+		switch msg.op {
+		case bulkDelete:
+			m.expenses = applyDelete(m.expenses, i)
+			continue
+		case bulkCategory:
+			e.Category = msg.category
+		case bulkMove:
+			e.Date = moveToMonth(e.Date, msg.month)
+		}
+		m.expenses = applyEdit(m.expenses, i, e)
+	}
+	count := fmt.Sprintf("%d expenses", len(msg.rows))
+	switch {
+	case needsApproval():
+		m.status = fmt.Sprintf("Saved for %s to approve.", cfg.Owner)
+	case msg.op == bulkDelete:
+		m.status = "Deleted " + count + "."
+	case msg.op == bulkCategory && msg.category == "":
+		m.status = "Removed the category of " + count + "."
+	case msg.op == bulkCategory:
+		m.status = "Filed " + count + " under " + msg.category + "."
+	case msg.op == bulkMove:
+		m.status = "Moved " + count + " to " + msg.month.Format("January 2006") + "."
+	}
+	m.marked = nil
+	if n := len(m.visibleExpenses()); m.selectedRow >= n {
+		m.selectedRow = max(n-1, 0)
+	}
+	m.updateExpensesTable()
+	return m, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil)
+}
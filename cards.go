@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+)
+
+// Credit cards are the accounts of the Accounts sheet with a Statement
+// Day. Expenses paid with one accrue on it as on any account, running its
+// balance below zero, and paying the card off is a transfer into it from
+// another account, which is not spending, so nothing is counted twice.
+
+// cardCyclesShown is how many statement cycles of each card the Cards
+// screen lists, the current one included.
+const cardCyclesShown = 12
+
+// dayOfMonth reads a day of the month from the Accounts sheet, 0 when it
+// is blank or not one.
+func dayOfMonth(s string) int {
+	day, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || day < 1 || day > 31 {
+		return 0
+	}
+	return day
+}
+
+// dayIn is day of the month of month, or its last day when it is shorter.
+func dayIn(month time.Time, day int) time.Time {
+	month = calendarMonth(month)
+	return month.AddDate(0, 0, min(day, month.AddDate(0, 1, -1).Day())-1)
+}
+
+// cardCycle is a statement cycle of a credit card: what was bought with it
+// and paid into it from the day after one statement to the next.
+type cardCycle struct {
+	// start and end are the first and last days of the cycle, end being
+	// the statement day, and due is the day the statement is to be paid
+	// by, zero without a Due Day.
+	start, end, due time.Time
+	// opening and closing are the card's balance before and after the
+	// cycle, purchases what its expenses added up to, refunds taking off,
+	// and payments what was transferred into it, less any out.
+	opening, purchases, payments, closing float64
+	// paid is what was paid into the card after the statement up to its
+	// due day, or until today without one.
+	paid float64
+}
+
+// owed is what the statement asks to be paid: the card's balance on the
+// statement day, when it is in debt.
+func (c cardCycle) owed() float64 {
+	return max(0, -c.closing)
+}
+
+// cardCycles works out the statement cycles of card, oldest first, from
+// the one its first expense or transfer falls in to the one day falls in.
+// moves are what moved it, from bookAccounts.
+func cardCycles(card account, moves []accountMove, day time.Time) []cardCycle {
+	first := day
+	if !card.AsOf.IsZero() && card.AsOf.Before(first) {
+		first = card.AsOf
+	}
+	if len(moves) > 0 && moves[0].date.Before(first) {
+		first = moves[0].date
+	}
+	end := dayIn(first, card.StatementDay)
+	if end.Before(first) {
+		end = dayIn(calendarMonth(first).AddDate(0, 1, 0), card.StatementDay)
+	}
+	var cycles []cardCycle
+	balance := card.Opening
+	for {
+		c := cardCycle{start: dayIn(calendarMonth(end).AddDate(0, -1, 0), card.StatementDay).AddDate(0, 0, 1), end: end, opening: balance}
+		if card.DueDay > 0 {
+			c.due = dayIn(end, card.DueDay)
+			if !c.due.After(end) {
+				c.due = dayIn(calendarMonth(end).AddDate(0, 1, 0), card.DueDay)
+			}
+		}
+		for _, mv := range moves {
+			switch {
+			case mv.date.After(end):
+				if mv.transfer && mv.amount > 0 && (c.due.IsZero() || !mv.date.After(c.due)) {
+					c.paid += mv.amount
+				}
+			case mv.date.Before(c.start):
+			case mv.transfer:
+				c.payments += mv.amount
+			default:
+				c.purchases += mv.amount
+			}
+		}
+		c.purchases, c.payments, c.paid = cfg.Rounding.round(c.purchases), cfg.Rounding.round(c.payments), cfg.Rounding.round(c.paid)
+		c.closing = cfg.Rounding.round(c.opening + c.purchases + c.payments)
+		balance = c.closing
+		cycles = append(cycles, c)
+		if !end.Before(day) {
+			return cycles
+		}
+		end = dayIn(calendarMonth(end).AddDate(0, 1, 0), card.StatementDay)
+	}
+}
+
+// status says whether the cycle's statement is paid, by day.
+func (c cardCycle) status(day time.Time) string {
+	left := cfg.Rounding.round(c.owed() - c.paid)
+	switch {
+	case !c.end.Before(day):
+		return "open"
+	case c.owed() == 0:
+		return "nothing due"
+	case left <= 0:
+		return "paid"
+	case c.due.IsZero():
+		return money(left) + " to pay"
+	case day.After(c.due):
+		return "overdue: " + money(left) + " unpaid"
+	}
+	return fmt.Sprintf("%s due in %d days", money(left), int(c.due.Sub(day).Hours()/24))
+}
+
+// updateCards handles keys on the Cards screen.
+func (m *model) updateCards(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "b", "esc":
+		m.currentScreen = screenMenu
+	}
+	return m, nil
+}
+
+// viewCards lists the recent statement cycles of every credit card.
+func (m *model) viewCards() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	buffer.WriteString(screenTitleStyle.Render("Cards"))
+	buffer.WriteString("\n\n")
+	book := bookAccounts(m.accounts, m.transfers, m.statementRows(), m.rates)
+	day := today()
+	cards := 0
+	for i, a := range m.accounts {
+		if a.StatementDay == 0 {
+			continue
+		}
+		cards++
+		title := fmt.Sprintf("%s · statements on day %d", a.Name, a.StatementDay)
+		if a.DueDay > 0 {
+			title += fmt.Sprintf(", due on day %d", a.DueDay)
+		}
+		buffer.WriteString(title + " · balance " + a.amount(book.balances[i]) + "\n")
+		cycles := cardCycles(a, book.moves[i], day)
+		buffer.WriteString(cardCyclesTable(cycles[max(0, len(cycles)-cardCyclesShown):], day) + "\n\n")
+	}
+	if cards == 0 {
+		buffer.WriteString(fmt.Sprintf("No credit cards yet. Give an account of the %s sheet a Statement Day, and a Due Day, to follow its statements here.\n\n", accountsSheet))
+	}
+	buffer.WriteString("Purchases accrue on the card; record paying it off as a transfer into it in the " + transfersSheet + " sheet, which is not counted as spending.\n")
+	buffer.WriteString("\nPress 'b' to go back.\n")
+	buffer.WriteString(m.statusLine())
+	return buffer.String()
+}
+
+// cardCyclesTable lists cycles newest first, the status coloured green
+// once paid, orange while due and red when overdue.
+func cardCyclesTable(cycles []cardCycle, day time.Time) string {
+	headers := []string{"Cycle", "Due", "Opening", "Purchases", "Payments", "Statement", "Status"}
+	var data [][]string
+	var statuses []string
+	for i := len(cycles) - 1; i >= 0; i-- {
+		c := cycles[i]
+		due := ""
+		if !c.due.IsZero() {
+			due = c.due.Format("2 Jan 2006")
+		}
+		status := c.status(day)
+		statuses = append(statuses, status)
+		data = append(data, []string{c.start.Format("2 Jan") + " – " + c.end.Format("2 Jan 2006"), due,
+			money(c.opening), money(c.purchases), money(c.payments), money(c.closing), status})
+	}
+
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	paidStyle := baseStyle.Foreground(lipgloss.Color("42"))
+	dueStyle := baseStyle.Foreground(lipgloss.Color("214"))
+	overdueStyle := baseStyle.Foreground(lipgloss.Color("203"))
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			if col != len(headers)-1 {
+				return rowStyle
+			}
+			switch s := statuses[row]; {
+			case s == "paid":
+				return paidStyle
+			case strings.HasPrefix(s, "overdue"):
+				return overdueStyle
+			case strings.Contains(s, "due in"), strings.HasSuffix(s, "to pay"):
+				return dueStyle
+			}
+			return rowStyle
+		})
+	return t.String()
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xuri/excelize/v2"
+)
+
+// cashConfig picks out cash withdrawals, which are not spending: they are
+// moved to the Transfers sheet as money into the cash account, and what the
+// cash then went on is entered as expenses paid with it.
+type cashConfig struct {
+	// Account is the account of the Accounts sheet cash is kept in, "Cash"
+	// by default. Without such an account withdrawals are left alone.
+	Account string `json:"account"`
+	// Withdrawals are words the names of withdrawals contain, in any case;
+	// "ATM" and "cash withdrawal" by default.
+	Withdrawals []string `json:"withdrawals"`
+}
+
+func (c cashConfig) validate() error {
+	if strings.TrimSpace(c.Account) == "" {
+		return errors.New("cash.account must not be blank")
+	}
+	return nil
+}
+
+// withdrawalPattern matches the names of withdrawals, or nothing without
+// any words to look for.
+func (c cashConfig) withdrawalPattern() *regexp.Regexp {
+	var words []string
+	for _, w := range c.Withdrawals {
+		if w = strings.TrimSpace(w); w != "" {
+			words = append(words, regexp.QuoteMeta(w))
+		}
+	}
+	if len(words) == 0 {
+		return regexp.MustCompile(`$^`)
+	}
+	return regexp.MustCompile(`(?i)\b(` + strings.Join(words, "|") + `)\b`)
+}
+
+// isWithdrawal reports whether e is cash taken out of an account: spending
+// named like a withdrawal, not itself paid in cash and not awaiting
+// approval.
+func isWithdrawal(e Expense) bool {
+	return e.Kind == kindExpense && e.Amount < 0 && e.Pending == "" && !e.Date.IsZero() &&
+		payeeKey(e.Payment) != payeeKey(cfg.Cash.Account) && cfg.Cash.withdrawalPattern().MatchString(e.Name)
+}
+
+// withdrawals counts the withdrawals among expenses and what they add up
+// to, positive.
+func withdrawals(expenses []Expense) (count int, total float64) {
+	for _, e := range expenses {
+		if isWithdrawal(e) {
+			count++
+			total -= e.Amount
+		}
+	}
+	return count, cfg.Rounding.round(total)
+}
+
+// withdrawalTransfer is the transfer a withdrawal stands for: from the
+// account it was paid from into the cash account.
+func withdrawalTransfer(e Expense) accountTransfer {
+	return accountTransfer{Date: e.Date, From: e.Payment, Amount: -e.Amount, To: cfg.Cash.Account}
+}
+
+// moveWithdrawals moves the withdrawals of the Expenses sheet of filename
+// to its Transfers sheet, as money into the cash account, returning how
+// many were moved. A workbook without the cash account is left alone.
+func moveWithdrawals(filename string) (int, error) {
+	if _, err := upgradeWorkbook(filename); err != nil {
+		return 0, err
+	}
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if accountNamed(readAccounts(f), cfg.Cash.Account) < 0 {
+		return 0, nil
+	}
+	rows, err := f.GetRows("Expenses")
+	if err != nil {
+		return 0, err
+	}
+	expenses, err := readExpenses(f)
+	if err != nil {
+		return 0, err
+	}
+	var keep []Expense
+	var audit []rowChange
+	free, err := openSheet(f, transfersSheet, transfersHeaders)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range expenses {
+		if !isWithdrawal(e) {
+			keep = append(keep, e)
+			continue
+		}
+		t := withdrawalTransfer(e)
+		values := []any{formatExpenseDate(t.Date), t.From, t.Amount, t.To}
+		cell, _ := excelize.CoordinatesToCellName(1, free)
+		if err := f.SetSheetRow(transfersSheet, cell, &values); err != nil {
+			return 0, err
+		}
+		free++
+		audit = append(audit, rowChange{kind: rowChanged, sheet: "Expenses", key: expenseKey(e), before: expenseSummary(e),
+			after: "moved to " + transfersSheet + " as a withdrawal into " + cfg.Cash.Account})
+	}
+	if len(audit) == 0 {
+		return 0, nil
+	}
+	if err := compactExpenses(f, keep, len(rows)); err != nil {
+		return 0, err
+	}
+	if err := writeExpenseTotal(f, keep); err != nil {
+		return 0, err
+	}
+	if err := appendAudit(f, audit); err != nil {
+		return 0, err
+	}
+
+	// Sessions holding the old rows must reload before saving over them.
+	current, _ := strconv.Atoi(getMeta(f, "revision"))
+	if err := setMeta(f, "revision", strconv.Itoa(current+1)); err != nil {
+		return 0, err
+	}
+	return len(audit), f.Save()
+}
+
+// cashLines prompt, below the expenses table, to move withdrawals still
+// counted as spending and to itemize the cash not yet accounted for. They
+// are empty without the cash account.
+func cashLines(accounts []account, transfers []accountTransfer, rows []Expense, rates fxRates) string {
+	a := accountNamed(accounts, cfg.Cash.Account)
+	if a < 0 {
+		return ""
+	}
+	var s string
+	if count, total := withdrawals(rows); count > 0 {
+		s += aheadOfPaceStyle.Render(fmt.Sprintf("%d cash withdrawals (%s) count as spending: press 'W' to move them to %s.", count, money(total), accounts[a].Name)) + "\n"
+	}
+	book := bookAccounts(accounts, transfers, rows, rates)
+	if balance := book.balances[a]; balance > 0 {
+		s += fmt.Sprintf("%s of %s is not itemized yet: add what it went on with 'n', paid with %s, or leave it while it is still in your wallet.\n",
+			accounts[a].amount(balance), accounts[a].Name, accounts[a].Name)
+	}
+	return s
+}
+
+type withdrawalsMovedMsg struct {
+	moved int
+	err   error
+}
+
+// moveWithdrawalsCmd moves the withdrawals of the workbook to the cash
+// account, then reloads it.
+func (m *model) moveWithdrawalsCmd() tea.Cmd {
+	path := m.path
+	return func() tea.Msg {
+		var moved int
+		err := saves.run(func() (err error) {
+			moved, err = moveWithdrawals(path)
+			return err
+		})
+		return withdrawalsMovedMsg{moved: moved, err: err}
+	}
+}
+
+// reportWithdrawalsMoved moves the withdrawals of the workbook at file to
+// the cash account after an import, saying how many it moved.
+func reportWithdrawalsMoved(file string) error {
+	if !isWorkbook(file) {
+		return nil
+	}
+	moved, err := moveWithdrawals(file)
+	if err != nil {
+		return err
+	}
+	if moved > 0 {
+		fmt.Printf("Moved %d cash withdrawals to the %s account.\n", moved, cfg.Cash.Account)
+	}
+	return nil
+}
+
+// runCash moves the withdrawals of a workbook to the cash account and says
+// how much cash is not itemized yet.
+func runCash(args []string) error {
+	flags := flag.NewFlagSet("cash", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "workbook with the Accounts sheet")
+	flags.Parse(args)
+
+	if !isWorkbook(*file) {
+		return fmt.Errorf("cash: %s is not an .xlsx workbook", *file)
+	}
+	f, err := openWorkbook(*file)
+	if err != nil {
+		return err
+	}
+	found := accountNamed(readAccounts(f), cfg.Cash.Account) >= 0
+	f.Close()
+	if !found {
+		return fmt.Errorf("cash: %s has no %s account; add it to the %s sheet", *file, cfg.Cash.Account, accountsSheet)
+	}
+	if err := reportWithdrawalsMoved(*file); err != nil {
+		return err
+	}
+
+	f, err = openWorkbook(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	expenses, err := readExpenses(f)
+	if err != nil {
+		return err
+	}
+	accounts := readAccounts(f)
+	a := accountNamed(accounts, cfg.Cash.Account)
+	transfers, err := readTransfers(f)
+	if err != nil {
+		return err
+	}
+	book := bookAccounts(accounts, transfers, expenses, readRates(f))
+	fmt.Printf("%s holds %s not itemized yet.\n", accounts[a].Name, accounts[a].amount(book.balances[a]))
+	return nil
+}
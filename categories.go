@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+)
+
+// uncategorised stands for expenses without a category where expenses are
+// grouped by it.
+const uncategorised = "Uncategorised"
+
+// categorySeparator splits a category into a parent and a subcategory, as
+// in "Food > Restaurants". Budgets, the variance report and the grouped
+// table work at either level, a parent taking in its subcategories.
+const categorySeparator = ">"
+
+// categoryName is category as written, with one space either side of each
+// separator.
+func categoryName(category string) string {
+	var parts []string
+	for _, p := range strings.Split(category, categorySeparator) {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " "+categorySeparator+" ")
+}
+
+// categoryKey matches categories case-insensitively, however the spaces
+// around their separators are written.
+func categoryKey(category string) string {
+	return strings.ToLower(categoryName(category))
+}
+
+// parentCategory is the parent of category, itself when it has none.
+func parentCategory(category string) string {
+	parent, _, _ := strings.Cut(category, " "+categorySeparator+" ")
+	return parent
+}
+
+// withinCategory reports whether the category with key counts towards
+// the one with key parent: it is that category or one of its
+// subcategories.
+func withinCategory(key, parent string) bool {
+	return key == parent || strings.HasPrefix(key, parent+" "+categorySeparator+" ")
+}
+
+// nestedCategory reports whether category is a subcategory of another of
+// categories, whose total already takes it in.
+func nestedCategory(category string, categories []string) bool {
+	key := categoryKey(category)
+	for _, c := range categories {
+		if other := categoryKey(c); other != key && withinCategory(key, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCategories checks the categories list of the config file.
+func validateCategories(categories []string) error {
+	seen := make(map[string]bool)
+	for _, c := range categories {
+		key := categoryKey(c)
+		if key == "" {
+			return errors.New("categories must not be blank")
+		}
+		if seen[key] {
+			return fmt.Errorf("categories lists %q twice", strings.TrimSpace(c))
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// categoryChoices are the categories the expense forms offer: the config
+// file's list, or else those of the Budgets sheet, followed by any other
+// category already in use and current, the expense's own. The first
+// choice leaves the expense without one.
+func categoryChoices(current string, expenses []Expense, budgets []budget) []huh.Option[string] {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		name = categoryName(name)
+		if key := categoryKey(name); key != "" && !seen[key] {
+			seen[key] = true
+			names = append(names, name)
+		}
+	}
+	for _, c := range cfg.Categories {
+		add(c)
+	}
+	if len(cfg.Categories) == 0 {
+		for _, b := range budgets {
+			add(b.Category)
+		}
+	}
+	listed := len(names)
+	for _, e := range expenses {
+		add(e.Category)
+	}
+	slices.SortFunc(names[listed:], func(a, b string) int { return strings.Compare(categoryKey(a), categoryKey(b)) })
+	add(current)
+
+	options := []huh.Option[string]{huh.NewOption("(none)", "")}
+	for _, name := range names {
+		options = append(options, huh.NewOption(name, name))
+	}
+	// Keep the expense's category as it is written, even when it differs in
+	// case from the listed one.
+	for i, o := range options {
+		if o.Value != "" && categoryKey(o.Value) == categoryKey(current) {
+			options[i].Value = current
+		}
+	}
+	return options
+}
+
+// categoryLabel is the category an expense is grouped under.
+func categoryLabel(e Expense) string {
+	if strings.TrimSpace(e.Category) == "" {
+		return uncategorised
+	}
+	return categoryName(e.Category)
+}
+
+// groupByCategory orders rows, and index along with them, by category,
+// subcategories after their parent and uncategorised ones last, keeping
+// the order of the rows within each.
+func groupByCategory(rows []Expense, index []int) {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	key := func(e Expense) string {
+		if strings.TrimSpace(e.Category) == "" {
+			return "\uffff"
+		}
+		return categoryKey(e.Category)
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		ka, kb := key(rows[a]), key(rows[b])
+		if c := strings.Compare(parentCategory(ka), parentCategory(kb)); c != 0 {
+			return c
+		}
+		return strings.Compare(ka, kb)
+	})
+	sortedRows := make([]Expense, len(rows))
+	sortedIndex := make([]int, len(index))
+	for i, j := range order {
+		sortedRows[i], sortedIndex[i] = rows[j], index[j]
+	}
+	copy(rows, sortedRows)
+	copy(index, sortedIndex)
+}
+
+// categoryTotalsLine sums rows by category, in the order they are grouped,
+// for below the grouped expenses table: each parent's total, its
+// subcategories included, followed by theirs. Pending rows count towards
+// none.
+func categoryTotalsLine(rows []Expense, icons map[string]string) string {
+	var labels []string
+	totals := make(map[string]float64)
+	subcategories := make(map[string][]string)
+	for _, e := range rows {
+		if e.Pending != "" {
+			continue
+		}
+		label := categoryLabel(e)
+		parent := parentCategory(label)
+		for _, l := range []string{parent, label} {
+			key := categoryKey(l)
+			if _, ok := totals[key]; !ok {
+				if l == parent {
+					labels = append(labels, parent)
+				} else {
+					subcategories[categoryKey(parent)] = append(subcategories[categoryKey(parent)], label)
+				}
+			}
+			totals[key] = cfg.Rounding.round(totals[key] + e.Amount)
+			if l == label {
+				break
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		parts[i] = withIcon(categoryIcon(icons, label), label) + " " + money(totals[categoryKey(label)])
+		var subs []string
+		for _, sub := range subcategories[categoryKey(label)] {
+			_, name, _ := strings.Cut(sub, " "+categorySeparator+" ")
+			subs = append(subs, name+" "+money(totals[categoryKey(sub)]))
+		}
+		if len(subs) > 0 {
+			parts[i] += " (" + strings.Join(subs, ", ") + ")"
+		}
+	}
+	return "By category: " + strings.Join(parts, " · ") + "\n"
+}
+
+// categorySubtotalsLimit is how many categories the subtotal table lists
+// before putting the rest together.
+const categorySubtotalsLimit = 8
+
+// categorySubtotals tabulates the spending of rows by category, refunds
+// taken off, largest first, with each one's share of it all, for under the
+// expenses table. Pending rows count towards none. It is empty when
+// nothing was spent.
+func categorySubtotals(rows []Expense, icons map[string]string) string {
+	var approved []Expense
+	for _, e := range rows {
+		if e.Pending == "" {
+			approved = append(approved, e)
+		}
+	}
+	names, spent := spendingBy(approved, dimensionCategory)
+	if len(names) == 0 {
+		return ""
+	}
+	var total float64
+	for _, s := range spent {
+		total += s
+	}
+	if len(names) > categorySubtotalsLimit {
+		var rest float64
+		for _, s := range spent[categorySubtotalsLimit-1:] {
+			rest += s
+		}
+		others := fmt.Sprintf("%d others", len(names)-categorySubtotalsLimit+1)
+		names = append(names[:categorySubtotalsLimit-1], others)
+		spent = append(spent[:categorySubtotalsLimit-1], cfg.Rounding.round(rest))
+	}
+
+	data := make([][]string, len(names))
+	for i, name := range names {
+		share := strconv.FormatFloat(spent[i]/total*100, 'f', 1, 64) + "%"
+		data[i] = []string{withIcon(categoryIcon(icons, name), name), money(spent[i]), budgetBar(spent[i], total) + " " + share}
+	}
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers("Category", "Spent", "Share").
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			return rowStyle
+		})
+	return t.String() + "\n"
+}
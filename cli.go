@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// commands are the headless entry points, run as `tet <command> [flags]`.
+// Without a command the TUI starts.
+var commands = map[string]func(args []string) error{
+	"accounts":  runAccounts,
+	"archive":   runArchive,
+	"backfill":  runBackfill,
+	"bench":     runBench,
+	"cash":      runCash,
+	"close":     runClose,
+	"diff":      runDiff,
+	"dump":      runDump,
+	"encrypt":   runEncrypt,
+	"export":    runExport,
+	"generate":  runGenerate,
+	"household": runHousehold,
+	"import":    runImport,
+	"login":     runLogin,
+	"quick":     runQuick,
+	"query":     runQuery,
+	"merge":     runMerge,
+	"serve":     runServe,
+	"migrate":   runMigrate,
+	"upgrade":   runUpgrade,
+	"usage":     runUsage,
+	"variance":  runVariance,
+	"version":   runVersion,
+	"wishlist":  runWishlist,
+}
+
+// runCommand runs the subcommand named by args[0], reporting whether one
+// matched.
+func runCommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printCommands()
+		return true, nil
+	}
+	run, ok := commands[args[0]]
+	if !ok {
+		return false, nil
+	}
+	return true, run(args[1:])
+}
+
+func printCommands() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(os.Stderr, "Usage: tet [command] [flags]")
+	fmt.Fprintln(os.Stderr, "\nWithout a command the interactive tracker starts.")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", name)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun `tet <command> -h` for the command's flags.")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// clock tells the time and waits, for everything that depends on what time
+// it is: the date new expenses get, the month closed or budgeted, the
+// stamps of backups and snapshots, and the pauses that let a file settle
+// before it is read.
+type clock interface {
+	Now() time.Time
+	Sleep(time.Duration)
+}
+
+// clk is the clock in use; today and now read it.
+var clk clock = systemClock{}
+
+func now() time.Time { return clk.Now() }
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// shiftedClock runs on from a pretend time, set with $TET_NOW or --now, to
+// see what happens on another day, such as when a month rolls over on the
+// 1st. Only tet's own idea of the time moves: locks and sign-ins, which
+// other programs share, keep the real one.
+type shiftedClock struct {
+	offset time.Duration
+}
+
+func (c shiftedClock) Now() time.Time        { return time.Now().Add(c.offset) }
+func (c shiftedClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// nowEnv pretends it is another time, like --now.
+const nowEnv = "TET_NOW"
+
+// pretendLayouts are the times --now takes besides the dates of
+// dateLayouts, read in the local time zone unless they name one.
+var pretendLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"}
+
+// pretendNow sets the clock to run from s, a date, at midnight, or a time.
+func pretendNow(s string) error {
+	t, ok := time.Time{}, false
+	for _, layout := range pretendLayouts {
+		if parsed, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			t, ok = parsed, true
+			break
+		}
+	}
+	if !ok {
+		d, ok := parseDateText(s)
+		if !ok {
+			return fmt.Errorf("%q is not a date or time; use YYYY-MM-DD or YYYY-MM-DDTHH:MM", s)
+		}
+		t = time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.Local)
+	}
+	clk = shiftedClock{offset: time.Until(t)}
+	return nil
+}
+
+// pretending describes the pretend time, empty when the clock is right.
+func pretending() string {
+	if _, ok := clk.(shiftedClock); !ok {
+		return ""
+	}
+	return "Pretending it is " + now().Format("Mon 2 Jan 2006 15:04") + "."
+}
+
+// fileSystem is the file access of backups, snapshots, locks and the
+// workbook watcher, so that they can run on files other than the disk's.
+// The workbook itself is read and written by its store.
+type fileSystem interface {
+	Open(name string) (io.ReadCloser, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (io.WriteCloser, error)
+	Stat(name string) (fs.FileInfo, error)
+	Glob(pattern string) ([]string, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	Remove(name string) error
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+// files is the file system in use.
+var files fileSystem = osFS{}
+
+// osFS is the disk.
+type osFS struct{}
+
+func (osFS) Open(name string) (io.ReadCloser, error) { return os.Open(name) }
+
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (io.WriteCloser, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (osFS) Glob(pattern string) ([]string, error)        { return filepath.Glob(pattern) }
+func (osFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (osFS) Remove(name string) error                     { return os.Remove(name) }
+
+func (osFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock stands still until something sleeps on it, which moves it on
+// at once. onSleep, when set, runs during each sleep, like whatever else
+// would have happened meanwhile.
+type fakeClock struct {
+	t       time.Time
+	slept   time.Duration
+	onSleep func()
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.t = c.t.Add(d)
+	c.slept += d
+	if c.onSleep != nil {
+		c.onSleep()
+	}
+}
+
+// useClock has the test run on a fake clock set to t.
+func useClock(t *testing.T, at time.Time) *fakeClock {
+	t.Helper()
+	c := &fakeClock{t: at}
+	old := clk
+	clk = c
+	t.Cleanup(func() { clk = old })
+	return c
+}
+
+// memFile is a file of a memFS.
+type memFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// memFS keeps its files in memory, stamped with the clock's time.
+type memFS struct {
+	files map[string]*memFile
+}
+
+// useFiles has the test run on an empty memFS.
+func useFiles(t *testing.T) *memFS {
+	t.Helper()
+	m := &memFS{files: make(map[string]*memFile)}
+	old := files
+	files = m
+	t.Cleanup(func() { files = old })
+	return m
+}
+
+func (m *memFS) write(name string, data []byte) {
+	m.files[name] = &memFile{data: slices.Clone(data), modTime: now()}
+}
+
+func (m *memFS) Open(name string) (io.ReadCloser, error) {
+	f, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+// memWriter writes a memFS file when it is closed.
+type memWriter struct {
+	bytes.Buffer
+	fs   *memFS
+	name string
+}
+
+func (w *memWriter) Close() error {
+	w.fs.write(w.name, w.Bytes())
+	return nil
+}
+
+func (m *memFS) OpenFile(name string, flag int, perm fs.FileMode) (io.WriteCloser, error) {
+	if _, ok := m.files[name]; ok && flag&os.O_EXCL != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+	w := &memWriter{fs: m, name: name}
+	if f, ok := m.files[name]; ok && flag&os.O_TRUNC == 0 {
+		w.Write(f.data)
+	}
+	return w, nil
+}
+
+// memInfo describes a memFS file.
+type memInfo struct {
+	name string
+	f    *memFile
+}
+
+func (i memInfo) Name() string       { return filepath.Base(i.name) }
+func (i memInfo) Size() int64        { return int64(len(i.f.data)) }
+func (i memInfo) Mode() fs.FileMode  { return 0o644 }
+func (i memInfo) ModTime() time.Time { return i.f.modTime }
+func (i memInfo) IsDir() bool        { return false }
+func (i memInfo) Sys() any           { return nil }
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	f, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return memInfo{name: name, f: f}, nil
+}
+
+func (m *memFS) Glob(pattern string) ([]string, error) {
+	var matches []string
+	for name := range m.files {
+		if ok, err := filepath.Match(pattern, name); err != nil {
+			return nil, err
+		} else if ok {
+			matches = append(matches, name)
+		}
+	}
+	slices.Sort(matches)
+	return matches, nil
+}
+
+// MkdirAll does nothing: a memFS has no directories, only paths.
+func (m *memFS) MkdirAll(path string, perm fs.FileMode) error { return nil }
+
+func (m *memFS) Remove(name string) error {
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+func (m *memFS) Chtimes(name string, atime, mtime time.Time) error {
+	f, ok := m.files[name]
+	if !ok {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrNotExist}
+	}
+	f.modTime = mtime
+	return nil
+}
+
+func TestBackupsKeepNewestCopies(t *testing.T) {
+	c := useClock(t, time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local))
+	disk := useFiles(t)
+	keep := cfg.Backups.Keep
+	cfg.Backups.Keep = 3
+	t.Cleanup(func() { cfg.Backups.Keep = keep })
+
+	path := filepath.Join("books", "data.xlsx")
+	var want []string
+	for i := range 5 {
+		disk.write(path, []byte{byte(i)})
+		if err := backupBeforeWrite(path); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, autoBackupPath(path, now()))
+		c.Sleep(time.Second)
+	}
+	// data-2.xlsx's copies are not data.xlsx's.
+	disk.write(filepath.Join("books", autoBackupDir, "data-2-"+now().Format(autoBackupLayout)+".xlsx"), nil)
+
+	got := listAutoBackups(path)
+	slices.Reverse(want)
+	if !slices.Equal(got, want[:3]) {
+		t.Fatalf("kept %q, want the newest three, %q", got, want[:3])
+	}
+	for i, backup := range got {
+		f := disk.files[backup]
+		if n := 4 - i; !bytes.Equal(f.data, []byte{byte(n)}) {
+			t.Errorf("%s holds %v, want the workbook as saved %d", backup, f.data, n)
+		}
+		if stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(backup), "data-"), ".xlsx"); stamp != f.modTime.Format(autoBackupLayout) {
+			t.Errorf("%s was modified at %s, want the time it is stamped with", backup, f.modTime.Format(autoBackupLayout))
+		}
+	}
+}
+
+func TestBackupsOff(t *testing.T) {
+	useClock(t, time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local))
+	disk := useFiles(t)
+	keep := cfg.Backups.Keep
+	cfg.Backups.Keep = 0
+	t.Cleanup(func() { cfg.Backups.Keep = keep })
+
+	disk.write("data.xlsx", []byte("v1"))
+	if err := backupBeforeWrite("data.xlsx"); err != nil {
+		t.Fatal(err)
+	}
+	if len(disk.files) != 1 {
+		t.Errorf("copies were taken with backups off: %d files", len(disk.files))
+	}
+}
+
+func TestQuoteRefreshesTakeNoBackup(t *testing.T) {
+	c := useClock(t, time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local))
+	keep := cfg.Backups.Keep
+	cfg.Backups.Keep = 5
+	t.Cleanup(func() { cfg.Backups.Keep = keep })
+	path := filepath.Join(t.TempDir(), "data.xlsx")
+	if err := generateWorkbook(path, fixtureOptions{rows: 10, seed: 2, months: 1}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := (&excelStore{path: path}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range data.Stonks {
+		data.Stonks[i].Price++
+	}
+	for i := range data.WatchList {
+		data.WatchList[i].Price++
+	}
+	if err := writeExcelData(path, data.Revision, data.Expenses, data.Stonks, data.WatchList, nil); err != nil {
+		t.Fatal(err)
+	}
+	if backups := listAutoBackups(path); len(backups) != 0 {
+		t.Fatalf("a save of new prices took copies %q", backups)
+	}
+
+	c.Sleep(time.Second)
+	data.Expenses[0].Amount--
+	if err := writeExcelData(path, data.Revision+1, data.Expenses, data.Stonks, data.WatchList, nil); err != nil {
+		t.Fatal(err)
+	}
+	if backups := listAutoBackups(path); len(backups) != 1 {
+		t.Errorf("an edited expense was saved with copies %q, want one", backups)
+	}
+}
+
+func TestWatcherSettlesBeforeReloading(t *testing.T) {
+	c := useClock(t, time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local))
+	disk := useFiles(t)
+	disk.write("data.xlsx", []byte("loaded"))
+	last, err := fingerprintFile("data.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The app's own save, already fingerprinted, is not reloaded.
+	if settled("data.xlsx", last) {
+		t.Error("reloaded a workbook that has not changed since it was loaded")
+	}
+	if c.slept != settleTime {
+		t.Errorf("waited %s for the write to settle, want %s", c.slept, settleTime)
+	}
+
+	// Touching it, or saving it again as it was, changes nothing either.
+	c.onSleep = func() { disk.write("data.xlsx", []byte("loaded")) }
+	if settled("data.xlsx", last) {
+		t.Error("reloaded a workbook that was only touched")
+	}
+
+	// A write finishing while the watcher waits is read once it is done.
+	c.onSleep = func() { disk.write("data.xlsx", []byte("changed")) }
+	if !settled("data.xlsx", last) {
+		t.Error("did not reload a workbook written while the watcher waited")
+	}
+}
+
+func TestMonthRollsOverAtMidnight(t *testing.T) {
+	c := useClock(t, time.Date(2026, 1, 31, 23, 59, 30, 0, time.Local))
+	m, _ := newTestModel(t, Expense{Name: "Beans", Amount: -50, Date: parseExpenseDate("2026-01-20"), Category: "Coffee"})
+	m.budgets = []budget{{Category: "Coffee", Limit: 10}}
+	m.updateExpensesTable()
+	if !strings.Contains(m.budgetWarning, "January 2026") {
+		t.Fatalf("no January overspending warning before midnight: %q", m.budgetWarning)
+	}
+
+	wait := untilMidnight(now())
+	if wait != 30*time.Second {
+		t.Fatalf("the day tick waits %s, want the 30s to midnight", wait)
+	}
+	c.Sleep(wait)
+	m.Update(dayTickMsg{})
+	if m.budgetWarning != "" {
+		t.Errorf("January's overspending is still warned of in February: %q", m.budgetWarning)
+	}
+	if !strings.Contains(m.budgetProgress, periodName(monthOf(today()))) {
+		t.Errorf("the budgets do not follow the day into February:\n%s", m.budgetProgress)
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/xuri/excelize/v2"
+)
+
+// historySheet keeps the totals of every closed month, one row each. A
+// month with a row is closed, and the TUI refuses to edit its expenses.
+const historySheet = "History"
+
+var historyHeaders = []string{"Month", "Income", "Spent", "Refunds", "Net Spend", "Closed"}
+
+// recurringSheet lists the expenses posted to each new month when the one
+// before it is closed. Day is the day of the month they are dated, the
+// 1st when blank, and the last day in months too short for it.
+// It is laid out like Expenses, with the Day in place of the Date.
+const recurringSheet = "Recurring"
+
+const monthLayout = "2006-01"
+
+// readClosedMonths returns the months listed in the History sheet, keyed
+// by monthOf.
+func readClosedMonths(f *excelize.File) map[time.Time]bool {
+	closed := make(map[time.Time]bool)
+	rows, err := f.GetRows(historySheet)
+	if err != nil {
+		return closed
+	}
+	for i := 1; i < len(rows); i++ {
+		if len(rows[i]) == 0 {
+			continue
+		}
+		if month, err := time.Parse(monthLayout, strings.TrimSpace(rows[i][0])); err == nil {
+			closed[month] = true
+		}
+	}
+	return closed
+}
+
+// recurringItem is a row of the Recurring sheet.
+type recurringItem struct {
+	Expense
+	Day int
+}
+
+// in dates the item in month, on its day within the month's budgeting
+// period when periods start on a payday.
+func (r recurringItem) in(month time.Time) Expense {
+	start := periodStart(month)
+	month = calendarMonth(start)
+	if r.Day < start.Day() {
+		month = month.AddDate(0, 1, 0)
+	}
+	last := month.AddDate(0, 1, -1).Day()
+	e := r.Expense
+	e.Date = time.Date(month.Year(), month.Month(), min(max(r.Day, 1), last), 0, 0, 0, 0, time.UTC)
+	return e
+}
+
+func readRecurring(f *excelize.File) ([]recurringItem, error) {
+	if idx, _ := f.GetSheetIndex(recurringSheet); idx == -1 {
+		return nil, nil
+	}
+	rows, err := f.GetRows(recurringSheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+	var items []recurringItem
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) < 2 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		amt, _ := strconv.ParseFloat(row[1], 64)
+		item := recurringItem{Expense: Expense{Name: row[0], Amount: cfg.Rounding.round(amt)}}
+		if len(row) > 2 {
+			item.Kind = parseExpenseKind(row[2])
+		}
+		if len(row) > 3 {
+			item.Day, _ = strconv.Atoi(strings.TrimSpace(row[3]))
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// monthClose is what closing a month did.
+type monthClose struct {
+	month  time.Time
+	totals expenseTotals
+	// posted counts the recurring expenses added to the next month.
+	posted int
+	// report is the workbook the month was exported to, empty when there
+	// was nothing to export or one already existed.
+	report string
+	// variance compares the month's budgets with its spending, empty
+	// without a Budgets sheet.
+	variance []varianceRow
+	// archived counts the expenses moved to the archive sheets, and
+	// snapshot is the copy of the workbook taken before they were.
+	archived int
+	snapshot string
+}
+
+// closeMonth closes month in filename in one step: its totals are
+// recorded in the History sheet, which locks its rows, the Recurring
+// items are posted to the next month and the month's report is exported
+// beside the workbook, as `tet export` would. With archive, the month's
+// expenses and any older ones are then moved to the archive sheets, after
+// a snapshot of the workbook is taken, leaving the Expenses sheet to the
+// months still open.
+func closeMonth(filename string, month time.Time, archive bool) (monthClose, error) {
+	month = calendarMonth(month)
+	result := monthClose{month: month}
+	if !month.Before(monthOf(today())) {
+		return result, fmt.Errorf("close: %s has not ended yet", month.Format("January 2006"))
+	}
+	if _, err := upgradeWorkbook(filename); err != nil {
+		return result, err
+	}
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return result, err
+	}
+	defer unlock()
+
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+
+	if readClosedMonths(f)[month] {
+		return result, fmt.Errorf("close: %s is already closed", month.Format("January 2006"))
+	}
+	expenses, err := readExpenses(f)
+	if err != nil {
+		return result, err
+	}
+	if archive {
+		result.snapshot = fmt.Sprintf("%s.close-%s-%s.bak", filename, month.Format(monthLayout), now().Format("20060102-150405"))
+		if err := copyFile(filename, result.snapshot); err != nil {
+			return result, fmt.Errorf("close: taking a snapshot before archiving: %w", err)
+		}
+	}
+	recurring, err := readRecurring(f)
+	if err != nil {
+		return result, fmt.Errorf("%s sheet: %w", recurringSheet, err)
+	}
+	// Expenses in other currencies count converted.
+	base, _ := readRates(f).inBase(expenses)
+	rows := monthExpenses(base, month)
+	result.totals = totalsOf(rows)
+	budgets, _, err := readBudgets(f)
+	if err != nil {
+		return result, fmt.Errorf("%s sheet: %w", budgetsSheet, err)
+	}
+	result.variance = budgetVariance(budgets, base, month)
+
+	row, err := openSheet(f, historySheet, historyHeaders)
+	if err != nil {
+		return result, err
+	}
+	t := result.totals
+	values := []any{month.Format(monthLayout), t.income, t.spent, t.refunds, t.netSpend(), now().Format(time.RFC3339)}
+	if err := f.SetSheetRow(historySheet, fmt.Sprintf("A%d", row), &values); err != nil {
+		return result, err
+	}
+	audit := []rowChange{{kind: rowAdded, sheet: historySheet, key: month.Format(monthLayout),
+		after: fmt.Sprintf("%s closed, net spend %s", month.Format(monthLayout), money(t.netSpend()))}}
+
+	// Items already in the next month, say from an earlier close that was
+	// undone by hand, are not posted twice, whoever entered them.
+	next := month.AddDate(0, 1, 0)
+	existing := make(map[string]bool)
+	for _, e := range expenses {
+		e.EnteredBy = ""
+		existing[expenseSummary(e)] = true
+	}
+	sheetRows, err := f.GetRows("Expenses")
+	if err != nil {
+		return result, err
+	}
+	free := len(sheetRows) + 1
+	for _, item := range recurring {
+		e := item.in(next)
+		if existing[expenseSummary(e)] {
+			continue
+		}
+		if err := writeExpenseRow(f, "Expenses", free, e); err != nil {
+			return result, err
+		}
+		audit = append(audit, rowChange{kind: rowAdded, sheet: "Expenses", key: expenseKey(e), after: expenseSummary(e)})
+		expenses = append(expenses, e)
+		free++
+		result.posted++
+	}
+	if archive {
+		var moved []rowChange
+		if expenses, moved, err = moveToArchive(f, expenses, free-1, periodStart(next)); err != nil {
+			return result, err
+		}
+		result.archived = len(moved)
+		audit = append(audit, moved...)
+	}
+	if err := writeExpenseTotal(f, expenses); err != nil {
+		return result, err
+	}
+	if err := appendAudit(f, audit); err != nil {
+		return result, err
+	}
+
+	// Sessions holding the old rows must reload before saving over them.
+	current, _ := strconv.Atoi(getMeta(f, "revision"))
+	if err := setMeta(f, "revision", strconv.Itoa(current+1)); err != nil {
+		return result, err
+	}
+	if err := f.Save(); err != nil {
+		return result, err
+	}
+
+	if len(rows) == 0 {
+		return result, nil
+	}
+	report := filepath.Join(filepath.Dir(filename), "expenses-"+month.Format(monthLayout)+".xlsx")
+	if _, err := os.Stat(report); !errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	}
+	if err := exportWorkbook(report, month, rows, dimensionPayee); err != nil {
+		return result, fmt.Errorf("close: %s was closed but its report could not be written: %w", month.Format("January 2006"), err)
+	}
+	result.report = report
+	return result, nil
+}
+
+// openSheet creates sheet with headers if needed and returns its first
+// free row.
+func openSheet(f *excelize.File, sheet string, headers []string) (int, error) {
+	if idx, _ := f.GetSheetIndex(sheet); idx == -1 {
+		if _, err := f.NewSheet(sheet); err != nil {
+			return 0, err
+		}
+		if err := f.SetSheetRow(sheet, "A1", &headers); err != nil {
+			return 0, err
+		}
+		return 2, nil
+	}
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return 0, err
+	}
+	return max(len(rows), 1) + 1, nil
+}
+
+func (c monthClose) String() string {
+	s := fmt.Sprintf("Closed %s: net spend %s", c.month.Format("January 2006"), money(c.totals.netSpend()))
+	if c.posted > 0 {
+		s += fmt.Sprintf(", %d recurring expenses posted to %s", c.posted, c.month.AddDate(0, 1, 0).Format("January"))
+	}
+	if c.report != "" {
+		s += ", report saved as " + filepath.Base(c.report)
+	}
+	if c.archived > 0 {
+		s += fmt.Sprintf(", %d expenses archived (the workbook as it was is saved as %s)", c.archived, filepath.Base(c.snapshot))
+	}
+	return s + "."
+}
+
+// isLocked reports whether e is dated in a closed month that has not been
+// unlocked this session.
+func (m *model) isLocked(e Expense) bool {
+	month := monthOf(e.Date)
+	return !e.Date.IsZero() && m.closed[month] && !m.unlocked[month]
+}
+
+// unlockedMsg answers unlockForm: next runs once month is unlocked, and
+// month is zero when it was left locked.
+type unlockedMsg struct {
+	month time.Time
+	next  tea.Cmd
+}
+
+// unlockForm asks before changing a closed month, whose totals reports
+// may already have used. Unlocking lasts for the rest of the session.
+func unlockForm(month time.Time, next tea.Cmd) tea.Cmd {
+	var confirmed bool
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("%s is closed. Unlock it to make changes?", month.Format("January 2006"))).
+				Description("Its totals are already in the History sheet and its report; they are not updated.").
+				Affirmative("Unlock").
+				Negative("Keep locked").
+				Value(&confirmed),
+		),
+	)
+	return func() tea.Msg {
+		if err := form.Run(); err != nil || !confirmed {
+			return unlockedMsg{}
+		}
+		return unlockedMsg{month: month, next: next}
+	}
+}
+
+type monthClosedMsg struct {
+	result monthClose
+	err    error
+}
+
+// archiveOnClose is whether closing a month archives its expenses unless
+// asked not to, in the TUI and tet close alike.
+const archiveOnClose = true
+
+// closeMonthForm asks before closing the month before the current one.
+func (m *model) closeMonthForm() tea.Cmd {
+	month := monthOf(today()).AddDate(0, -1, 0)
+	var confirmed bool
+	archive := archiveOnClose
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("Close %s?", month.Format("January 2006"))).
+				Description("Its expenses are locked, its totals saved to the History sheet, recurring items posted to this month and its report exported.").
+				Value(&confirmed),
+			huh.NewConfirm().
+				Title("Archive its expenses?").
+				Description("They move to the Archive sheets with any older ones, after a snapshot of the workbook, so the table starts afresh with this month. 'a' still shows them.").
+				Affirmative("Archive").
+				Negative("Keep them").
+				Value(&archive),
+		),
+	)
+	path := m.path
+	return func() tea.Msg {
+		if err := form.Run(); err != nil || !confirmed {
+			return monthClosedMsg{}
+		}
+		var result monthClose
+		err := saves.run(func() (err error) {
+			result, err = closeMonth(path, month, archive)
+			return err
+		})
+		return monthClosedMsg{result: result, err: err}
+	}
+}
+
+// runClose closes a month from the command line.
+func runClose(args []string) error {
+	flags := flag.NewFlagSet("close", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "workbook to close the month in")
+	monthFlag := flags.String("month", monthOf(today()).AddDate(0, -1, 0).Format(monthLayout), "month to close, as YYYY-MM")
+	archive := flags.Bool("archive", archiveOnClose, "move the month's expenses, and older ones, to the archive sheets (--archive=false keeps them)")
+	flags.Parse(args)
+
+	month, err := time.Parse(monthLayout, *monthFlag)
+	if err != nil {
+		return fmt.Errorf("close: --month must be YYYY-MM: %w", err)
+	}
+	if !isWorkbook(*file) {
+		return fmt.Errorf("close: %s is not an .xlsx workbook", *file)
+	}
+	result, err := closeMonth(*file, month, *archive)
+	if err != nil {
+		return err
+	}
+	fmt.Println(result)
+	if len(result.variance) > 0 {
+		fmt.Println(varianceTable(result.variance))
+		fmt.Print(varianceLegend())
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloseArchivesByDefault(t *testing.T) {
+	useClock(t, time.Date(2026, 10, 3, 9, 0, 0, 0, time.Local))
+	for _, tc := range []struct {
+		args    []string
+		archive bool
+	}{
+		{nil, true},
+		{[]string{"--archive=false"}, false},
+	} {
+		path := filepath.Join(t.TempDir(), "data.xlsx")
+		if err := generateWorkbook(path, fixtureOptions{rows: 30, seed: 9, months: 2}); err != nil {
+			t.Fatal(err)
+		}
+		if err := runClose(append([]string{"--file", path}, tc.args...)); err != nil {
+			t.Fatalf("tet close %s: %v", strings.Join(tc.args, " "), err)
+		}
+		f, err := openWorkbook(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx, _ := f.GetSheetIndex("Archive 2026")
+		f.Close()
+		if archived := idx != -1; archived != tc.archive {
+			t.Errorf("tet close %s archived: %v, want %v", strings.Join(tc.args, " "), archived, tc.archive)
+		}
+	}
+}
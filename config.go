@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// config holds user settings, read from config.json in the user's config
+// directory (e.g. ~/.config/tet/config.json). Every field is optional.
+type config struct {
+	Rounding     roundingPolicy     `json:"rounding"`
+	GoogleSheets googleSheetsConfig `json:"google_sheets"`
+	Alerts       alertConfig        `json:"alerts"`
+	Receipts     receiptsConfig     `json:"receipts"`
+	Email        emailConfig        `json:"email"`
+	Quotes       quoteConfig        `json:"quotes"`
+	Dividends    dividendConfig     `json:"dividends"`
+	CSVImport    csvImportConfig    `json:"csv_import"`
+	Backups      backupsConfig      `json:"backups"`
+	Variance     varianceConfig     `json:"variance"`
+	Period       periodConfig       `json:"period"`
+	Cash         cashConfig         `json:"cash"`
+	Goods        goodsConfig        `json:"goods"`
+	// Members are the household the expense forms offer to set who an
+	// expense was for, in this order.
+	Members []string `json:"members"`
+	// ShareFormula keeps each expense's share of its month's spending in
+	// the Expenses sheet too, as a formula, as the expenses table shows it.
+	ShareFormula bool `json:"share_formula"`
+	// Categories are offered by the expense forms, in this order; by
+	// default those of the Budgets sheet.
+	Categories []string `json:"categories"`
+	// CategoryIcons are shown beside categories, such as emoji, unless
+	// the Budgets sheet gives them others.
+	CategoryIcons map[string]string `json:"category_icons"`
+	// Templates are quick entries added with 'T' and their number on the
+	// Expenses screen.
+	Templates []string `json:"templates"`
+	// PriceHooks are the commands that print a wishlist item's price, by
+	// the name its Price Hook cell picks them with; see fetchWishPrice.
+	PriceHooks map[string]string `json:"price_hooks"`
+	// Emoji says whether the terminal shows emoji, when $TERM does not
+	// tell; see emojiSupported.
+	Emoji *bool `json:"emoji"`
+	// RowRules colour the rows of the expenses table meeting their
+	// conditions.
+	RowRules rowRules `json:"row_rules"`
+	// User is who new expenses are entered by, the login name by default.
+	User string `json:"user"`
+	// Owner approves the changes everyone else makes to a shared book.
+	// Without one, changes apply straight away.
+	Owner string `json:"owner"`
+	// Offline skips every network call, leaving prices as last saved.
+	Offline bool `json:"offline"`
+	// UpdateCheck looks for a newer release of tet at start-up, noting one
+	// on the menu.
+	UpdateCheck bool `json:"update_check"`
+	// File is the data file opened by default instead of data.xlsx;
+	// $TET_FILE overrides it.
+	File string `json:"file"`
+	// Profiles are named sets of settings, each with a file of its own,
+	// applied over the rest; see applyProfile.
+	Profiles map[string]json.RawMessage `json:"profiles"`
+	// profile is the profile applied, empty for none.
+	profile string
+}
+
+// cfg is the configuration in use, replaced when the TUI switches profile.
+var cfg = defaultConfig()
+
+func defaultConfig() config {
+	return config{
+		Rounding: roundingPolicy{Mode: roundHalfUp, Currency: "EUR"},
+		Backups:  backupsConfig{Keep: 20},
+		Variance: varianceConfig{WarnPercent: 10},
+		Cash:     cashConfig{Account: "Cash", Withdrawals: []string{"ATM", "cash withdrawal"}},
+		Goods:    goodsConfig{ReturnDays: 14, RemindDays: 7},
+		Members:  []string{"Me", "Partner", "Kids", "Shared"},
+	}
+}
+
+// configPath returns where the config file lives; $TET_CONFIG overrides it.
+func configPath() (string, error) {
+	if p := os.Getenv("TET_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tet", "config.json"), nil
+}
+
+// fileEnv names the data file to open by default, over the config file.
+const fileEnv = "TET_FILE"
+
+// dataFile is the data file the TUI and every command open unless told
+// otherwise: the profile's file, else $TET_FILE, else the config file's
+// "file", else data.xlsx. A
+// leading ~ stands for the home directory, for paths such as
+// ~/Dropbox/tet/data.xlsx.
+func dataFile() string {
+	path := os.Getenv(fileEnv)
+	if path == "" || cfg.profile != "" {
+		path = cfg.File
+	}
+	if path == "" {
+		return defaultPaths["xlsx"]
+	}
+	return expandHome(path)
+}
+
+// expandHome replaces a leading ~ with the home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// loadConfig reads the config file over the defaults. A missing file is
+// not an error.
+func loadConfig() (config, error) {
+	c := defaultConfig()
+	c.Offline = offlineFromEnv()
+	path, err := configPath()
+	if err != nil {
+		return c, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if name := os.Getenv(profileEnv); name != "" {
+			return c, fmt.Errorf("config: no profile %q, as %s does not exist", name, path)
+		}
+		return c, nil
+	} else if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.validateProfiles(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if name := os.Getenv(profileEnv); name != "" {
+		if err := c.applyProfile(name); err != nil {
+			return c, fmt.Errorf("config: %s: %w", path, err)
+		}
+	}
+	if err := c.Rounding.validate(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.Quotes.validate(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.CSVImport.validate(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.Backups.validate(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.Email.validate(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.Variance.validate(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.Period.validate(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.Cash.validate(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.Goods.validate(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := validateMembers(c.Members); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := validateCategories(c.Categories); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := validateTemplates(c.Templates); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := validateCategoryIcons(c.CategoryIcons); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := c.RowRules.compile(); err != nil {
+		return c, fmt.Errorf("config: %s: %w", path, err)
+	}
+	c.Offline = c.Offline || offlineFromEnv()
+	return c, nil
+}
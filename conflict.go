@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// updateConflict handles the prompt shown when a save lost the race against
+// another session.
+func (m *model) updateConflict(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	c := m.conflict
+	switch key.String() {
+	case "o":
+		m.conflict = nil
+		m.status = "Overwrote the other session's changes."
+		return m, writeExcelCmd(m.path, c.err.current, m.loaded, c.expenses, c.stonks, c.watchList, c.quotes)
+	case "r", "esc":
+		m.conflict = nil
+		m.status = "Discarded your edit and reloaded the workbook."
+		return m, loadSheetsCmd(m.path, m.loaded)
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *model) viewConflict() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	buffer.WriteString(screenTitleStyle.Render("Save conflict"))
+	buffer.WriteString("\n\n  ")
+	buffer.WriteString(m.conflict.err.Error())
+	buffer.WriteString(".\n\n")
+	buffer.WriteString("  Press 'o' to overwrite their changes with yours,\n")
+	buffer.WriteString("  or 'r' to discard your edit and reload theirs.\n")
+	return buffer.String()
+}
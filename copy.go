@@ -0,0 +1,37 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copiedExpense is e logged again today, by whoever is entering it: its
+// name, amount, category, tags, how it was paid and who it was for are
+// kept, and its note, which is about the one occasion, is not. Goods come
+// with the same warranty and as long to return them.
+func copiedExpense(e Expense) Expense {
+	c := Expense{Name: e.Name, Amount: e.Amount, Kind: e.Kind, Date: today(), EnteredBy: currentUser(),
+		Category: e.Category, Tags: e.Tags, Payment: e.Payment, Currency: e.Currency, Method: e.Method, Member: e.Member,
+		WarrantyMonths: e.WarrantyMonths}
+	if !e.ReturnBy.IsZero() && !e.Date.IsZero() {
+		c.ReturnBy = c.Date.AddDate(0, 0, daysBetween(e.Date, e.ReturnBy))
+	}
+	return c
+}
+
+// copySelected adds a copy of the selected expense, archived ones
+// included, dated today: straight away, or through the new-expense form
+// filled in with it when inForm is set, to change its amount or date
+// first.
+func (m *model) copySelected(inForm bool) (tea.Model, tea.Cmd) {
+	rows := m.visibleExpenses()
+	if m.selectedRow >= len(rows) {
+		return m, nil
+	}
+	e := copiedExpense(rows[m.selectedRow])
+	m.editing = true
+	if inForm {
+		return m, m.newExpenseForm(e)
+	}
+	m.status = "Copied " + e.Name + " to today."
+	return m, func() tea.Msg { return expenseEditedMsg{index: -1, expense: e} }
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// encryptedMagic prefixes data files encrypted at rest. It is followed by
+// the GCM nonce and the sealed contents.
+var encryptedMagic = []byte("TETENC1\n")
+
+const (
+	keyringService = "tet"
+	keyringAccount = "data-encryption-key"
+	// keyEnv overrides the keyring, for systems without one.
+	keyEnv = "TET_ENCRYPTION_KEY"
+)
+
+// errNoKey is the keyring's answer when it has no key for tet yet, the
+// only one on which a new key is made.
+var errNoKey = errors.New("no encryption key in the OS keyring")
+
+// encryptable is implemented by stores that can encrypt their data file:
+// only the JSON store. A SQLite database is read and written in place by
+// the driver, so encrypting it as a whole would mean a plaintext copy on
+// disk for as long as it is open; keep it on an encrypted disk instead.
+type encryptable interface {
+	setEncrypted(on bool)
+}
+
+func isEncrypted(b []byte) bool {
+	return bytes.HasPrefix(b, encryptedMagic)
+}
+
+func encryptData(plain []byte) ([]byte, error) {
+	key, err := dataKey(true)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append([]byte(nil), encryptedMagic...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plain, nil), nil
+}
+
+func decryptData(b []byte) ([]byte, error) {
+	key, err := dataKey(false)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	b = b[len(encryptedMagic):]
+	if len(b) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data is truncated")
+	}
+	plain, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting data (wrong key?): %w", err)
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// dataKey returns the AES-256 key from $TET_ENCRYPTION_KEY or the OS
+// keyring. With create set, a key the keyring does not have is generated
+// and stored in it; a keyring that is locked or cannot be reached fails
+// instead, so the key of files already encrypted is never replaced.
+func dataKey(create bool) ([]byte, error) {
+	encoded := os.Getenv(keyEnv)
+	if encoded == "" {
+		var err error
+		encoded, err = keyringGet()
+		switch {
+		case errors.Is(err, errNoKey) && create:
+			// Generated below.
+		case errors.Is(err, errNoKey):
+			return nil, fmt.Errorf("%w (set $%s to supply one)", err, keyEnv)
+		case err != nil:
+			return nil, fmt.Errorf("reading the encryption key from the OS keyring (set $%s to supply one): %w", keyEnv, err)
+		}
+	}
+	if encoded == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		encoded = base64.StdEncoding.EncodeToString(key)
+		if err := keyringSet(encoded); err != nil {
+			return nil, fmt.Errorf("storing new encryption key in the OS keyring (set $%s instead): %w", keyEnv, err)
+		}
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes, base64 encoded")
+	}
+	return key, nil
+}
+
+// keyringGet and keyringSet use the platform's keyring CLI: security(1) on
+// macOS and secret-tool(1) from libsecret elsewhere.
+func keyringGet() (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+	case "windows":
+		return "", errors.New("keyring not supported on windows")
+	default:
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "account", keyringAccount)
+	}
+	out, err := cmd.Output()
+	if keyringMissing(runtime.GOOS, err) {
+		return "", errNoKey
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// keyringMissing reports whether err is the keyring CLI of goos saying it
+// has no such secret: security(1) exits with 44, and secret-tool(1) with 1
+// and nothing on stderr, where a locked or absent keyring says why.
+func keyringMissing(goos string, err error) bool {
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) {
+		return false
+	}
+	if goos == "darwin" {
+		return exit.ExitCode() == 44
+	}
+	return exit.ExitCode() == 1 && len(bytes.TrimSpace(exit.Stderr)) == 0
+}
+
+func keyringSet(secret string) error {
+	cmd, err := keyringSetCmd(runtime.GOOS, secret)
+	if err != nil {
+		return err
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// keyringSetCmd is the command storing secret in the keyring of goos. The
+// secret goes in on stdin, never in the arguments, which other users can
+// list with ps. security(1) reads the whole command from stdin in its
+// interactive mode; the secret is base64, so it needs no quoting there.
+func keyringSetCmd(goos, secret string) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	switch goos {
+	case "darwin":
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keyringService, keyringAccount, secret))
+	case "windows":
+		return nil, errors.New("keyring not supported on windows")
+	default:
+		cmd = exec.Command("secret-tool", "store", "--label=tet data encryption key",
+			"service", keyringService, "account", keyringAccount)
+		cmd.Stdin = strings.NewReader(secret)
+	}
+	return cmd, nil
+}
+
+// runEncrypt turns encryption at rest on or off for a data file by loading
+// and re-saving it.
+func runEncrypt(args []string) error {
+	flags := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	backend := flags.String("backend", "json", "backend of the data file")
+	file := flags.String("file", "", "data file (defaults to the backend's default file)")
+	off := flags.Bool("decrypt", false, "store the file as plaintext again")
+	flags.Parse(args)
+
+	s, err := openStore(*backend, *file)
+	if err != nil {
+		return err
+	}
+	enc, ok := s.(encryptable)
+	if !ok {
+		return fmt.Errorf("the %s backend is not encrypted at rest: only JSON files are; use full-disk encryption for it", *backend)
+	}
+	data, err := s.Load()
+	if err != nil {
+		return err
+	}
+	enc.setEncrypted(!*off)
+	if err := s.Save(data); err != nil {
+		return err
+	}
+	if *off {
+		fmt.Println("Data file is now stored as plaintext.")
+	} else {
+		fmt.Println("Data file is now encrypted at rest.")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/base64"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestKeyringSecretNotInArguments(t *testing.T) {
+	const secret = "c2VjcmV0IGtleSBmb3IgdGhlIGtleXJpbmcgdGVzdHM="
+	for _, goos := range []string{"darwin", "linux"} {
+		cmd, err := keyringSetCmd(goos, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.ContainsFunc(cmd.Args, func(arg string) bool { return strings.Contains(arg, secret) }) {
+			t.Errorf("%s: the secret is in the arguments %q", goos, cmd.Args)
+		}
+		in, err := io.ReadAll(cmd.Stdin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(in), secret) {
+			t.Errorf("%s: the secret is not on stdin: %q", goos, in)
+		}
+	}
+}
+
+// SQLite is out of the scope of encryption at rest; asking for it must not
+// leave a database the user believes is encrypted.
+func TestEncryptRefusesSQLite(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "data.json"), filepath.Join(dir, "data.sqlite")
+	if err := (&jsonStore{path: src}).Save(Dataset{Expenses: testExpenses}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigrate([]string{"--from", "json", "--to", "sqlite", "--src", src, "--dst", dst, "--encrypt"}); err == nil {
+		t.Error("migrated to an unencrypted SQLite database when asked to encrypt it")
+	}
+	if err := checkMissing(dst); err != nil {
+		t.Errorf("a refused encrypted migration still wrote %s", dst)
+	}
+	if err := runEncrypt([]string{"--backend", "sqlite", "--file", dst}); err == nil {
+		t.Error("encrypting a SQLite database did not fail")
+	}
+}
+
+// useTestKey supplies the encryption key, keeping the tests off the keyring.
+func useTestKey(t *testing.T) {
+	t.Setenv(keyEnv, base64.StdEncoding.EncodeToString(make([]byte, 32)))
+}
+
+func TestEncryptedJSONStaysEncrypted(t *testing.T) {
+	useTestKey(t)
+	path := filepath.Join(t.TempDir(), "data.json")
+	plain, err := storeForPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Save(Dataset{Expenses: testExpenses}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runEncrypt([]string{"--file", path}); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted := func(when string) {
+		t.Helper()
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isEncrypted(b) {
+			t.Fatalf("the file is plaintext %s", when)
+		}
+	}
+	encrypted("after tet encrypt")
+
+	// The TUI loads through one store and saves through another.
+	s, err := storeForPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Expenses = append(data.Expenses, Expense{Name: "Books", Amount: -20, Date: parseExpenseDate("2026-09-03")})
+	if err := s.Save(data); err != nil {
+		t.Fatal(err)
+	}
+	encrypted("after a load and save")
+
+	data.Revision++
+	fresh, err := storeForPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fresh.Save(data); err != nil {
+		t.Fatal(err)
+	}
+	encrypted("after a save through a store that did not load it")
+
+	if err := runEncrypt([]string{"--file", path, "--decrypt"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); isEncrypted(b) {
+		t.Error("the file is still encrypted after tet encrypt --decrypt")
+	}
+}
+
+func TestKeyringMissingOnlyWhenNotFound(t *testing.T) {
+	for _, tc := range []struct {
+		goos, script string
+		missing      bool
+	}{
+		{"darwin", "exit 44", true},
+		{"darwin", "echo 'User interaction is not allowed.' >&2; exit 36", false},
+		{"linux", "exit 1", true},
+		{"linux", "echo 'Cannot autolaunch D-Bus without X11 $DISPLAY' >&2; exit 1", false},
+		{"linux", "exit 0", false},
+	} {
+		_, err := exec.Command("sh", "-c", tc.script).Output()
+		if got := keyringMissing(tc.goos, err); got != tc.missing {
+			t.Errorf("%s %q: missing %v, want %v", tc.goos, tc.script, got, tc.missing)
+		}
+	}
+}
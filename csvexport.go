@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// delimitedFormats are the plain-text formats sheets can be exported to,
+// by the field separator they use.
+var delimitedFormats = map[string]rune{
+	"csv": ',',
+	"tsv": '\t',
+}
+
+// sheetTable is a sheet as text: its header row, then a row of cells per
+// record, with numbers written plainly and dates as YYYY-MM-DD.
+func sheetTable(sheet string, data Dataset) [][]string {
+	var rows [][]any
+	switch sheet {
+	case "Expenses":
+		for _, e := range data.Expenses {
+			rows = append(rows, expenseCells(e))
+		}
+	case "Stonks":
+		for _, st := range data.Stonks {
+			rows = append(rows, stonkCells(st))
+		}
+	case "WatchList":
+		for _, w := range data.WatchList {
+			rows = append(rows, watchCells(w))
+		}
+	}
+	table := [][]string{sheetHeaders[sheet]}
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = textCell(v)
+		}
+		table = append(table, cells)
+	}
+	return table
+}
+
+func textCell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// delimitedPath is where sheet of the data file at path is exported in
+// format, in dir: data.xlsx's Stonks become data-stonks.csv.
+func delimitedPath(dir, path, sheet, format string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return filepath.Join(dir, base+"-"+strings.ToLower(sheet)+"."+format)
+}
+
+// exportDelimited writes sheet to file in format, replacing the file from
+// any earlier export.
+func exportDelimited(file, sheet, format string, data Dataset) error {
+	comma, ok := delimitedFormats[format]
+	if !ok {
+		return fmt.Errorf("export: unknown format %q", format)
+	}
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Comma = comma
+	if err := w.WriteAll(sheetTable(sheet, data)); err != nil {
+		return err
+	}
+	return writeFileAtomic(file, []byte(b.String()))
+}
+
+// exportSheets exports every sheet of data in format into dir, returning
+// the files written.
+func exportSheets(dir, path, format string, data Dataset) ([]string, error) {
+	var files []string
+	for _, sheet := range sheetNames {
+		file := delimitedPath(dir, path, sheet, format)
+		if err := exportDelimited(file, sheet, format, data); err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+type sheetExportedMsg struct {
+	file string
+	err  error
+}
+
+// exportScreenCmd exports the sheet shown on the current screen as CSV,
+// beside the data file.
+func (m *model) exportScreenCmd() tea.Cmd {
+	sheet := map[screen]string{
+		screenExpenses:  "Expenses",
+		screenStonks:    "Stonks",
+		screenWatchlist: "WatchList",
+	}[m.currentScreen]
+	if sheet == "" {
+		return nil
+	}
+	data := Dataset{Expenses: m.expenses, Stonks: m.stonks, WatchList: m.watchList}
+	file := delimitedPath(filepath.Dir(m.path), m.path, sheet, "csv")
+	return func() tea.Msg {
+		return sheetExportedMsg{file: file, err: exportDelimited(file, sheet, "csv", data)}
+	}
+}
+
+// runExportSheets is `tet export --format csv|tsv`.
+func runExportSheets(file, out, format string) error {
+	s, err := readOnlyStoreForPath(file)
+	if err != nil {
+		return err
+	}
+	data, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		out = filepath.Dir(file)
+	}
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+	files, err := exportSheets(out, file, format, data)
+	for _, f := range files {
+		fmt.Printf("Exported %s.\n", f)
+	}
+	return err
+}
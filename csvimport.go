@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// csvImportConfig maps the columns of a CSV file onto expenses. Columns
+// are named by their header, in any case, or numbered from 1 for files
+// without one.
+type csvImportConfig struct {
+	Name   string `json:"name,omitempty"`
+	Amount string `json:"amount,omitempty"`
+	Date   string `json:"date,omitempty"`
+	// DateFormat is a Go time layout, "2006-01-02" by default.
+	DateFormat string `json:"date_format,omitempty"`
+	// Delimiter separates fields, "," by default.
+	Delimiter string `json:"delimiter,omitempty"`
+	// DecimalComma reads amounts written like 1.234,56.
+	DecimalComma bool `json:"decimal_comma,omitempty"`
+}
+
+// withDefaults fills in the mapping of a name,amount,date file.
+func (c csvImportConfig) withDefaults() csvImportConfig {
+	if c.Name == "" {
+		c.Name = "name"
+	}
+	if c.Amount == "" {
+		c.Amount = "amount"
+	}
+	if c.Date == "" {
+		c.Date = "date"
+	}
+	if c.DateFormat == "" {
+		c.DateFormat = dateLayout
+	}
+	if c.Delimiter == "" {
+		c.Delimiter = ","
+	}
+	return c
+}
+
+func (c csvImportConfig) validate() error {
+	if c.Delimiter != "" && len([]rune(c.Delimiter)) != 1 && c.Delimiter != `\t` {
+		return fmt.Errorf("csv_import: delimiter must be a single character, not %q", c.Delimiter)
+	}
+	return nil
+}
+
+func (c csvImportConfig) delimiter() rune {
+	if c.Delimiter == `\t` {
+		return '\t'
+	}
+	return []rune(c.Delimiter)[0]
+}
+
+// csvSkip is a row left out of an import and why.
+type csvSkip struct {
+	line   int
+	reason string
+}
+
+func (s csvSkip) String() string { return fmt.Sprintf("line %d: %s", s.line, s.reason) }
+
+// csvImport is the outcome of reading a CSV file.
+type csvImport struct {
+	expenses []Expense
+	skipped  []csvSkip
+}
+
+func (r csvImport) String() string {
+	s := fmt.Sprintf("Imported %d expenses", len(r.expenses))
+	if len(r.skipped) == 0 {
+		return s + "."
+	}
+	reasons := make([]string, len(r.skipped))
+	for i, skip := range r.skipped {
+		reasons[i] = skip.String()
+	}
+	return fmt.Sprintf("%s, skipped %d rows (%s).", s, len(r.skipped), strings.Join(reasons, "; "))
+}
+
+// columnIndex finds the column named by ref: a 1-based number, or a header
+// matched case-insensitively. header is nil for files without one.
+func columnIndex(ref string, header []string) (int, error) {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 {
+			return 0, fmt.Errorf("column numbers start at 1, not %d", n)
+		}
+		return n - 1, nil
+	}
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(ref)) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no %q column", ref)
+}
+
+// parseCSVAmount reads an amount, dropping thousands separators.
+func parseCSVAmount(s string, decimalComma bool) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if decimalComma {
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.Replace(s, ",", ".", 1)
+	} else {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	return cfg.Rounding.parse(s)
+}
+
+// readExpensesCSV reads the expenses of a CSV file mapped by c. Rows that
+// cannot be read, or that are dated in a closed month, are skipped rather
+// than failing the import. The file has a header row unless every column
+// is mapped by number.
+func readExpensesCSV(r io.Reader, c csvImportConfig, closed map[time.Time]bool) (csvImport, error) {
+	c = c.withDefaults()
+	cr := csv.NewReader(r)
+	cr.Comma = c.delimiter()
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var header []string
+	if !numbered(c.Name) || !numbered(c.Amount) || !numbered(c.Date) {
+		var err error
+		if header, err = cr.Read(); err != nil {
+			return csvImport{}, fmt.Errorf("reading the header: %w", err)
+		}
+		if len(header) > 0 {
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		}
+	}
+	var cols [3]int
+	for i, ref := range []string{c.Name, c.Amount, c.Date} {
+		col, err := columnIndex(ref, header)
+		if err != nil {
+			return csvImport{}, err
+		}
+		cols[i] = col
+	}
+
+	var result csvImport
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				result.skipped = append(result.skipped, csvSkip{perr.StartLine, perr.Err.Error()})
+				continue
+			}
+			return result, err
+		}
+		// Blank lines are not records, so the reader counts the lines.
+		line, _ := cr.FieldPos(0)
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		field := func(i int) string {
+			if cols[i] < len(record) {
+				return strings.TrimSpace(record[cols[i]])
+			}
+			return ""
+		}
+		name, amount, date := field(0), field(1), field(2)
+		if name == "" {
+			result.skipped = append(result.skipped, csvSkip{line, "no name"})
+			continue
+		}
+		amt, err := parseCSVAmount(amount, c.DecimalComma)
+		if err != nil {
+			result.skipped = append(result.skipped, csvSkip{line, fmt.Sprintf("amount %q is not a number", amount)})
+			continue
+		}
+		e := Expense{Name: name, Amount: amt, EnteredBy: currentUser()}
+		if needsApproval() {
+			e.Pending = pendingNew
+		}
+		if date != "" {
+			t, err := time.Parse(c.DateFormat, date)
+			if err != nil {
+				result.skipped = append(result.skipped, csvSkip{line, fmt.Sprintf("date %q is not %s", date, c.DateFormat)})
+				continue
+			}
+			e.Date = dateOf(t)
+		}
+		if !e.Date.IsZero() && closed[monthOf(e.Date)] {
+			result.skipped = append(result.skipped, csvSkip{line, e.Date.Format("January 2006") + " is closed"})
+			continue
+		}
+		result.expenses = append(result.expenses, e)
+	}
+	return result, nil
+}
+
+func numbered(ref string) bool {
+	_, err := strconv.Atoi(ref)
+	return err == nil
+}
+
+func importCSVFile(path string, c csvImportConfig, closed map[time.Time]bool) (csvImport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return csvImport{}, err
+	}
+	defer f.Close()
+	result, err := readExpensesCSV(f, c, closed)
+	if err != nil {
+		return result, fmt.Errorf("import: %s: %w", filepath.Base(path), err)
+	}
+	return result, nil
+}
+
+// csvImportedMsg carries the rows read from file, which is empty when the
+// import was cancelled.
+type csvImportedMsg struct {
+	file   string
+	result csvImport
+	err    error
+}
+
+// importForm asks for a CSV file and reads it with the configured
+// mapping; the rows are appended and saved once they are back. A bank
+// statement is read for review on the Statement screen instead.
+func (m *model) importForm() tea.Cmd {
+	var path string
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().Title("CSV, QIF or OFX file to import").Value(&path).Validate(func(s string) error {
+				_, err := os.Stat(strings.TrimSpace(s))
+				return err
+			}),
+		),
+	)
+	closed := m.closed
+	existing := slices.Clone(m.expenses)
+	return func() tea.Msg {
+		if err := form.Run(); err != nil {
+			return csvImportedMsg{}
+		}
+		path = strings.TrimSpace(path)
+		if isStatement(path) {
+			return loadStatement(path, existing, closed)
+		}
+		result, err := importCSVFile(path, cfg.CSVImport, closed)
+		return csvImportedMsg{file: path, result: result, err: err}
+	}
+}
+
+// runImport appends the expenses of a CSV file to the data file, or
+// restores a JSON backup over it.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "data file to append the expenses to")
+	c := cfg.CSVImport
+	flags.StringVar(&c.Name, "name", c.Name, "column holding the expense name, by header or 1-based number (default name)")
+	flags.StringVar(&c.Amount, "amount", c.Amount, "column holding the amount (default amount)")
+	flags.StringVar(&c.Date, "date", c.Date, "column holding the date (default date)")
+	flags.StringVar(&c.DateFormat, "date-format", c.DateFormat, "Go layout of the dates (default 2006-01-02)")
+	flags.StringVar(&c.Delimiter, "delimiter", c.Delimiter, `field separator, e.g. ";" or \t (default ",")`)
+	flags.BoolVar(&c.DecimalComma, "decimal-comma", c.DecimalComma, "amounts are written like 1.234,56")
+	dryRun := flags.Bool("dry-run", false, "report what would be imported without writing")
+	duplicates := flags.String("duplicates", "skip", "what to do with rows matching an expense already in the book by name, amount and date: skip, merge or add")
+	format := flags.String("format", "", "csv, qif or ofx for a bank statement, or json to restore a backup from tet export --format json (default from the file extension)")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: tet import [flags] expenses.csv|statement.qif|statement.ofx|backup.json")
+	}
+	if *format == "" {
+		*format = "csv"
+		switch ext := strings.ToLower(filepath.Ext(flags.Arg(0))); ext {
+		case ".json", ".qif", ".ofx":
+			*format = ext[1:]
+		case ".qfx":
+			*format = "ofx"
+		}
+	}
+	switch *format {
+	case "json":
+		return restoreBackup(*file, flags.Arg(0), *dryRun)
+	case "qif", "ofx":
+		return importStatement(*file, flags.Arg(0), *format, *dryRun)
+	case "csv":
+	default:
+		return fmt.Errorf("import: unknown format %q (available: csv, qif, ofx, json)", *format)
+	}
+	if err := c.validate(); err != nil {
+		return err
+	}
+	choice, err := parseDuplicateChoice(*duplicates)
+	if err != nil {
+		return fmt.Errorf("import: --duplicates: %w", err)
+	}
+
+	var closed map[time.Time]bool
+	if isWorkbook(*file) {
+		if f, err := openWorkbook(*file); err == nil {
+			closed = readClosedMonths(f)
+			f.Close()
+		}
+	}
+	result, err := importCSVFile(flags.Arg(0), c, closed)
+	if err != nil {
+		return err
+	}
+	var dups []duplicate
+	if len(result.expenses) > 0 {
+		// The book is read for its duplicates even on a dry run.
+		open := storeForPath
+		if *dryRun {
+			open = readOnlyStoreForPath
+		}
+		s, err := open(*file)
+		if err != nil {
+			return err
+		}
+		data, err := s.Load()
+		if err != nil {
+			return err
+		}
+		result.expenses, dups = findDuplicates(data.Expenses, result.expenses)
+		if !*dryRun && (len(result.expenses) > 0 || (len(dups) > 0 && choice != duplicateSkip)) {
+			data.Expenses = addExpenses(data.Expenses, result.expenses, dups, choice)
+			if err := s.Save(data); err != nil {
+				return err
+			}
+		}
+	}
+	if *dryRun {
+		fmt.Print("Dry run, nothing written. ")
+	}
+	fmt.Println(result)
+	if len(dups) > 0 {
+		fmt.Printf("%d rows matched expenses already in the book and were %s.\n", len(dups), choice)
+	}
+	if *dryRun {
+		return nil
+	}
+	return reportWithdrawalsMoved(*file)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// csvRows is what an import read, one "date|name|amount" per expense.
+func csvRows(expenses []Expense) []string {
+	rows := make([]string, len(expenses))
+	for i, e := range expenses {
+		rows[i] = formatExpenseDate(e.Date) + "|" + e.Name + "|" + money(e.Amount)
+	}
+	return rows
+}
+
+func TestReadExpensesCSVColumns(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		c    csvImportConfig
+		csv  string
+		want []string
+	}{
+		{
+			name: "defaults",
+			csv:  "name,amount,date\nCoffee,-3.50,2026-10-01\nSalary,2500,2026-10-02\n",
+			want: []string{"2026-10-01|Coffee|" + money(-3.5), "2026-10-02|Salary|" + money(2500)},
+		},
+		{
+			name: "headers in any case and order",
+			c:    csvImportConfig{Name: "Payee", Amount: "Value", Date: "Booked"},
+			csv:  "\ufeffBooked, Value ,PAYEE,Balance\n2026-10-01,-12,Lunch,88\n",
+			want: []string{"2026-10-01|Lunch|" + money(-12)},
+		},
+		{
+			name: "numbered columns without a header",
+			c:    csvImportConfig{Name: "2", Amount: "3", Date: "1"},
+			csv:  "2026-10-01,Bus,-2.40\n2026-10-02,Train,-15\n",
+			want: []string{"2026-10-01|Bus|" + money(-2.4), "2026-10-02|Train|" + money(-15)},
+		},
+		{
+			name: "European locale",
+			c:    csvImportConfig{DateFormat: "02.01.2006", Delimiter: ";", DecimalComma: true},
+			csv:  "name;amount;date\nRent;-1.234,56;01.10.2026\nRefund;7,5;02.10.2026\n",
+			want: []string{"2026-10-01|Rent|" + money(-1234.56), "2026-10-02|Refund|" + money(7.5)},
+		},
+		{
+			name: "thousands separators",
+			csv:  "name,amount,date\nCar,\"-12,500.00\",2026-10-03\nFlat,- 1 000,2026-10-04\n",
+			want: []string{"2026-10-03|Car|" + money(-12500), "2026-10-04|Flat|" + money(-1000)},
+		},
+		{
+			name: "tab-separated, undated",
+			c:    csvImportConfig{Delimiter: `\t`, Date: "when"},
+			csv:  "name\tamount\twhen\nGift\t-20\t\n",
+			want: []string{"|Gift|" + money(-20)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readExpensesCSV(strings.NewReader(tc.csv), tc.c, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.skipped) > 0 {
+				t.Errorf("skipped %v", got.skipped)
+			}
+			if rows := csvRows(got.expenses); !reflect.DeepEqual(rows, tc.want) {
+				t.Errorf("read %q, want %q", rows, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadExpensesCSVSkipsBadRows(t *testing.T) {
+	csv := "name,amount,date\n" +
+		"Coffee,-3.50,2026-10-01\n" +
+		",-1,2026-10-01\n" +
+		"Lunch,twelve,2026-10-01\n" +
+		"Dinner,-30,1 Oct 2026\n" +
+		"\n" +
+		"Rent,-900,2026-09-01\n" +
+		"Taxi,-18,\"2026-10-02\n"
+	closed := map[time.Time]bool{time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC): true}
+	got, err := readExpensesCSV(strings.NewReader(csv), csvImportConfig{}, closed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, want := csvRows(got.expenses), []string{"2026-10-01|Coffee|" + money(-3.5)}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("read %q, want %q", rows, want)
+	}
+	var lines []int
+	for _, skip := range got.skipped {
+		lines = append(lines, skip.line)
+	}
+	if want := []int{3, 4, 5, 7, 8}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("skipped lines %d (%v), want %d", lines, got.skipped, want)
+	}
+}
+
+func TestReadExpensesCSVMissingColumn(t *testing.T) {
+	for name, c := range map[string]csvImportConfig{
+		"no such header": {Amount: "value"},
+		"column zero":    {Name: "0", Amount: "2", Date: "3"},
+	} {
+		if _, err := readExpensesCSV(strings.NewReader("name,amount,date\nCoffee,-3.50,2026-10-01\n"), c, nil); err == nil {
+			t.Errorf("%s: read the file, want an error", name)
+		}
+	}
+}
+
+// TestImportCSVDuplicates imports a file repeating an expense of the book,
+// its name in another case, under each --duplicates choice.
+func TestImportCSVDuplicates(t *testing.T) {
+	book := []Expense{{Name: "Coffee", Amount: -3.5, Date: parseExpenseDate("2026-10-01")}}
+	csv := "name,amount,date\ncoffee ,-3.50,2026-10-01\nLunch,-12,2026-10-01\n"
+	for _, tc := range []struct {
+		choice string
+		want   []string
+	}{
+		{"skip", []string{"2026-10-01|Coffee|" + money(-3.5), "2026-10-01|Lunch|" + money(-12)}},
+		{"merge", []string{"2026-10-01|Coffee|" + money(-3.5), "2026-10-01|Lunch|" + money(-12)}},
+		{"add", []string{"2026-10-01|Coffee|" + money(-3.5), "2026-10-01|Lunch|" + money(-12), "2026-10-01|coffee|" + money(-3.5)}},
+	} {
+		t.Run(tc.choice, func(t *testing.T) {
+			dir := t.TempDir()
+			file, in := filepath.Join(dir, "data.json"), filepath.Join(dir, "bank.csv")
+			if err := (&jsonStore{path: file}).Save(Dataset{Expenses: book}); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(in, []byte(csv), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := runImport([]string{"--file", file, "--duplicates", tc.choice, in}); err != nil {
+				t.Fatal(err)
+			}
+			data, err := (&jsonStore{path: file}).Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows := csvRows(data.Expenses)
+			slices.Sort(rows)
+			if !reflect.DeepEqual(rows, tc.want) {
+				t.Errorf("the book holds %q, want %q", rows, tc.want)
+			}
+		})
+	}
+}
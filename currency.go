@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xuri/excelize/v2"
+)
+
+// Expenses can be in other currencies than the configured one
+// (rounding.currency), named in their Currency column. They are shown in
+// their own, and converted at the rate of their date wherever they are
+// added up: totals, budgets, balances and settling up.
+
+// foreignCurrency reports whether an expense in currency needs converting.
+func foreignCurrency(currency string) bool {
+	return currency != "" && !strings.EqualFold(currency, cfg.Rounding.Currency)
+}
+
+// validateCurrency accepts a three-letter ISO code, or blank for the
+// configured currency.
+func validateCurrency(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if len(s) != 3 || strings.IndexFunc(s, func(r rune) bool { return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') }) >= 0 {
+		return errors.New("use a three-letter code such as USD, or leave it blank")
+	}
+	return nil
+}
+
+// expenseAmount is the amount of e as shown, with its currency when it is
+// not the configured one.
+func expenseAmount(e Expense) string {
+	if foreignCurrency(e.Currency) {
+		return money(e.Amount) + " " + strings.ToUpper(e.Currency)
+	}
+	return money(e.Amount)
+}
+
+// fxRates are the exchange rates of the FX sheet by currency, each series
+// oldest first: the configured currency paid for one unit of it.
+type fxRates map[string][]pricePoint
+
+// readRates reads the FX sheet; a workbook without one, or whose sheet
+// cannot be read, has no rates.
+func readRates(f *excelize.File) fxRates {
+	points, _ := readPriceSheet(f, fxSheet)
+	return seriesBySymbol(points)
+}
+
+// rate is the configured currency paid for one unit of currency on day, 1
+// for the configured currency itself; ok is false without rates for it.
+func (r fxRates) rate(currency string, day time.Time) (rate float64, ok bool) {
+	if !foreignCurrency(currency) {
+		return 1, true
+	}
+	series := r[symbolKey(currency)]
+	if len(series) == 0 {
+		return 0, false
+	}
+	return valueOn(series, day), true
+}
+
+// inBase converts the expenses in other currencies to the configured one,
+// at the rate of their date or, when undated, the latest. Expenses in a
+// currency without rates count as zero, and missing lists those
+// currencies. expenses itself is returned when none needs converting.
+func (r fxRates) inBase(expenses []Expense) (converted []Expense, missing []string) {
+	if !slices.ContainsFunc(expenses, func(e Expense) bool { return foreignCurrency(e.Currency) }) {
+		return expenses, nil
+	}
+	converted = make([]Expense, len(expenses))
+	for i, e := range expenses {
+		if foreignCurrency(e.Currency) {
+			currency := symbolKey(e.Currency)
+			series := r[currency]
+			day := e.Date
+			if day.IsZero() {
+				day = today()
+			}
+			if len(series) == 0 {
+				e.Amount = 0
+				if !slices.Contains(missing, currency) {
+					missing = append(missing, currency)
+				}
+			} else {
+				e.Amount = cfg.Rounding.round(e.Amount * valueOn(series, day))
+			}
+			e.Currency = ""
+		}
+		converted[i] = e
+	}
+	slices.Sort(missing)
+	return converted, missing
+}
+
+// inBase is expenses in the configured currency, converted at the rates of
+// the workbook and any fetched this session.
+func (m *model) inBase(expenses []Expense) []Expense {
+	converted, _ := m.rates.inBase(expenses)
+	return converted
+}
+
+// missingRates lists the currencies of expenses and accounts that there
+// are no rates for.
+func (m *model) missingRates() []string {
+	_, missing := m.rates.inBase(m.statementRows())
+	for _, c := range currenciesWithoutRates(m.accounts, m.rates) {
+		if !slices.Contains(missing, c) {
+			missing = append(missing, c)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// ratesLine warns about expenses and accounts left out of the totals for
+// want of rates.
+func (m *model) ratesLine() string {
+	missing := m.missingRates()
+	if len(missing) == 0 {
+		return ""
+	}
+	return overBudgetStyle.Render(fmt.Sprintf("⚠ No %s rates for %s: what is in them counts as zero until `tet backfill --fx` fetches them.",
+		strings.ToUpper(cfg.Rounding.Currency), strings.Join(missing, ", "))) + "\n"
+}
+
+type ratesMsg struct {
+	rates  fxRates
+	failed []string
+}
+
+// fetchRatesCmd fetches the rates of the currencies that expenses and
+// accounts are in and neither the FX sheet nor an earlier fetch has rates for, from the
+// oldest of their expenses on. They are kept for the session only; `tet
+// backfill --fx` stores them in the workbook.
+func (m *model) fetchRatesCmd() tea.Cmd {
+	if cfg.Offline {
+		return nil
+	}
+	from := make(map[string]time.Time)
+	for _, c := range m.missingRates() {
+		if m.ratesFetched[c] {
+			continue
+		}
+		from[c] = today()
+		for _, e := range m.statementRows() {
+			if symbolKey(e.Currency) == c && !e.Date.IsZero() && e.Date.Before(from[c]) {
+				from[c] = e.Date
+			}
+		}
+		for _, a := range m.accounts {
+			if a.Currency == c && !a.AsOf.IsZero() && a.AsOf.Before(from[c]) {
+				from[c] = a.AsOf
+			}
+		}
+	}
+	if len(from) == 0 {
+		return nil
+	}
+	if m.ratesFetched == nil {
+		m.ratesFetched = make(map[string]bool)
+	}
+	for c := range from {
+		m.ratesFetched[c] = true
+	}
+	base := strings.ToUpper(cfg.Rounding.Currency)
+	return func() tea.Msg {
+		msg := ratesMsg{rates: make(fxRates)}
+		for c, day := range from {
+			// A week earlier, so that an expense on a weekend or holiday
+			// has the close before it.
+			points, err := fetchCloses(fxTicker(c, base), day.AddDate(0, 0, -7), time.Now().UTC())
+			if err != nil || len(points) == 0 {
+				msg.failed = append(msg.failed, c)
+				continue
+			}
+			for i := range points {
+				points[i].Symbol = c
+			}
+			msg.rates[c] = points
+		}
+		slices.Sort(msg.failed)
+		return msg
+	}
+}
+
+// applyRates adds fetched rates to those of the workbook.
+func (m *model) applyRates(msg ratesMsg) {
+	if m.rates == nil {
+		m.rates = make(fxRates)
+	}
+	if m.fetchedRates == nil {
+		m.fetchedRates = make(fxRates)
+	}
+	var fetched []string
+	for c, series := range msg.rates {
+		m.rates[c], m.fetchedRates[c] = series, series
+		fetched = append(fetched, c)
+	}
+	slices.Sort(fetched)
+	switch {
+	case len(msg.failed) > 0:
+		m.status = "Could not fetch rates for " + strings.Join(msg.failed, ", ") + "."
+	case len(fetched) > 0:
+		m.status = fmt.Sprintf("Fetched %s rates for this session; `tet backfill --fx %s` keeps them in the workbook.",
+			strings.Join(fetched, ", "), fetched[0])
+	}
+	if m.currentScreen == screenExpenses {
+		m.updateExpensesTable()
+	} else {
+		m.expensesDirty = true
+	}
+}
+
+// baseOrCurrency is the Currency of an expense entered in currency, blank
+// when it is the configured one.
+func baseOrCurrency(currency string) string {
+	if !foreignCurrency(strings.TrimSpace(currency)) {
+		return ""
+	}
+	return symbolKey(currency)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// dateLayout is how expense dates are written to the workbook and typed in
+// forms.
+const dateLayout = "2006-01-02"
+
+// dateLayouts are the other ways dates typed by hand or exported by other
+// programs are written: with a time of day, with slashes or dots, or with
+// the month's name. Numeric dates are read day first, unless that is not
+// a date and month first is.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/1/2",
+	"2006.1.2",
+	"2/1/2006",
+	"1/2/2006",
+	"2.1.2006",
+	"2-1-2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	"2-Jan-2006",
+	"2-Jan-06",
+	"Jan 2, 2006",
+	"January 2, 2006",
+}
+
+// parseDateText reads a date written as text in any of the layouts.
+func parseDateText(s string) (time.Time, bool) {
+	for _, layout := range append([]string{dateLayout}, dateLayouts...) {
+		if t, err := time.Parse(layout, s); err == nil {
+			return dateOf(t), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseExpenseDate reads a Date cell, either as text or as the serial
+// number Excel stores real dates as. Unparseable cells count as undated.
+func parseExpenseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	if t, ok := parseDateText(s); ok {
+		return t
+	}
+	if serial, err := strconv.ParseFloat(s, 64); err == nil {
+		if t, err := excelize.ExcelDateToTime(serial, false); err == nil {
+			return dateOf(t)
+		}
+	}
+	return time.Time{}
+}
+
+// validateDate accepts a blank or written date in forms, such as
+// YYYY-MM-DD; bare numbers are not taken for Excel's serial dates there.
+func validateDate(s string) error {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	if _, ok := parseDateText(s); !ok {
+		return fmt.Errorf("%q is not a date; use YYYY-MM-DD", s)
+	}
+	return nil
+}
+
+func formatExpenseDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
+
+// dateOf drops the time of day, keeping dates comparable with ==.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func today() time.Time {
+	return dateOf(now())
+}
+
+// expenseMonths lists the months expenses are dated in, newest first.
+func expenseMonths(expenses []Expense) []time.Time {
+	var months []time.Time
+	for _, e := range expenses {
+		if month := monthOf(e.Date); !e.Date.IsZero() && !slices.Contains(months, month) {
+			months = append(months, month)
+		}
+	}
+	slices.SortFunc(months, func(a, b time.Time) int { return b.Compare(a) })
+	return months
+}
+
+// nextMonthFilter is the month filter after current: each month in turn,
+// newest first, then every month again.
+func nextMonthFilter(current time.Time, months []time.Time) time.Time {
+	i := slices.Index(months, current)
+	if !current.IsZero() && i < 0 {
+		return time.Time{}
+	}
+	if i+1 < len(months) {
+		return months[i+1]
+	}
+	return time.Time{}
+}
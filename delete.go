@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// expenseDeletedMsg answers deleteExpenseForm: expense is the row at index
+// to delete, and index is -1 when the user kept it.
+type expenseDeletedMsg struct {
+	index   int
+	expense Expense
+}
+
+// deleteExpenseForm asks before deleting the expense at index.
+func (m *model) deleteExpenseForm(index int) tea.Cmd {
+	e := m.expenses[index]
+	description := "It is removed from the workbook, which is backed up before saving."
+	if e.Pending == "" && needsApproval() {
+		description = fmt.Sprintf("It stays until %s approves the deletion.", cfg.Owner)
+	}
+	var confirmed bool
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("Delete %s?", expenseSummary(e))).
+				Description(description).
+				Affirmative("Delete").
+				Negative("Keep").
+				Value(&confirmed),
+		),
+	)
+	return func() tea.Msg {
+		if err := form.Run(); err != nil || !confirmed {
+			return expenseDeletedMsg{index: -1, expense: e}
+		}
+		return expenseDeletedMsg{index: index, expense: e}
+	}
+}
+
+// deleteExpenses deletes the marked rows of the expenses table, or else
+// the selected one, once the user confirms, unlocking its month first if
+// it is closed.
+func (m *model) deleteExpenses() (tea.Model, tea.Cmd) {
+	if len(m.marked) > 0 {
+		return m.startBulk(bulkDelete)
+	}
+	if m.readOnly {
+		m.status = "Read-only session: editing is disabled."
+		return m, nil
+	}
+	_, index := m.visibleRows()
+	if m.selectedRow >= len(index) || index[m.selectedRow] < 0 {
+		return m, nil
+	}
+	i := index[m.selectedRow]
+	m.editing = true
+	if e := m.expenses[i]; m.isLocked(e) {
+		return m, unlockForm(monthOf(e.Date), m.deleteExpenseForm(i))
+	}
+	return m, m.deleteExpenseForm(i)
+}
+
+// expenseDeleted deletes the expense the user confirmed and saves, unless
+// the row changed while they were asked, such as by a reload.
+func (m *model) expenseDeleted(msg expenseDeletedMsg) (tea.Model, tea.Cmd) {
+	m.editing = false
+	if msg.index < 0 {
+		m.status = "Kept " + expenseSummary(msg.expense) + "."
+		return m, nil
+	}
+	if msg.index >= len(m.expenses) || expenseSummary(m.expenses[msg.index]) != expenseSummary(msg.expense) {
+		m.status = expenseSummary(msg.expense) + " changed in the meantime; nothing was deleted."
+		return m, nil
+	}
+	m.expenses = applyDelete(m.expenses, msg.index)
+	if needsApproval() && msg.expense.Pending == "" {
+		m.status = fmt.Sprintf("Saved for %s to approve.", cfg.Owner)
+	} else {
+		m.status = "Deleted " + expenseSummary(msg.expense) + "."
+	}
+	if n := len(m.visibleExpenses()); m.selectedRow >= n {
+		m.selectedRow = max(n-1, 0)
+	}
+	m.updateExpensesTable()
+	return m, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil)
+}
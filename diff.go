@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type changeKind int
+
+const (
+	rowAdded changeKind = iota
+	rowRemoved
+	rowChanged
+)
+
+func (k changeKind) String() string {
+	switch k {
+	case rowAdded:
+		return "+"
+	case rowRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// rowChange is a single difference between two versions of a sheet.
+type rowChange struct {
+	kind          changeKind
+	sheet         string
+	key           string
+	before, after string
+}
+
+func (c rowChange) String() string {
+	switch c.kind {
+	case rowAdded:
+		return fmt.Sprintf("+ %s", c.after)
+	case rowRemoved:
+		return fmt.Sprintf("- %s", c.before)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.key, c.before, c.after)
+	}
+}
+
+// pairRows matches rows of a and b by key, pairing the n-th occurrence of
+// a key on each side. It returns the matched index pairs and the indexes
+// present on only one side.
+func pairRows[T any](a, b []T, key func(T) string) (pairs [][2]int, onlyA, onlyB []int) {
+	positions := make(map[string][]int)
+	for i, row := range a {
+		k := key(row)
+		positions[k] = append(positions[k], i)
+	}
+	matched := make([]bool, len(a))
+	seen := make(map[string]int)
+	for j, row := range b {
+		k := key(row)
+		n := seen[k]
+		seen[k]++
+		if n >= len(positions[k]) {
+			onlyB = append(onlyB, j)
+			continue
+		}
+		i := positions[k][n]
+		matched[i] = true
+		pairs = append(pairs, [2]int{i, j})
+	}
+	for i, ok := range matched {
+		if !ok {
+			onlyA = append(onlyA, i)
+		}
+	}
+	return pairs, onlyA, onlyB
+}
+
+func diffRows[T any](sheet string, a, b []T, key, render func(T) string) []rowChange {
+	pairs, onlyA, onlyB := pairRows(a, b, key)
+	var changes []rowChange
+	for _, i := range onlyA {
+		changes = append(changes, rowChange{kind: rowRemoved, sheet: sheet, key: key(a[i]), before: render(a[i])})
+	}
+	for _, p := range pairs {
+		if reflect.DeepEqual(a[p[0]], b[p[1]]) {
+			continue
+		}
+		changes = append(changes, rowChange{
+			kind: rowChanged, sheet: sheet, key: key(a[p[0]]),
+			before: render(a[p[0]]), after: render(b[p[1]]),
+		})
+	}
+	for _, j := range onlyB {
+		changes = append(changes, rowChange{kind: rowAdded, sheet: sheet, key: key(b[j]), after: render(b[j])})
+	}
+	return changes
+}
+
+func expenseKey(e Expense) string { return e.Name }
+func stonkKey(s Stonk) string     { return s.Symbol }
+func watchKey(w WatchItem) string { return w.Symbol }
+
+func expenseSummary(e Expense) string {
+	s := fmt.Sprintf("%s %s", e.Name, expenseAmount(e))
+	if !e.Date.IsZero() {
+		s = formatExpenseDate(e.Date) + " " + s
+	}
+	if e.Kind != kindExpense {
+		s += fmt.Sprintf(" (%s)", e.Kind)
+	}
+	if e.Category != "" {
+		s += " in " + e.Category
+	}
+	for _, tag := range e.Tags {
+		s += " #" + tag
+	}
+	if e.Payment != "" {
+		s += " @" + e.Payment
+	}
+	if e.Method != methodUnknown {
+		s += " by " + strings.ToLower(string(e.Method))
+	}
+	if e.Member != "" {
+		s += " for " + e.Member
+	}
+	if e.EnteredBy != "" {
+		s += " by " + e.EnteredBy
+	}
+	if e.Pending != "" {
+		s += " (pending)"
+	}
+	return s
+}
+func stonkSummary(s Stonk) string {
+	str := fmt.Sprintf("%s qty %g avg %g price %g open %g day %s", s.Symbol, s.Quantity, s.AvgPrice, s.Price, s.Open, formatPercent(s.DayChange))
+	if s.Currency != "" {
+		str += " " + s.Currency
+	}
+	if s.Comment != "" {
+		str += fmt.Sprintf(" %q", s.Comment)
+	}
+	return str
+}
+func watchSummary(w WatchItem) string {
+	s := fmt.Sprintf("%s qty %g", w.Symbol, w.Qty)
+	if w.TargetBuy != 0 || w.TargetSell != 0 {
+		s += fmt.Sprintf(" buy %g sell %g", w.TargetBuy, w.TargetSell)
+	}
+	if w.Note != "" {
+		s += fmt.Sprintf(" %q", w.Note)
+	}
+	return s
+}
+
+// diffDatasets lists every change needed to turn a into b, sheet by sheet.
+func diffDatasets(a, b Dataset) []rowChange {
+	var changes []rowChange
+	changes = append(changes, diffRows("Expenses", a.Expenses, b.Expenses, expenseKey, expenseSummary)...)
+	changes = append(changes, diffRows("Stonks", a.Stonks, b.Stonks, stonkKey, stonkSummary)...)
+	changes = append(changes, diffRows("WatchList", a.WatchList, b.WatchList, watchKey, watchSummary)...)
+	return changes
+}
+
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tet diff a.xlsx b.xlsx")
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errors.New("diff: expected two workbooks")
+	}
+
+	var data [2]Dataset
+	for i, path := range flags.Args() {
+		s, err := readOnlyStoreForPath(path)
+		if err != nil {
+			return err
+		}
+		if data[i], err = s.Load(); err != nil {
+			return fmt.Errorf("diff: reading %s: %w", path, err)
+		}
+	}
+
+	changes := diffDatasets(data[0], data[1])
+	for _, sheet := range sheetNames {
+		fmt.Println(sheet)
+		n := 0
+		for _, c := range changes {
+			if c.sheet == sheet {
+				fmt.Println("  " + c.String())
+				n++
+			}
+		}
+		if n == 0 {
+			fmt.Println("  no changes")
+		}
+	}
+	return nil
+}
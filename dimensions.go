@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// reportDimension is what expenses are grouped by: in the expenses table,
+// with 'g', and in the chart of `tet export`, with --by. The zero value
+// leaves them ungrouped.
+type reportDimension int
+
+const (
+	dimensionNone reportDimension = iota
+	dimensionCategory
+	dimensionTag
+	dimensionPayee
+	dimensionAccount
+	dimensionProject
+	dimensionMethod
+	dimensionMember
+)
+
+var dimensionNames = map[reportDimension]string{
+	dimensionCategory: "category",
+	dimensionTag:      "tag",
+	dimensionPayee:    "payee",
+	dimensionAccount:  "account",
+	dimensionProject:  "project",
+	dimensionMethod:   "method",
+	dimensionMember:   "member",
+}
+
+// projectTagPrefix marks the tag naming an expense's project, as in
+// project:Kitchen.
+const projectTagPrefix = "project:"
+
+func (d reportDimension) String() string {
+	return dimensionNames[d]
+}
+
+// parseDimension reads the name of a dimension, in any case.
+func parseDimension(name string) (reportDimension, error) {
+	for d := dimensionCategory; d <= dimensionMember; d++ {
+		if strings.EqualFold(strings.TrimSpace(name), d.String()) {
+			return d, nil
+		}
+	}
+	return dimensionNone, fmt.Errorf("unknown dimension %q (available: category, tag, payee, account, project, method, member)", name)
+}
+
+// next is the dimension after d: each in turn, then none again.
+func (d reportDimension) next() reportDimension {
+	return (d + 1) % (dimensionMember + 1)
+}
+
+// projectOf is the project e is tagged with, blank when it has none.
+func projectOf(e Expense) string {
+	for _, t := range e.Tags {
+		if len(t) > len(projectTagPrefix) && strings.EqualFold(t[:len(projectTagPrefix)], projectTagPrefix) {
+			return strings.TrimSpace(t[len(projectTagPrefix):])
+		}
+	}
+	return ""
+}
+
+// groups are the groups of d that e counts towards: its category, each of
+// its tags other than its project, its name, what it was paid with, its
+// project, how it was paid or who it was for, or else the group of
+// expenses without one.
+func (d reportDimension) groups(e Expense) []string {
+	var groups []string
+	switch d {
+	case dimensionCategory:
+		return []string{categoryLabel(e)}
+	case dimensionTag:
+		for _, t := range e.Tags {
+			if !strings.HasPrefix(strings.ToLower(t), projectTagPrefix) {
+				groups = append(groups, t)
+			}
+		}
+	case dimensionPayee:
+		groups = append(groups, strings.TrimSpace(e.Name))
+	case dimensionAccount:
+		groups = append(groups, strings.TrimSpace(e.Payment))
+	case dimensionProject:
+		groups = append(groups, projectOf(e))
+	case dimensionMethod:
+		groups = append(groups, string(e.Method))
+	case dimensionMember:
+		groups = append(groups, memberName(e.Member))
+	}
+	groups = slices.DeleteFunc(groups, func(g string) bool { return g == "" })
+	if len(groups) == 0 {
+		return []string{d.ungrouped()}
+	}
+	return groups
+}
+
+// ungrouped stands for the expenses without a group of d.
+func (d reportDimension) ungrouped() string {
+	return map[reportDimension]string{
+		dimensionCategory: uncategorised,
+		dimensionTag:      "Untagged",
+		dimensionPayee:    "No payee",
+		dimensionAccount:  "No account",
+		dimensionProject:  "No project",
+		dimensionMethod:   "No method",
+		dimensionMember:   noMember,
+	}[d]
+}
+
+// groupRows orders rows, and index along with them, by their first group
+// of d, those without one last, keeping the order of the rows within each.
+// Categories keep their subcategories after them.
+func groupRows(rows []Expense, index []int, d reportDimension) {
+	switch d {
+	case dimensionNone:
+		return
+	case dimensionCategory:
+		groupByCategory(rows, index)
+		return
+	}
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	key := func(e Expense) string {
+		group := d.groups(e)[0]
+		if group == d.ungrouped() {
+			return "\uffff"
+		}
+		return strings.ToLower(group)
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return strings.Compare(key(rows[a]), key(rows[b])) })
+	sortedRows := make([]Expense, len(rows))
+	sortedIndex := make([]int, len(index))
+	for i, j := range order {
+		sortedRows[i], sortedIndex[i] = rows[j], index[j]
+	}
+	copy(rows, sortedRows)
+	copy(index, sortedIndex)
+}
+
+// dimensionTotalsLine sums rows by their groups of d, in the order they
+// are grouped, for below the grouped expenses table. An expense with
+// several tags counts towards each. Pending rows count towards none. By
+// member, what each spent is broken down by category too.
+func dimensionTotalsLine(rows []Expense, d reportDimension, icons map[string]string) string {
+	if d == dimensionCategory {
+		return categoryTotalsLine(rows, icons)
+	}
+	var labels []string
+	totals := make(map[string]float64)
+	for _, e := range rows {
+		if e.Pending != "" {
+			continue
+		}
+		for _, label := range d.groups(e) {
+			key := strings.ToLower(label)
+			if _, ok := totals[key]; !ok {
+				labels = append(labels, label)
+			}
+			totals[key] = cfg.Rounding.round(totals[key] + e.Amount)
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		parts[i] = label + " " + money(totals[strings.ToLower(label)])
+	}
+	line := "By " + d.String() + ": " + strings.Join(parts, " · ") + "\n"
+	if d == dimensionMember {
+		line += memberCategoryLines(rows)
+	}
+	return line
+}
+
+// spendingBy sums the spending of each group of d, refunds taken off,
+// largest first. Groups differing only in case count as one, under the
+// name they first appear with.
+func spendingBy(rows []Expense, d reportDimension) (names []string, spent []float64) {
+	totals := make(map[string]float64)
+	var labels []string
+	for _, e := range rows {
+		var v float64
+		switch {
+		case e.Kind == kindRefund:
+			v = -math.Abs(e.Amount)
+		case e.Amount < 0:
+			v = -e.Amount
+		default:
+			continue
+		}
+		for _, label := range d.groups(e) {
+			key := strings.ToLower(label)
+			if _, ok := totals[key]; !ok {
+				labels = append(labels, label)
+			}
+			totals[key] += v
+		}
+	}
+	for _, label := range labels {
+		if totals[strings.ToLower(label)] > 0 {
+			names = append(names, label)
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool { return totals[strings.ToLower(names[i])] > totals[strings.ToLower(names[j])] })
+	for _, name := range names {
+		spent = append(spent, cfg.Rounding.round(totals[strings.ToLower(name)]))
+	}
+	return names, spent
+}
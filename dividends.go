@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+)
+
+// dividendConfig sets up reminders of upcoming ex-dividend dates.
+type dividendConfig struct {
+	// RemindDays reminds, when the TUI starts, of ex-dividend dates of held
+	// symbols up to this many days ahead; 0 turns reminders off.
+	RemindDays int `json:"remind_days,omitempty"`
+}
+
+// dividendEndpoint serves a symbol's declared and past dividends, as
+// Nasdaq's dividend history JSON.
+var dividendEndpoint = "https://api.nasdaq.com/api/quote/%s/dividends"
+
+// dividendEvent is one dividend of a held symbol.
+type dividendEvent struct {
+	Symbol string
+	// ExDate is the first day the stock trades without the dividend;
+	// PayDate is zero until it is announced.
+	ExDate, PayDate time.Time
+	// Amount is paid per share.
+	Amount float64
+	// Quantity is the position held now, which income assumes.
+	Quantity float64
+	// Estimated events are projected from past ones, not declared.
+	Estimated bool
+}
+
+func (e dividendEvent) income() float64 { return e.Amount * e.Quantity }
+
+// paidOn is when the dividend is paid, or goes ex when that is unknown.
+func (e dividendEvent) paidOn() time.Time {
+	if e.PayDate.IsZero() {
+		return e.ExDate
+	}
+	return e.PayDate
+}
+
+func (e dividendEvent) status(today time.Time) string {
+	switch {
+	case e.paidOn().Before(today):
+		return "paid"
+	case e.Estimated:
+		return "estimated"
+	default:
+		return "declared"
+	}
+}
+
+// fetchDividends downloads the dividend history of symbol, declared but
+// unpaid ones included, oldest first.
+func fetchDividends(symbol string) ([]dividendEvent, error) {
+	endpoint := fmt.Sprintf(dividendEndpoint, url.PathEscape(strings.ToLower(symbol))) + "?assetclass=stocks"
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; tet)")
+	req.Header.Set("Accept", "application/json")
+	resp, err := doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data *struct {
+			Dividends struct {
+				Rows []struct {
+					ExOrEffDate string `json:"exOrEffDate"`
+					PaymentDate string `json:"paymentDate"`
+					Amount      string `json:"amount"`
+				} `json:"rows"`
+			} `json:"dividends"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s: %s", symbol, resp.Status)
+	}
+	if body.Data == nil {
+		return nil, fmt.Errorf("%s: no dividend data", symbol)
+	}
+	var events []dividendEvent
+	for _, row := range body.Data.Dividends.Rows {
+		e := dividendEvent{Symbol: symbol, ExDate: parseUSDate(row.ExOrEffDate), PayDate: parseUSDate(row.PaymentDate)}
+		e.Amount, _ = strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(row.Amount), "$"), 64)
+		if e.ExDate.IsZero() || e.Amount <= 0 {
+			continue
+		}
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ExDate.Before(events[j].ExDate) })
+	return events, nil
+}
+
+// parseUSDate reads an MM/DD/YYYY date; anything else, such as "N/A", is
+// zero.
+func parseUSDate(s string) time.Time {
+	t, err := time.Parse("01/02/2006", strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// projectDividends extends history with estimated dividends up to until,
+// repeating the latest amount at the average spacing of the past year's
+// dividends. Symbols paying less than yearly are not projected.
+func projectDividends(history []dividendEvent, until time.Time) []dividendEvent {
+	if len(history) == 0 {
+		return nil
+	}
+	last := history[len(history)-1]
+	var recent int
+	for _, e := range history {
+		if !e.ExDate.Before(last.ExDate.AddDate(-1, 0, 0)) && e.ExDate.Before(last.ExDate) {
+			recent++
+		}
+	}
+	if recent == 0 {
+		return history
+	}
+	step := 12 / recent
+	if step == 0 {
+		step = 1
+	}
+	payLag := 0
+	if !last.PayDate.IsZero() {
+		payLag = int(last.PayDate.Sub(last.ExDate).Hours() / 24)
+	}
+	events := history
+	for k := 1; ; k++ {
+		e := last
+		e.Estimated = true
+		e.ExDate = last.ExDate.AddDate(0, k*step, 0)
+		if e.ExDate.After(until) {
+			break
+		}
+		e.PayDate = time.Time{}
+		if payLag > 0 {
+			e.PayDate = e.ExDate.AddDate(0, 0, payLag)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// dividendCalendar is the Dividends screen: this year's dividends and
+// those due in the next twelve months.
+type dividendCalendar struct {
+	events []dividendEvent
+	// received and projected are this year's income so far and in total.
+	received, projected float64
+	year                int
+	failed              []string
+}
+
+func buildDividendCalendar(held []Stonk, histories map[string][]dividendEvent, failed []string, today time.Time) dividendCalendar {
+	c := dividendCalendar{year: today.Year(), failed: failed}
+	start := time.Date(today.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	until := today.AddDate(1, 0, 0)
+	for _, s := range held {
+		for _, e := range projectDividends(histories[symbolKey(s.Symbol)], until) {
+			if e.ExDate.Before(start) && e.paidOn().Before(start) {
+				continue
+			}
+			e.Quantity = s.Quantity
+			c.events = append(c.events, e)
+			if e.paidOn().Year() == c.year {
+				c.projected += e.income()
+				if e.paidOn().Before(today) {
+					c.received += e.income()
+				}
+			}
+		}
+	}
+	sort.SliceStable(c.events, func(i, j int) bool { return c.events[i].ExDate.Before(c.events[j].ExDate) })
+	return c
+}
+
+// reminders lists the ex-dividend dates within days of today.
+func (c dividendCalendar) reminders(today time.Time, days int) []string {
+	var due []string
+	for _, e := range c.events {
+		if !e.ExDate.Before(today) && !e.ExDate.After(today.AddDate(0, 0, days)) {
+			due = append(due, fmt.Sprintf("%s goes ex-dividend on %s (%g per share)", e.Symbol, formatExpenseDate(e.ExDate), e.Amount))
+		}
+	}
+	return due
+}
+
+type dividendsMsg struct {
+	calendar dividendCalendar
+}
+
+// heldStonks are the positions with shares.
+func heldStonks(stonks []Stonk) []Stonk {
+	var held []Stonk
+	for _, s := range stonks {
+		if s.Quantity > 0 && symbolKey(s.Symbol) != "" {
+			held = append(held, s)
+		}
+	}
+	return held
+}
+
+// fetchDividendsCmd fetches the dividends of every held position, one
+// symbol after another.
+func fetchDividendsCmd(held []Stonk) tea.Cmd {
+	return func() tea.Msg {
+		histories := make(map[string][]dividendEvent)
+		var failed []string
+		for _, s := range held {
+			events, err := fetchDividends(strings.TrimSpace(s.Symbol))
+			if err != nil {
+				failed = append(failed, strings.TrimSpace(s.Symbol))
+				continue
+			}
+			histories[symbolKey(s.Symbol)] = events
+		}
+		return dividendsMsg{calendar: buildDividendCalendar(held, histories, failed, today())}
+	}
+}
+
+// dividendsCmd fetches the calendar, loading the positions first when
+// they have not been read yet.
+func (m *model) dividendsCmd() tea.Cmd {
+	if m.loaded&sheetStonks == 0 {
+		m.dividendsPending = true
+		return m.loadSheetCmd(sheetStonks)
+	}
+	m.dividendsPending = false
+	return fetchDividendsCmd(heldStonks(m.stonks))
+}
+
+func (m *model) applyDividends(c dividendCalendar) {
+	m.dividends = &c
+	if days := cfg.Dividends.RemindDays; days > 0 && !m.reminded {
+		m.reminded = true
+		if due := c.reminders(today(), days); len(due) > 0 {
+			m.status = "Dividend reminder: " + strings.Join(due, "; ")
+		}
+	}
+}
+
+func (m *model) viewDividends() string {
+	s := "=== DIVIDENDS ===\n"
+	s += m.sheetErrorBanner(sheetStonks)
+	c := m.dividends
+	switch {
+	case cfg.Offline:
+		s += offlineStyle.Render("Offline: dividend dates cannot be fetched.") + "\n"
+	case c == nil:
+		s += "Fetching dividends of the held positions…\n"
+	case len(c.events) == 0:
+		s += "No dividends this year or in the next twelve months for positions with a quantity.\n"
+	default:
+		s += m.dividendTable(*c)
+		s += fmt.Sprintf("\nDividend income %d: %s received, %s projected for the year.\n",
+			c.year, money(c.received), money(c.projected))
+		s += "Estimated dates repeat each symbol's latest dividend; income assumes the current quantity.\n"
+	}
+	if c != nil && len(c.failed) > 0 {
+		s += "No dividend data for " + strings.Join(c.failed, ", ") + ".\n"
+	}
+	s += "\nPress 'b' to go back.\n"
+	s += m.statusLine()
+	return s
+}
+
+func (m *model) dividendTable(c dividendCalendar) string {
+	now := today()
+	headers := []string{"Ex-date", "Pay date", "Symbol", "Per share", "Qty", "Income", "Status"}
+	var data [][]string
+	for _, e := range c.events {
+		data = append(data, []string{
+			formatExpenseDate(e.ExDate), formatExpenseDate(e.PayDate), e.Symbol,
+			strconv.FormatFloat(e.Amount, 'f', -1, 64), formatOptional(e.Quantity), money(e.income()), e.status(now),
+		})
+	}
+
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	dimStyle := baseStyle.Foreground(lipgloss.Color("240"))
+	estimatedStyle := baseStyle.Foreground(lipgloss.Color("245")).Italic(true)
+
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			switch c.events[row].status(now) {
+			case "paid":
+				return dimStyle
+			case "estimated":
+				return estimatedStyle
+			}
+			return rowStyle
+		})
+	return t.String()
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/xuri/excelize/v2"
+)
+
+// dumpTable is a sheet laid out for analysis tools such as pandas or
+// DuckDB: snake_case column names and one typed value per cell, a
+// float64, time.Time, bool or string, or nil where the sheet has none.
+type dumpTable struct {
+	name    string
+	columns []string
+	rows    [][]any
+}
+
+// snakeCase turns a header such as "Day Change %" into day_change_pct.
+func snakeCase(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "%", "pct")
+	var b strings.Builder
+	underscore := false
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			underscore = false
+		} else {
+			underscore = true
+		}
+	}
+	return b.String()
+}
+
+// columnType is the type every value of column i shares: float64,
+// time.Time or bool, or string when they differ or are text.
+func (t dumpTable) columnType(i int) any {
+	var typ any
+	for _, row := range t.rows {
+		switch v := row[i].(type) {
+		case nil:
+			continue
+		case float64, time.Time, bool:
+			if typ == nil {
+				typ = v
+			} else if fmt.Sprintf("%T", typ) != fmt.Sprintf("%T", v) {
+				return ""
+			}
+		default:
+			return ""
+		}
+	}
+	if typ == nil {
+		return ""
+	}
+	return typ
+}
+
+func dumpText(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(dateLayout)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return textCell(v)
+	}
+}
+
+func expensesDumpTable(current, archived []Expense) dumpTable {
+	t := dumpTable{name: "expenses", columns: []string{"date", "expense", "amount", "type", "entered_by", "pending", "category", "parent_category", "tags", "payment", "method", "member", "currency", "note", "return_by", "warranty_months", "usage", "usage_unit", "archived"}}
+	for i, e := range append(append([]Expense(nil), current...), archived...) {
+		var date, returnBy, warranty, usage any
+		if !e.Date.IsZero() {
+			date = e.Date
+		}
+		if !e.ReturnBy.IsZero() {
+			returnBy = e.ReturnBy
+		}
+		if e.WarrantyMonths > 0 {
+			warranty = e.WarrantyMonths
+		}
+		if e.Usage > 0 {
+			usage = e.Usage
+		}
+		kind := "expense"
+		if e.Kind == kindRefund {
+			kind = "refund"
+		}
+		t.rows = append(t.rows, []any{date, e.Name, e.Amount, kind, e.EnteredBy, e.Pending != "", e.Category, parentCategory(categoryName(e.Category)), formatTags(e.Tags), e.Payment, string(e.Method), e.Member, e.Currency, e.Note, returnBy, warranty, usage, e.UsageUnit, i >= len(current)})
+	}
+	return t
+}
+
+// cellsDumpTable lays out rows of cells in the order of headers, as
+// stonkCells and watchCells return them.
+func cellsDumpTable(name string, headers []string, rows [][]any) dumpTable {
+	t := dumpTable{name: name}
+	// Columns need names, and distinct ones, to be queried.
+	seen := make(map[string]bool)
+	for i, h := range headers {
+		col := snakeCase(h)
+		if col == "" {
+			col = fmt.Sprintf("column_%d", i+1)
+		}
+		for n, base := 2, col; seen[col]; n++ {
+			col = fmt.Sprintf("%s_%d", base, n)
+		}
+		seen[col] = true
+		t.columns = append(t.columns, col)
+	}
+	for _, row := range rows {
+		cells := make([]any, len(headers))
+		for i := range cells {
+			if i < len(row) {
+				if s, ok := row[i].(string); !ok || s != "" {
+					cells[i] = row[i]
+				}
+			}
+		}
+		t.rows = append(t.rows, cells)
+	}
+	return t
+}
+
+// sheetDumpTable reads any other sheet of the workbook, typing each column
+// by its values: numbers when they all are, dates when they are all
+// YYYY-MM-DD, text otherwise.
+func sheetDumpTable(f *excelize.File, sheet string) (dumpTable, error) {
+	raw, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil || len(raw) == 0 {
+		return dumpTable{name: snakeCase(sheet)}, err
+	}
+	var rows [][]any
+	for _, r := range raw[1:] {
+		row := make([]any, len(raw[0]))
+		empty := true
+		for i := range row {
+			if i < len(r) && strings.TrimSpace(r[i]) != "" {
+				row[i] = strings.TrimSpace(r[i])
+				empty = false
+			}
+		}
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+	for i := range raw[0] {
+		numbers, dates := true, true
+		for _, row := range rows {
+			s, ok := row[i].(string)
+			if !ok {
+				continue
+			}
+			if _, err := strconv.ParseFloat(s, 64); err != nil {
+				numbers = false
+			}
+			if _, err := time.Parse(dateLayout, s); err != nil {
+				dates = false
+			}
+		}
+		for _, row := range rows {
+			s, ok := row[i].(string)
+			switch {
+			case !ok:
+			case numbers:
+				row[i], _ = strconv.ParseFloat(s, 64)
+			case dates:
+				row[i], _ = time.Parse(dateLayout, s)
+			}
+		}
+	}
+	return cellsDumpTable(snakeCase(sheet), raw[0], rows), nil
+}
+
+// dumpTables gathers the tables of the data file at path: its expenses,
+// positions and watchlist and, with all, archived expenses and every other
+// sheet of a workbook, such as Prices, FX and History.
+func dumpTables(path string, all bool) ([]dumpTable, error) {
+	s, err := readOnlyStoreForPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	var archived []Expense
+	var extra []dumpTable
+	if all && isWorkbook(path) {
+		if archived, err = readArchive(path); err != nil {
+			return nil, err
+		}
+		f, err := openWorkbook(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		for _, sheet := range f.GetSheetList() {
+			if strings.HasPrefix(sheet, archiveSheetPrefix) || sheet == metaSheet || isTrackerSheet(sheet) {
+				continue
+			}
+			t, err := sheetDumpTable(f, sheet)
+			if err != nil {
+				return nil, fmt.Errorf("dump: %s sheet: %w", sheet, err)
+			}
+			// An empty sheet has no columns to make a table of.
+			if len(t.columns) == 0 {
+				continue
+			}
+			extra = append(extra, t)
+		}
+	}
+
+	tables := []dumpTable{expensesDumpTable(data.Expenses, archived)}
+	var rows [][]any
+	for _, st := range data.Stonks {
+		rows = append(rows, stonkCells(st))
+	}
+	tables = append(tables, cellsDumpTable("stonks", sheetHeaders["Stonks"], rows))
+	rows = nil
+	for _, w := range data.WatchList {
+		rows = append(rows, watchCells(w))
+	}
+	tables = append(tables, cellsDumpTable("watchlist", sheetHeaders["WatchList"], rows))
+	return append(tables, extra...), nil
+}
+
+func isTrackerSheet(sheet string) bool {
+	for _, name := range sheetNames {
+		if strings.EqualFold(name, sheet) {
+			return true
+		}
+	}
+	return false
+}
+
+// dumpFormats write a table to a file.
+var dumpFormats = map[string]func(file string, t dumpTable) error{
+	"csv":     writeDumpCSV,
+	"parquet": writeDumpParquet,
+}
+
+func writeDumpCSV(file string, t dumpTable) error {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(t.columns); err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = dumpText(v)
+		}
+		if err := w.Write(cells); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return writeFileAtomic(file, []byte(b.String()))
+}
+
+// writeDumpParquet writes t with a column per table column, every one
+// optional: numbers as doubles, dates as DATE, flags as booleans and the
+// rest as UTF-8 strings.
+func writeDumpParquet(file string, t dumpTable) error {
+	types := make([]any, len(t.columns))
+	group := parquet.Group{}
+	for i, name := range t.columns {
+		types[i] = t.columnType(i)
+		switch types[i].(type) {
+		case float64:
+			group[name] = parquet.Optional(parquet.Leaf(parquet.DoubleType))
+		case time.Time:
+			group[name] = parquet.Optional(parquet.Date())
+		case bool:
+			group[name] = parquet.Optional(parquet.Leaf(parquet.BooleanType))
+		default:
+			group[name] = parquet.Optional(parquet.String())
+		}
+	}
+	schema := parquet.NewSchema(t.name, group)
+
+	rows := make([]parquet.Row, len(t.rows))
+	for r, cells := range t.rows {
+		row := make(parquet.Row, len(t.columns))
+		for i, name := range t.columns {
+			leaf, _ := schema.Lookup(name)
+			v := parquet.NullValue()
+			if cells[i] != nil {
+				switch types[i].(type) {
+				case float64:
+					v = parquet.DoubleValue(cells[i].(float64))
+				case time.Time:
+					v = parquet.Int32Value(int32(cells[i].(time.Time).Unix() / 86400))
+				case bool:
+					v = parquet.BooleanValue(cells[i].(bool))
+				default:
+					v = parquet.ByteArrayValue([]byte(dumpText(cells[i])))
+				}
+				v = v.Level(0, 1, leaf.ColumnIndex)
+			} else {
+				v = v.Level(0, 0, leaf.ColumnIndex)
+			}
+			row[leaf.ColumnIndex] = v
+		}
+		rows[r] = row
+	}
+
+	var buf strings.Builder
+	w := parquet.NewWriter(&buf, schema)
+	if _, err := w.WriteRows(rows); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return writeFileAtomic(file, []byte(buf.String()))
+}
+
+// runDump writes the data for analysis: a file per table in --out.
+func runDump(args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "data file to dump")
+	all := flags.Bool("all", false, "also dump archived expenses and every other sheet of the workbook (Prices, FX, History, Budgets, …)")
+	format := flags.String("format", "csv", "csv or parquet")
+	out := flags.String("out", "", "directory to write to (default <file>-dump beside the data file)")
+	flags.Parse(args)
+
+	write, ok := dumpFormats[*format]
+	if !ok {
+		return fmt.Errorf("dump: unknown format %q (available: csv, parquet)", *format)
+	}
+	if _, err := os.Stat(*file); err != nil && !isSheetsPath(*file) {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("dump: %s does not exist", *file)
+		}
+		return err
+	}
+	tables, err := dumpTables(*file, *all)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(*file, filepath.Ext(*file)) + "-dump"
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		path := filepath.Join(*out, t.name+"."+*format)
+		if err := write(path, t); err != nil {
+			return fmt.Errorf("dump: %s: %w", path, err)
+		}
+		fmt.Printf("Dumped %d rows to %s.\n", len(t.rows), path)
+	}
+	return nil
+}
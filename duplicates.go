@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// duplicate is an expense being added that matches one already in the
+// book: same name, in any case, amount and date.
+type duplicate struct {
+	expense Expense
+	// of indexes the expense it matches.
+	of int
+}
+
+// duplicateOf is the index of the expense in expenses that e repeats, or
+// -1. Rows awaiting deletion do not count.
+func duplicateOf(expenses []Expense, e Expense) int {
+	return slices.IndexFunc(expenses, func(x Expense) bool {
+		return payeeKey(x.Name) == payeeKey(e.Name) && x.Amount == e.Amount && x.Date.Equal(e.Date) &&
+			!strings.HasPrefix(x.Pending, pendingDeletePrefix)
+	})
+}
+
+// findDuplicates splits incoming into the expenses new to the book and
+// those repeating one in it.
+func findDuplicates(existing, incoming []Expense) (fresh []Expense, dups []duplicate) {
+	for _, e := range incoming {
+		if i := duplicateOf(existing, e); i >= 0 {
+			dups = append(dups, duplicate{expense: e, of: i})
+		} else {
+			fresh = append(fresh, e)
+		}
+	}
+	return fresh, dups
+}
+
+// duplicateChoice is what to do with expenses already in the book.
+type duplicateChoice int
+
+const (
+	duplicateSkip duplicateChoice = iota
+	duplicateMerge
+	duplicateAdd
+)
+
+// parseDuplicateChoice reads the --duplicates flag of `tet import`.
+func parseDuplicateChoice(s string) (duplicateChoice, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "skip":
+		return duplicateSkip, nil
+	case "merge":
+		return duplicateMerge, nil
+	case "add":
+		return duplicateAdd, nil
+	}
+	return duplicateSkip, fmt.Errorf("unknown choice %q (available: skip, merge, add)", s)
+}
+
+func (c duplicateChoice) String() string {
+	return [...]string{"skipped", "merged", "added anyway"}[c]
+}
+
+// mergeExpense fills in what existing lacks from e: its category, payment,
+// method, member, currency, return date, warranty and usage when it has
+// none, e's tags it does not have, and e's note after its own.
+func mergeExpense(existing, e Expense) Expense {
+	if existing.Category == "" {
+		existing.Category = e.Category
+	}
+	if existing.Payment == "" {
+		existing.Payment = e.Payment
+	}
+	if existing.Method == methodUnknown {
+		existing.Method = e.Method
+	}
+	if existing.Member == "" {
+		existing.Member = e.Member
+	}
+	if existing.Currency == "" {
+		existing.Currency = e.Currency
+	}
+	if existing.ReturnBy.IsZero() {
+		existing.ReturnBy = e.ReturnBy
+	}
+	if existing.WarrantyMonths == 0 {
+		existing.WarrantyMonths = e.WarrantyMonths
+	}
+	if existing.Usage == 0 {
+		existing.Usage, existing.UsageUnit = e.Usage, e.UsageUnit
+	}
+	existing.Tags = slices.Clone(existing.Tags)
+	for _, t := range e.Tags {
+		if !hasTag(existing, t) {
+			existing.Tags = append(existing.Tags, t)
+		}
+	}
+	switch {
+	case existing.Note == "":
+		existing.Note = e.Note
+	case e.Note != "" && e.Note != existing.Note:
+		existing.Note += "\n" + e.Note
+	}
+	return existing
+}
+
+// addExpenses adds fresh to expenses and deals with dups as chosen, each
+// through applyEdit so that what needs approval waits for it.
+func addExpenses(expenses, fresh []Expense, dups []duplicate, choice duplicateChoice) []Expense {
+	for _, d := range dups {
+		switch choice {
+		case duplicateMerge:
+			expenses = applyEdit(expenses, d.of, mergeExpense(expenses[d.of], d.expense))
+		case duplicateAdd:
+			expenses = applyEdit(expenses, -1, d.expense)
+		}
+	}
+	for _, e := range fresh {
+		expenses = applyEdit(expenses, -1, e)
+	}
+	return expenses
+}
+
+// duplicatesMsg answers duplicatesForm with the choice for dups, skipping
+// them when the form was cancelled. status reports the rest of the add.
+type duplicatesMsg struct {
+	fresh  []Expense
+	dups   []duplicate
+	choice duplicateChoice
+	status string
+}
+
+// duplicatesForm asks once what to do with dups before adding fresh with
+// them.
+func (m *model) duplicatesForm(fresh []Expense, dups []duplicate, status string) (tea.Model, tea.Cmd) {
+	title := fmt.Sprintf("%d of the %d expenses being added are already in the book.", len(dups), len(fresh)+len(dups))
+	if len(fresh) == 0 && len(dups) == 1 {
+		d := dups[0]
+		title = fmt.Sprintf("%s is already in the book as row %d.", expenseSummary(d.expense), d.of+1)
+	}
+	choice := duplicateSkip
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[duplicateChoice]().
+				Title(title).
+				Description("Merging fills in the category, tags, payment and note the existing row lacks.").
+				Options(
+					huh.NewOption("Skip", duplicateSkip),
+					huh.NewOption("Merge into the existing row", duplicateMerge),
+					huh.NewOption("Add anyway", duplicateAdd),
+				).
+				Value(&choice),
+		),
+	)
+	m.editing = true
+	return m, func() tea.Msg {
+		if err := form.Run(); err != nil {
+			choice = duplicateSkip
+		}
+		return duplicatesMsg{fresh: fresh, dups: dups, choice: choice, status: status}
+	}
+}
+
+// duplicatesResolved adds the expenses as the user chose and saves them.
+func (m *model) duplicatesResolved(msg duplicatesMsg) (tea.Model, tea.Cmd) {
+	m.editing = false
+	m.currentScreen = screenExpenses
+	if len(msg.fresh) == 0 && msg.choice == duplicateSkip {
+		m.status = strings.TrimSpace(msg.status + " Kept the book as it was.")
+		return m, nil
+	}
+	m.expenses = addExpenses(m.expenses, msg.fresh, msg.dups, msg.choice)
+	switch {
+	case len(msg.fresh) == 0 && len(msg.dups) == 1 && msg.choice == duplicateMerge:
+		m.status = fmt.Sprintf("Merged into row %d.", msg.dups[0].of+1)
+	case len(msg.fresh) == 0 && len(msg.dups) == 1:
+		m.status = "Added it anyway."
+	default:
+		m.status = strings.TrimSpace(fmt.Sprintf("%s %d duplicates %s.", msg.status, len(msg.dups), msg.choice))
+	}
+	if needsApproval() {
+		m.status += fmt.Sprintf(" Saved for %s to approve.", cfg.Owner)
+	}
+	m.updateExpensesTable()
+	return m, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil)
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"math"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// emailConfig polls a mailbox for e-receipts and order confirmations,
+// staging the expenses found in them on the Review screen.
+type emailConfig struct {
+	// Server is the IMAP server as host:port, reached over TLS, such as
+	// imap.gmail.com:993. Mail is not polled without one.
+	Server string `json:"server,omitempty"`
+	User   string `json:"user,omitempty"`
+	// PasswordCommand prints the password, such as `pass show mail/tet`,
+	// so that it need not be written in the config file. Without one it is
+	// read from $TET_IMAP_PASSWORD.
+	PasswordCommand string `json:"password_command,omitempty"`
+	// Mailbox is the folder searched, or with Gmail the label; INBOX by
+	// default.
+	Mailbox string `json:"mailbox,omitempty"`
+	// Interval is how often the mailbox is checked, e.g. "30m"; 15 minutes
+	// by default.
+	Interval string `json:"interval,omitempty"`
+	// Merchants say which messages are receipts and where their amount
+	// is. Messages matching none are left alone.
+	Merchants []merchantPattern `json:"merchants,omitempty"`
+}
+
+// merchantPattern recognizes the receipts of one merchant.
+type merchantPattern struct {
+	// Name is what the expenses are called.
+	Name string `json:"name"`
+	// From is part of the sender's address, such as "amazon.com", matched
+	// case-insensitively.
+	From string `json:"from"`
+	// Subject is a regular expression the subject must match, such as
+	// "^Your order"; any subject does when it is empty.
+	Subject string `json:"subject,omitempty"`
+	// Amount is a regular expression finding the amount in the message
+	// text, its first group capturing the number, such as
+	// `Order Total: \$([0-9.,]+)`.
+	Amount string `json:"amount"`
+	// DecimalComma reads amounts like 1.234,56.
+	DecimalComma bool `json:"decimal_comma,omitempty"`
+}
+
+const imapPasswordEnv = "TET_IMAP_PASSWORD"
+
+// emailLookback is how far back the first check of a mailbox looks.
+const emailLookback = 30 * 24 * time.Hour
+
+// pendingEmailPrefix marks an expense found in an e-receipt, followed by
+// the message's subject. Like a receipt, it waits on the Review screen.
+const pendingEmailPrefix = "Email "
+
+func (c emailConfig) validate() error {
+	if c.Server == "" {
+		return nil
+	}
+	if c.User == "" {
+		return errors.New("email: set the user to log in to " + c.Server + " as")
+	}
+	if c.Interval != "" {
+		d, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return fmt.Errorf("email: interval: %w", err)
+		}
+		if d < time.Minute {
+			return fmt.Errorf("email: interval must be at least a minute, not %s", d)
+		}
+	}
+	for i, p := range c.Merchants {
+		if p.Name == "" || p.From == "" || p.Amount == "" {
+			return fmt.Errorf("email: merchant %d needs a name, from and amount", i+1)
+		}
+		if _, err := regexp.Compile(p.Subject); err != nil {
+			return fmt.Errorf("email: merchant %s: subject: %w", p.Name, err)
+		}
+		if _, err := regexp.Compile(p.Amount); err != nil {
+			return fmt.Errorf("email: merchant %s: amount: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func (c emailConfig) interval() time.Duration {
+	if d, err := time.ParseDuration(c.Interval); err == nil {
+		return d
+	}
+	return 15 * time.Minute
+}
+
+func (c emailConfig) mailbox() string {
+	if c.Mailbox == "" {
+		return "INBOX"
+	}
+	return c.Mailbox
+}
+
+func (c emailConfig) password() (string, error) {
+	if c.PasswordCommand == "" {
+		if p := os.Getenv(imapPasswordEnv); p != "" {
+			return p, nil
+		}
+		return "", fmt.Errorf("email: set password_command in the config file or $%s", imapPasswordEnv)
+	}
+	out, err := shellCommand(c.PasswordCommand).Output()
+	if err != nil {
+		return "", fmt.Errorf("email: password_command: %w", err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// match returns the merchant whose receipt the message is, or nil.
+func (c emailConfig) match(from, subject string) *merchantPattern {
+	for i, p := range c.Merchants {
+		if !strings.Contains(strings.ToLower(from), strings.ToLower(p.From)) {
+			continue
+		}
+		if ok, _ := regexp.MatchString(p.Subject, subject); ok {
+			return &c.Merchants[i]
+		}
+	}
+	return nil
+}
+
+// amount finds the amount in the text of a receipt, as spent.
+func (p merchantPattern) amount(text string) (float64, error) {
+	m := regexp.MustCompile(p.Amount).FindStringSubmatch(text)
+	if m == nil {
+		return 0, errors.New("no amount found")
+	}
+	s := m[0]
+	if len(m) > 1 {
+		s = m[1]
+	}
+	amt, err := parseCSVAmount(s, p.DecimalComma)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q is not a number", s)
+	}
+	return -math.Abs(amt), nil
+}
+
+// emailState is how far each mailbox has been read, kept in
+// email-state.json beside the config file so that no message is staged
+// twice. A mailbox whose UIDVALIDITY changes is read afresh.
+type emailState map[string]mailboxState
+
+type mailboxState struct {
+	UIDValidity uint32 `json:"uid_validity"`
+	LastUID     uint32 `json:"last_uid"`
+}
+
+func emailStatePath() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "email-state.json"), nil
+}
+
+func loadEmailState() (emailState, error) {
+	state := make(emailState)
+	path, err := emailStatePath()
+	if err != nil {
+		return state, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return state, fmt.Errorf("%s: %w", path, err)
+	}
+	return state, nil
+}
+
+func saveEmailState(state emailState) error {
+	path, err := emailStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+// imapDial connects to an IMAP server.
+var imapDial = func(addr string) (*client.Client, error) {
+	return client.DialTLS(addr, nil)
+}
+
+// emailReceipts are the expenses found in one check of the mailbox, and
+// the receipts they could not be read from.
+type emailReceipts struct {
+	expenses []Expense
+	failed   []string
+}
+
+// fetchEmailReceipts reads the messages that arrived since the last check,
+// read-only so that none is marked as read, and stages those matching a
+// merchant pattern.
+func fetchEmailReceipts() (emailReceipts, error) {
+	var result emailReceipts
+	c := cfg.Email
+	if cfg.Offline {
+		return result, fmt.Errorf("email: %w", errOffline)
+	}
+	password, err := c.password()
+	if err != nil {
+		return result, err
+	}
+	conn, err := imapDial(c.Server)
+	if err != nil {
+		return result, fmt.Errorf("email: %w", err)
+	}
+	defer conn.Logout()
+	if err := conn.Login(c.User, password); err != nil {
+		return result, fmt.Errorf("email: %w", err)
+	}
+	status, err := conn.Select(c.mailbox(), true)
+	if err != nil {
+		return result, fmt.Errorf("email: %s: %w", c.mailbox(), err)
+	}
+
+	state, err := loadEmailState()
+	if err != nil {
+		return result, err
+	}
+	key := c.User + "@" + c.Server + "/" + c.mailbox()
+	seen := state[key]
+	criteria := imap.NewSearchCriteria()
+	if seen.UIDValidity != status.UidValidity {
+		seen = mailboxState{UIDValidity: status.UidValidity}
+		criteria.Since = time.Now().Add(-emailLookback)
+	}
+	criteria.Uid = new(imap.SeqSet)
+	criteria.Uid.AddRange(seen.LastUID+1, 0)
+	uids, err := conn.UidSearch(criteria)
+	if err != nil {
+		return result, fmt.Errorf("email: %w", err)
+	}
+	// n:* always includes the newest message, even when it is older than n.
+	set := new(imap.SeqSet)
+	for _, uid := range uids {
+		if uid > seen.LastUID {
+			set.AddNum(uid)
+		}
+	}
+	if set.Empty() {
+		state[key] = seen
+		return result, saveEmailState(state)
+	}
+
+	section := &imap.BodySectionName{Peek: true}
+	messages := make(chan *imap.Message, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- conn.UidFetch(set, []imap.FetchItem{imap.FetchUid, imap.FetchEnvelope, imap.FetchInternalDate, section.FetchItem()}, messages)
+	}()
+	last := seen.LastUID
+	for msg := range messages {
+		last = max(last, msg.Uid)
+		if msg.Envelope == nil || len(msg.Envelope.From) == 0 {
+			continue
+		}
+		subject := strings.TrimSpace(msg.Envelope.Subject)
+		p := c.match(msg.Envelope.From[0].Address(), subject)
+		if p == nil {
+			continue
+		}
+		body := msg.GetBody(section)
+		if body == nil {
+			result.failed = append(result.failed, subject+": no body")
+			continue
+		}
+		text, err := messageText(body)
+		var amount float64
+		if err == nil {
+			amount, err = p.amount(text)
+		}
+		if err != nil {
+			result.failed = append(result.failed, fmt.Sprintf("%s: %v", subject, err))
+			continue
+		}
+		// Messages without a Date header are dated when they arrived.
+		date := msg.Envelope.Date
+		if date.IsZero() {
+			date = msg.InternalDate
+		}
+		result.expenses = append(result.expenses, Expense{
+			Name:      p.Name,
+			Amount:    cfg.Rounding.round(amount),
+			Date:      dateOf(date.Local()),
+			EnteredBy: currentUser(),
+			Pending:   pendingEmailPrefix + subject,
+		})
+	}
+	if err := <-done; err != nil {
+		return result, fmt.Errorf("email: %w", err)
+	}
+	seen.LastUID = last
+	state[key] = seen
+	return result, saveEmailState(state)
+}
+
+var (
+	htmlTags   = regexp.MustCompile(`(?s)<(style|script)[^>]*>.*?</(style|script)>|<[^>]+>`)
+	htmlSpaces = regexp.MustCompile(`[ \t\r\n]+`)
+)
+
+// messageText is the text of a message for amount patterns to search: its
+// plain-text parts or, when it has none, its HTML parts without the tags.
+func messageText(r io.Reader) (string, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return "", err
+	}
+	var plain, htm []string
+	if err := collectText(mail.Header(msg.Header), msg.Body, &plain, &htm); err != nil {
+		return "", err
+	}
+	if len(plain) > 0 {
+		return strings.Join(plain, "\n"), nil
+	}
+	text := html.UnescapeString(htmlTags.ReplaceAllString(strings.Join(htm, "\n"), " "))
+	return htmlSpaces.ReplaceAllString(text, " "), nil
+}
+
+func collectText(header mail.Header, body io.Reader, plain, htm *[]string) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		parts := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := parts.NextRawPart()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if err := collectText(mail.Header(part.Header), part, plain, htm); err != nil {
+				return err
+			}
+		}
+	}
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &newlineSkipper{body})
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	switch mediaType {
+	case "text/plain":
+		*plain = append(*plain, string(b))
+	case "text/html":
+		*htm = append(*htm, string(b))
+	}
+	return nil
+}
+
+// newlineSkipper drops the line breaks base64 bodies are wrapped with.
+type newlineSkipper struct{ r io.Reader }
+
+func (s *newlineSkipper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	n = copy(p, bytes.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, p[:n]))
+	return n, err
+}
+
+// emailReceiptsMsg is the result of one check of the mailbox.
+type emailReceiptsMsg struct {
+	emailReceipts
+	err error
+}
+
+// pollEmailCmd checks the mailbox after delay.
+func pollEmailCmd(delay time.Duration) tea.Cmd {
+	if cfg.Email.Server == "" || cfg.Offline {
+		return nil
+	}
+	return func() tea.Msg {
+		time.Sleep(delay)
+		r, err := fetchEmailReceipts()
+		return emailReceiptsMsg{emailReceipts: r, err: err}
+	}
+}
+
+// stageEmailReceipts adds the expenses found in e-receipts for review and
+// arms the next check. A failed check is retried at the next one.
+func (m *model) stageEmailReceipts(msg emailReceiptsMsg) (tea.Model, tea.Cmd) {
+	next := pollEmailCmd(cfg.Email.interval())
+	var notes []string
+	if msg.err != nil {
+		notes = append(notes, msg.err.Error())
+	}
+	if n := len(msg.expenses); n == 1 {
+		notes = append(notes, "1 e-receipt is waiting on the Review screen")
+	} else if n > 1 {
+		notes = append(notes, fmt.Sprintf("%d e-receipts are waiting on the Review screen", n))
+	}
+	if len(msg.failed) > 0 {
+		notes = append(notes, "could not read "+strings.Join(msg.failed, "; "))
+	}
+	if len(notes) > 0 {
+		m.status = strings.Join(notes, "; ") + "."
+	}
+	if len(msg.expenses) == 0 {
+		return m, next
+	}
+	m.expenses = append(m.expenses, msg.expenses...)
+	m.updateExpensesTable()
+	return m, tea.Batch(next, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil))
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// errorAction is a way out of an error panel.
+type errorAction int
+
+const (
+	actionRetry errorAction = iota
+	actionOpenBackup
+	actionReadOnly
+	actionKeepPrices
+)
+
+// label is the action's button; its first letter picks it too.
+func (a errorAction) label() string {
+	switch a {
+	case actionRetry:
+		return "Retry"
+	case actionOpenBackup:
+		return "Open backup"
+	case actionReadOnly:
+		return "Switch to read-only"
+	case actionKeepPrices:
+		return "Keep last prices"
+	}
+	return ""
+}
+
+var (
+	errorButtonStyle = lipgloss.NewStyle().
+				Padding(0, 1).
+				Foreground(lipgloss.Color("252")).
+				Background(lipgloss.Color("238"))
+	errorSelectedButtonStyle = errorButtonStyle.
+					Foreground(lipgloss.Color("229")).
+					Background(lipgloss.Color("57")).
+					Bold(true)
+)
+
+// errorPanel takes over the screen when something failed that the user can
+// do something about, offering the ways out instead of a restart. It says
+// what happened in a sentence, keeping the error itself for 'd'.
+type errorPanel struct {
+	title   string
+	summary string
+	err     error
+	actions []errorAction
+	// hints explain each action under the buttons while it is chosen.
+	hints   map[errorAction]string
+	cursor  int
+	details bool
+	retry   tea.Cmd
+	// backup is opened read-only by actionOpenBackup when the workbook
+	// cannot be read; otherwise the action goes to the Snapshots screen.
+	backup string
+}
+
+// saveFailedMsg reports that saving failed; retry saves the same data
+// again.
+type saveFailedMsg struct {
+	err   error
+	retry tea.Cmd
+}
+
+// loadFailedMsg reports that reading the workbook failed; retry reads it
+// again.
+type loadFailedMsg struct {
+	err   error
+	retry tea.Cmd
+}
+
+// backupOpenedMsg carries a backup opened in place of a workbook that
+// cannot be read.
+type backupOpenedMsg struct {
+	backup string
+	data   excelDataMsg
+}
+
+func openBackupCmd(backup string, sheets sheetMask) tea.Cmd {
+	return func() tea.Msg {
+		data, err := loadSheets(backup, sheets)
+		if err != nil {
+			return loadFailedMsg{err: fmt.Errorf("opening %s: %w", filepath.Base(backup), err), retry: openBackupCmd(backup, sheets)}
+		}
+		return backupOpenedMsg{backup: backup, data: data}
+	}
+}
+
+// loadSheetsCmd reads sheets of path, reporting a failure as one the user
+// can retry.
+func loadSheetsCmd(path string, sheets sheetMask) tea.Cmd {
+	return func() tea.Msg {
+		data, err := loadSheets(path, sheets)
+		if err != nil {
+			return loadFailedMsg{err: err, retry: loadSheetsCmd(path, sheets)}
+		}
+		return data
+	}
+}
+
+// backupActions are the ways out of a failed save or load: a backup can
+// only be opened when there is one.
+func (m *model) backupActions() []errorAction {
+	actions := []errorAction{actionRetry}
+	if len(listSnapshots(m.path)) > 0 {
+		actions = append(actions, actionOpenBackup)
+	}
+	if !m.readOnly {
+		actions = append(actions, actionReadOnly)
+	}
+	return actions
+}
+
+// saveFailed opens the panel for a failed save, over any other: the edit
+// is only saved if it is retried.
+func (m *model) saveFailed(msg saveFailedMsg) {
+	name := filepath.Base(m.path)
+	p := &errorPanel{
+		title:   "Could not save",
+		summary: "Your change could not be saved to " + name + ". It is kept on screen until it is.",
+		err:     msg.err,
+		actions: m.backupActions(),
+		retry:   msg.retry,
+		hints: map[errorAction]string{
+			actionRetry:      "Save the change again.",
+			actionOpenBackup: "Compare the backups with the workbook, and restore one, on the Snapshots screen.",
+			actionReadOnly:   "Carry on browsing without saving anything more this session.",
+		},
+	}
+	var locked *lockedError
+	if errors.As(msg.err, &locked) {
+		p.summary = "Another program is saving " + name + ", so your change was not saved. It is kept on screen until it is."
+		p.hints[actionRetry] = "Save the change again, once the other program is done."
+	}
+	m.failure = p
+}
+
+// loadFailed opens the panel for a failed read, unless one is open already:
+// a failed save still has an edit to retry.
+func (m *model) loadFailed(msg loadFailedMsg) {
+	if m.failure != nil {
+		return
+	}
+	p := &errorPanel{
+		title:   "Could not read the workbook",
+		summary: filepath.Base(m.path) + " could not be read, so the tables show what was last loaded from it.",
+		err:     msg.err,
+		actions: m.backupActions(),
+		retry:   msg.retry,
+		hints: map[errorAction]string{
+			actionRetry:    "Read the workbook again.",
+			actionReadOnly: "Keep what was last loaded without saving over the workbook this session.",
+		},
+	}
+	if snapshots := listSnapshots(m.path); len(snapshots) > 0 {
+		p.backup = snapshots[0]
+		p.hints[actionOpenBackup] = "Show " + filepath.Base(p.backup) + ", the newest backup, read-only."
+	}
+	m.failure = p
+}
+
+// quotesFailed opens the panel when no symbol got a price, once until
+// prices come through again.
+func (m *model) quotesFailed(msg quotesMsg) {
+	if len(msg.failed) == 0 || len(msg.quotes) > 0 {
+		m.quotesDown = false
+		return
+	}
+	if m.quotesDown || m.failure != nil {
+		return
+	}
+	m.quotesDown = true
+	m.failure = &errorPanel{
+		title:   "Could not fetch prices",
+		summary: msg.provider + " could not be reached, so prices are as last fetched.",
+		err:     errors.New("no quote for " + strings.Join(msg.failed, ", ")),
+		actions: []errorAction{actionRetry, actionKeepPrices},
+		retry:   refreshQuotesCmd(msg.failed),
+		hints: map[errorAction]string{
+			actionRetry:      "Fetch the prices again.",
+			actionKeepPrices: "Carry on with the last prices until the next refresh.",
+		},
+	}
+}
+
+// updateFailure handles the keys of the error panel: ←/→ choose an action,
+// enter or its first letter takes it, 'd' shows the error itself and esc
+// closes the panel.
+func (m *model) updateFailure(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	p := m.failure
+	switch key.String() {
+	case "left", "shift+tab":
+		p.cursor = (p.cursor + len(p.actions) - 1) % len(p.actions)
+	case "right", "tab":
+		p.cursor = (p.cursor + 1) % len(p.actions)
+	case "d":
+		p.details = !p.details
+	case "enter":
+		return m.takeAction(p.actions[p.cursor])
+	case "esc":
+		m.failure = nil
+	case "ctrl+c":
+		return m, tea.Quit
+	default:
+		for _, a := range p.actions {
+			if key.String() == strings.ToLower(a.label()[:1]) {
+				return m.takeAction(a)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *model) takeAction(a errorAction) (tea.Model, tea.Cmd) {
+	p := m.failure
+	m.failure = nil
+	switch a {
+	case actionRetry:
+		return m, p.retry
+	case actionOpenBackup:
+		if p.backup != "" {
+			return m, openBackupCmd(p.backup, m.loaded)
+		}
+		m.currentScreen = screenSnapshots
+		m.snapshots = listSnapshots(m.path)
+		m.snapshotRow = 0
+		m.snapshotDiff = nil
+	case actionReadOnly:
+		m.readOnly = true
+		m.status = "Read-only session: nothing more is saved."
+	case actionKeepPrices:
+		m.status = "Showing the last prices fetched."
+	}
+	return m, nil
+}
+
+func (m *model) viewFailure() string {
+	p := m.failure
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	buffer.WriteString(screenTitleStyle.Render(p.title))
+	buffer.WriteString("\n\n")
+	buffer.WriteString(errorBannerStyle.Render("⚠ " + p.summary))
+	buffer.WriteString("\n\n  ")
+	for i, a := range p.actions {
+		if i > 0 {
+			buffer.WriteString("  ")
+		}
+		if i == p.cursor {
+			buffer.WriteString(errorSelectedButtonStyle.Render(a.label()))
+		} else {
+			buffer.WriteString(errorButtonStyle.Render(a.label()))
+		}
+	}
+	buffer.WriteString("\n  " + p.hints[p.actions[p.cursor]] + "\n")
+	if p.details {
+		buffer.WriteString("\n  Details: " + p.err.Error() + "\n")
+	}
+	buffer.WriteString("\nPress ←/→ to choose and 'enter' to go ahead, 'd' to show or hide the details, 'esc' to close.\n")
+	return buffer.String()
+}
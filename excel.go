@@ -1 +1,291 @@
 package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetNames are the worksheets the tracker reads and writes.
+var sheetNames = []string{"Expenses", "Stonks", "WatchList"}
+
+// sheetHeaders is the header row written to each sheet of a new workbook.
+var sheetHeaders = map[string][]string{
+	"Expenses":  {"Expense", "Amount", "Type", "Date", "Entered By", "Pending", "Category", "Tags", "Payment", "Currency", "Note", "Method", "Member", "Return By", "Warranty", "Usage"},
+	"Stonks":    {"Symbol", "Quantity", "Avg Price", "Price", "Open", "Day Change %", "Total Return", "Currency", "Comment"},
+	"WatchList": {"Symbol", "Qty", "Target Buy", "Target Sell", "Price", "Note"},
+}
+
+// excelStore is the Store backed by an .xlsx workbook.
+type excelStore struct {
+	path     string
+	upgraded bool
+	// readOnly stores never modify the file, not even to upgrade it.
+	readOnly bool
+}
+
+func newExcelStore(path string) (Store, error) {
+	return &excelStore{path: path}, nil
+}
+
+var errReadOnly = errors.New("store is read-only")
+
+// upgrade migrates an older workbook to the current schema the first time
+// the store touches it.
+func (s *excelStore) upgrade() error {
+	if s.upgraded {
+		return nil
+	}
+	if _, err := upgradeWorkbook(s.path); err != nil {
+		return err
+	}
+	s.upgraded = true
+	return nil
+}
+
+func (s *excelStore) Load() (Dataset, error) {
+	var data excelDataMsg
+	var err error
+	if s.readOnly {
+		data, err = readUpgraded(s.path)
+	} else if err = s.upgrade(); err == nil {
+		data, err = readExcelData(s.path)
+	}
+	if err == nil {
+		// Unlike the TUI, callers of a Store would silently drop a broken
+		// sheet's rows, so any sheet error fails the load.
+		err = data.err()
+	}
+	if err != nil {
+		return Dataset{}, err
+	}
+	sheets, err := readOtherSheets(s.path)
+	if err != nil {
+		return Dataset{}, err
+	}
+	return Dataset{
+		Expenses:  data.expenses,
+		Stonks:    data.stonks,
+		WatchList: data.watchList,
+		Sheets:    sheets,
+		Revision:  data.revision,
+	}, nil
+}
+
+func (s *excelStore) Save(data Dataset) error {
+	if s.readOnly {
+		return errReadOnly
+	}
+	if _, err := os.Stat(s.path); errors.Is(err, fs.ErrNotExist) {
+		if err := createWorkbook(s.path, Dataset{}); err != nil {
+			return err
+		}
+	}
+	if err := s.upgrade(); err != nil {
+		return err
+	}
+	return writeExcelData(s.path, data.Revision, data.Expenses, data.Stonks, data.WatchList, data.Sheets)
+}
+
+// derivedSheets are sheets a workbook keeps for itself, which need not
+// follow its data elsewhere: the schema stamp and the quotes last fetched.
+var derivedSheets = []string{metaSheet, quotesSheet}
+
+// readOtherSheets reads the sheets of filename besides sheetNames and
+// derivedSheets for Dataset.Sheets, leaving out empty ones.
+func readOtherSheets(filename string) (map[string][][]string, error) {
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return otherSheets(f)
+}
+
+// otherSheets is readOtherSheets for a workbook already open.
+func otherSheets(f *excelize.File) (map[string][][]string, error) {
+	var sheets map[string][][]string
+	for _, name := range f.GetSheetList() {
+		if slices.Contains(sheetNames, name) || slices.Contains(derivedSheets, name) {
+			continue
+		}
+		rows, err := f.GetRows(name, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		// Blanked rows are still there, without values.
+		for len(rows) > 0 && !slices.ContainsFunc(rows[len(rows)-1], func(c string) bool { return c != "" }) {
+			rows = rows[:len(rows)-1]
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		if sheets == nil {
+			sheets = make(map[string][][]string)
+		}
+		sheets[name] = rows
+	}
+	return sheets, nil
+}
+
+// writeOtherSheets replaces the rows of each of sheets in f, adding the
+// sheets it lacks. Sheets already holding those rows are left alone.
+func writeOtherSheets(f *excelize.File, sheets map[string][][]string) error {
+	for _, name := range sortedSheets(sheets) {
+		if idx, _ := f.GetSheetIndex(name); idx < 0 {
+			if _, err := f.NewSheet(name); err != nil {
+				return err
+			}
+		}
+		previous, err := f.GetRows(name, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return err
+		}
+		rows := sheets[name]
+		if slices.EqualFunc(rows, previous, slices.Equal) {
+			continue
+		}
+		for r := range max(len(rows), len(previous)) {
+			// Cells past the end of the new row are blanked.
+			var width int
+			if r < len(previous) {
+				width = len(previous[r])
+			}
+			var row []string
+			if r < len(rows) {
+				row = rows[r]
+			}
+			cells := make([]any, max(width, len(row)))
+			for i, c := range row {
+				cells[i] = sheetCell(c)
+			}
+			cell, _ := excelize.CoordinatesToCellName(1, r+1)
+			if err := f.SetSheetRow(name, cell, &cells); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortedSheets are the names of sheets in order, for writing them in the
+// same order every time.
+func sortedSheets(sheets map[string][][]string) []string {
+	names := make([]string, 0, len(sheets))
+	for name := range sheets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// sheetCell is the value to write for a cell read as s: numbers are
+// written as numbers, as the tracker writes them, but text such as
+// "007" stays text.
+func sheetCell(s string) any {
+	if n, err := strconv.ParseFloat(s, 64); err == nil && strconv.FormatFloat(n, 'f', -1, 64) == s {
+		return n
+	}
+	return s
+}
+
+// readUpgraded reads filename as if it had been upgraded to the current
+// schema, leaving the file itself untouched.
+func readUpgraded(filename string) (excelDataMsg, error) {
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return excelDataMsg{}, err
+	}
+	defer f.Close()
+	if err := applyMigrations(f); err != nil {
+		return excelDataMsg{}, err
+	}
+	return readWorkbook(f, allSheets)
+}
+
+// expenseTotalCells are where the Expenses sheet keeps a total for people
+// opening the workbook in Excel: a label and a SUM formula in row 2, just
+// right of the data columns. The tracker itself never reads them.
+func expenseTotalCells() (label, value string) {
+	col := len(sheetHeaders["Expenses"]) + 1
+	label, _ = excelize.CoordinatesToCellName(col, 2)
+	value, _ = excelize.CoordinatesToCellName(col+1, 2)
+	return label, value
+}
+
+// writeExpenseTotal points the total formula at every amount row, with the
+// total computed here as its cached value so it shows before Excel
+// recalculates. A sum of amounts in different currencies means nothing,
+// so with expenses in other currencies the total is converted here and
+// written without the formula. The shares of each month's spending are
+// written beside it.
+func writeExpenseTotal(f *excelize.File, expenses []Expense) error {
+	label, value := expenseTotalCells()
+	if err := f.SetCellValue("Expenses", label, "Total"); err != nil {
+		return err
+	}
+	base, _ := readRates(f).inBase(expenses)
+	var total float64
+	for _, e := range base {
+		total += e.Amount
+	}
+	if err := f.SetCellValue("Expenses", value, cfg.Rounding.round(total)); err != nil {
+		return err
+	}
+	formula := fmt.Sprintf("SUM(B2:B%d)", len(expenses)+1)
+	if slices.ContainsFunc(expenses, func(e Expense) bool { return foreignCurrency(e.Currency) }) {
+		formula = ""
+	}
+	if err := f.SetCellFormula("Expenses", value, formula); err != nil {
+		return err
+	}
+	return writeExpenseShares(f, expenses)
+}
+
+// createWorkbook writes a workbook containing the tracker's sheets, with
+// the rows of seed, if any, and the expense total.
+func createWorkbook(filename string, seed Dataset) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	rows := map[string][][]any{}
+	for _, e := range seed.Expenses {
+		rows["Expenses"] = append(rows["Expenses"], expenseCells(e))
+	}
+	for _, st := range seed.Stonks {
+		rows["Stonks"] = append(rows["Stonks"], stonkCells(st))
+	}
+	for _, w := range seed.WatchList {
+		rows["WatchList"] = append(rows["WatchList"], watchCells(w))
+	}
+	for i, name := range sheetNames {
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(name); err != nil {
+			return err
+		}
+		header := sheetHeaders[name]
+		if err := f.SetSheetRow(name, "A1", &header); err != nil {
+			return err
+		}
+		for r, row := range rows[name] {
+			if err := f.SetSheetRow(name, fmt.Sprintf("A%d", r+2), &row); err != nil {
+				return err
+			}
+		}
+	}
+	if err := writeExpenseTotal(f, seed.Expenses); err != nil {
+		return err
+	}
+	if err := setMeta(f, "schema_version", strconv.Itoa(schemaVersion)); err != nil {
+		return err
+	}
+	return f.SaveAs(filename)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// googleSheetsConfig names the spreadsheet `tet export --sheets` writes to,
+// which the gsheets backend also keeps its data in.
+type googleSheetsConfig struct {
+	SpreadsheetID string `json:"spreadsheet_id"`
+	// Sheet is the tab that is overwritten, "Sheet1" by default.
+	Sheet string `json:"sheet,omitempty"`
+	// ClientID and ClientSecret are the OAuth client `tet login` signs in
+	// with.
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// googleTokenEnv supplies an OAuth access token for the Sheets API; without
+// it the one saved by `tet login` is used, or one is requested from the
+// gcloud CLI.
+const googleTokenEnv = "TET_GOOGLE_TOKEN"
+
+// monthExpenses returns the approved expenses dated in the month, or
+// budgeting period, of month.
+func monthExpenses(expenses []Expense, month time.Time) []Expense {
+	var rows []Expense
+	month = calendarMonth(month)
+	for _, e := range expenses {
+		if !e.Date.IsZero() && monthOf(e.Date).Equal(month) && e.Pending == "" {
+			rows = append(rows, e)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Date.Before(rows[j].Date) })
+	return rows
+}
+
+// exportRows is the table shared by every export format: a header, one row
+// per expense and the month's totals.
+func exportRows(rows []Expense) [][]any {
+	table := [][]any{{"Date", "Expense", "Amount", "Type"}}
+	for _, e := range rows {
+		table = append(table, []any{formatExpenseDate(e.Date), e.Name, e.Amount, string(e.Kind)})
+	}
+	t := totalsOf(rows)
+	table = append(table,
+		[]any{},
+		[]any{"", "Income", t.income},
+		[]any{"", "Spent", t.spent},
+		[]any{"", "Refunds", t.refunds},
+		[]any{"", "Net spend", t.netSpend()},
+	)
+	return table
+}
+
+// exportWorkbook writes a standalone workbook of one month's expenses,
+// with a styled table and a chart of spending by d.
+func exportWorkbook(path string, month time.Time, rows []Expense, d reportDimension) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := month.Format("January 2006")
+	if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		return err
+	}
+
+	table := exportRows(rows)
+	for i, row := range table {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	header, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"25A065"}},
+	})
+	if err != nil {
+		return err
+	}
+	numFmt := cfg.Rounding.numFmt()
+	amount, err := f.NewStyle(&excelize.Style{CustomNumFmt: &numFmt})
+	if err != nil {
+		return err
+	}
+	totals, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}, CustomNumFmt: &numFmt})
+	if err != nil {
+		return err
+	}
+	last := len(table)
+	if err := f.SetCellStyle(sheet, "A1", "D1", header); err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(sheet, "C2", fmt.Sprintf("C%d", last), amount); err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(sheet, fmt.Sprintf("B%d", last-3), fmt.Sprintf("C%d", last), totals); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "A", "A", 12); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "B", "B", 28); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "C", "D", 12); err != nil {
+		return err
+	}
+
+	// The chart reads its data from a small table beside the expenses.
+	names, spent := spendingBy(rows, d)
+	if len(names) > 0 {
+		column := strings.ToUpper(d.String()[:1]) + d.String()[1:]
+		if err := f.SetSheetRow(sheet, "F1", &[]any{column, "Spent"}); err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(sheet, "F1", "G1", header); err != nil {
+			return err
+		}
+		for i, name := range names {
+			cell, _ := excelize.CoordinatesToCellName(6, i+2)
+			if err := f.SetSheetRow(sheet, cell, &[]any{name, spent[i]}); err != nil {
+				return err
+			}
+		}
+		end := len(names) + 1
+		err := f.AddChart(sheet, "I1", &excelize.Chart{
+			Type: excelize.Pie,
+			Series: []excelize.ChartSeries{{
+				Name:       "Spent",
+				Categories: fmt.Sprintf("'%s'!$F$2:$F$%d", sheet, end),
+				Values:     fmt.Sprintf("'%s'!$G$2:$G$%d", sheet, end),
+			}},
+			Title:  []excelize.RichTextRun{{Text: "Spending by " + d.String() + ", " + sheet}},
+			Legend: excelize.ChartLegend{Position: "right"},
+			Format: excelize.GraphicOptions{OffsetX: 10, OffsetY: 10},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return f.SaveAs(path)
+}
+
+// exportToSheets overwrites the configured Google spreadsheet tab with the
+// month's table through the Sheets API.
+func exportToSheets(gs googleSheetsConfig, rows []Expense) error {
+	if gs.SpreadsheetID == "" {
+		return errors.New("export: set google_sheets.spreadsheet_id in the config file")
+	}
+	if cfg.Offline {
+		return fmt.Errorf("export: %w", errOffline)
+	}
+	sheet := gs.Sheet
+	if sheet == "" {
+		sheet = "Sheet1"
+	}
+	token, err := googleToken()
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	base := sheetsAPI + url.PathEscape(gs.SpreadsheetID) + "/values/" + url.PathEscape(sheetsRange(sheet))
+	if err := sheetsRequest(http.MethodPost, base+":clear", token, struct{}{}, nil); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	body := struct {
+		Values [][]any `json:"values"`
+	}{exportRows(rows)}
+	if err := sheetsRequest(http.MethodPut, base+"?valueInputOption=RAW", token, body, nil); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+var sheetsAPI = "https://sheets.googleapis.com/v4/spreadsheets/"
+
+// sheetsRange is the A1 range of a whole tab.
+func sheetsRange(tab string) string {
+	return "'" + strings.ReplaceAll(tab, "'", "''") + "'"
+}
+
+// sheetsRequest calls the Sheets API with body, if not nil, as JSON and
+// decodes the response into out, if not nil.
+func sheetsRequest(method, endpoint, token string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, endpoint, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("Google Sheets API: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// googleToken returns $TET_GOOGLE_TOKEN or the token saved by `tet login`,
+// or else asks gcloud(1) for one.
+func googleToken() (string, error) {
+	if token := os.Getenv(googleTokenEnv); token != "" {
+		return token, nil
+	}
+	if token, err := savedGoogleToken(); err != nil || token != "" {
+		return token, err
+	}
+	out, err := exec.Command("gcloud", "auth", "print-access-token").Output()
+	if err != nil {
+		return "", fmt.Errorf("no Google access token (run tet login, set $%s or log in with gcloud): %w", googleTokenEnv, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// runExport shares one month of expenses, as a standalone workbook or in a
+// Google spreadsheet, or dumps every sheet as CSV or TSV.
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "data file to export from")
+	monthFlag := flags.String("month", monthOf(today()).Format(monthLayout), "month to export, as YYYY-MM")
+	out := flags.String("out", "", "file to write (default expenses-YYYY-MM.xlsx, <file>-backup-YYYY-MM-DD.json or <file>.journal)")
+	toSheets := flags.Bool("sheets", false, "write to the Google spreadsheet in the config file instead")
+	format := flags.String("format", "xlsx", "xlsx for one month's workbook, csv or tsv for every sheet (--out is then a directory), json for a backup of all the data, or ledger for a journal of every expense")
+	account := flags.String("account", "Assets:Checking", "ledger account expenses without a Payment are paid from")
+	by := flags.String("by", "payee", "what the xlsx chart groups spending by: category, tag, payee, account, project, method or member")
+	flags.Parse(args)
+
+	if _, ok := delimitedFormats[*format]; ok {
+		return runExportSheets(*file, *out, *format)
+	} else if *format == "json" {
+		return runExportJSON(*file, *out)
+	} else if *format == "ledger" {
+		return runExportLedger(*file, *out, *account)
+	} else if *format != "xlsx" {
+		return fmt.Errorf("export: unknown format %q (available: xlsx, csv, tsv, json, ledger)", *format)
+	}
+
+	month, err := time.Parse("2006-01", *monthFlag)
+	if err != nil {
+		return fmt.Errorf("export: --month must be YYYY-MM: %w", err)
+	}
+	dimension, err := parseDimension(*by)
+	if err != nil {
+		return fmt.Errorf("export: --by: %w", err)
+	}
+	s, err := readOnlyStoreForPath(*file)
+	if err != nil {
+		return err
+	}
+	data, err := s.Load()
+	if err != nil {
+		return err
+	}
+	rows := monthExpenses(data.Expenses, month)
+	if len(rows) == 0 {
+		return fmt.Errorf("export: no expenses dated in %s", month.Format("January 2006"))
+	}
+
+	if *toSheets {
+		if err := exportToSheets(cfg.GoogleSheets, rows); err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d expenses to Google Sheets.\n", len(rows))
+		return nil
+	}
+	if *out == "" {
+		*out = "expenses-" + month.Format("2006-01") + ".xlsx"
+	}
+	if _, err := os.Stat(*out); err == nil {
+		return fmt.Errorf("export: %s already exists, refusing to overwrite it", *out)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := exportWorkbook(*out, month, rows, dimension); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d expenses to %s.\n", len(rows), *out)
+	return nil
+}
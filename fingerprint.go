@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// fileFingerprint identifies the contents of a file cheaply enough to check
+// on every watcher event.
+type fileFingerprint struct {
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+func fingerprintFile(path string) (fileFingerprint, error) {
+	info, err := files.Stat(path)
+	if err != nil {
+		return fileFingerprint{}, err
+	}
+	f, err := files.Open(path)
+	if err != nil {
+		return fileFingerprint{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fileFingerprint{}, err
+	}
+	fp := fileFingerprint{modTime: info.ModTime(), size: info.Size()}
+	h.Sum(fp.sum[:0])
+	return fp, nil
+}
+
+// unchangedSince reports whether path still has the contents fingerprinted
+// in last. Matching modification time and size are trusted without
+// hashing; otherwise the hash decides, so files that were merely touched
+// or re-saved identically count as unchanged.
+func unchangedSince(path string, last fileFingerprint) bool {
+	if last.size == 0 && last.modTime.IsZero() {
+		return false
+	}
+	info, err := files.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.ModTime().Equal(last.modTime) && info.Size() == last.size {
+		return true
+	}
+	if info.Size() != last.size {
+		return false
+	}
+	fp, err := fingerprintFile(path)
+	return err == nil && fp.sum == last.sum
+}
+
+// settleTime is how long a write to the workbook is given to finish
+// before it is read, so that a save in several steps reloads once.
+const settleTime = 500 * time.Millisecond
+
+// settled waits for a write to path to finish and reports whether it left
+// contents other than those fingerprinted in last.
+func settled(path string, last fileFingerprint) bool {
+	clk.Sleep(settleTime)
+	return !unchangedSince(path, last)
+}
+
+// lastLoad holds the fingerprint of the most recent read, shared between
+// the model and the file watcher goroutine so that the watcher also skips
+// the events caused by the app's own saves.
+type lastLoad struct {
+	mu sync.Mutex
+	fp fileFingerprint
+}
+
+func (l *lastLoad) get() fileFingerprint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fp
+}
+
+func (l *lastLoad) set(fp fileFingerprint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fp = fp
+}
+
+// fingerprintData identifies data kept elsewhere than in a file, such as in
+// a Google spreadsheet, by its contents alone.
+func fingerprintData(data Dataset) fileFingerprint {
+	b, _ := json.Marshal(data)
+	return fileFingerprint{size: int64(len(b)), sum: sha256.Sum256(b)}
+}
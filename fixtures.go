@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// fixtureMess is the ways a generated workbook departs from the one tet
+// writes, the way workbooks kept by hand or exported by other programs do.
+type fixtureMess int
+
+const (
+	// messLocale writes some amounts as text in European layouts, such
+	// as "-1.234,56".
+	messLocale fixtureMess = 1 << iota
+	// messColumns leaves out the columns added in later versions, from
+	// the headers and from some rows more than others.
+	messColumns
+	// messSheets adds sheets tet knows nothing about, before and after
+	// its own.
+	messSheets
+	// messDates writes dates as text in the layouts of dateLayouts, or as
+	// Excel serial numbers.
+	messDates
+	// messBlanks scatters blank rows and stray spaces around names.
+	messBlanks
+)
+
+// messNames are the names --messy takes.
+var messNames = map[string]fixtureMess{
+	"locale":  messLocale,
+	"columns": messColumns,
+	"sheets":  messSheets,
+	"dates":   messDates,
+	"blanks":  messBlanks,
+}
+
+func parseMess(s string) (fixtureMess, error) {
+	var mess fixtureMess
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "", "none":
+		case "all":
+			mess = messLocale | messColumns | messSheets | messDates | messBlanks
+		default:
+			m, ok := messNames[name]
+			if !ok {
+				return 0, fmt.Errorf("unknown kind of mess %q (available: all, blanks, columns, dates, locale, sheets)", name)
+			}
+			mess |= m
+		}
+	}
+	return mess, nil
+}
+
+// fixtureOptions say what generateFixture makes.
+type fixtureOptions struct {
+	// rows is the number of expenses, with a tenth as many positions and
+	// watchlist items.
+	rows int
+	// seed makes the data: the same seed gives the same data.
+	seed int64
+	// months spreads the expenses over as many months up to the current
+	// one; with none they are undated.
+	months int
+	mess   fixtureMess
+}
+
+// fixturePayees are the expenses generated for each category, with the
+// range of their amounts.
+var fixturePayees = []struct {
+	category string
+	names    []string
+	min, max float64
+}{
+	{"Groceries", []string{"Groceries", "Supermarket", "Bakery", "Farmers market"}, 5, 150},
+	{"Housing", []string{"Rent", "Electricity", "Water", "Internet"}, 30, 900},
+	{"Transport", []string{"Train ticket", "Fuel", "Bus pass", "Taxi"}, 2, 90},
+	{"Fun", []string{"Cinema", "Dinner out", "Concert", "Books"}, 8, 120},
+	{"Health", []string{"Gym", "Pharmacy", "Dentist"}, 10, 200},
+	{"Coffee", []string{"Coffee", "Tea", "Pastry"}, 1.5, 8},
+}
+
+var (
+	fixtureSymbols = []string{"AMD", "META", "NVDA", "GOOG", "AMZN", "TSLA", "NKE", "SOFI", "HIMS", "UBER"}
+	fixtureUsers   = []string{"alex", "sam"}
+	fixtureTags    = []string{"vacation", "reimbursable", "work", "gift"}
+)
+
+// generateFixture makes a Dataset of plausible, deterministic data for
+// demos, benchmarks and tests.
+func generateFixture(opts fixtureOptions) Dataset {
+	rng := rand.New(rand.NewSource(opts.seed))
+	amount := func(min, max float64) float64 {
+		return math.Round((min+rng.Float64()*(max-min))*100) / 100
+	}
+	first := monthOf(today()).AddDate(0, 1-opts.months, 0)
+	days := int(today().Sub(first).Hours()/24) + 1
+
+	var data Dataset
+	for i := 0; i < opts.rows; i++ {
+		var e Expense
+		// About one row in twenty is income.
+		if rng.Intn(20) == 0 {
+			e = Expense{Name: "Salary", Amount: amount(1500, 3500), Category: "Income"}
+		} else {
+			p := fixturePayees[rng.Intn(len(fixturePayees))]
+			e = Expense{Name: p.names[rng.Intn(len(p.names))], Amount: -amount(p.min, p.max), Category: p.category}
+			if rng.Intn(40) == 0 {
+				e.Kind = kindRefund
+				e.Amount = -e.Amount
+			}
+			if rng.Intn(8) == 0 {
+				e.Tags = []string{fixtureTags[rng.Intn(len(fixtureTags))]}
+			}
+		}
+		if opts.months > 0 {
+			e.Date = first.AddDate(0, 0, rng.Intn(days))
+		}
+		e.EnteredBy = fixtureUsers[rng.Intn(len(fixtureUsers))]
+		data.Expenses = append(data.Expenses, e)
+	}
+	// Entered as they happened, like a real book.
+	slices.SortStableFunc(data.Expenses, func(a, b Expense) int { return a.Date.Compare(b.Date) })
+	for i := 0; i < opts.rows/10; i++ {
+		qty, avg, price := float64(rng.Intn(100)+1), amount(1, 300), amount(1, 300)
+		data.Stonks = append(data.Stonks, Stonk{Symbol: fixtureSymbols[rng.Intn(len(fixtureSymbols))],
+			Quantity: qty, AvgPrice: avg, Price: price, Open: price, DayChange: amount(-5, 5)})
+	}
+	for i := 0; i < opts.rows/10; i++ {
+		price := amount(1, 300)
+		data.WatchList = append(data.WatchList, WatchItem{Symbol: fixtureSymbols[rng.Intn(len(fixtureSymbols))],
+			Qty: float64(rng.Intn(50)), TargetBuy: math.Round(price * 0.9), TargetSell: math.Round(price * 1.2), Price: price})
+	}
+	return data
+}
+
+// generateWorkbook writes the data generateFixture makes to a workbook,
+// with the mess asked for. It streams the rows, so that workbooks large
+// enough to benchmark with are quick to make.
+func generateWorkbook(path string, opts fixtureOptions) error {
+	data := generateFixture(opts)
+	rng := rand.New(rand.NewSource(opts.seed + 1))
+	mess := func(m fixtureMess) bool { return opts.mess&m != 0 }
+
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, name := range sheetNames {
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(name); err != nil {
+			return err
+		}
+	}
+
+	// columns are how many of a sheet's columns are written: with
+	// messColumns only those of the first versions.
+	columns := map[string]int{"Expenses": 4, "Stonks": 7, "WatchList": 2}
+	write := func(sheet string, rows [][]any) error {
+		n := len(sheetHeaders[sheet])
+		if mess(messColumns) {
+			n = columns[sheet]
+		}
+		sw, err := f.NewStreamWriter(sheet)
+		if err != nil {
+			return err
+		}
+		header := make([]any, n)
+		for i, h := range sheetHeaders[sheet][:n] {
+			header[i] = h
+		}
+		if err := sw.SetRow("A1", header); err != nil {
+			return err
+		}
+		r := 2
+		for _, row := range rows {
+			if mess(messBlanks) && rng.Intn(25) == 0 {
+				r++
+			}
+			row = row[:n]
+			// Some rows stop short even of the columns in the header.
+			if mess(messColumns) && rng.Intn(3) == 0 {
+				row = row[:2+rng.Intn(n-1)]
+			}
+			cell, _ := excelize.CoordinatesToCellName(1, r)
+			if err := sw.SetRow(cell, row); err != nil {
+				return err
+			}
+			r++
+		}
+		return sw.Flush()
+	}
+
+	var expenses [][]any
+	for _, e := range data.Expenses {
+		row := expenseCells(e)
+		if mess(messLocale) && rng.Intn(5) == 0 {
+			row[1] = localeAmount(e.Amount, rng)
+		}
+		if mess(messDates) && !e.Date.IsZero() {
+			switch n := rng.Intn(len(dateLayouts) + 2); {
+			case n < len(dateLayouts):
+				// Only in a layout that reads back as the same date: month
+				// first ones are read day first when they can be.
+				s := e.Date.Format(dateLayouts[n])
+				if t, ok := parseDateText(s); ok && formatExpenseDate(t) == formatExpenseDate(e.Date) {
+					row[3] = s
+				}
+			case n == len(dateLayouts):
+				// Excel counts days from 30 December 1899.
+				row[3] = int(e.Date.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+			}
+		}
+		if mess(messBlanks) && rng.Intn(10) == 0 {
+			row[0] = "  " + e.Name + " "
+		}
+		expenses = append(expenses, row)
+	}
+	var stonks, watchList [][]any
+	for _, st := range data.Stonks {
+		stonks = append(stonks, stonkCells(st))
+	}
+	for _, w := range data.WatchList {
+		watchList = append(watchList, watchCells(w))
+	}
+	if err := write("Expenses", expenses); err != nil {
+		return err
+	}
+	if err := write("Stonks", stonks); err != nil {
+		return err
+	}
+	if err := write("WatchList", watchList); err != nil {
+		return err
+	}
+
+	if mess(messSheets) {
+		if err := addStraySheets(f); err != nil {
+			return err
+		}
+	}
+	// Without all the columns the workbook is as old as its layout, so
+	// opening it runs the migrations that add the rest.
+	if !mess(messColumns) {
+		if err := setMeta(f, "schema_version", strconv.Itoa(schemaVersion)); err != nil {
+			return err
+		}
+	}
+	return f.SaveAs(path)
+}
+
+// localeAmount writes amount the way a European spreadsheet would show it,
+// as text.
+func localeAmount(amount float64, rng *rand.Rand) string {
+	s := strconv.FormatFloat(math.Abs(amount), 'f', 2, 64)
+	whole, cents, _ := strings.Cut(s, ".")
+	sep := []string{".", " ", " "}[rng.Intn(3)]
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + sep + whole[i:]
+	}
+	s = whole + "," + cents
+	if amount < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// addStraySheets adds a notes sheet in front of the tracker's sheets and a
+// summary and an empty sheet after them.
+func addStraySheets(f *excelize.File) error {
+	sheets := map[string][][]any{
+		"Notes":   {{"Kept by hand, do not sort"}, {"Last checked", "2024-03-01"}},
+		"Summary": {{"Month", "Total"}, {"January", 1234.5}, {"February", "n/a"}},
+		"Sheet2":  nil,
+	}
+	for _, name := range []string{"Notes", "Summary", "Sheet2"} {
+		if _, err := f.NewSheet(name); err != nil {
+			return err
+		}
+		for r, row := range sheets[name] {
+			cell, _ := excelize.CoordinatesToCellName(1, r+1)
+			if err := f.SetSheetRow(name, cell, &row); err != nil {
+				return err
+			}
+		}
+	}
+	return f.MoveSheet("Notes", sheetNames[0])
+}
+
+// demoPath is the memory store tet --demo runs on.
+const demoPath = memoryPath + "demo"
+
+// seedDemo fills the demo's memory store with a year of expenses.
+func seedDemo() error {
+	s, err := openStore("memory", demoPath)
+	if err != nil {
+		return err
+	}
+	return s.Save(generateFixture(fixtureOptions{rows: 400, seed: 1, months: 12}))
+}
+
+// runGenerate writes a data file of generated data, for trying tet out
+// and as a fixture for tests.
+func runGenerate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	out := flags.String("out", "fixture.xlsx", "file to write; a .json or .sqlite one is written by that backend, without mess")
+	rows := flags.Int("rows", 200, "number of expenses, with a tenth as many positions and watchlist items")
+	seed := flags.Int64("seed", 1, "seed of the generated data; the same seed gives the same data")
+	months := flags.Int("months", 12, "months up to the current one to date the expenses over; 0 leaves them undated")
+	messy := flags.String("messy", "", "comma-separated ways to depart from the layout tet writes: locale, columns, sheets, dates, blanks, or all")
+	flags.Parse(args)
+
+	if *rows < 0 || *months < 0 {
+		return errors.New("generate: --rows and --months cannot be negative")
+	}
+	mess, err := parseMess(*messy)
+	if err != nil {
+		return fmt.Errorf("generate: %w", err)
+	}
+	opts := fixtureOptions{rows: *rows, seed: *seed, months: *months, mess: mess}
+	if _, err := os.Stat(*out); err == nil {
+		return fmt.Errorf("generate: %s already exists", *out)
+	}
+	s, err := storeForPath(*out)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.(*excelStore); ok {
+		err = generateWorkbook(*out, opts)
+	} else if mess != 0 {
+		err = errors.New("generate: --messy only applies to .xlsx workbooks")
+	} else {
+		err = s.Save(generateFixture(opts))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Generated %d expenses in %s.\n", *rows, *out)
+	return nil
+}
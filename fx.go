@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fxSheet holds daily exchange rates, filled by `tet backfill --fx`. Each
+// rate is the configured currency paid for one unit of Currency.
+const fxSheet = "FX"
+
+var fxHeaders = []string{"Currency", "Date", "Rate"}
+
+// fxTicker is the symbol the price history of currency in base is quoted
+// under, e.g. USDEUR=X.
+func fxTicker(currency, base string) string {
+	return currency + base + "=X"
+}
+
+// seriesBySymbol groups points by symbol, each series oldest first.
+func seriesBySymbol(points []pricePoint) map[string][]pricePoint {
+	series := make(map[string][]pricePoint)
+	for _, p := range points {
+		k := symbolKey(p.Symbol)
+		series[k] = append(series[k], p)
+	}
+	for _, s := range series {
+		sort.Slice(s, func(i, j int) bool { return s[i].Date.Before(s[j].Date) })
+	}
+	return series
+}
+
+// valueOn is the last value of series on or before day, or its first one
+// when day predates it.
+func valueOn(series []pricePoint, day time.Time) float64 {
+	i := sort.Search(len(series), func(i int) bool { return series[i].Date.After(day) })
+	if i == 0 {
+		return series[0].Close
+	}
+	return series[i-1].Close
+}
+
+// fxReturn splits a position's return over its price history into the
+// asset's own return in its currency, which is what a currency-hedged
+// holder gets, and the effect of the exchange rate on top of it.
+type fxReturn struct {
+	symbol, currency string
+	from, to         time.Time
+	asset, fx        float64
+	// start and end value the position in the configured currency, for
+	// weighting the total.
+	start, end float64
+	// problem says why the position could not be split, if it could not.
+	problem string
+}
+
+// unhedged is the return in the configured currency.
+func (r fxReturn) unhedged() float64 { return (1+r.asset)*(1+r.fx) - 1 }
+
+// fxReturns splits every position with a quantity using the closes in
+// prices and the rates in rates. total combines them weighted by their
+// value at the start; positions in the configured currency have no FX
+// effect.
+func fxReturns(stonks []Stonk, prices, rates []pricePoint, base string) (rows []fxReturn, total fxReturn) {
+	priceSeries, rateSeries := seriesBySymbol(prices), seriesBySymbol(rates)
+	total.symbol = "Total"
+	for _, s := range stonks {
+		if s.Quantity == 0 || s.Symbol == "" {
+			continue
+		}
+		r := fxReturn{symbol: s.Symbol, currency: strings.ToUpper(s.Currency)}
+		if r.currency == "" {
+			r.currency = strings.ToUpper(base)
+		}
+		closes := priceSeries[symbolKey(s.Symbol)]
+		if len(closes) < 2 {
+			r.problem = "no price history, run tet backfill --symbol " + s.Symbol
+			rows = append(rows, r)
+			continue
+		}
+		first, last := closes[0], closes[len(closes)-1]
+		r.from, r.to = first.Date, last.Date
+		r.asset = last.Close/first.Close - 1
+		startRate, endRate := 1.0, 1.0
+		if r.currency != strings.ToUpper(base) {
+			fx := rateSeries[r.currency]
+			if len(fx) == 0 {
+				r.problem = "no FX rates, run tet backfill --fx " + r.currency
+				rows = append(rows, r)
+				continue
+			}
+			startRate, endRate = valueOn(fx, r.from), valueOn(fx, r.to)
+			r.fx = endRate/startRate - 1
+		}
+		r.start = s.Quantity * first.Close * startRate
+		r.end = s.Quantity * last.Close * endRate
+		total.start += r.start
+		total.end += r.end
+		total.asset += r.start * r.asset
+		rows = append(rows, r)
+	}
+	if total.start > 0 {
+		total.asset /= total.start
+		total.fx = (total.end/total.start)/(1+total.asset) - 1
+	}
+	return rows, total
+}
+
+func (r fxReturn) cells() []string {
+	if r.problem != "" {
+		return []string{r.symbol, r.currency, "", "", "", "", r.problem}
+	}
+	period := ""
+	if !r.from.IsZero() {
+		period = formatExpenseDate(r.from) + " – " + formatExpenseDate(r.to)
+	}
+	return []string{r.symbol, r.currency, period,
+		formatPercent(r.asset * 100), formatPercent(r.fx * 100), formatPercent(r.unhedged() * 100), ""}
+}
+
+// renderFXReturns lays the split out as a plain text table.
+func renderFXReturns(rows []fxReturn, total fxReturn) string {
+	if len(rows) == 0 {
+		return "No positions with a quantity to split.\n"
+	}
+	table := [][]string{{"Symbol", "Currency", "Period", "Hedged", "FX effect", "Unhedged", ""}}
+	for _, r := range rows {
+		table = append(table, r.cells())
+	}
+	if total.start > 0 {
+		table = append(table, total.cells())
+	}
+	widths := make([]int, len(table[0]))
+	for _, row := range table {
+		for i, c := range row {
+			widths[i] = max(widths[i], len([]rune(c)))
+		}
+	}
+	var b bytes.Buffer
+	for _, row := range table {
+		for i, c := range row {
+			fmt.Fprintf(&b, "%-*s  ", widths[i], c)
+		}
+		b.Truncate(len(bytes.TrimRight(b.Bytes(), " ")))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+type fxReturnsMsg struct {
+	rows  []fxReturn
+	total fxReturn
+	err   error
+}
+
+// loadFXReturnsCmd reads the Prices and FX sheets, which the everyday load
+// skips, and splits the returns of stonks.
+func loadFXReturnsCmd(filename string, stonks []Stonk) tea.Cmd {
+	return func() tea.Msg {
+		f, err := openWorkbook(filename)
+		if err != nil {
+			return fxReturnsMsg{err: err}
+		}
+		defer f.Close()
+		prices, err := readPriceSheet(f, pricesSheet)
+		if err != nil {
+			return fxReturnsMsg{err: fmt.Errorf("%s sheet: %w", pricesSheet, err)}
+		}
+		rates, err := readPriceSheet(f, fxSheet)
+		if err != nil {
+			return fxReturnsMsg{err: fmt.Errorf("%s sheet: %w", fxSheet, err)}
+		}
+		rows, total := fxReturns(stonks, prices, rates, cfg.Rounding.Currency)
+		return fxReturnsMsg{rows: rows, total: total}
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+	"github.com/xuri/excelize/v2"
+)
+
+// The Goals sheet lists savings goals: what each is to reach, by when,
+// and what is saved towards it so far. Contributing to one on the Goals
+// screen adds to its Saved and records the money put aside as a transfer,
+// into the goal's account, so it is not counted as spending.
+const goalsSheet = "Goals"
+
+var goalsHeaders = []string{"Goal", "Target", "Target Date", "Saved", "Account"}
+
+// goal is a row of the Goals sheet.
+type goal struct {
+	Name   string
+	Target float64
+	// Date is when the target is to be reached, zero for no date.
+	Date  time.Time
+	Saved float64
+	// Account is the account of the Accounts sheet the money is kept in,
+	// blank to have the goal's name stand for it.
+	Account string
+	// row is the goal's row of the sheet.
+	row int
+}
+
+// left is what is still to be saved for g.
+func (g goal) left() float64 {
+	return max(0, cfg.Rounding.round(g.Target-g.Saved))
+}
+
+// account is where contributions to g are transferred to.
+func (g goal) account() string {
+	if g.Account != "" {
+		return g.Account
+	}
+	return g.Name
+}
+
+// perMonth is what would have to be saved each month, from month on, to
+// reach g by its date, zero when it has none or is reached.
+func (g goal) perMonth(month time.Time) float64 {
+	if g.Date.IsZero() || g.left() == 0 {
+		return 0
+	}
+	months := 1
+	for m := month; m.Before(monthOf(g.Date)); m = m.AddDate(0, 1, 0) {
+		months++
+	}
+	return cfg.Rounding.round(g.left() / float64(months))
+}
+
+// readGoals reads the Goals sheet, in its order. Rows without a goal or a
+// target are skipped, and workbooks without the sheet have no goals.
+func readGoals(f *excelize.File) ([]goal, error) {
+	if idx, _ := f.GetSheetIndex(goalsSheet); idx == -1 {
+		return nil, nil
+	}
+	rows, err := f.GetRows(goalsSheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+	var goals []goal
+	for i := 1; i < len(rows); i++ {
+		row := append(rows[i], make([]string, len(goalsHeaders))...)
+		g := goal{Name: strings.TrimSpace(row[0]), Date: parseExpenseDate(row[2]), Account: strings.TrimSpace(row[4]), row: i + 1}
+		target, _ := strconv.ParseFloat(strings.TrimSpace(row[1]), 64)
+		saved, _ := strconv.ParseFloat(strings.TrimSpace(row[3]), 64)
+		g.Target, g.Saved = cfg.Rounding.round(target), cfg.Rounding.round(saved)
+		if g.Name == "" || g.Target <= 0 {
+			continue
+		}
+		goals = append(goals, g)
+	}
+	return goals, nil
+}
+
+// goalSummary describes g for the audit log.
+func goalSummary(g goal) string {
+	return fmt.Sprintf("%s saved %s of %s", g.Name, money(g.Saved), money(g.Target))
+}
+
+// contributeToGoal puts amount towards the goal called name in the
+// workbook filename: its Saved goes up by amount, and a transfer of it from
+// the account from, blank for money from outside the accounts, into the
+// goal's account is added to the Transfers sheet.
+func contributeToGoal(filename, name string, amount float64, from string) (goal, error) {
+	if _, err := upgradeWorkbook(filename); err != nil {
+		return goal{}, err
+	}
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return goal{}, err
+	}
+	defer unlock()
+
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return goal{}, err
+	}
+	defer f.Close()
+
+	goals, err := readGoals(f)
+	if err != nil {
+		return goal{}, err
+	}
+	i := slices.IndexFunc(goals, func(g goal) bool { return payeeKey(g.Name) == payeeKey(name) })
+	if i < 0 {
+		return goal{}, fmt.Errorf("the %s sheet has no goal %s", goalsSheet, name)
+	}
+	g := goals[i]
+	before := goalSummary(g)
+	g.Saved = cfg.Rounding.round(g.Saved + amount)
+	if err := f.SetCellValue(goalsSheet, "D"+strconv.Itoa(g.row), g.Saved); err != nil {
+		return goal{}, err
+	}
+
+	t := accountTransfer{Date: today(), From: from, Amount: amount, To: g.account()}
+	free, err := openSheet(f, transfersSheet, transfersHeaders)
+	if err != nil {
+		return goal{}, err
+	}
+	values := []any{formatExpenseDate(t.Date), t.From, t.Amount, t.To}
+	cell, _ := excelize.CoordinatesToCellName(1, free)
+	if err := f.SetSheetRow(transfersSheet, cell, &values); err != nil {
+		return goal{}, err
+	}
+	audit := []rowChange{
+		{kind: rowChanged, sheet: goalsSheet, key: g.Name, before: before, after: goalSummary(g)},
+		{kind: rowAdded, sheet: transfersSheet, key: g.Name, after: fmt.Sprintf("%s %s from %s to %s", formatExpenseDate(t.Date), money(t.Amount), t.From, t.To)},
+	}
+	if err := appendAudit(f, audit); err != nil {
+		return goal{}, err
+	}
+
+	// Sessions holding the old rows must reload before saving over them.
+	current, _ := strconv.Atoi(getMeta(f, "revision"))
+	if err := setMeta(f, "revision", strconv.Itoa(current+1)); err != nil {
+		return goal{}, err
+	}
+	return g, f.Save()
+}
+
+type goalContributedMsg struct {
+	goal   goal
+	amount float64
+	err    error
+}
+
+// contributeForm asks how much to put towards g, and from which account,
+// then records it.
+func (m *model) contributeForm(g goal) tea.Cmd {
+	var amount, from string
+	options := []huh.Option[string]{huh.NewOption("Outside the accounts", "")}
+	for _, a := range m.accounts {
+		if payeeKey(a.Name) != payeeKey(g.account()) {
+			options = append(options, huh.NewOption(a.Name, a.Name))
+		}
+	}
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(fmt.Sprintf("Amount to put towards %s (%s left)", g.Name, money(g.left()))).
+				Value(&amount).
+				Validate(func(s string) error {
+					if x, err := cfg.Rounding.parse(s); err != nil || x <= 0 {
+						return fmt.Errorf("enter an amount above zero")
+					}
+					return nil
+				}),
+			huh.NewSelect[string]().Title("From").Options(options...).Value(&from),
+		),
+	)
+	path := m.path
+	return func() tea.Msg {
+		if err := form.Run(); err != nil {
+			return goalContributedMsg{}
+		}
+		x, _ := cfg.Rounding.parse(amount)
+		var updated goal
+		err := saves.run(func() (err error) {
+			updated, err = contributeToGoal(path, g.Name, x, from)
+			return err
+		})
+		return goalContributedMsg{goal: updated, amount: x, err: err}
+	}
+}
+
+// updateGoals handles keys on the Goals screen.
+func (m *model) updateGoals(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.editing {
+		return m, nil
+	}
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "b", "esc":
+		m.currentScreen = screenMenu
+	case "up":
+		if m.goalRow > 0 {
+			m.goalRow--
+		}
+	case "down":
+		if m.goalRow < len(m.goals)-1 {
+			m.goalRow++
+		}
+	case "c":
+		if m.goalRow >= len(m.goals) {
+			return m, nil
+		}
+		if m.readOnly {
+			m.status = "Read-only session: editing is disabled."
+			return m, nil
+		}
+		m.editing = true
+		return m, m.contributeForm(m.goals[m.goalRow])
+	}
+	return m, nil
+}
+
+// viewGoals shows how far saving for each goal has come.
+func (m *model) viewGoals() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	buffer.WriteString(screenTitleStyle.Render("Goals"))
+	buffer.WriteString("\n\n")
+	if len(m.goals) == 0 {
+		buffer.WriteString(fmt.Sprintf("No savings goals yet. Add a %s sheet with %s columns to follow them here.\n",
+			goalsSheet, strings.Join(goalsHeaders, ", ")))
+		buffer.WriteString("\nPress 'b' to go back.\n")
+		buffer.WriteString(m.statusLine())
+		return buffer.String()
+	}
+	m.goalRow = min(m.goalRow, len(m.goals)-1)
+	buffer.WriteString(goalsTable(m.goals, m.goalRow, today()) + "\n")
+	var saved, target float64
+	for _, g := range m.goals {
+		saved += g.Saved
+		target += g.Target
+	}
+	buffer.WriteString(fmt.Sprintf("Saved %s of %s across %d goals.\n", money(cfg.Rounding.round(saved)), money(cfg.Rounding.round(target)), len(m.goals)))
+	buffer.WriteString(fmt.Sprintf("\nContributions are added to the goal's %s and recorded in the %s sheet as a transfer into its %s, or an account named after it.\n",
+		goalsHeaders[3], transfersSheet, goalsHeaders[4]))
+	buffer.WriteString("\nUse ↑/↓ to move, 'c' to contribute to the selected goal, 'b' to go back.\n")
+	buffer.WriteString(m.statusLine())
+	return buffer.String()
+}
+
+// goalsTable lists the goals with a bar of what is saved of each, in green
+// once it all is, and what would have to be saved each month to reach it
+// by its date, in red once the date has passed short of it. The selected
+// goal is marked.
+func goalsTable(goals []goal, selected int, day time.Time) string {
+	headers := []string{"", "Goal", "Target", "Saved", "Left", "Progress", "Target Date", "Per Month"}
+	month := monthOf(day)
+	var data [][]string
+	for i, g := range goals {
+		marker, date, perMonth := "", "", ""
+		if i == selected {
+			marker = ">"
+		}
+		if !g.Date.IsZero() {
+			date = formatExpenseDate(g.Date)
+		}
+		if needed := g.perMonth(month); needed > 0 {
+			perMonth = money(needed)
+		}
+		percent := min(g.Saved/g.Target*100, 100)
+		data = append(data, []string{marker, g.Name, money(g.Target), money(g.Saved), money(g.left()),
+			budgetBar(g.Saved, g.Target) + fmt.Sprintf(" %3.0f%%", percent), date, perMonth})
+	}
+
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	completeStyle := baseStyle.Foreground(lipgloss.Color("42"))
+	overdueStyle := baseStyle.Foreground(lipgloss.Color("203"))
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			style := rowStyle
+			g := goals[row]
+			switch {
+			case col == 5 && g.left() == 0:
+				style = completeStyle
+			case col == 6 && g.left() > 0 && !g.Date.IsZero() && g.Date.Before(dateOf(day)):
+				style = overdueStyle
+			}
+			if row == selected {
+				style = style.Bold(true)
+			}
+			return style
+		})
+	return t.String()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+)
+
+// goodsConfig sets up the return windows of goods bought: how long they
+// usually are and when the menu starts warning that one is closing.
+type goodsConfig struct {
+	// ReturnDays is how long after it was bought goods can be returned
+	// when the form is left without a return date; 14 by default, 0 for
+	// none.
+	ReturnDays int `json:"return_days"`
+	// RemindDays is how many days ahead the menu lists the return windows
+	// about to close; 7 by default.
+	RemindDays int `json:"remind_days"`
+}
+
+func (c goodsConfig) validate() error {
+	if c.ReturnDays < 0 {
+		return errors.New("goods.return_days must not be negative")
+	}
+	if c.RemindDays < 0 {
+		return errors.New("goods.remind_days must not be negative")
+	}
+	return nil
+}
+
+// isGoods reports whether e is goods bought, which can be returned or are
+// under warranty.
+func isGoods(e Expense) bool {
+	return !e.ReturnBy.IsZero() || e.WarrantyMonths > 0
+}
+
+// warrantyEnd is the last day e is under warranty, zero without one or a
+// date to count it from.
+func warrantyEnd(e Expense) time.Time {
+	if e.WarrantyMonths <= 0 || e.Date.IsZero() {
+		return time.Time{}
+	}
+	return e.Date.AddDate(0, e.WarrantyMonths, -1)
+}
+
+// parseWarranty reads the Warranty column, a number of months, also
+// written like "24 months" or "2 years". Anything else is no warranty.
+func parseWarranty(s string) int {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 0 {
+		return 0
+	}
+	if len(fields) == 2 && strings.HasPrefix(fields[1], "year") {
+		return n * 12
+	}
+	return n
+}
+
+// warrantyCell is a warranty as written to the Warranty column, blank for
+// none.
+func warrantyCell(months int) any {
+	if months <= 0 {
+		return ""
+	}
+	return months
+}
+
+// formatWarranty shows a warranty in the expense forms, blank for none.
+func formatWarranty(months int) string {
+	if months <= 0 {
+		return ""
+	}
+	return strconv.Itoa(months)
+}
+
+// validateWarranty checks the warranty typed in the expense forms.
+func validateWarranty(s string) error {
+	if strings.TrimSpace(s) != "" && parseWarranty(s) == 0 {
+		return errors.New("enter the warranty in months, such as 24, or leave it blank")
+	}
+	return nil
+}
+
+// goodsFields are the questions the expense forms ask about goods: the
+// last day they can be returned and how long their warranty lasts. They
+// are a group of their own, only asked once the expense is said to be
+// goods.
+func goodsFields(goods *bool, returnBy, warranty *string) (*huh.Confirm, *huh.Group) {
+	confirm := huh.NewConfirm().Title("Goods with a return window or warranty?").Value(goods)
+	group := huh.NewGroup(
+		huh.NewInput().Title("Returnable until (YYYY-MM-DD, blank for the usual window)").Value(returnBy).Validate(validateDate),
+		huh.NewInput().Title("Warranty (months, blank for none)").Value(warranty).Validate(validateWarranty),
+	).WithHideFunc(func() bool { return !*goods })
+	return confirm, group
+}
+
+// withGoods sets the return date and warranty of e as answered in the
+// expense forms, the return date defaulting to return_days after the
+// purchase. Expenses that are not goods have neither.
+func withGoods(e Expense, goods bool, returnBy, warranty string) Expense {
+	e.ReturnBy, e.WarrantyMonths = time.Time{}, 0
+	if !goods {
+		return e
+	}
+	e.ReturnBy = parseExpenseDate(returnBy)
+	if e.ReturnBy.IsZero() && cfg.Goods.ReturnDays > 0 && !e.Date.IsZero() {
+		e.ReturnBy = e.Date.AddDate(0, 0, cfg.Goods.ReturnDays)
+	}
+	e.WarrantyMonths = parseWarranty(warranty)
+	return e
+}
+
+// returnReminders lists the goods among expenses that can still be
+// returned, but only for remind_days more from day, soonest first, for the
+// menu. Refunded purchases are taken to have been returned.
+func returnReminders(expenses []Expense, day time.Time) string {
+	day = dateOf(day)
+	var due []Expense
+	for _, e := range expenses {
+		if e.ReturnBy.IsZero() || e.Kind == kindRefund || e.Pending != "" {
+			continue
+		}
+		if left := daysBetween(day, e.ReturnBy); left >= 0 && left <= cfg.Goods.RemindDays && !refunded(e, expenses) {
+			due = append(due, e)
+		}
+	}
+	if len(due) == 0 {
+		return ""
+	}
+	slices.SortStableFunc(due, func(a, b Expense) int { return a.ReturnBy.Compare(b.ReturnBy) })
+	var b strings.Builder
+	b.WriteString(aheadOfPaceStyle.Render("Return windows closing:") + "\n")
+	for _, e := range due {
+		last := formatExpenseDate(e.ReturnBy) + fmt.Sprintf(", in %d days", daysBetween(day, e.ReturnBy))
+		switch daysBetween(day, e.ReturnBy) {
+		case 0:
+			last = "today"
+		case 1:
+			last = "tomorrow"
+		}
+		b.WriteString(fmt.Sprintf("  %s (%s, bought %s): the last day to return it is %s.\n", e.Name, money(-e.Amount), formatExpenseDate(e.Date), last))
+	}
+	return b.String()
+}
+
+// daysBetween counts the days from one date to another, negative when to
+// is earlier.
+func daysBetween(from, to time.Time) int {
+	return int(dateOf(to).Sub(dateOf(from)).Hours() / 24)
+}
+
+// refunded reports whether a refund of e, by the same name and no earlier,
+// is among expenses.
+func refunded(e Expense, expenses []Expense) bool {
+	for _, r := range expenses {
+		if r.Kind == kindRefund && payeeKey(r.Name) == payeeKey(e.Name) && !r.Date.Before(e.Date) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetsPathPrefix marks the path of a Google spreadsheet, followed by its
+// ID: gsheets:1BxiMVs0XRA5nFMdKvBdBZjgmUUqptlbs74OgvE2upms.
+const sheetsPathPrefix = "gsheets:"
+
+func isSheetsPath(path string) bool { return strings.HasPrefix(path, sheetsPathPrefix) }
+
+func sheetsPath(id string) string { return sheetsPathPrefix + id }
+
+// sheetsTabs are the tabs a spreadsheet keeps the data in, laid out like
+// the sheets of a workbook.
+var sheetsTabs = append(append([]string(nil), sheetNames...), metaSheet)
+
+// sheetsPollInterval is how often an open spreadsheet is checked for edits
+// made elsewhere, the Sheets API having no way to notify a terminal.
+const sheetsPollInterval = 15 * time.Second
+
+// sheetsStore keeps the Dataset in a Google spreadsheet, with the same
+// Expenses, Stonks and WatchList tabs as a workbook, a tab for each of its
+// other sheets and the revision in a Meta tab. The values are read into an in-memory workbook so that they
+// are parsed exactly as a workbook's are.
+type sheetsStore struct {
+	path string
+	id   string
+}
+
+// newSheetsStore opens the spreadsheet named by path, or the one in the
+// config file when path has no ID.
+func newSheetsStore(path string) (Store, error) {
+	id := strings.TrimPrefix(path, sheetsPathPrefix)
+	if id == "" {
+		id = cfg.GoogleSheets.SpreadsheetID
+	}
+	if id == "" {
+		return nil, fmt.Errorf("gsheets: set google_sheets.spreadsheet_id in the config file, or open %s<spreadsheet id>", sheetsPathPrefix)
+	}
+	return &sheetsStore{path: sheetsPath(id), id: id}, nil
+}
+
+func (s *sheetsStore) token() (string, error) {
+	if cfg.Offline {
+		return "", fmt.Errorf("%s: %w", s.path, errOffline)
+	}
+	token, err := googleToken()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", s.path, err)
+	}
+	return token, nil
+}
+
+// fetch reads every tab of the spreadsheet into a workbook, with empty
+// sheets for the sheetsTabs it lacks, and returns the titles of those it
+// has.
+func (s *sheetsStore) fetch(token string) (*excelize.File, []string, error) {
+	base := sheetsAPI + url.PathEscape(s.id)
+	var meta struct {
+		Sheets []struct {
+			Properties struct {
+				Title string `json:"title"`
+			} `json:"properties"`
+		} `json:"sheets"`
+	}
+	if err := sheetsRequest(http.MethodGet, base+"?fields=sheets.properties.title", token, nil, &meta); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	var titles []string
+	for _, sheet := range meta.Sheets {
+		titles = append(titles, sheet.Properties.Title)
+	}
+
+	// Dates typed into the spreadsheet come back as they are shown rather
+	// than as serial numbers, and parse like dates in a workbook.
+	q := url.Values{"valueRenderOption": {"UNFORMATTED_VALUE"}, "dateTimeRenderOption": {"FORMATTED_STRING"}}
+	var present []string
+	for _, tab := range titles {
+		if slices.Contains(sheetsTabs, tab) || !slices.Contains(derivedSheets, tab) {
+			present = append(present, tab)
+			q.Add("ranges", sheetsRange(tab))
+		}
+	}
+	var values struct {
+		ValueRanges []struct {
+			Values [][]any `json:"values"`
+		} `json:"valueRanges"`
+	}
+	if len(present) > 0 {
+		if err := sheetsRequest(http.MethodGet, base+"/values:batchGet?"+q.Encode(), token, nil, &values); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", s.path, err)
+		}
+	}
+
+	f := excelize.NewFile()
+	for _, tab := range slices.Concat(sheetsTabs, present) {
+		if _, err := f.NewSheet(tab); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+	}
+	for i, vr := range values.ValueRanges {
+		if i >= len(present) {
+			break
+		}
+		for r, row := range vr.Values {
+			if err := f.SetSheetRow(present[i], fmt.Sprintf("A%d", r+1), &row); err != nil {
+				f.Close()
+				return nil, nil, err
+			}
+		}
+	}
+	return f, titles, nil
+}
+
+func (s *sheetsStore) Load() (Dataset, error) {
+	token, err := s.token()
+	if err != nil {
+		return Dataset{}, err
+	}
+	f, _, err := s.fetch(token)
+	if err != nil {
+		return Dataset{}, err
+	}
+	defer f.Close()
+	data, err := readWorkbook(f, allSheets)
+	if err == nil {
+		err = data.err()
+	}
+	if err != nil {
+		return Dataset{}, fmt.Errorf("%s: %w", s.path, err)
+	}
+	sheets, err := otherSheets(f)
+	if err != nil {
+		return Dataset{}, fmt.Errorf("%s: %w", s.path, err)
+	}
+	return Dataset{
+		Expenses:  data.expenses,
+		Stonks:    data.stonks,
+		WatchList: data.watchList,
+		Sheets:    sheets,
+		Revision:  data.revision,
+	}, nil
+}
+
+// Save writes every row in one batch, adding any tabs the spreadsheet
+// lacks first. Rows left over from a longer sheet are blanked, as
+// writeExcelData does. The revision is checked just before writing, so a
+// save racing another is unlikely but, the API having no transactions,
+// not impossible.
+func (s *sheetsStore) Save(data Dataset) error {
+	token, err := s.token()
+	if err != nil {
+		return err
+	}
+	f, titles, err := s.fetch(token)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if current, _ := strconv.Atoi(getMeta(f, "revision")); current != data.Revision {
+		return &conflictError{path: s.path, loaded: data.Revision, current: current}
+	}
+
+	base := sheetsAPI + url.PathEscape(s.id)
+	var add []any
+	for _, tab := range slices.Concat(sheetsTabs, sortedSheets(data.Sheets)) {
+		if !slices.Contains(titles, tab) {
+			add = append(add, map[string]any{"addSheet": map[string]any{"properties": map[string]string{"title": tab}}})
+		}
+	}
+	if len(add) > 0 {
+		if err := sheetsRequest(http.MethodPost, base+":batchUpdate", token, map[string]any{"requests": add}, nil); err != nil {
+			return fmt.Errorf("%s: %w", s.path, err)
+		}
+	}
+
+	type valueRange struct {
+		Range  string  `json:"range"`
+		Values [][]any `json:"values"`
+	}
+	var ranges []valueRange
+	put := func(tab string, rows [][]any) {
+		headers := sheetHeaders[tab]
+		values := [][]any{make([]any, len(headers))}
+		for i, h := range headers {
+			values[0][i] = h
+		}
+		for _, row := range rows {
+			cells := make([]any, len(headers))
+			for i := range cells {
+				// A null leaves the cell as it was, so empty ones are
+				// written as "".
+				cells[i] = ""
+				if i < len(row) && row[i] != nil {
+					cells[i] = row[i]
+				}
+			}
+			values = append(values, cells)
+		}
+		previous, _ := f.GetRows(tab)
+		for len(values) < len(previous) {
+			values = append(values, slices.Repeat([]any{""}, len(headers)))
+		}
+		ranges = append(ranges, valueRange{Range: sheetsRange(tab), Values: values})
+	}
+	// Saving skips sheets passed as nil, as for a workbook.
+	if data.Expenses != nil {
+		rows := make([][]any, len(data.Expenses))
+		for i, e := range data.Expenses {
+			rows[i] = expenseCells(e)
+		}
+		put("Expenses", rows)
+	}
+	if data.Stonks != nil {
+		rows := make([][]any, len(data.Stonks))
+		for i, st := range data.Stonks {
+			rows[i] = stonkCells(st)
+		}
+		put("Stonks", rows)
+	}
+	if data.WatchList != nil {
+		rows := make([][]any, len(data.WatchList))
+		for i, w := range data.WatchList {
+			rows[i] = watchCells(w)
+		}
+		put("WatchList", rows)
+	}
+	// The other sheets are written as they are, header row included.
+	for _, tab := range sortedSheets(data.Sheets) {
+		rows := data.Sheets[tab]
+		previous, _ := f.GetRows(tab)
+		var width int
+		for _, row := range slices.Concat(rows, previous) {
+			width = max(width, len(row))
+		}
+		values := make([][]any, max(len(rows), len(previous)))
+		for r := range values {
+			values[r] = slices.Repeat([]any{""}, width)
+			if r < len(rows) {
+				for i, c := range rows[r] {
+					values[r][i] = sheetCell(c)
+				}
+			}
+		}
+		if len(values) > 0 {
+			ranges = append(ranges, valueRange{Range: sheetsRange(tab), Values: values})
+		}
+	}
+	ranges = append(ranges, valueRange{
+		Range:  sheetsRange(metaSheet) + "!A1:B1",
+		Values: [][]any{{"revision", strconv.Itoa(data.Revision + 1)}},
+	})
+	body := map[string]any{"valueInputOption": "RAW", "data": ranges}
+	if err := sheetsRequest(http.MethodPost, base+"/values:batchUpdate", token, body, nil); err != nil {
+		return fmt.Errorf("%s: %w", s.path, err)
+	}
+	return nil
+}
+
+// pollSheetsCmd is watchExcelCmd for a spreadsheet: it checks for edits
+// every sheetsPollInterval and reloads the given sheets once their
+// contents differ from the last load. Failed checks, such as while the
+// network is down, are retried at the next interval.
+func pollSheetsCmd(path string, sheets sheetMask, last *lastLoad) tea.Cmd {
+	if cfg.Offline {
+		return nil
+	}
+	stop := stopping
+	return func() tea.Msg {
+		for {
+			select {
+			case <-time.After(sheetsPollInterval):
+			case <-stop:
+				return nil
+			}
+			data, err := loadSheets(path, sheets)
+			if err != nil || data.fingerprint.sum == last.get().sum {
+				continue
+			}
+			data.watched = true
+			return data
+		}
+	}
+}
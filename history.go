@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// payeeHistory gathers every expense sharing a name with the selected one.
+type payeeHistory struct {
+	name string
+	// rows are indexes into the expense list, in sheet order.
+	rows    []int
+	total   float64
+	average float64
+	// trend is the latest entry minus the average of the ones before it;
+	// zero when there is only one entry.
+	trend float64
+}
+
+func payeeKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// historyFor returns the history of the payee of expenses[index], matching
+// names case-insensitively.
+func historyFor(expenses []Expense, index int) payeeHistory {
+	h := payeeHistory{name: expenses[index].Name}
+	key := payeeKey(h.name)
+	for i, e := range expenses {
+		if payeeKey(e.Name) == key {
+			h.rows = append(h.rows, i)
+			h.total += e.Amount
+		}
+	}
+	h.average = cfg.Rounding.round(h.total / float64(len(h.rows)))
+	if n := len(h.rows); n > 1 {
+		last := expenses[h.rows[n-1]].Amount
+		h.trend = cfg.Rounding.round(last - (h.total-last)/float64(n-1))
+	}
+	h.total = cfg.Rounding.round(h.total)
+	return h
+}
+
+func (h payeeHistory) render(expenses []Expense, selected int) string {
+	var buffer bytes.Buffer
+	buffer.WriteString(screenTitleStyle.Render(fmt.Sprintf("History: %s", h.name)))
+	buffer.WriteString("\n")
+	for _, i := range h.rows {
+		marker := "  "
+		if i == selected {
+			marker = "> "
+		}
+		date := formatExpenseDate(expenses[i].Date)
+		if date == "" {
+			date = "undated"
+		}
+		buffer.WriteString(fmt.Sprintf("%srow %-5d %-10s %10s\n", marker, i+1, date, money(expenses[i].Amount)))
+	}
+	entries := "entries"
+	if len(h.rows) == 1 {
+		entries = "entry"
+	}
+	buffer.WriteString(fmt.Sprintf("\n%d %s, total %s, average %s", len(h.rows), entries, money(h.total), money(h.average)))
+	if len(h.rows) > 1 {
+		arrow := "→"
+		switch {
+		case h.trend > 0:
+			arrow = "↑"
+		case h.trend < 0:
+			arrow = "↓"
+		}
+		trend := money(h.trend)
+		if h.trend > 0 {
+			trend = "+" + trend
+		}
+		buffer.WriteString(fmt.Sprintf(", latest %s %s vs earlier average", arrow, trend))
+	}
+	buffer.WriteString("\n")
+	return buffer.String()
+}
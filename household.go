@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+)
+
+// noMember stands for the expenses not set to be for anyone in the
+// household.
+const noMember = "No member"
+
+// validateMembers checks the household members of the config file: none
+// blank and none twice.
+func validateMembers(members []string) error {
+	seen := make(map[string]bool)
+	for _, name := range members {
+		key := payeeKey(name)
+		if key == "" {
+			return errors.New("members must not be blank")
+		}
+		if seen[key] {
+			return fmt.Errorf("members lists %q twice", strings.TrimSpace(name))
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// memberName is who an expense is for as written in the Member column,
+// spelt as in the config file when it names one of the members.
+func memberName(s string) string {
+	s = strings.TrimSpace(s)
+	for _, name := range cfg.Members {
+		if payeeKey(name) == payeeKey(s) {
+			return strings.TrimSpace(name)
+		}
+	}
+	return s
+}
+
+// memberOptions are the choices of the For select of the expense forms:
+// the members of the config file and current, the expense's own, if it is
+// someone else.
+func memberOptions(current string) []huh.Option[string] {
+	options := []huh.Option[string]{huh.NewOption("Not set", "")}
+	for _, name := range cfg.Members {
+		options = append(options, huh.NewOption(strings.TrimSpace(name), strings.TrimSpace(name)))
+	}
+	if current != "" && !slices.ContainsFunc(cfg.Members, func(name string) bool { return payeeKey(name) == payeeKey(current) }) {
+		options = append(options, huh.NewOption(current, current))
+	}
+	return options
+}
+
+// householdReport splits spending between the members of the household
+// and, for each, between categories.
+type householdReport struct {
+	// members and categories are in order of spending, largest first.
+	members    []string
+	categories []string
+	// spent is what each member spent on each category, by category then
+	// member, and totals what each spent in all.
+	spent  [][]float64
+	totals []float64
+}
+
+// splitByMember reports the spending of rows, refunds taken off, per
+// member and per member and category. Expenses for no one count towards
+// noMember.
+func splitByMember(rows []Expense) householdReport {
+	var r householdReport
+	r.members, r.totals = spendingBy(rows, dimensionMember)
+	r.categories, _ = spendingBy(rows, dimensionCategory)
+	r.spent = make([][]float64, len(r.categories))
+	for i := range r.spent {
+		r.spent[i] = make([]float64, len(r.members))
+	}
+	for j, member := range r.members {
+		var own []Expense
+		for _, e := range rows {
+			if strings.EqualFold(dimensionMember.groups(e)[0], member) {
+				own = append(own, e)
+			}
+		}
+		names, spent := spendingBy(own, dimensionCategory)
+		for k, name := range names {
+			// A category refunded more than spent overall has no row.
+			if i := slices.IndexFunc(r.categories, func(c string) bool { return strings.EqualFold(c, name) }); i >= 0 {
+				r.spent[i][j] = spent[k]
+			}
+		}
+	}
+	return r
+}
+
+// total is what the household spent in all.
+func (r householdReport) total() float64 {
+	var total float64
+	for _, t := range r.totals {
+		total += t
+	}
+	return cfg.Rounding.round(total)
+}
+
+// shareLine says what part of the spending each member accounts for.
+func (r householdReport) shareLine() string {
+	total := r.total()
+	if total <= 0 {
+		return ""
+	}
+	parts := make([]string, len(r.members))
+	for j, member := range r.members {
+		parts[j] = fmt.Sprintf("%s %s (%.0f%%)", member, money(r.totals[j]), r.totals[j]/total*100)
+	}
+	return "Per person: " + strings.Join(parts, " · ") + "\n"
+}
+
+// householdTable lays the report out with a column per member and a row
+// per category, the totals of each member last.
+func householdTable(r householdReport) string {
+	headers := append([]string{"Category"}, r.members...)
+	headers = append(headers, "Total")
+	var data [][]string
+	for i, category := range r.categories {
+		row := []string{category}
+		var total float64
+		for _, spent := range r.spent[i] {
+			row = append(row, memberAmount(spent))
+			total += spent
+		}
+		data = append(data, append(row, money(cfg.Rounding.round(total))))
+	}
+	totals := []string{"Total"}
+	for _, t := range r.totals {
+		totals = append(totals, money(t))
+	}
+	data = append(data, append(totals, money(r.total())))
+
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			switch {
+			case row == ltable.HeaderRow:
+				return headerStyle
+			case row == len(data)-1 || col == len(headers)-1:
+				return rowStyle.Bold(true)
+			}
+			return rowStyle
+		})
+	return t.String()
+}
+
+// memberAmount is a cell of householdTable, blank for nothing spent.
+func memberAmount(spent float64) string {
+	if spent == 0 {
+		return ""
+	}
+	return money(spent)
+}
+
+// memberCategoryLines break down what each member spent by category, for
+// below the expenses table grouped by member.
+func memberCategoryLines(rows []Expense) string {
+	var approved []Expense
+	for _, e := range rows {
+		if e.Pending == "" {
+			approved = append(approved, e)
+		}
+	}
+	r := splitByMember(approved)
+	var s string
+	for j, member := range r.members {
+		var parts []string
+		for i, category := range r.categories {
+			if r.spent[i][j] != 0 {
+				parts = append(parts, category+" "+money(r.spent[i][j]))
+			}
+		}
+		s += member + " spent " + money(r.totals[j]) + ": " + strings.Join(parts, " · ") + "\n"
+	}
+	return s
+}
+
+// runHousehold prints what each member of the household spent in a month,
+// by category.
+func runHousehold(args []string) error {
+	flags := flag.NewFlagSet("household", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "workbook with the expenses")
+	monthFlag := flags.String("month", monthOf(today()).Format(monthLayout), "month to report, as YYYY-MM, or all for every month")
+	flags.Parse(args)
+
+	if !isWorkbook(*file) {
+		return fmt.Errorf("household: %s is not an .xlsx workbook", *file)
+	}
+	f, err := openWorkbook(*file)
+	if err != nil {
+		return err
+	}
+	expenses, err := readExpenses(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	expenses, _ = readRates(f).inBase(expenses)
+	f.Close()
+
+	var rows []Expense
+	title, period := "All months", "any month"
+	if *monthFlag == "all" {
+		for _, e := range expenses {
+			if e.Pending == "" {
+				rows = append(rows, e)
+			}
+		}
+	} else {
+		month, err := time.Parse(monthLayout, *monthFlag)
+		if err != nil {
+			return fmt.Errorf("household: --month must be YYYY-MM or all: %w", err)
+		}
+		rows = monthExpenses(expenses, month)
+		title = month.Format("January 2006")
+		period = title
+	}
+	r := splitByMember(rows)
+	if len(r.members) == 0 {
+		return fmt.Errorf("household: %s has no spending in %s", *file, period)
+	}
+	fmt.Println(title)
+	fmt.Println(householdTable(r))
+	fmt.Print(r.shareLine())
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// validateCategoryIcons checks the category_icons of the config file.
+func validateCategoryIcons(icons map[string]string) error {
+	for category, icon := range icons {
+		if strings.TrimSpace(category) == "" {
+			return errors.New("category_icons: categories must not be blank")
+		}
+		if strings.TrimSpace(icon) == "" {
+			return errors.New("category_icons: the icon of " + category + " is blank")
+		}
+	}
+	return nil
+}
+
+// icon is the budget's icon: the one in its Icon column, or else the
+// config file's for its category.
+func (b budget) icon() string {
+	if b.Icon != "" {
+		return b.Icon
+	}
+	return categoryIcon(categoryIcons(nil), b.Category)
+}
+
+// categoryIcon is the icon of category in icons, or else its parent's.
+func categoryIcon(icons map[string]string, category string) string {
+	if icon, ok := icons[categoryKey(category)]; ok {
+		return icon
+	}
+	return icons[categoryKey(parentCategory(categoryName(category)))]
+}
+
+// categoryIcons maps categories, by categoryKey, to their icons: those of the
+// config file, and over them those of the Budgets sheet.
+func categoryIcons(budgets []budget) map[string]string {
+	icons := make(map[string]string)
+	for category, icon := range cfg.CategoryIcons {
+		icons[categoryKey(category)] = strings.TrimSpace(icon)
+	}
+	for _, b := range budgets {
+		if b.Icon != "" {
+			icons[categoryKey(b.Category)] = b.Icon
+		}
+	}
+	return icons
+}
+
+// noEmojiTerminals are values of $TERM for terminals without emoji, such
+// as the Linux console.
+var noEmojiTerminals = map[string]bool{"linux": true, "dumb": true, "vt100": true, "vt220": true, "cons25": true}
+
+// emojiSupported reports whether the terminal shows emoji: as the config
+// file's "emoji" says, or else unless $TERM names one known not to.
+func emojiSupported() bool {
+	if cfg.Emoji != nil {
+		return *cfg.Emoji
+	}
+	return !noEmojiTerminals[os.Getenv("TERM")]
+}
+
+// isEmoji reports whether s holds emoji, which some terminals cannot
+// show, rather than plain text such as "$" or "[H]".
+func isEmoji(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 0x1F000, r >= 0x2600 && r <= 0x27BF, r >= 0x2B00 && r <= 0x2BFF, r == 0xFE0F, r == 0x200D:
+			return true
+		}
+	}
+	return false
+}
+
+// withIcon puts icon before label. Emoji are left out on terminals that
+// cannot show them, leaving the label on its own.
+func withIcon(icon, label string) string {
+	if icon == "" || (isEmoji(icon) && !emojiSupported()) {
+		return label
+	}
+	return icon + " " + label
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/huh"
+)
+
+// instance identifies the TUI that has a data file open, kept in
+// path.instance while it runs, so that a second one opened on the same
+// file does not fight it over saves and reloads.
+type instance struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	User    string    `json:"user"`
+	Started time.Time `json:"started"`
+}
+
+func instancePath(path string) string {
+	return path + ".instance"
+}
+
+func thisInstance() instance {
+	host, _ := os.Hostname()
+	return instance{PID: os.Getpid(), Host: host, User: currentUser(), Started: time.Now()}
+}
+
+// running reports whether the instance may still be running: its process
+// is alive on this host, or it is on another host, where that cannot be
+// told.
+func (i instance) running() bool {
+	host, _ := os.Hostname()
+	return i.Host != host || processAlive(i.PID)
+}
+
+func (i instance) String() string {
+	return fmt.Sprintf("%s's tet (pid %d on %s, since %s)", i.User, i.PID, i.Host, i.Started.Local().Format("2 Jan 15:04"))
+}
+
+func readInstance(path string) (instance, error) {
+	var i instance
+	b, err := os.ReadFile(instancePath(path))
+	if err != nil {
+		return i, err
+	}
+	return i, json.Unmarshal(b, &i)
+}
+
+// claimInstance records this TUI as the one with path open. When another
+// one that is still running has, it asks whether to open the file
+// read-only, which is returned as readOnly, to open it anyway, taking it
+// over, or to quit, returned as huh.ErrUserAborted. The returned func
+// gives the file up on quitting.
+func claimInstance(path string) (release func(), readOnly bool, err error) {
+	me := thisInstance()
+	b, err := json.MarshalIndent(me, "", "  ")
+	if err != nil {
+		return nil, false, err
+	}
+	release = func() {
+		// Another TUI may have taken the file over since.
+		if i, err := readInstance(path); err == nil && i.PID == me.PID && i.Host == me.Host {
+			os.Remove(instancePath(path))
+		}
+	}
+	for {
+		f, err := os.OpenFile(instancePath(path), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = f.Write(append(b, '\n'))
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			return release, false, err
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, false, err
+		}
+		other, err := readInstance(path)
+		if err != nil || !other.running() {
+			// Left behind by a TUI that crashed, or half written.
+			os.Remove(instancePath(path))
+			continue
+		}
+		choice := "read-only"
+		form := huh.NewForm(huh.NewGroup(
+			huh.NewSelect[string]().
+				Title(fmt.Sprintf("%s is already open in %s.", filepath.Base(path), other)).
+				Description("Two sessions saving the same file would overwrite each other's changes.").
+				Options(
+					huh.NewOption("Open it read-only, following their changes", "read-only"),
+					huh.NewOption("Open it anyway, taking it over", "take-over"),
+					huh.NewOption("Quit", "quit"),
+				).
+				Value(&choice),
+		))
+		if err := form.Run(); err != nil {
+			return nil, false, err
+		}
+		switch choice {
+		case "read-only":
+			return func() {}, true, nil
+		case "take-over":
+			if err := writeFileAtomic(instancePath(path), append(b, '\n')); err != nil {
+				return nil, false, err
+			}
+			return release, false, nil
+		}
+		return nil, false, huh.ErrUserAborted
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ledgerPath is the default journal of the data file at path: data.xlsx is
+// exported to data.journal beside it.
+func ledgerPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".journal"
+}
+
+// ledgerAccount turns a name into a ledger account name under parent. Runs
+// of spaces end an account name in a journal, so they are collapsed; a
+// name already holding a colon is taken to be a full account name.
+func ledgerAccount(parent, name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if strings.Contains(name, ":") {
+		return name
+	}
+	return parent + ":" + name
+}
+
+// ledgerAmount is x with the configured currency as its commodity.
+func ledgerAmount(x float64) string {
+	if c := strings.ToUpper(cfg.Rounding.Currency); c != "" {
+		return money(x) + " " + c
+	}
+	return money(x)
+}
+
+// ledgerEntry renders e as a journal transaction: spending and refunds
+// post to Expenses:<category>, income to Income:<category>, balanced by
+// the account it was paid with, or by fallback when it has no Payment.
+// Who entered it and its tags are kept as hledger tags.
+func ledgerEntry(e Expense, fallback string) string {
+	category := e.Category
+	if category == "" {
+		category = e.Name
+	}
+	parent := "Expenses"
+	if e.Amount > 0 && e.Kind != kindRefund {
+		parent = "Income"
+	}
+	paidWith := fallback
+	if e.Payment != "" {
+		paidWith = ledgerAccount("Assets", e.Payment)
+	}
+
+	var b strings.Builder
+	b.WriteString(e.Date.Format(dateLayout) + " " + strings.Join(strings.Fields(e.Name), " "))
+	var tags []string
+	if e.EnteredBy != "" {
+		tags = append(tags, "by:"+e.EnteredBy)
+	}
+	for _, t := range e.Tags {
+		tags = append(tags, t+":")
+	}
+	if len(tags) > 0 {
+		b.WriteString("  ; " + strings.Join(tags, ", "))
+	}
+	b.WriteString("\n")
+	amount := ledgerAmount(-e.Amount)
+	if foreignCurrency(e.Currency) {
+		// Ledger keeps each commodity apart, so the expense stays in its own.
+		amount = money(-e.Amount) + " " + strings.ToUpper(e.Currency)
+	}
+	fmt.Fprintf(&b, "    %-36s  %s\n", ledgerAccount(parent, category), amount)
+	fmt.Fprintf(&b, "    %s\n", paidWith)
+	return b.String()
+}
+
+// ledgerJournal renders expenses as a journal for ledger or hledger, in
+// date order. Pending expenses have not happened yet and undated ones
+// cannot be placed in a journal, so both are left out; skipped counts them.
+func ledgerJournal(expenses []Expense, source, fallback string) (journal string, exported, skipped int) {
+	var rows []Expense
+	for _, e := range expenses {
+		if e.Pending != "" || e.Date.IsZero() {
+			skipped++
+			continue
+		}
+		rows = append(rows, e)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Date.Before(rows[j].Date) })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "; Exported from %s on %s.\n", filepath.Base(source), today().Format(dateLayout))
+	for _, e := range rows {
+		b.WriteString("\n")
+		b.WriteString(ledgerEntry(e, fallback))
+	}
+	return b.String(), len(rows), skipped
+}
+
+// runExportLedger writes the expenses of file, archived ones included, to
+// out as a ledger journal, replacing an earlier export.
+func runExportLedger(file, out, account string) error {
+	if strings.TrimSpace(account) == "" {
+		return fmt.Errorf("export: --account must not be empty")
+	}
+	s, err := readOnlyStoreForPath(file)
+	if err != nil {
+		return err
+	}
+	data, err := s.Load()
+	if err != nil {
+		return err
+	}
+	expenses := data.Expenses
+	if isWorkbook(file) {
+		archived, err := readArchive(file)
+		if err != nil {
+			return err
+		}
+		expenses = append(archived, expenses...)
+	}
+	if out == "" {
+		out = ledgerPath(file)
+	}
+	journal, exported, skipped := ledgerJournal(expenses, file, ledgerAccount("Assets", account))
+	if err := writeFileAtomic(out, []byte(journal)); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d expenses to %s.\n", exported, out)
+	if skipped > 0 {
+		fmt.Printf("Left out %d pending or undated expenses.\n", skipped)
+	}
+	return nil
+}
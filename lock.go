@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// staleLockAge is how old a lock file must be before it is assumed to
+// belong to a crashed writer and removed.
+const staleLockAge = 30 * time.Second
+
+// conflictError reports that the data on disk changed after it was loaded,
+// so saving would overwrite someone else's edit.
+type conflictError struct {
+	path            string
+	loaded, current int
+}
+
+func (e *conflictError) Error() string {
+	return fmt.Sprintf("%s was changed by another session (revision %d, you loaded %d)",
+		e.path, e.current, e.loaded)
+}
+
+// lockedError reports that another writer held the lock on path for longer
+// than lockFile waits.
+type lockedError struct {
+	path string
+}
+
+func (e *lockedError) Error() string {
+	return e.path + " is locked by another writer"
+}
+
+// lockFile takes an advisory lock on path by creating path.lock, waiting up
+// to a few seconds for another writer to finish. The returned func
+// releases it.
+func lockFile(path string) (func(), error) {
+	lock := path + ".lock"
+	// Other programs take the lock too, so it keeps the real time, not
+	// the clock's.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f, err := files.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { files.Remove(lock) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := files.Stat(lock); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			files.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, &lockedError{path: path}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
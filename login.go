@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Google's OAuth endpoints for devices without a browser, which a
+// terminal is as far as OAuth is concerned.
+var (
+	googleDeviceCodeURL = "https://oauth2.googleapis.com/device/code"
+	googleTokenURL      = "https://oauth2.googleapis.com/token"
+)
+
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// googleLoginToken is what `tet login` saves: a short-lived access token
+// and the refresh token that renews it.
+type googleLoginToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// googleTokenPath is google-token.json, kept beside the config file.
+func googleTokenPath() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "google-token.json"), nil
+}
+
+// oauthResponse is the reply of both OAuth endpoints; Error is set instead
+// of the rest when a request is refused.
+type oauthResponse struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	Interval        int    `json:"interval"`
+	AccessToken     string `json:"access_token"`
+	RefreshToken    string `json:"refresh_token"`
+	ExpiresIn       int    `json:"expires_in"`
+	Error           string `json:"error"`
+	Description     string `json:"error_description"`
+}
+
+func oauthRequest(endpoint string, form url.Values) (oauthResponse, error) {
+	var r oauthResponse
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := doRequest(req)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(body, &r); err != nil || (resp.StatusCode/100 != 2 && r.Error == "") {
+		return r, fmt.Errorf("Google sign-in: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return r, nil
+}
+
+func (r oauthResponse) token(refresh string) googleLoginToken {
+	if r.RefreshToken != "" {
+		refresh = r.RefreshToken
+	}
+	return googleLoginToken{
+		AccessToken:  r.AccessToken,
+		RefreshToken: refresh,
+		Expiry:       time.Now().Add(time.Duration(r.ExpiresIn) * time.Second),
+	}
+}
+
+func saveGoogleToken(t googleLoginToken) error {
+	path, err := googleTokenPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	// writeFileAtomic creates the file readable by its owner only.
+	return writeFileAtomic(path, b)
+}
+
+// savedGoogleToken returns the access token saved by `tet login`, renewing
+// it when it has expired. It returns "" when nobody has logged in.
+func savedGoogleToken() (string, error) {
+	path, err := googleTokenPath()
+	if err != nil {
+		return "", nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	var t googleLoginToken
+	if err := json.Unmarshal(b, &t); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if time.Until(t.Expiry) > time.Minute {
+		return t.AccessToken, nil
+	}
+	if t.RefreshToken == "" {
+		return "", nil
+	}
+	gs := cfg.GoogleSheets
+	r, err := oauthRequest(googleTokenURL, url.Values{
+		"client_id":     {gs.ClientID},
+		"client_secret": {gs.ClientSecret},
+		"refresh_token": {t.RefreshToken},
+		"grant_type":    {"refresh_token"},
+	})
+	if err != nil {
+		return "", err
+	}
+	if r.Error != "" {
+		return "", fmt.Errorf("Google sign-in has expired (%s), run tet login again", r.Error)
+	}
+	t = r.token(t.RefreshToken)
+	return t.AccessToken, saveGoogleToken(t)
+}
+
+// googleLogin signs in with the OAuth device flow: the user opens a URL on
+// any device and enters the code shown, while tet waits for the grant.
+func googleLogin() error {
+	gs := cfg.GoogleSheets
+	if gs.ClientID == "" || gs.ClientSecret == "" {
+		return errors.New(`login: set google_sheets.client_id and client_secret in the config file to an OAuth client of type "TVs and Limited Input devices"`)
+	}
+	if cfg.Offline {
+		return fmt.Errorf("login: %w", errOffline)
+	}
+	code, err := oauthRequest(googleDeviceCodeURL, url.Values{"client_id": {gs.ClientID}, "scope": {sheetsScope}})
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if code.Error != "" {
+		return fmt.Errorf("login: %s: %s", code.Error, code.Description)
+	}
+	fmt.Printf("To let tet use your Google spreadsheets, visit %s and enter the code %s\n", code.VerificationURL, code.UserCode)
+
+	interval := time.Duration(max(code.Interval, 5)) * time.Second
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		r, err := oauthRequest(googleTokenURL, url.Values{
+			"client_id":     {gs.ClientID},
+			"client_secret": {gs.ClientSecret},
+			"device_code":   {code.DeviceCode},
+			"grant_type":    {"urn:ietf:params:oauth:grant-type:device_code"},
+		})
+		if err != nil {
+			return fmt.Errorf("login: %w", err)
+		}
+		switch r.Error {
+		case "":
+			if err := saveGoogleToken(r.token("")); err != nil {
+				return err
+			}
+			fmt.Println("Logged in to Google.")
+			return nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return errors.New("login: access was denied")
+		default:
+			return fmt.Errorf("login: %s: %s", r.Error, r.Description)
+		}
+	}
+	return errors.New("login: the code expired before it was entered, run tet login again")
+}
+
+// ensureGoogleLogin signs in unless a Google access token is already at
+// hand, before the TUI takes over the terminal.
+func ensureGoogleLogin() error {
+	if _, err := googleToken(); err == nil {
+		return nil
+	}
+	return googleLogin()
+}
+
+func runLogin(args []string) error {
+	flags := flag.NewFlagSet("login", flag.ExitOnError)
+	flags.Parse(args)
+	return googleLogin()
+}
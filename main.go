@@ -2,15 +2,20 @@ package main
 
 import (
 	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
@@ -24,6 +29,17 @@ const (
 	screenExpenses
 	screenStonks
 	screenWatchlist
+	screenSnapshots
+	screenDividends
+	screenBudgets
+	screenReview
+	screenQuery
+	screenProfiles
+	screenAudit
+	screenStatement
+	screenCards
+	screenWishlist
+	screenGoals
 )
 
 var (
@@ -54,6 +70,28 @@ var (
 		Foreground(lipgloss.Color("#FFF7DB")).
 		SetString("Expenses")
 
+	screenTitleStyle = lipgloss.NewStyle().
+		MarginLeft(1).
+		MarginRight(5).
+		Padding(0, 1).
+		Bold(true).
+		Italic(true).
+		Foreground(lipgloss.Color("#FFF7DB"))
+
+	errorBannerStyle = lipgloss.NewStyle().
+		MarginLeft(1).
+		Padding(0, 1).
+		Bold(true).
+		Foreground(lipgloss.Color("#FFF7DB")).
+		Background(lipgloss.Color("124"))
+
+	offlineStyle = lipgloss.NewStyle().
+		MarginLeft(1).
+		Padding(0, 1).
+		Bold(true).
+		Foreground(lipgloss.Color("#FFF7DB")).
+		Background(lipgloss.Color("94"))
+
 	titleStyle        = lipgloss.NewStyle().MarginLeft(2)
 	itemStyle         = lipgloss.NewStyle().PaddingLeft(4)
 	selectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("170"))
@@ -93,6 +131,27 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 
 type screen int
 
+// mutatingKeys are the keybindings of the Expenses screen that change
+// data, or start to, disabled in read-only sessions. The screens with keys
+// of their own check for themselves.
+var mutatingKeys = map[string]bool{
+	"e": true,
+	"n": true,
+	"C": true,
+	"i": true,
+	"+": true,
+	"T": true,
+	"y": true,
+	"Y": true,
+	"W": true,
+	"P": true,
+	"d": true,
+	"x": true,
+	" ": true,
+	"M": true,
+	"c": true,
+}
+
 // expenseEditedMsg now includes both an index and the updated expense.
 type expenseEditedMsg struct {
 	index   int
@@ -103,54 +162,341 @@ type expenseEditedMsg struct {
 type Expense struct {
 	Name   string
 	Amount float64
+	Kind   expenseKind
+	// Date is when the expense happened, zero for rows entered before
+	// expenses were dated.
+	Date time.Time
+	// EnteredBy is who logged the expense in a shared book, empty for rows
+	// entered before expenses were attributed.
+	EnteredBy string
+	// Pending is set while the expense awaits the owner's approval, see
+	// applyEdit.
+	Pending string
+	// Category is what the expense counts towards in budgets, its name when
+	// empty. Tags label it further, nil when it has none, and Payment is
+	// what it was paid with, such as a card.
+	Category string
+	Tags     []string
+	Payment  string
+	// Currency is what Amount is in, blank for the configured currency.
+	Currency string
+	// Note is free text about the expense, of any number of lines.
+	Note string
+	// Method is how it was paid, in cash, by card or from an account,
+	// unknown when blank.
+	Method paymentMethod
+	// Member is who in the household it was for, such as a partner or the
+	// kids, blank when not set.
+	Member string
+	// ReturnBy is the last day goods bought can be returned, and
+	// WarrantyMonths how long they are under warranty from their date;
+	// both are zero for anything else.
+	ReturnBy       time.Time
+	WarrantyMonths int
+	// Usage is how much of a utility a bill was for, in UsageUnit, such as
+	// 320 kWh; zero when not recorded.
+	Usage     float64
+	UsageUnit string
 }
+// Stonk is a position on the Stonks sheet. Its total return is derived,
+// see totalReturn.
 type Stonk struct {
-	Symbol  string
-	Change  float64
-	Comment string
-	Extra   float64
+	Symbol string
+	// Quantity is the number of shares held and AvgPrice what was paid
+	// for each on average.
+	Quantity float64
+	AvgPrice float64
+	// Price is the last known share price, Open the day's opening price
+	// and DayChange the change from the previous close, in percent.
+	Price     float64
+	Open      float64
+	DayChange float64
+	// Currency is what the prices are quoted in; blank for the configured
+	// currency.
+	Currency string
+	Comment  string
 }
+// WatchItem is a symbol being watched. Whether it is owned is not stored
+// here but derived from the Stonks positions, see ownedSymbols.
 type WatchItem struct {
 	Symbol string
-	Qty    string
-	Owned  bool
+	Qty    float64
+	// TargetBuy and TargetSell are the prices to buy at or below and sell
+	// at or above; zero when unset.
+	TargetBuy  float64
+	TargetSell float64
+	// Price is the last known price, compared against the targets.
+	Price float64
+	Note  string
+
+	// legacyOwned is the Owned flag of data files from before ownership
+	// was derived, only set while loading them.
+	legacyOwned bool
 }
 
+// sheetMask selects which of the tracker's sheets to read.
+type sheetMask uint8
+
+const (
+	sheetExpenses sheetMask = 1 << iota
+	sheetStonks
+	sheetWatchList
+
+	allSheets = sheetExpenses | sheetStonks | sheetWatchList
+)
+
 type excelDataMsg struct {
 	expenses      []Expense
 	stonks        []Stonk
 	watchList     []WatchItem
-	totalExpenses float64
+	revision      int
+	// sheets says which of the fields above were read; the rest are unset.
+	sheets sheetMask
+	// watched is set on reloads triggered by the file watcher, which has to
+	// be re-armed after each one.
+	watched     bool
+	fingerprint fileFingerprint
+	// sheetErrs holds the load error of each selected sheet, nil if it
+	// loaded fine.
+	sheetErrs map[sheetMask]error
+	// closed are the closed months, and accounts, transfers, wishlist and
+	// goals the rows of the Accounts, Transfers, Wishlist and Goals sheets,
+	// read with the expenses.
+	closed    map[time.Time]bool
+	accounts  []account
+	transfers []accountTransfer
+	wishlist  []wish
+	goals     []goal
+	// otherErr joins the errors of the Transfers, Wishlist and Goals
+	// sheets, nil if they loaded fine.
+	otherErr error
+	// icons are the category icons, by categoryKey, of the config file and
+	// the Budgets sheet.
+	icons map[string]string
+	// budgets are the rows of the Budgets sheet, for the expenses screen's
+	// budget progress.
+	budgets []budget
+	// rates are the exchange rates of the FX sheet, for expenses in other
+	// currencies.
+	rates fxRates
 }
 
 // model is the Bubble Tea model.
 type model struct {
+	// path is the workbook being tracked.
+	path          string
 	expenses      []Expense
-	expensesTable *ltable.Table
+	// expensesTable is rendered once per data or selection change rather
+	// than on every frame, which is costly for long expense lists.
+	expensesTable string
+	// history is the detail and payee history of the selected expense,
+	// shown while showHistory is on.
+	history       string
+	showHistory   bool
+	// categorySubtotals sums the spending of the listed expenses by
+	// category, below the table unless hidden with 'v'.
+	categorySubtotals string
+	hideCategories    bool
+	// archived expenses are only read when showArchive is first turned
+	// on; they are listed after the current ones and cannot be edited.
+	archived      []Expense
+	showArchive   bool
+	// enteredBy shows only the expenses one person entered, or everyone's
+	// when empty.
+	enteredBy     string
+	// groupBy orders the expenses table by a report dimension, chosen
+	// with 'g'.
+	groupBy reportDimension
+	// sort orders the expenses table by a column, chosen with 's'.
+	sort expenseSort
+	// tagFilter shows only the expenses with a tag, or every expense when
+	// empty.
+	tagFilter string
+	// monthFilter shows only the expenses dated in a month, or every
+	// expense when zero.
+	monthFilter time.Time
+	// closed are the months listed in the History sheet, whose expenses
+	// are only edited once unlocked for the session.
+	closed        map[time.Time]bool
+	unlocked      map[time.Time]bool
+	// accounts, when the workbook lists any, add a running balance to the
+	// expenses table, which transfers between them move too.
+	accounts      []account
+	transfers     []accountTransfer
+	// wishlist are the planned purchases of the Wishlist sheet, whose
+	// prices are fetched once a session, as recorded in wishPricesFetched.
+	wishlist          []wish
+	wishPricesFetched bool
+	// goals are the savings goals of the Goals sheet, and goalRow the one
+	// selected on the Goals screen.
+	goals   []goal
+	goalRow int
+	// rates convert expenses in other currencies: those of the FX sheet
+	// and, for currencies it has none of, fetchedRates, fetched once per
+	// session as recorded in ratesFetched.
+	rates        fxRates
+	fetchedRates fxRates
+	ratesFetched map[string]bool
+	// icons are shown beside the categories of the expenses table.
+	icons         map[string]string
 	stonks        []Stonk
 	watchList     []WatchItem
+	// watchlistTable and stonksTable are rendered like expensesTable.
+	watchlistTable string
+	watchlistDirty bool
+	stonksTable    string
+	stonksDirty    bool
+	// stonksMetric is the change shown on the Stonks screen, kept for the
+	// rest of the session once switched.
+	stonksMetric changeMetric
+	// showFX splits the Stonks returns into asset and currency effects,
+	// read from the Prices and FX sheets when toggled on.
+	showFX    bool
+	fxReturns string
+	// quotesPaused counts the symbols the last quote refresh skipped as
+	// their markets were closed.
+	quotesPaused int
+	// quoteCache is every quote fetched this session, by symbolKey.
+	quoteCache map[string]quoteRecord
+	// dividends is the calendar last fetched, nil until then.
+	// dividendsPending waits for the positions to load before fetching,
+	// and reminded is set once reminders have been shown.
+	dividends        *dividendCalendar
+	dividendsPending bool
+	reminded         bool
+	// budgets are the rows of the Budgets sheet, read when the Budgets
+	// screen opens, and budgetMonth the month it shows, as a variance
+	// report when budgetVariance is set, or projected to the year's end
+	// under each of budgetScenarios when budgetCompare is.
+	budgets         []budget
+	budgetsFound    bool
+	budgetsErr      error
+	budgetMonth     time.Time
+	budgetVariance  bool
+	budgetScenarios []budgetScenario
+	budgetCompare   bool
+	// monthPicker jumps the Budgets screen to any month.
+	monthPicker monthPicker
+	// alerted holds the watchlist targets currently hit, so each alerts
+	// once.
+	alerted       map[string]bool
 	err           error
 	editing       bool
 	currentScreen screen
-	totalExpenses float64
+	// totals are computed from expenses whenever they change; the sheet's
+	// own total formula is only kept up to date for Excel users.
+	totals        expenseTotals
+	// budgetProgress and budgetWarning show spending against the budgets
+	// of the month shown, rendered with the expenses table.
+	budgetProgress string
+	budgetWarning  string
+	// budgetsOver marks the budgets spending went over in the months of
+	// the rows shown, worked out with budgetProgress rather than on every
+	// move of the selection.
+	budgetsOver map[string]map[time.Time]bool
 	list          list.Model
 	selectedRow   int
+	status        string
+	snapshots     []string
+	snapshotRow   int
+	reviewRow     int
+	snapshotDiff  *snapshotDiffMsg
+	// queryInput is the SQL typed on the Query screen and queryResult
+	// what it last returned.
+	queryInput   textinput.Model
+	queryResult  *queryResult
+	queryErr     error
+	queryRunning bool
+	// quickInput is the one-line entry opened with '+' on the Expenses
+	// screen, taking keys while quickAdding.
+	quickInput  textinput.Model
+	quickAdding bool
+	// pickingTemplate lists the config file's templates, opened with 'T',
+	// taking keys until one is picked.
+	pickingTemplate bool
+	// search narrows the expenses table to the rows matching what was
+	// typed on the line opened with '/', which takes keys while
+	// searching.
+	search      expenseSearch
+	searchInput textinput.Model
+	searching   bool
+	// profileRow is the selected row of the Profiles screen, and
+	// nextProfile the profile chosen there, which ends the session so that
+	// main can start one with its settings.
+	profileRow  int
+	nextProfile *string
+	// auditEntries are the Audit sheet's rows, newest first, read when the
+	// Audit Log screen opens; auditRow is the one selected.
+	auditEntries []auditEntry
+	auditErr     error
+	auditRow     int
+	// width and height are the terminal's size, zero until it is known.
+	// Tables taller or wider than it scroll in their windows, keeping
+	// their header row in view.
+	width           int
+	height          int
+	// statement is the bank statement being reviewed on the Statement
+	// screen before it is imported.
+	statement       statementReview
+	expensesWindow  tableWindow
+	stonksWindow    tableWindow
+	watchlistWindow tableWindow
+	// readOnly sessions, such as SSH spectators, cannot change any data.
+	readOnly bool
+	// local is set for the session run in this terminal rather than over
+	// SSH. Only it stages receipts and e-receipts, so that SSH sessions do
+	// not stage each one again.
+	local bool
+	// revision is the workbook revision the data was loaded from.
+	revision int
+	conflict *writeConflictMsg
+	// failure is the error panel shown over the screen, if any.
+	failure *errorPanel
+	// quotesDown is set while no price can be fetched, so that the panel
+	// saying so is shown once.
+	quotesDown bool
+	// update is a newer release of tet, noted on the menu.
+	update string
+	// marked are the indexes in expenses of the rows marked with space for
+	// a bulk change.
+	marked map[int]bool
+	// loaded tracks which sheets have been read so far.
+	loaded sheetMask
+	// sheetErrs marks sheets that failed to load, shown as a banner on
+	// their screen.
+	sheetErrs map[sheetMask]error
+	// lastLoad identifies the workbook contents last read.
+	lastLoad *lastLoad
+	// expensesDirty defers rebuilding the expenses table until its screen
+	// is shown again.
+	expensesDirty bool
+	// debug shows the timing overlay, toggled with ctrl+d.
+	debug bool
 }
 
 type errMsg struct{ err error }
 
 func (e errMsg) Error() string { return e.err.Error() }
 
-func initialModel() *model {
-	data, err := readExcelData("data.xlsx")
+func initialModel(path string) *model {
+	var status string
+	// Other backends bring their schema up to date when they are opened.
+	if isWorkbook(path) {
+		if backup, err := upgradeWorkbook(path); err != nil {
+			log.Printf("Error upgrading workbook: %v", err)
+		} else if backup != "" {
+			status = fmt.Sprintf("Upgraded %s to schema v%d (backup saved as %s)", filepath.Base(path), schemaVersion, backup)
+		}
+	}
+
+	// Stonks and the watchlist are only read once their screens are opened.
+	data, err := loadSheets(path, sheetExpenses)
 	if err != nil {
 		log.Printf("Error reading Excel data: %v", err)
 		data = excelDataMsg{
 			expenses:  []Expense{},
 			stonks:    []Stonk{},
 			watchList: []WatchItem{},
-			// Ensure a default total if needed:
-			totalExpenses: 0,
 		}
 	}
 
@@ -159,23 +505,54 @@ func initialModel() *model {
 		menuItem("Expenses"),
 		menuItem("Stonks"),
 		menuItem("Watchlist"),
+		menuItem("Budgets"),
+		menuItem("Cards"),
+		menuItem("Wishlist"),
+		menuItem("Goals"),
+		menuItem("Dividends"),
+		menuItem("Review"),
+		menuItem("Snapshots"),
+		menuItem("Query"),
+		menuItem("Profiles"),
+		menuItem("Audit Log"),
 	}
 
 	// Create the list model. Adjust the width and height as needed.
-	l := list.New(items, itemDelegate{}, 20, 7)
+	l := list.New(items, itemDelegate{}, 20, len(items)+4)
 	l.Title = "Main Menu"
 	l.SetFilteringEnabled(false)
 	l.SetShowStatusBar(false)
 	l.SetShowHelp(false)
 
 	m := model{
+		path:          path,
 		currentScreen: screenMenu,
 		expenses:      data.expenses,
+		closed:        data.closed,
+		accounts:      data.accounts,
+		transfers:     data.transfers,
+		wishlist:      data.wishlist,
+		goals:         data.goals,
+		icons:         data.icons,
+		budgets:       data.budgets,
+		rates:         data.rates,
 		stonks:        data.stonks,
 		watchList:     data.watchList,
-		totalExpenses: data.totalExpenses,
+		revision:      data.revision,
+		loaded:        data.sheets,
+		lastLoad:      &lastLoad{fp: data.fingerprint},
+		sheetErrs:     make(map[sheetMask]error),
 		list:          l,
+		queryInput:    newQueryInput(),
+		quickInput:    newQuickInput(),
+		searchInput:   newSearchInput(),
 		editing:       false,
+		status:        status,
+	}
+	for sheet, err := range data.sheetErrs {
+		if err != nil {
+			m.sheetErrs[sheet] = err
+		}
 	}
 	m.updateExpensesTable()
 	return &m
@@ -183,14 +560,183 @@ func initialModel() *model {
 
 // entry point
 func main() {
-	p := tea.NewProgram(initialModel())
-	if err, _ := p.Run(); err != nil {
+	// The profile picks the config, so it is read before anything else,
+	// commands included: tet --profile business quick.
+	if name, rest, ok := profileArg(os.Args[1:]); ok {
+		os.Setenv(profileEnv, name)
+		os.Args = append(os.Args[:1], rest...)
+	}
+	c, err := loadConfig()
+	if err != nil {
 		log.Fatal(err)
 	}
+	cfg = c
+	if s := os.Getenv(nowEnv); s != "" {
+		if err := pretendNow(s); err != nil {
+			log.Fatalf("$%s: %v", nowEnv, err)
+		}
+	}
+
+	if ran, err := runCommand(os.Args[1:]); ran {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	pprofAddr := flag.String("pprof", "", "serve net/http/pprof and timing metrics on this address, e.g. :6060")
+	offline := flag.Bool("offline", false, "skip every network call and show prices as last saved")
+	backend := flag.String("backend", "xlsx", "where data is kept when no recent file is picked ("+backendNames()+")")
+	file := flag.String("file", "", "data file to open, skipping the recent list (default $"+fileEnv+", the config file's \"file\" or data.xlsx)")
+	flag.String("profile", "", "profile from the config file to use, with its own settings and workbook (default $"+profileEnv+")")
+	demo := flag.Bool("demo", false, "try tet out on a year of generated data kept in memory, saving nothing")
+	pretend := flag.String("now", "", "pretend it is this date or time, such as 2027-01-01 or 2027-01-01T09:00, for dates, month ends and backups (default $"+nowEnv+")")
+	flag.Parse()
+	if *pretend != "" {
+		if err := pretendNow(*pretend); err != nil {
+			log.Fatalf("--now: %v", err)
+		}
+	}
+	offlineFlag = *offline
+	cfg.Offline = cfg.Offline || offlineFlag
+	if _, ok := backends[*backend]; !ok {
+		log.Fatalf("unknown backend %q (available: %s)", *backend, backendNames())
+	}
+	if *pprofAddr != "" {
+		startPprof(*pprofAddr)
+	}
+	if *demo {
+		if err := seedDemo(); err != nil {
+			log.Fatal(err)
+		}
+		*file = demoPath
+	}
+
+	for {
+		path := defaultPaths[*backend]
+		switch {
+		case *file != "":
+			path = *file
+		case *backend == "xlsx":
+			path = dataFile()
+		case *backend == "gsheets":
+			path = sheetsPath(cfg.GoogleSheets.SpreadsheetID)
+		}
+		next, err := runSession(path, *file == "")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if next == nil {
+			return
+		}
+		// Switching profile starts over with its settings and workbook.
+		os.Setenv(profileEnv, *next)
+		if cfg, err = loadConfig(); err != nil {
+			log.Fatal(err)
+		}
+		cfg.Offline = cfg.Offline || offlineFlag
+		*file = dataFile()
+	}
+}
+
+// runSession runs the TUI on the data file at path, first letting the user
+// pick a recent workbook instead when pick is set. It returns the profile
+// switched to on the Profiles screen, or nil when the user quit.
+func runSession(path string, pick bool) (*string, error) {
+	if isSheetsPath(path) {
+		if err := ensureGoogleLogin(); err != nil {
+			return nil, err
+		}
+	}
+	// A scratch session in memory and a spreadsheet have nothing to pick
+	// or remember, and a file named with --file is opened straight away.
+	remember := !isMemoryPath(path) && !isSheetsPath(path)
+	ws := workspace{Path: path}
+	if remember && pick {
+		var err error
+		ws, err = pickWorkspace(path)
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+	} else if remember {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		ws.Path = abs
+	}
+	if ok, err := firstRun(ws.Path); errors.Is(err, huh.ErrUserAborted) || (err == nil && !ok) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := unlockWorkbook(ws.Path); errors.Is(err, huh.ErrUserAborted) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	readOnly := false
+	if remember {
+		release, ro, err := claimInstance(ws.Path)
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		defer release()
+		readOnly = ro
+	}
+	auditSource = "TUI"
+	m := initialModel(ws.Path)
+	m.local = true
+	if readOnly {
+		m.readOnly = true
+		m.status = "Read-only session: " + filepath.Base(ws.Path) + " is open in another tet."
+	}
+	m.restore(ws)
+	if remember {
+		if err := rememberWorkspace(m.workspace()); err != nil {
+			log.Printf("Error saving recent workbooks: %v", err)
+		}
+	}
+
+	p := tea.NewProgram(m)
+	notifySignals(p)
+	final, err := p.Run()
+	endSession()
+	if n := saves.pending(); n > 0 {
+		fmt.Println("Saving your last changes before quitting…")
+	}
+	if err := saves.flush(); err != nil {
+		log.Printf("Error saving before quitting: %v", err)
+	}
+	// An interrupt (SIGINT) quits like 'q' does.
+	interrupted := errors.Is(err, tea.ErrInterrupted)
+	if err != nil && !interrupted {
+		return nil, err
+	}
+	if remember {
+		if err := rememberWorkspace(final.(*model).workspace()); err != nil {
+			log.Printf("Error saving recent workbooks: %v", err)
+		}
+	}
+	return final.(*model).nextProfile, nil
 }
 
 // --- File Watching & Excel Reading ---
-func watchExcelCmd(filename string) tea.Cmd {
+// watchExcelCmd waits for filename to change and reloads the given sheets.
+// Events that leave the contents identical to the last load are ignored.
+func watchExcelCmd(filename string, sheets sheetMask, last *lastLoad) tea.Cmd {
+	// Data kept in memory only changes through this session.
+	if isMemoryPath(filename) {
+		return nil
+	}
+	if isSheetsPath(filename) {
+		return pollSheetsCmd(filename, sheets, last)
+	}
+	stop := stopping
 	return func() tea.Msg {
 		watcher, err := fsnotify.NewWatcher()
 		if err != nil {
@@ -207,54 +753,185 @@ func watchExcelCmd(filename string) tea.Cmd {
 			select {
 			case event := <-watcher.Events:
 				if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
-					time.Sleep(500 * time.Millisecond)
-					data, err := readExcelData(filename)
+					if !settled(filename, last.get()) {
+						continue
+					}
+					data, err := loadSheets(filename, sheets)
 					if err != nil {
-						return errMsg{err}
+						return loadFailedMsg{err: err, retry: loadSheetsCmd(filename, sheets)}
 					}
+					data.watched = true
 					return data
 				}
 			case err := <-watcher.Errors:
 				return errMsg{err}
+			case <-stop:
+				return nil
 			}
 		}
 	}
 }
 
 func readExcelData(filename string) (excelDataMsg, error) {
-	f, err := excelize.OpenFile(filename)
+	return readExcelSheets(filename, allSheets)
+}
+
+// isWorkbook reports whether path is an .xlsx workbook rather than the
+// data file of another backend, going by its extension like storeForPath.
+func isWorkbook(path string) bool {
+	s, _ := storeForPath(path)
+	_, ok := s.(*excelStore)
+	return ok
+}
+
+// loadSheets reads the selected sheets of the data file at path. Other
+// backends than xlsx have no sheets to skip and are loaded whole.
+func loadSheets(path string, sheets sheetMask) (excelDataMsg, error) {
+	if isWorkbook(path) {
+		return readExcelSheets(path, sheets)
+	}
+	s, err := storeForPath(path)
 	if err != nil {
 		return excelDataMsg{}, err
 	}
-	defer f.Close()
-
-	expenses, err := readExpenses(f)
+	data, err := s.Load()
 	if err != nil {
 		return excelDataMsg{}, err
 	}
-	stonks, err := readStonks(f)
+	var fp fileFingerprint
+	switch {
+	case isSheetsPath(path):
+		fp = fingerprintData(data)
+	case !isMemoryPath(path):
+		if fp, err = fingerprintFile(path); err != nil {
+			return excelDataMsg{}, err
+		}
+	}
+	return excelDataMsg{
+		expenses:    data.Expenses,
+		stonks:      data.Stonks,
+		watchList:   data.WatchList,
+		revision:    data.Revision,
+		sheets:      allSheets,
+		fingerprint: fp,
+		sheetErrs:   make(map[sheetMask]error),
+	}, nil
+}
+
+// saveData is writeExcelData for the data file of any backend.
+func saveData(path string, revision int, expenses []Expense, stonks []Stonk, watchList []WatchItem) error {
+	if isWorkbook(path) {
+		return writeExcelData(path, revision, expenses, stonks, watchList, nil)
+	}
+	s, err := storeForPath(path)
+	if err != nil {
+		return err
+	}
+	return s.Save(Dataset{Expenses: expenses, Stonks: stonks, WatchList: watchList, Revision: revision})
+}
+
+// readExcelSheets reads only the selected sheets of filename.
+func readExcelSheets(filename string, sheets sheetMask) (excelDataMsg, error) {
+	defer metrics.track("load")()
+
+	fp, err := fingerprintFile(filename)
 	if err != nil {
 		return excelDataMsg{}, err
 	}
-	watchList, err := readWatchList(f)
+	f, err := openWorkbook(filename)
 	if err != nil {
 		return excelDataMsg{}, err
 	}
+	defer f.Close()
+	data, err := readWorkbook(f, sheets)
+	data.fingerprint = fp
+	return data, err
+}
 
-	f.SetCellFormula("Expenses", "D2", "=SUM(B3:B9)")
-	computed, _ := f.CalcCellValue("Expenses", "D2")
-	total, _ := strconv.ParseFloat(computed, 64)
+// readWorkbook reads the selected sheets of f. A sheet that cannot be read
+// does not fail the others: its error is recorded in sheetErrs and its
+// field left empty.
+func readWorkbook(f *excelize.File, sheets sheetMask) (excelDataMsg, error) {
+	data := excelDataMsg{sheets: sheets, sheetErrs: make(map[sheetMask]error)}
+	if sheets&sheetExpenses != 0 {
+		data.sheetErrs[sheetExpenses] = readSheet("Expenses", func() (err error) {
+			data.expenses, err = readExpenses(f)
+			return err
+		})
+		data.closed = readClosedMonths(f)
+		data.accounts = readAccounts(f)
+		data.otherErr = errors.Join(
+			readSheet(transfersSheet, func() (err error) {
+				data.transfers, err = readTransfers(f)
+				return err
+			}),
+			readSheet(wishlistSheet, func() (err error) {
+				data.wishlist, err = readWishlist(f)
+				return err
+			}),
+			readSheet(goalsSheet, func() (err error) {
+				data.goals, err = readGoals(f)
+				return err
+			}),
+		)
+		data.budgets, _, _ = readBudgets(f)
+		data.icons = categoryIcons(data.budgets)
+		data.rates = readRates(f)
+	}
+	if sheets&sheetStonks != 0 {
+		data.sheetErrs[sheetStonks] = readSheet("Stonks", func() (err error) {
+			data.stonks, err = readStonks(f)
+			return err
+		})
+	}
+	if sheets&sheetWatchList != 0 {
+		data.sheetErrs[sheetWatchList] = readSheet("WatchList", func() (err error) {
+			data.watchList, err = readWatchList(f)
+			return err
+		})
+	}
+	data.revision, _ = strconv.Atoi(getMeta(f, "revision"))
+	return data, nil
+}
 
-	return excelDataMsg{
-		expenses:      expenses,
-		stonks:        stonks,
-		watchList:     watchList,
-		totalExpenses: total,
-	}, nil
+// readSheet runs read, turning a panic from malformed sheet XML into an
+// error so that one broken sheet cannot take the whole app down.
+func readSheet(name string, read func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s sheet is corrupt: %v", name, r)
+		}
+	}()
+	if err := read(); err != nil {
+		return fmt.Errorf("%s sheet: %w", name, err)
+	}
+	return nil
 }
 
+// err joins the errors of every sheet that failed to load.
+func (d excelDataMsg) err() error {
+	var errs []error
+	for _, sheet := range []sheetMask{sheetExpenses, sheetStonks, sheetWatchList} {
+		if err := d.sheetErrs[sheet]; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readExpenses reads the expense rows. The sheet's total formula is never
+// evaluated: totals are computed from the rows themselves, since
+// calculating formulas through excelize parses the whole sheet a second
+// time, which dominated load time on large workbooks.
 func readExpenses(f *excelize.File) ([]Expense, error) {
-	rows, err := f.GetRows("Expenses")
+	return readExpenseRows(f, "Expenses")
+}
+
+// readExpenseRows reads a sheet laid out like Expenses, such as an archive.
+func readExpenseRows(f *excelize.File, sheet string) ([]Expense, error) {
+	// Raw values, so a number format with fewer decimals than the stored
+	// amount cannot round it on the way in.
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
 	if err != nil {
 		return nil, err
 	}
@@ -265,27 +942,121 @@ func readExpenses(f *excelize.File) ([]Expense, error) {
 			continue
 		}
 		name := line[0]
-		amt, _ := strconv.ParseFloat(line[1], 64)
-		expenses = append(expenses, Expense{Name: name, Amount: amt})
+		amt, err := parseAmountCell(line[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s row %d: %w", sheet, i+1, err)
+		}
+		amt = cfg.Rounding.round(amt)
+		e := Expense{Name: name, Amount: amt}
+		if len(line) > 2 {
+			e.Kind = parseExpenseKind(line[2])
+		}
+		if len(line) > 3 {
+			e.Date = parseExpenseDate(line[3])
+		}
+		if len(line) > 4 {
+			e.EnteredBy = strings.TrimSpace(line[4])
+		}
+		if len(line) > 5 {
+			e.Pending = strings.TrimSpace(line[5])
+		}
+		if len(line) > 6 {
+			e.Category = strings.TrimSpace(line[6])
+		}
+		if len(line) > 7 {
+			e.Tags = parseTags(line[7])
+		}
+		if len(line) > 8 {
+			e.Payment = strings.TrimSpace(line[8])
+		}
+		if len(line) > 9 {
+			e.Currency = symbolKey(line[9])
+		}
+		if len(line) > 10 {
+			e.Note = cleanNote(line[10])
+		}
+		if len(line) > 11 {
+			e.Method = parsePaymentMethod(line[11])
+		}
+		if len(line) > 12 {
+			e.Member = memberName(line[12])
+		}
+		if len(line) > 13 {
+			e.ReturnBy = parseExpenseDate(line[13])
+		}
+		if len(line) > 14 {
+			e.WarrantyMonths = parseWarranty(line[14])
+		}
+		if len(line) > 15 {
+			e.Usage, e.UsageUnit = parseUsage(line[15])
+		}
+		expenses = append(expenses, e)
 	}
 	return expenses, nil
 }
+
+// writeExpenseRow writes e to row of a sheet laid out like Expenses.
+func writeExpenseRow(f *excelize.File, sheet string, row int, e Expense) error {
+	cell, _ := excelize.CoordinatesToCellName(1, row)
+	values := expenseCells(e)
+	return f.SetSheetRow(sheet, cell, &values)
+}
+
+// blankRowsFrom blanks sheet from row on, clearing rows left over when a
+// save holds fewer than the sheet did, such as after a rejected change.
+// Sheets passed as nil were never loaded and are not blanked.
+func blankRowsFrom(f *excelize.File, sheet string, row int) error {
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return err
+	}
+	blank := make([]any, len(sheetHeaders[sheet]))
+	for ; row <= len(rows); row++ {
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		if err := f.SetSheetRow(sheet, cell, &blank); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expenseCells, stonkCells and watchCells are the cells of a row of each
+// sheet, in the order of sheetHeaders.
+func expenseCells(e Expense) []any {
+	return []any{e.Name, e.Amount, string(e.Kind), formatExpenseDate(e.Date), e.EnteredBy, e.Pending, e.Category, formatTags(e.Tags), e.Payment, e.Currency, e.Note, string(e.Method), e.Member, formatExpenseDate(e.ReturnBy), warrantyCell(e.WarrantyMonths), formatUsage(e.Usage, e.UsageUnit)}
+}
+
+func stonkCells(st Stonk) []any {
+	return []any{st.Symbol, optionalCell(st.Quantity), optionalCell(st.AvgPrice), optionalCell(st.Price),
+		optionalCell(st.Open), optionalCell(st.DayChange), st.totalReturnCell(), st.Currency, st.Comment}
+}
+
+func watchCells(w WatchItem) []any {
+	return []any{w.Symbol, optionalCell(w.Qty), optionalCell(w.TargetBuy), optionalCell(w.TargetSell), optionalCell(w.Price), w.Note}
+}
 func readStonks(f *excelize.File) ([]Stonk, error) {
 	rows, err := f.GetRows("Stonks")
 	if err != nil {
 		return nil, err
 	}
 	var stonks []Stonk
+	// Like the watchlist, blank rows are kept so saves stay aligned. Total
+	// Return (G) is derived, not read.
 	for i := 1; i < len(rows); i++ {
 		line := rows[i]
-		if len(line) < 4 {
-			continue
+		cell := func(i int) string {
+			if i < len(line) {
+				return line[i]
+			}
+			return ""
 		}
-		sym := line[0]
-		chg, _ := strconv.ParseFloat(line[1], 64)
-		cmt := line[2]
-		ext, _ := strconv.ParseFloat(line[3], 64)
-		stonks = append(stonks, Stonk{Symbol: sym, Change: chg, Comment: cmt, Extra: ext})
+		s := Stonk{Symbol: cell(0), Currency: cell(7), Comment: cell(8)}
+		s.Quantity, _ = strconv.ParseFloat(cell(1), 64)
+		s.AvgPrice, _ = strconv.ParseFloat(cell(2), 64)
+		s.Price, _ = strconv.ParseFloat(cell(3), 64)
+		s.Open, _ = strconv.ParseFloat(cell(4), 64)
+		s.DayChange, _ = strconv.ParseFloat(cell(5), 64)
+		stonks = append(stonks, s)
 	}
 	return stonks, nil
 }
@@ -295,73 +1066,256 @@ func readWatchList(f *excelize.File) ([]WatchItem, error) {
 		return nil, err
 	}
 	var items []WatchItem
+	// Blank rows are kept so every item is saved back to the row it was
+	// read from.
 	for i := 1; i < len(rows); i++ {
 		line := rows[i]
-		if len(line) < 3 {
-			continue
+		cell := func(i int) string {
+			if i < len(line) {
+				return line[i]
+			}
+			return ""
 		}
-		symbol := line[0]
-		qty := line[1]
-		owned := (line[2] == "Yes")
-		items = append(items, WatchItem{Symbol: symbol, Qty: qty, Owned: owned})
+		w := WatchItem{Symbol: cell(0), Note: cell(5)}
+		w.Qty, _ = strconv.ParseFloat(cell(1), 64)
+		w.TargetBuy, _ = strconv.ParseFloat(cell(2), 64)
+		w.TargetSell, _ = strconv.ParseFloat(cell(3), 64)
+		w.Price, _ = strconv.ParseFloat(cell(4), 64)
+		items = append(items, w)
 	}
 	return items, nil
 }
 
-func writeExcelCmd(exp []Expense, st []Stonk, wl []WatchItem) tea.Cmd {
+// writeConflictMsg asks the user whether to overwrite changes another
+// session saved after this one loaded the workbook.
+type writeConflictMsg struct {
+	err       *conflictError
+	expenses  []Expense
+	stonks    []Stonk
+	watchList []WatchItem
+	quotes    []quoteRecord
+}
+
+// writeExcelCmd saves the data and reads back the sheets in reload. Sheets
+// that were never loaded are passed as nil and left untouched, as is the
+// Quotes sheet without quotes.
+func writeExcelCmd(path string, revision int, reload sheetMask, exp []Expense, st []Stonk, wl []WatchItem, quotes []quoteRecord) tea.Cmd {
+	// The save is queued as soon as it is issued, so that quitting before
+	// the command has run still saves.
+	save := saves.add(func() error {
+		err := saveData(path, revision, exp, st, wl)
+		if err == nil && quotes != nil && isWorkbook(path) {
+			err = writeQuotesSheet(path, quotes)
+		}
+		return err
+	})
 	return func() tea.Msg {
-		err := writeExcelData("data.xlsx", exp, st, wl)
-		if err != nil {
-			return errMsg{err}
+		err := save()
+		var conflict *conflictError
+		if errors.As(err, &conflict) {
+			return writeConflictMsg{err: conflict, expenses: exp, stonks: st, watchList: wl, quotes: quotes}
 		}
-		time.Sleep(500 * time.Millisecond)
-		data, err := readExcelData("data.xlsx")
 		if err != nil {
-			return errMsg{err}
+			// Only a retry the user asks for is queued.
+			retry := func() tea.Msg { return writeExcelCmd(path, revision, reload, exp, st, wl, quotes)() }
+			return saveFailedMsg{err: err, retry: retry}
 		}
-		return data
+		clk.Sleep(500 * time.Millisecond)
+		return loadSheetsCmd(path, reload)()
 	}
 }
 
-func writeExcelData(filename string,
-	expenses []Expense, stonks []Stonk, watchList []WatchItem) error {
-	f, err := excelize.OpenFile(filename)
+// writeExcelData saves the data over the workbook's rows. revision is the
+// workbook revision the data was loaded from; if another writer has saved
+// since, a *conflictError is returned and nothing is written. sheets
+// replace the other sheets of the same name, as Dataset.Sheets does.
+func writeExcelData(filename string, revision int,
+	expenses []Expense, stonks []Stonk, watchList []WatchItem, sheets map[string][][]string) error {
+	defer metrics.track("save")()
+
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := openWorkbook(filename)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
+	current, _ := strconv.Atoi(getMeta(f, "revision"))
+	if current != revision {
+		return &conflictError{path: filename, loaded: revision, current: current}
+	}
+	if err := setMeta(f, "revision", strconv.Itoa(revision+1)); err != nil {
+		return err
+	}
+	changes, err := auditChanges(f, expenses, stonks, watchList)
+	if err != nil {
+		return err
+	}
+	// Saves that only move prices, like every quote refresh's, would soon
+	// push the copies worth keeping out of the rotation.
+	if len(changes) > 0 || len(sheets) > 0 {
+		if err := backupBeforeWrite(filename); err != nil {
+			return fmt.Errorf("backing up %s: %w", filename, err)
+		}
+	}
+	// Restored sheets go in first, so that this write's changes are
+	// audited below a restored Audit sheet's rows.
+	if err := writeOtherSheets(f, sheets); err != nil {
+		return err
+	}
+
 	// Overwrite rows for Expenses
 	for i, e := range expenses {
-		row := i + 2
-		f.SetCellValue("Expenses", fmt.Sprintf("A%d", row), e.Name)
-		f.SetCellValue("Expenses", fmt.Sprintf("B%d", row), e.Amount)
+		if err := writeExpenseRow(f, "Expenses", i+2, e); err != nil {
+			return err
+		}
+	}
+	if expenses != nil {
+		if err := blankRowsFrom(f, "Expenses", len(expenses)+2); err != nil {
+			return err
+		}
+	}
+	// Amounts show the configured currency's decimals in Excel too.
+	if n := len(expenses); n > 0 {
+		numFmt := cfg.Rounding.numFmt()
+		style, err := f.NewStyle(&excelize.Style{CustomNumFmt: &numFmt})
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellStyle("Expenses", "B2", fmt.Sprintf("B%d", n+1), style); err != nil {
+			return err
+		}
+	}
+	if err := writeExpenseTotal(f, expenses); err != nil {
+		return err
 	}
 	// Overwrite rows for Stonks
 	for i, st := range stonks {
-		row := i + 2
-		f.SetCellValue("Stonks", fmt.Sprintf("A%d", row), st.Symbol)
-		f.SetCellValue("Stonks", fmt.Sprintf("B%d", row), st.Change)
-		f.SetCellValue("Stonks", fmt.Sprintf("C%d", row), st.Comment)
-		f.SetCellValue("Stonks", fmt.Sprintf("D%d", row), st.Extra)
+		values := stonkCells(st)
+		if err := f.SetSheetRow("Stonks", fmt.Sprintf("A%d", i+2), &values); err != nil {
+			return err
+		}
+	}
+	if stonks != nil {
+		if err := blankRowsFrom(f, "Stonks", len(stonks)+2); err != nil {
+			return err
+		}
 	}
 	// Overwrite rows for WatchList
 	for i, w := range watchList {
-		row := i + 2
-		f.SetCellValue("WatchList", fmt.Sprintf("A%d", row), w.Symbol)
-		f.SetCellValue("WatchList", fmt.Sprintf("B%d", row), w.Qty)
-		if w.Owned {
-			f.SetCellValue("WatchList", fmt.Sprintf("C%d", row), "Yes")
-		} else {
-			f.SetCellValue("WatchList", fmt.Sprintf("C%d", row), "No")
+		values := watchCells(w)
+		if err := f.SetSheetRow("WatchList", fmt.Sprintf("A%d", i+2), &values); err != nil {
+			return err
+		}
+	}
+	if watchList != nil {
+		if err := blankRowsFrom(f, "WatchList", len(watchList)+2); err != nil {
+			return err
 		}
 	}
+	if err := appendAudit(f, changes); err != nil {
+		return err
+	}
 	return f.Save()
 }
 
+// applyData merges the sheets carried by msg into the model.
+func (m *model) applyData(msg excelDataMsg) {
+	if msg.sheets&sheetExpenses != 0 {
+		m.expenses = msg.expenses
+		// The rows may have moved.
+		m.marked = nil
+		m.closed = msg.closed
+		m.accounts = msg.accounts
+		// Failing to read them keeps what was last loaded.
+		if msg.otherErr == nil {
+			m.transfers = msg.transfers
+			m.wishlist = msg.wishlist
+			m.goals = msg.goals
+		}
+		m.icons = msg.icons
+		m.budgets = msg.budgets
+		m.rates = msg.rates
+		for c, series := range m.fetchedRates {
+			if _, ok := m.rates[c]; !ok {
+				if m.rates == nil {
+					m.rates = make(fxRates)
+				}
+				m.rates[c] = series
+			}
+		}
+		if m.currentScreen == screenExpenses {
+			m.updateExpensesTable()
+		} else {
+			m.expensesDirty = true
+		}
+	}
+	if msg.sheets&sheetStonks != 0 {
+		m.stonks = msg.stonks
+		m.stonksDirty = true
+		// Ownership on the watchlist follows the positions.
+		m.watchlistDirty = true
+	}
+	if m.stonksDirty && m.currentScreen == screenStonks {
+		m.updateStonksTable()
+	}
+	if msg.sheets&sheetWatchList != 0 {
+		m.watchList = msg.watchList
+		m.watchlistDirty = true
+	}
+	if m.watchlistDirty && m.currentScreen == screenWatchlist {
+		m.updateWatchlistTable()
+	}
+	for sheet, err := range msg.sheetErrs {
+		if err != nil {
+			m.sheetErrs[sheet] = err
+		} else {
+			delete(m.sheetErrs, sheet)
+		}
+	}
+	m.loaded |= msg.sheets
+	m.revision = msg.revision
+	m.lastLoad.set(msg.fingerprint)
+}
+
+// loadSheetCmd reads the sheets a screen needs the first time it is
+// opened.
+func (m *model) loadSheetCmd(sheets sheetMask) tea.Cmd {
+	return m.reloadSheetsCmd(sheets &^ m.loaded)
+}
+
+func (m *model) reloadSheetsCmd(sheets sheetMask) tea.Cmd {
+	if sheets == 0 {
+		return nil
+	}
+	return loadSheetsCmd(m.path, sheets)
+}
+
 // Init --- Bubble Tea Init, Update, & View ---
 func (m *model) Init() tea.Cmd {
-	return watchExcelCmd("data.xlsx")
+	// A restored workspace may open straight onto a lazily loaded sheet.
+	var load tea.Cmd
+	switch m.currentScreen {
+	case screenStonks:
+		load = m.loadSheetCmd(sheetStonks)
+	case screenWatchlist:
+		load = m.loadSheetCmd(sheetWatchList | sheetStonks)
+	}
+	var dividends tea.Cmd
+	if m.currentScreen == screenDividends || (cfg.Dividends.RemindDays > 0 && !cfg.Offline) {
+		dividends = m.dividendsCmd()
+	}
+	var receipts, email tea.Cmd
+	if m.local {
+		receipts = watchReceiptsCmd(m.path)
+		email = pollEmailCmd(0)
+	}
+	return tea.Batch(watchExcelCmd(m.path, m.loaded, m.lastLoad), load, quoteTickCmd(), dividends, receipts, email, checkUpdateCmd(), m.fetchRatesCmd(), dayTickCmd())
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -369,14 +1323,142 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case excelDataMsg:
-		m.expenses = msg.expenses
-		m.stonks = msg.stonks
-		m.watchList = msg.watchList
-		m.totalExpenses = msg.totalExpenses
-		return m, watchExcelCmd("data.xlsx")
+		// The watcher was armed with the sheets loaded at the time; any
+		// opened since then changed on disk too.
+		stale := m.loaded &^ msg.sheets
+		m.applyData(msg)
+		if msg.otherErr != nil {
+			m.loadFailed(loadFailedMsg{err: msg.otherErr, retry: loadSheetsCmd(m.path, sheetExpenses)})
+		}
+		var alerts tea.Cmd
+		if msg.sheets&sheetWatchList != 0 {
+			alerts = m.checkAlerts()
+		}
+		if m.dividendsPending && msg.sheets&sheetStonks != 0 {
+			// The dividend calendar was waiting for the positions.
+			alerts = tea.Batch(alerts, m.dividendsCmd())
+		}
+		if msg.sheets&sheetExpenses != 0 {
+			alerts = tea.Batch(alerts, m.fetchRatesCmd())
+		}
+		if msg.watched {
+			// Archiving moves rows between sheets, so archived rows are
+			// re-read too the next time they are shown.
+			var archive tea.Cmd
+			m.archived = nil
+			if m.showArchive {
+				archive = loadArchiveCmd(m.path)
+			}
+			var budgets tea.Cmd
+			if m.currentScreen == screenBudgets {
+				budgets = loadBudgetsCmd(m.path)
+			}
+			return m, tea.Batch(watchExcelCmd(m.path, m.loaded, m.lastLoad), m.reloadSheetsCmd(stale), archive, budgets, alerts)
+		}
+		return m, alerts
+	case alertHookFailedMsg:
+		m.status = msg.Error()
+		return m, nil
+	case writeConflictMsg:
+		m.conflict = &msg
+		return m, nil
+	case saveFailedMsg:
+		m.saveFailed(msg)
+		return m, nil
+	case loadFailedMsg:
+		m.loadFailed(msg)
+		return m, watchExcelCmd(m.path, m.loaded, m.lastLoad)
+	case backupOpenedMsg:
+		m.applyData(msg.data)
+		m.readOnly = true
+		m.status = "Showing " + filepath.Base(msg.backup) + " read-only; copy it over " + filepath.Base(m.path) + " to restore it."
+		return m, nil
+	case archiveMsg:
+		m.archived = msg.expenses
+		m.updateExpensesTable()
+		return m, nil
+	case dayTickMsg:
+		// Budget bars and the pace follow the current month.
+		if m.currentScreen == screenExpenses {
+			m.updateExpensesTable()
+		} else {
+			m.expensesDirty = true
+		}
+		return m, dayTickCmd()
+	case quoteTickMsg:
+		var refresh tea.Cmd
+		if symbols := m.quoteSymbols(); len(symbols) > 0 {
+			refresh = refreshQuotesCmd(symbols)
+		}
+		return m, tea.Batch(refresh, quoteTickCmd())
+	case updateMsg:
+		m.update = msg.latest
+	case quotesMsg:
+		m.quotesFailed(msg)
+		// With the Quotes sheet on, fetch times are saved even when no
+		// price moved.
+		if !m.applyQuotes(msg) && (m.cachedQuotes() == nil || len(msg.quotes) == 0) {
+			return m, nil
+		}
+		m.stonksDirty, m.watchlistDirty = true, true
+		switch m.currentScreen {
+		case screenStonks:
+			m.updateStonksTable()
+		case screenWatchlist:
+			m.updateWatchlistTable()
+		}
+		alerts := m.checkAlerts()
+		if m.readOnly {
+			return m, alerts
+		}
+		return m, tea.Batch(alerts, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, m.cachedQuotes()))
+	case budgetsMsg:
+		m.budgets, m.budgetsFound, m.budgetsErr = msg.budgets, msg.found, msg.err
+		m.budgetScenarios = msg.scenarios
+		m.expensesDirty = true
+	case auditMsg:
+		m.auditEntries, m.auditErr = msg.entries, msg.err
+		return m, nil
+	case wishPricesMsg:
+		return m, m.applyWishPrices(msg)
+	case dividendsMsg:
+		m.applyDividends(msg.calendar)
+		return m, nil
+	case receiptStagedMsg:
+		return m.stageReceiptExpense(msg)
+	case emailReceiptsMsg:
+		return m.stageEmailReceipts(msg)
+	case fxReturnsMsg:
+		if msg.err != nil {
+			m.showFX = false
+			m.status = "Currency split: " + msg.err.Error()
+			return m, nil
+		}
+		m.fxReturns = renderFXReturns(msg.rows, msg.total)
+		return m, nil
+	case reloadDataMsg:
+		m.status = "Reloading " + filepath.Base(m.path) + " on request."
+		return m, m.reloadData()
+	case reloadConfigMsg:
+		return m, m.reloadConfig()
 	case errMsg:
 		m.err = msg.err
-		return m, watchExcelCmd("data.xlsx")
+		return m, watchExcelCmd(m.path, m.loaded, m.lastLoad)
+	case tea.WindowSizeMsg:
+		m.resize(msg)
+		return m, nil
+	}
+
+	if key, ok := msg.(tea.KeyMsg); ok && key.String() == "ctrl+d" {
+		m.debug = !m.debug
+		return m, nil
+	}
+
+	if m.conflict != nil {
+		return m.updateConflict(msg)
+	}
+	if m.failure != nil {
+		return m.updateFailure(msg)
 	}
 
 	if m.currentScreen == screenMenu {
@@ -392,63 +1474,443 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				switch selected {
 				case "Expenses":
 					m.currentScreen = screenExpenses
+					if m.expensesDirty {
+						m.updateExpensesTable()
+					}
 				case "Stonks":
 					m.currentScreen = screenStonks
+					if m.stonksDirty {
+						m.updateStonksTable()
+					}
+					return m, m.loadSheetCmd(sheetStonks)
 				case "Watchlist":
 					m.currentScreen = screenWatchlist
+					if m.watchlistDirty {
+						m.updateWatchlistTable()
+					}
+					return m, m.loadSheetCmd(sheetWatchList | sheetStonks)
+				case "Budgets":
+					m.currentScreen = screenBudgets
+					m.budgetMonth = monthOf(today())
+					m.budgetVariance, m.budgetCompare = false, false
+					return m, loadBudgetsCmd(m.path)
+				case "Cards":
+					m.currentScreen = screenCards
+				case "Wishlist":
+					m.currentScreen = screenWishlist
+					if !m.wishPricesFetched && m.tracksWishPrices() {
+						return m, m.fetchWishPricesCmd()
+					}
+				case "Goals":
+					m.currentScreen = screenGoals
+				case "Dividends":
+					m.currentScreen = screenDividends
+					if m.dividends == nil && !cfg.Offline {
+						return m, m.dividendsCmd()
+					}
+				case "Review":
+					m.currentScreen = screenReview
+					m.reviewRow = 0
+				case "Snapshots":
+					m.currentScreen = screenSnapshots
+					m.snapshots = listSnapshots(m.path)
+					m.snapshotRow = 0
+					m.snapshotDiff = nil
+				case "Query":
+					m.currentScreen = screenQuery
+					return m, m.queryInput.Focus()
+				case "Profiles":
+					m.currentScreen = screenProfiles
+					m.selectCurrentProfile()
+				case "Audit Log":
+					m.currentScreen = screenAudit
+					m.auditRow = 0
+					return m, loadAuditCmd(m.path)
 				}
 			}
 		}
 		return m, cmd
 	}
 
+	if m.currentScreen == screenSnapshots {
+		return m.updateSnapshots(msg)
+	}
+	if m.currentScreen == screenQuery {
+		return m.updateQuery(msg)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.currentScreen == screenBudgets {
+		return m.updateBudgets(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.currentScreen == screenReview {
+		return m.updateReview(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.currentScreen == screenCards {
+		return m.updateCards(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.currentScreen == screenWishlist {
+		return m.updateWishlist(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.currentScreen == screenGoals {
+		return m.updateGoals(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.currentScreen == screenProfiles {
+		return m.updateProfiles(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.currentScreen == screenAudit {
+		return m.updateAudit(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.currentScreen == screenStatement {
+		return m.updateStatement(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.quickAdding {
+		return m.updateQuickAdd(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.pickingTemplate {
+		return m.updateTemplates(key)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && m.searching {
+		return m.updateSearch(key)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.readOnly && m.currentScreen == screenExpenses && mutatingKeys[msg.String()] {
+			m.status = "Read-only session: editing is disabled."
+			return m, nil
+		}
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
 		case "up":
-			if m.selectedRow > 0 {
+			if w := m.tableWindow(); w != nil && m.currentScreen != screenExpenses {
+				w.scrollRows(-1)
+				m.redrawTable()
+			} else if m.selectedRow > 0 {
 				m.selectedRow--
-				m.updateExpensesTable()
+				m.redrawExpensesTable()
 			}
 		case "down":
-			if m.selectedRow < len(m.expenses)-1 {
+			if w := m.tableWindow(); w != nil && m.currentScreen != screenExpenses {
+				w.scrollRows(1)
+				m.redrawTable()
+			} else if m.selectedRow < len(m.visibleExpenses())-1 {
 				m.selectedRow++
-				m.updateExpensesTable()
-
+				m.redrawExpensesTable()
+			}
+		case "pgup", "pgdown", "home", "end":
+			if w := m.tableWindow(); w != nil {
+				m.pageTable(w, msg.String())
+			}
+		case "left":
+			if w := m.tableWindow(); w != nil {
+				w.scrollColumns(-1)
+				m.redrawTable()
+			}
+		case "right":
+			if w := m.tableWindow(); w != nil {
+				w.scrollColumns(1)
+				m.redrawTable()
 			}
 		case "b":
 			m.currentScreen = screenMenu
 			return m, nil
+		case "c":
+			if m.currentScreen == screenStonks {
+				m.stonksMetric = m.stonksMetric.next()
+				m.updateStonksTable()
+			} else if m.currentScreen == screenExpenses && !m.editing {
+				return m.startBulk(bulkCategory)
+			}
+		case "x":
+			if m.currentScreen == screenStonks {
+				m.showFX = !m.showFX
+				if m.showFX {
+					m.fxReturns = "Loading prices and rates…\n"
+					return m, loadFXReturnsCmd(m.path, m.stonks)
+				}
+			} else if m.currentScreen == screenExpenses && !m.editing {
+				return m.deleteExpenses()
+			}
+		case "d":
+			if m.currentScreen == screenExpenses && !m.editing {
+				return m.deleteExpenses()
+			}
+		case " ":
+			if m.currentScreen == screenExpenses && !m.editing {
+				m.toggleMark()
+			}
+		case "M":
+			if m.currentScreen == screenExpenses && !m.editing {
+				return m.startBulk(bulkMove)
+			}
+		case "enter", "esc":
+			if m.currentScreen == screenExpenses && !m.editing && msg.String() == "esc" && len(m.marked) > 0 {
+				m.marked = nil
+				m.updateExpensesTable()
+			} else if m.currentScreen == screenExpenses && !m.editing && len(m.visibleExpenses()) > 0 {
+				m.showHistory = msg.String() == "enter" && !m.showHistory
+				m.updateExpensesTable()
+			}
+		case "a":
+			if m.currentScreen == screenExpenses && !m.editing {
+				m.showArchive = !m.showArchive
+				if n := len(m.visibleExpenses()); !m.showArchive && m.selectedRow >= n {
+					m.selectedRow = max(n-1, 0)
+				}
+				m.updateExpensesTable()
+				if m.showArchive && m.archived == nil {
+					return m, loadArchiveCmd(m.path)
+				}
+			}
+		case "v":
+			if m.currentScreen == screenExpenses && !m.editing {
+				m.hideCategories = !m.hideCategories
+				m.updateExpensesTable()
+			}
 		case "e":
-			if m.currentScreen == screenExpenses && !m.editing && len(m.expenses) > 0 {
+			if m.currentScreen == screenExpenses && !m.editing {
+				_, index := m.visibleRows()
+				if m.selectedRow >= len(index) || index[m.selectedRow] < 0 {
+					break
+				}
+				i := index[m.selectedRow]
 				m.editing = true
-				return m, m.editExpenseForm(m.selectedRow)
+				if e := m.expenses[i]; m.isLocked(e) {
+					return m, unlockForm(monthOf(e.Date), m.editExpenseForm(i))
+				}
+				return m, m.editExpenseForm(i)
+			}
+		case "w":
+			if m.currentScreen == screenExpenses && !m.editing {
+				all := m.expenses
+				if m.showArchive {
+					all = append(append([]Expense(nil), m.expenses...), m.archived...)
+				}
+				m.enteredBy = nextEnteredBy(m.enteredBy, enteredByNames(all))
+				m.selectedRow = 0
+				m.updateExpensesTable()
+			}
+		case "t":
+			if m.currentScreen == screenExpenses && !m.editing {
+				all := m.expenses
+				if m.showArchive {
+					all = append(slices.Clip(m.expenses), m.archived...)
+				}
+				m.tagFilter = nextTag(m.tagFilter, tagNames(all))
+				m.selectedRow = 0
+				m.updateExpensesTable()
+			}
+		case "m":
+			if m.currentScreen == screenExpenses && !m.editing {
+				m.monthFilter = nextMonthFilter(m.monthFilter, expenseMonths(m.statementRows()))
+				m.selectedRow = 0
+				m.updateExpensesTable()
+			}
+		case "/":
+			if m.currentScreen == screenExpenses && !m.editing {
+				return m.startSearch()
+			}
+		case "s", "S":
+			if m.currentScreen == screenExpenses && !m.editing {
+				if msg.String() == "s" {
+					m.sort = m.sort.next()
+				} else {
+					m.sort.descending = !m.sort.descending
+				}
+				m.selectedRow = 0
+				m.updateExpensesTable()
+			}
+		case "P":
+			if m.currentScreen == screenExpenses && !m.editing {
+				return m.saveSortForm()
+			}
+		case "g":
+			if m.currentScreen == screenExpenses && !m.editing {
+				m.groupBy = m.groupBy.next()
+				if m.groupBy == dimensionNone {
+					m.status = "Ungrouped the expenses."
+				} else {
+					m.status = "Grouped the expenses by " + m.groupBy.String() + "."
+				}
+				m.selectedRow = 0
+				m.updateExpensesTable()
 			}
 		case "n":
 			if m.currentScreen == screenExpenses && !m.editing {
 				m.editing = true
-				return m, m.newExpenseForm()
+				return m, m.newExpenseForm(Expense{Date: today()})
+			}
+		case "y", "Y":
+			if m.currentScreen == screenExpenses && !m.editing {
+				return m.copySelected(msg.String() == "Y")
+			}
+		case "+":
+			if m.currentScreen == screenExpenses && !m.editing {
+				m.quickAdding = true
+				m.quickInput.SetValue("")
+				return m, m.quickInput.Focus()
+			}
+		case "W":
+			if m.currentScreen == screenExpenses && !m.editing && isWorkbook(m.path) {
+				if count, _ := withdrawals(m.expenses); count > 0 && accountNamed(m.accounts, cfg.Cash.Account) >= 0 {
+					m.editing = true
+					return m, m.moveWithdrawalsCmd()
+				}
+			}
+		case "T":
+			if m.currentScreen == screenExpenses && !m.editing {
+				if len(cfg.Templates) == 0 {
+					m.status = `No templates yet: list quick entries such as "Rent 800 #Housing" as templates in the config file.`
+					return m, nil
+				}
+				m.pickingTemplate = true
+			}
+		case "E":
+			return m, m.exportScreenCmd()
+		case "i":
+			if m.currentScreen == screenExpenses && !m.editing {
+				m.editing = true
+				return m, m.importForm()
+			}
+		case "C":
+			if m.currentScreen == screenExpenses && !m.editing {
+				if !isWorkbook(m.path) {
+					m.status = "Closing a month needs an .xlsx workbook."
+					return m, nil
+				}
+				m.editing = true
+				return m, m.closeMonthForm()
 			}
 		}
-	case expenseEditedMsg:
-		if msg.index == -1 {
-			m.expenses = append(m.expenses, msg.expense)
+	case sheetExportedMsg:
+		if msg.err != nil {
+			m.status = msg.err.Error()
 		} else {
-			m.expenses[msg.index] = msg.expense
+			m.status = "Exported to " + msg.file
+		}
+		return m, nil
+	case unlockedMsg:
+		if msg.month.IsZero() {
+			m.editing = false
+			m.status = "Left the closed month locked; nothing was changed."
+			return m, nil
+		}
+		if m.unlocked == nil {
+			m.unlocked = make(map[time.Time]bool)
+		}
+		m.unlocked[msg.month] = true
+		m.status = msg.month.Format("January 2006") + " is unlocked until you quit."
+		return m, msg.next
+	case csvImportedMsg:
+		m.editing = false
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		if msg.file == "" {
+			return m, nil
+		}
+		m.status = msg.result.String()
+		if len(msg.result.expenses) == 0 {
+			return m, nil
 		}
+		if fresh, dups := findDuplicates(m.expenses, msg.result.expenses); len(dups) > 0 {
+			msg.result.expenses = fresh
+			return m.duplicatesForm(fresh, dups, msg.result.String())
+		}
+		m.expenses = append(m.expenses, msg.result.expenses...)
 		m.updateExpensesTable()
+		return m, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil)
+	case statementReadMsg:
+		return m.reviewStatement(msg)
+	case withdrawalsMovedMsg:
+		m.editing = false
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		m.status = fmt.Sprintf("Moved %d cash withdrawals to %s; add what the cash went on as expenses paid with it.", msg.moved, cfg.Cash.Account)
+		return m, m.reloadSheetsCmd(m.loaded)
+	case goalContributedMsg:
+		m.editing = false
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		if msg.goal.Name == "" {
+			return m, nil
+		}
+		m.status = fmt.Sprintf("Put %s towards %s, transferred to %s; %s left.", money(msg.amount), msg.goal.Name, msg.goal.account(), money(msg.goal.left()))
+		return m, m.reloadSheetsCmd(m.loaded)
+	case monthClosedMsg:
+		m.editing = false
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		if msg.result.month.IsZero() {
+			return m, nil
+		}
+		m.status = msg.result.String()
+		if len(msg.result.variance) > 0 {
+			// The month's variance report is the first thing to look at
+			// once it is closed.
+			m.currentScreen = screenBudgets
+			m.budgetMonth = msg.result.month
+			m.budgetVariance, m.budgetCompare = true, false
+			return m, tea.Batch(m.reloadSheetsCmd(m.loaded), loadBudgetsCmd(m.path))
+		}
+		return m, m.reloadSheetsCmd(m.loaded)
+	case expenseEditedMsg:
+		if m.isLocked(msg.expense) {
+			edited := msg
+			return m, unlockForm(monthOf(msg.expense.Date), func() tea.Msg { return edited })
+		}
+		if i := duplicateOf(m.expenses, msg.expense); msg.index == -1 && i >= 0 {
+			return m.duplicatesForm(nil, []duplicate{{expense: msg.expense, of: i}}, "")
+		}
 		m.editing = false
+		m.expenses = applyEdit(m.expenses, msg.index, msg.expense)
+		if needsApproval() {
+			m.status = fmt.Sprintf("Saved for %s to approve.", cfg.Owner)
+		}
+		m.updateExpensesTable()
 		m.currentScreen = screenExpenses
 
-		return m, writeExcelCmd(m.expenses, m.stonks, m.watchList)
+		return m, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil)
+	case expenseDeletedMsg:
+		return m.expenseDeleted(msg)
+	case bulkMsg:
+		return m.applyBulk(msg)
+	case sortSavedMsg:
+		return m.sortSaved(msg)
+	case duplicatesMsg:
+		return m.duplicatesResolved(msg)
+	case ratesMsg:
+		m.applyRates(msg)
+		return m, nil
 	}
 
 	return m, nil
 }
 
 func (m *model) View() string {
+	done := metrics.track("render")
+	s := m.view()
+	done()
+	if m.debug {
+		s += "\n" + metrics.overlay() + "\n"
+	}
+	return s
+}
+
+func (m *model) view() string {
+	if m.conflict != nil {
+		return m.viewConflict()
+	}
+	if m.failure != nil {
+		return m.viewFailure()
+	}
 	switch m.currentScreen {
 	case screenMenu:
 		return m.viewMenu()
@@ -458,13 +1920,63 @@ func (m *model) View() string {
 		return m.viewStonks()
 	case screenWatchlist:
 		return m.viewWatchlist()
+	case screenSnapshots:
+		return m.viewSnapshots()
+	case screenDividends:
+		return m.viewDividends()
+	case screenBudgets:
+		return m.viewBudgets()
+	case screenReview:
+		return m.viewReview()
+	case screenQuery:
+		return m.viewQuery()
+	case screenProfiles:
+		return m.viewProfiles()
+	case screenAudit:
+		return m.viewAudit()
+	case screenStatement:
+		return m.viewStatement()
+	case screenCards:
+		return m.viewCards()
+	case screenWishlist:
+		return m.viewWishlist()
+	case screenGoals:
+		return m.viewGoals()
 	default:
 		return "Unknown screen"
 	}
 }
 
 func (m *model) viewMenu() string {
-	return m.list.View() + "\nPress q to quit.\n"
+	s := m.list.View() + "\n"
+	if pace := spendingPace(m.budgets, m.inBase(m.expenses), today()); pace != "" {
+		s += pace + "\n"
+	}
+	if returns := returnReminders(m.expenses, today()); returns != "" {
+		s += returns + "\n"
+	}
+	s += "Press q to quit. This is " + currentVersion().String() + ".\n"
+	if m.update != "" {
+		s += "tet " + m.update + " is out: see " + releasesPage + ".\n"
+	}
+	if cfg.profile != "" {
+		s += "Profile: " + cfg.profile + "\n"
+	}
+	if cfg.Offline {
+		s += offlineStyle.Render("Offline mode: nothing is fetched from the network.") + "\n"
+	}
+	if p := pretending(); p != "" {
+		s += offlineStyle.Render(p) + "\n"
+	}
+	return s + m.statusLine()
+}
+
+// statusLine renders the latest status message, if any.
+func (m *model) statusLine() string {
+	if m.status == "" {
+		return ""
+	}
+	return "\n" + m.status + "\n"
 }
 
 func (m *model) viewExpenses() string {
@@ -472,37 +1984,241 @@ func (m *model) viewExpenses() string {
 	buffer.WriteString("\n")
 	buffer.WriteString(editExpensesTitle.String())
 	buffer.WriteString("\n")
-	buffer.WriteString(m.expensesTable.String())
-
-	buffer.WriteString("\nUse ↑/↓ to move, 'e' to edit the selected row, 'n' to insert a new expense, 'q' to quit.\n")
+	buffer.WriteString(m.sheetErrorBanner(sheetExpenses))
+	buffer.WriteString(m.expensesTable)
+	buffer.WriteString("\n" + m.totals.String() + "\n")
+	buffer.WriteString(m.expensesNotes())
+
+	if m.searching {
+		buffer.WriteString(m.viewSearch())
+		buffer.WriteString(m.budgetWarning + m.statusLine())
+		return buffer.String()
+	}
+	if m.readOnly {
+		buffer.WriteString("\nRead-only session. Use ↑/↓, 'pgup'/'pgdn' and 'home'/'end' to move, 'enter' for details and payee history, 'a' to show/hide archived expenses, 'w' to filter by who entered them, 't' to filter by tag, 'm' to filter by month, '/' to search, 's' to sort by date, name or amount, 'S' to reverse the sort, 'g' to group by category, tag, payee, account, project, payment method or household member, 'v' to show/hide the spending by category, 'E' to export to CSV, 'b' to go back, 'q' to quit.\n")
+		buffer.WriteString(m.budgetWarning + m.statusLine())
+		return buffer.String()
+	}
+	if m.quickAdding {
+		buffer.WriteString(m.viewQuickAdd())
+		buffer.WriteString(m.budgetWarning + m.statusLine())
+		return buffer.String()
+	}
+	if m.pickingTemplate {
+		buffer.WriteString(m.viewTemplates())
+		buffer.WriteString(m.budgetWarning + m.statusLine())
+		return buffer.String()
+	}
+	buffer.WriteString("\nUse ↑/↓, 'pgup'/'pgdn' and 'home'/'end' to move, 'enter' for details and payee history, 'a' to show/hide archived expenses, 'w' to filter by who entered them, 't' to filter by tag, 'm' to filter by month, '/' to search, 's' to sort by date, name or amount, 'S' to reverse the sort, 'P' to save the sorted order, 'g' to group by category, tag, payee, account, project, payment method or household member, 'v' to show/hide the spending by category, 'e' to edit the selected row, 'd' to delete it, 'space' to mark rows for a bulk change, 'n' to insert a new expense, 'y' to copy the selected one to today, 'Y' to copy it into the new-expense form, '+' to type one on a line, 'T' to add one from a template, 'i' to import a CSV file or bank statement, 'E' to export to CSV, 'C' to close last month, 'q' to quit.\n")
 	buffer.WriteString("\nPress 'b' to go back.\n")
 	buffer.WriteString("\nPress 'e' to edit.\n")
 	buffer.WriteString("\nPress 'n' to insert new expense.\n")
+	buffer.WriteString(m.budgetWarning + m.statusLine())
 
 	return buffer.String()
 }
 
+// expensesNotes are the lines between the totals and the help of the
+// expenses screen: the filters in effect, the group totals, balances and
+// prompts, the budgets and, while it is open, the selected expense's
+// details. The table is shortened by as many lines as they take.
+func (m *model) expensesNotes() string {
+	var buffer bytes.Buffer
+	if m.enteredBy != "" {
+		buffer.WriteString("Showing expenses entered by " + m.enteredBy + ".\n")
+	}
+	if m.tagFilter != "" {
+		buffer.WriteString("Showing expenses tagged " + m.tagFilter + ".\n")
+	}
+	if !m.monthFilter.IsZero() {
+		buffer.WriteString("Showing expenses of " + periodName(m.monthFilter) + ".\n")
+	}
+	if m.search.active() && !m.searching {
+		buffer.WriteString(fmt.Sprintf("Showing expenses matching %q; press '/' to change the search.\n", m.search.text))
+	}
+	if len(m.marked) > 0 {
+		buffer.WriteString(fmt.Sprintf("%d expenses marked: 'd' deletes them, 'c' sets their category, 'M' moves them to another month, 'esc' unmarks them.\n", len(m.marked)))
+	}
+	if m.groupBy != dimensionNone {
+		buffer.WriteString(dimensionTotalsLine(m.inBase(m.visibleExpenses()), m.groupBy, m.icons))
+	}
+	if !m.hideCategories {
+		buffer.WriteString(m.categorySubtotals)
+	}
+	buffer.WriteString(m.ratesLine())
+	buffer.WriteString(settlementLine(m.inBase(m.expenses)))
+	buffer.WriteString(balancesLine(m.accounts, m.transfers, m.statementRows(), m.rates))
+	buffer.WriteString(cashLines(m.accounts, m.transfers, m.statementRows(), m.rates))
+	if n := len(pendingRows(m.expenses)); n > 0 {
+		buffer.WriteString(fmt.Sprintf("%d changes await approval on the Review screen; pending rows count towards no totals.\n", n))
+	}
+	buffer.WriteString(m.budgetProgress)
+	if m.showHistory {
+		buffer.WriteString("\n")
+		buffer.WriteString(m.history)
+	}
+	return buffer.String()
+}
+
 func (m *model) viewStonks() string {
 	s := "=== STONKS ===\n"
-	// ...
+	s += m.sheetErrorBanner(sheetStonks)
+	s += m.stonksTable
+	s += m.quotesLine()
+	s += "\nSet Quantity, Avg Price, Price and Open in the workbook to see each position's change.\n"
+	if m.showFX {
+		s += "\nReturns over each position's price history, hedged and in " + strings.ToUpper(cfg.Rounding.Currency) + ":\n"
+		s += m.fxReturns
+	}
+	s += "\nPress 'c' to switch between intraday, daily and total return, 'x' to split returns by currency, 'E' to export to CSV.\n"
 	s += "\nPress 'b' to go back.\n"
+	s += m.statusLine()
 	return s
 }
 
 func (m *model) viewWatchlist() string {
 	s := "=== WATCHLIST ===\n"
-	// ...
-	s += "\nPress 'b' to go back.\n"
+	s += m.sheetErrorBanner(sheetWatchList)
+	s += m.watchlistTable
+	s += m.quotesLine()
+	s += "\nTargets reached are highlighted; set them in the Target Buy and Target Sell columns of the workbook.\n"
+	s += "\nPress 'E' to export to CSV, 'b' to go back.\n"
+	s += m.statusLine()
 	return s
 }
 
+// sheetErrorBanner renders the load error of sheet, if it has one.
+func (m *model) sheetErrorBanner(sheet sheetMask) string {
+	err := m.sheetErrs[sheet]
+	if err == nil {
+		return ""
+	}
+	return errorBannerStyle.Render("⚠ "+err.Error()+" — showing no rows; other sheets are unaffected.") + "\n"
+}
+
+// visibleExpenses are the rows of the expenses table: the current
+// expenses, followed by the archived ones while they are shown, less any
+// filtered out by who entered them, by tag, by month or by a search.
+func (m *model) visibleExpenses() []Expense {
+	rows, _ := m.visibleRows()
+	return rows
+}
+
+// statementRows are the expenses account balances are worked out over:
+// the current ones, and the archived ones while they are shown.
+func (m *model) statementRows() []Expense {
+	if !m.showArchive {
+		return m.expenses
+	}
+	return append(slices.Clip(m.expenses), m.archived...)
+}
+
+// visibleRows returns the rows of the expenses table along with the index
+// of each in m.expenses, or -1 for archived rows, sorted as chosen with
+// 's' and grouped as chosen with 'g'.
+func (m *model) visibleRows() ([]Expense, []int) {
+	var rows []Expense
+	var index []int
+	shown := func(e Expense) bool {
+		return (m.enteredBy == "" || e.EnteredBy == m.enteredBy) && (m.tagFilter == "" || hasTag(e, m.tagFilter)) &&
+			(m.monthFilter.IsZero() || (!e.Date.IsZero() && monthOf(e.Date).Equal(m.monthFilter))) && m.search.matches(e)
+	}
+	for i, e := range m.expenses {
+		if shown(e) {
+			rows = append(rows, e)
+			index = append(index, i)
+		}
+	}
+	if m.showArchive {
+		for _, e := range m.archived {
+			if shown(e) {
+				rows = append(rows, e)
+				index = append(index, -1)
+			}
+		}
+	}
+	sortRows(rows, index, m.sort)
+	groupRows(rows, index, m.groupBy)
+	return rows, index
+}
+
+// updateExpensesTable rebuilds the expenses table after the expenses, the
+// budgets or the rows shown change, working out the totals and budgets
+// again.
 func (m *model) updateExpensesTable() {
-	headers := []string{"#", "Expense", "Amount"}
+	m.expensesDirty = false
+	expenses, index := m.visibleRows()
+	m.totals = totalsOf(m.inBase(expenses))
+	m.categorySubtotals = categorySubtotals(m.inBase(expenses), m.icons)
+	month := m.monthFilter
+	if month.IsZero() {
+		month = monthOf(today())
+	}
+	m.budgetProgress, m.budgetWarning = budgetProgress(m.budgets, m.inBase(m.expenses), month)
+	m.budgetsOver = overBudget(m.budgets, m.inBase(m.expenses), expenses)
+	m.drawExpensesTable(expenses, index)
+}
+
+// redrawExpensesTable draws the expenses table again for a new selection,
+// keeping the totals and budgets, which only change with the rows shown.
+func (m *model) redrawExpensesTable() {
+	expenses, index := m.visibleRows()
+	m.drawExpensesTable(expenses, index)
+}
+
+// drawExpensesTable lays out expenses, the rows shown, whose index into
+// m.expenses is index.
+func (m *model) drawExpensesTable(expenses []Expense, index []int) {
+	over := m.budgetsOver
+	headers := []string{"#", m.sort.header(sortDate, "Date"), m.sort.header(sortName, "Expense"), "Category", "Tags", m.sort.header(sortAmount, "Amount"), "Share", "By"}
+	// The balance reads like a bank statement only with every row listed.
+	var balances []runningBalance
+	if len(m.accounts) > 0 && m.enteredBy == "" && m.tagFilter == "" && m.monthFilter.IsZero() && !m.search.active() {
+		balances = runningBalances(m.accounts, m.transfers, expenses, m.rates)
+		headers = append(headers, "Balance")
+	}
+
+	// Shares are of the month's spending in the whole book, whatever the
+	// filters leave.
+	spent := monthSpending(m.inBase(m.statementRows()))
+	inBase := m.inBase(expenses)
 
 	var data [][]string
-	for i, e := range m.expenses {
+	for i, e := range expenses {
 		// i+1 is row number for display
-		row := []string{strconv.Itoa(i + 1), e.Name, fmt.Sprintf("%.2f", e.Amount)}
+		amount := expenseAmount(e)
+		if e.Kind == kindRefund {
+			amount += " ↩"
+		}
+		name := e.Name
+		if index[i] < 0 {
+			name += " (archived)"
+		} else if e.Pending != "" {
+			name += " (pending)"
+		}
+		if e.Note != "" {
+			name += noteMarker
+		}
+		category := e.Category
+		if category != "" {
+			category = withIcon(categoryIcon(m.icons, category), category)
+		}
+		number := strconv.Itoa(i + 1)
+		if index[i] >= 0 && m.marked[index[i]] {
+			number = "✓ " + number
+		}
+		share := ""
+		if s, ok := spendingShare(inBase[i], spent); ok {
+			share = formatShare(s)
+		}
+		row := []string{number, formatExpenseDate(e.Date), name, category, formatTags(e.Tags), amount, share, e.EnteredBy}
+		if balances != nil {
+			balance := ""
+			if b := balances[i]; b.account >= 0 {
+				balance = m.accounts[b.account].amount(b.balance) + " " + m.accounts[b.account].Name
+			}
+			row = append(row, balance)
+		}
 		data = append(data, row)
 	}
 
@@ -511,6 +2227,11 @@ func (m *model) updateExpensesTable() {
 	baseStyle := re.NewStyle().Padding(0, 1)
 	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
 	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	// Refunds stand out from ordinary spending and income.
+	refundStyle := baseStyle.Foreground(lipgloss.Color("42")).Italic(true)
+	archivedStyle := baseStyle.Foreground(lipgloss.Color("240"))
+	pendingStyle := baseStyle.Foreground(lipgloss.Color("214")).Italic(true)
+	markedStyle := baseStyle.Background(lipgloss.Color("238")).Foreground(lipgloss.Color("229"))
 
 	// Define a highlight style for the selected row
 	highlightStyle := baseStyle.
@@ -518,75 +2239,167 @@ func (m *model) updateExpensesTable() {
 		Foreground(lipgloss.Color("229")).
 		Bold(true)
 
-	// Build the table
-	t := ltable.New().
-		Border(lipgloss.NormalBorder()).
-		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
-		Headers(headers...).
-		Width(80).
-		Rows(data...).
-		StyleFunc(func(row, col int) lipgloss.Style {
+	// Build the table; #, Date and Expense stay in view when it is paged
+	// sideways.
+	t := windowedTable{
+		headers:  headers,
+		rows:     data,
+		frozen:   3,
+		selected: m.selectedRow,
+		fill:     80,
+		style: func(row, col int) lipgloss.Style {
 			if row == ltable.HeaderRow {
 				return headerStyle
 			}
 			if row == m.selectedRow {
 				return highlightStyle
 			}
-
-			if row%2 == 0 {
-				return rowStyle.Foreground(lipgloss.Color("245"))
+			style := rowStyle
+			switch {
+			case index[row] >= 0 && m.marked[index[row]]:
+				style = markedStyle
+			case index[row] < 0:
+				style = archivedStyle
+			case expenses[row].Pending != "":
+				style = pendingStyle
+			case expenses[row].Kind == kindRefund:
+				style = refundStyle
+			case row%2 == 0:
+				style = rowStyle.Foreground(lipgloss.Color("245"))
 			}
-			return rowStyle
-		})
+			// Categories over budget in the row's month are in red.
+			if e := expenses[row]; col == 3 && !e.Date.IsZero() && overIn(over, categoryOf(e), monthOf(e.Date)) {
+				style = style.Foreground(lipgloss.Color("203"))
+			}
+			// The rules of the config file go over the table's own colours.
+			return cfg.RowRules.style(style, expenses[row])
+		},
+	}
 
-	m.expensesTable = t
+	m.history = ""
+	if m.showHistory && m.selectedRow < len(expenses) {
+		m.history = expenseDetail(expenses[m.selectedRow]) + "\n" + historyFor(expenses, m.selectedRow).render(expenses, m.selectedRow)
+	}
+	// However many lines the notes below the table take, the header row
+	// stays on screen.
+	chrome := expensesChrome + screenLines(m.expensesNotes()+m.budgetWarning, m.width)
+	if m.searching {
+		chrome++
+	}
+	m.expensesTable = m.expensesWindow.render(t, m.width, m.tableHeight(chrome))
 }
 
 func (m *model) editExpenseForm(index int) tea.Cmd {
 	var newName string = m.expenses[index].Name
-	var newAmount string = fmt.Sprintf("%.2f", m.expenses[index].Amount)
+	var newAmount string = money(m.expenses[index].Amount)
+	var refund bool = m.expenses[index].Kind == kindRefund
+	var newDate string = formatExpenseDate(m.expenses[index].Date)
+	var category string = m.expenses[index].Category
+	var tags string = formatTags(m.expenses[index].Tags)
+	var currency string = m.expenses[index].Currency
+	var note string = m.expenses[index].Note
+	var method paymentMethod = m.expenses[index].Method
+	var member string = m.expenses[index].Member
+	var goods bool = isGoods(m.expenses[index])
+	var returnBy string = formatExpenseDate(m.expenses[index].ReturnBy)
+	var warranty string = formatWarranty(m.expenses[index].WarrantyMonths)
+	var usage string = formatUsage(m.expenses[index].Usage, m.expenses[index].UsageUnit)
+	// The form edits the name, amount, currency, date, category, tags,
+	// method, member, usage, note, type, return date and warranty; the
+	// rest is kept.
+	original := m.expenses[index]
+	goodsConfirm, goodsGroup := goodsFields(&goods, &returnBy, &warranty)
 
 	form := huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().Title("Expense Name").Value(&newName),
 			huh.NewInput().Title("Amount").Value(&newAmount),
+			huh.NewInput().Title("Currency (blank for "+strings.ToUpper(cfg.Rounding.Currency)+")").Value(&currency).Validate(validateCurrency),
+			huh.NewInput().Title("Date (YYYY-MM-DD, blank if unknown)").Value(&newDate).Validate(validateDate),
+			huh.NewSelect[string]().Title("Category").Options(categoryChoices(category, m.expenses, m.budgets)...).Value(&category),
+			huh.NewInput().Title("Tags (comma-separated)").Value(&tags),
+			huh.NewSelect[paymentMethod]().Title("Paid by").Options(methodOptions()...).Value(&method),
+			huh.NewSelect[string]().Title("For").Options(memberOptions(member)...).Value(&member),
+			huh.NewInput().Title("Usage (optional, such as 320 kWh or 12 m3)").Value(&usage).Validate(validateUsage),
+			huh.NewText().Title("Note (optional)").Value(&note),
+			huh.NewConfirm().Title("Refund or reimbursement?").Value(&refund),
+			goodsConfirm,
 		),
+		goodsGroup,
 	)
 
 	return func() tea.Msg {
 		if err := form.Run(); err != nil {
 			return errMsg{err}
 		}
-		amt, err := strconv.ParseFloat(newAmount, 64)
+		amt, err := cfg.Rounding.parse(newAmount)
 		if err != nil {
 			return errMsg{err}
 		}
-		updated := Expense{Name: newName, Amount: amt}
+		updated := Expense{Name: newName, Amount: amt, Date: parseExpenseDate(newDate), EnteredBy: original.EnteredBy,
+			Category: category, Tags: parseTags(tags), Payment: original.Payment, Method: method, Member: member, Currency: baseOrCurrency(currency), Note: cleanNote(note)}
+		if refund {
+			updated.Kind = kindRefund
+		}
+		updated = withGoods(updated, goods, returnBy, warranty)
+		updated.Usage, updated.UsageUnit = parseUsage(usage)
 
 		return expenseEditedMsg{index: index, expense: updated}
 	}
 }
 
-func (m *model) newExpenseForm() tea.Cmd {
-	var newName string = ""
-	var newAmount string = "0.00"
+// newExpenseForm asks for an expense to add, filled in from from: blank
+// but for today's date, or a copy of another expense. Its payment is kept.
+func (m *model) newExpenseForm(from Expense) tea.Cmd {
+	var newName string = from.Name
+	var newAmount string = money(from.Amount)
+	var refund bool = from.Kind == kindRefund
+	var newDate string = formatExpenseDate(from.Date)
+	var category string = from.Category
+	var tags string = formatTags(from.Tags)
+	var currency string = from.Currency
+	var note string = from.Note
+	var method paymentMethod = from.Method
+	var member string = from.Member
+	var goods bool = isGoods(from)
+	var returnBy string = formatExpenseDate(from.ReturnBy)
+	var warranty string = formatWarranty(from.WarrantyMonths)
+	var usage string = formatUsage(from.Usage, from.UsageUnit)
+	goodsConfirm, goodsGroup := goodsFields(&goods, &returnBy, &warranty)
 
 	form := huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().Title("Expense Name").Value(&newName),
 			huh.NewInput().Title("Amount").Value(&newAmount),
+			huh.NewInput().Title("Currency (blank for "+strings.ToUpper(cfg.Rounding.Currency)+")").Value(&currency).Validate(validateCurrency),
+			huh.NewInput().Title("Date (YYYY-MM-DD, blank if unknown)").Value(&newDate).Validate(validateDate),
+			huh.NewSelect[string]().Title("Category").Options(categoryChoices(category, m.expenses, m.budgets)...).Value(&category),
+			huh.NewInput().Title("Tags (comma-separated)").Value(&tags),
+			huh.NewSelect[paymentMethod]().Title("Paid by").Options(methodOptions()...).Value(&method),
+			huh.NewSelect[string]().Title("For").Options(memberOptions(member)...).Value(&member),
+			huh.NewInput().Title("Usage (optional, such as 320 kWh or 12 m3)").Value(&usage).Validate(validateUsage),
+			huh.NewText().Title("Note (optional)").Value(&note),
+			huh.NewConfirm().Title("Refund or reimbursement?").Value(&refund),
+			goodsConfirm,
 		),
+		goodsGroup,
 	)
 
 	return func() tea.Msg {
 		if err := form.Run(); err != nil {
 			return errMsg{err}
 		}
-		amt, err := strconv.ParseFloat(newAmount, 64)
+		amt, err := cfg.Rounding.parse(newAmount)
 		if err != nil {
 			return errMsg{err}
 		}
-		updated := Expense{Name: newName, Amount: amt}
+		updated := Expense{Name: newName, Amount: amt, Date: parseExpenseDate(newDate), EnteredBy: currentUser(), Category: category, Tags: parseTags(tags),
+			Payment: from.Payment, Method: method, Member: member, Currency: baseOrCurrency(currency), Note: cleanNote(note)}
+		if refund {
+			updated.Kind = kindRefund
+		}
+		updated = withGoods(updated, goods, returnBy, warranty)
+		updated.Usage, updated.UsageUnit = parseUsage(usage)
 		return expenseEditedMsg{index: -1, expense: updated}
 	}
 }
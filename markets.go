@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // exchange time zones on systems without a zoneinfo database
+)
+
+// exchangeHours is when an exchange trades, Monday to Friday, in its own
+// time zone. Holidays are not known; quotes are fetched on them and simply
+// do not change.
+type exchangeHours struct {
+	Timezone string `json:"timezone"`
+	// Open and Close are HH:MM local times.
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// defaultExchanges are the built-in trading hours; the config file can
+// override them or add more.
+var defaultExchanges = map[string]exchangeHours{
+	"NYSE":   {"America/New_York", "09:30", "16:00"},
+	"NASDAQ": {"America/New_York", "09:30", "16:00"},
+	"XETRA":  {"Europe/Berlin", "09:00", "17:30"},
+	"LSE":    {"Europe/London", "08:00", "16:30"},
+	"TSE":    {"Asia/Tokyo", "09:00", "15:00"},
+}
+
+// symbolSuffixes map Yahoo-style ticker suffixes to exchanges.
+var symbolSuffixes = map[string]string{
+	".DE": "XETRA",
+	".L":  "LSE",
+	".T":  "TSE",
+}
+
+func (h exchangeHours) validate() error {
+	if _, err := time.LoadLocation(h.Timezone); err != nil {
+		return err
+	}
+	for _, hm := range []string{h.Open, h.Close} {
+		if _, err := time.Parse("15:04", hm); err != nil {
+			return fmt.Errorf("trading hours must be HH:MM: %w", err)
+		}
+	}
+	return nil
+}
+
+// openAt reports whether the exchange is trading at t.
+func (h exchangeHours) openAt(t time.Time) bool {
+	loc, err := time.LoadLocation(h.Timezone)
+	if err != nil {
+		return true
+	}
+	local := t.In(loc)
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return false
+	}
+	now := local.Hour()*60 + local.Minute()
+	return now >= minuteOfDay(h.Open) && now < minuteOfDay(h.Close)
+}
+
+// minuteOfDay reads a validated HH:MM time.
+func minuteOfDay(hm string) int {
+	t, _ := time.Parse("15:04", hm)
+	return t.Hour()*60 + t.Minute()
+}
+
+// exchangeFor names the exchange symbol trades on: configured per symbol,
+// else by its suffix, else the default exchange.
+func (q quoteConfig) exchangeFor(symbol string) string {
+	key := symbolKey(symbol)
+	for s, ex := range q.Symbols {
+		if symbolKey(s) == key {
+			return strings.ToUpper(ex)
+		}
+	}
+	for suffix, ex := range symbolSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return ex
+		}
+	}
+	if q.DefaultExchange != "" {
+		return strings.ToUpper(q.DefaultExchange)
+	}
+	return "NYSE"
+}
+
+func (q quoteConfig) hours(exchange string) (exchangeHours, bool) {
+	for name, h := range q.Exchanges {
+		if strings.EqualFold(name, exchange) {
+			return h, true
+		}
+	}
+	h, ok := defaultExchanges[exchange]
+	return h, ok
+}
+
+// marketOpen reports whether symbol's exchange is trading at t. Symbols on
+// an exchange without known hours are always refreshed.
+func (q quoteConfig) marketOpen(symbol string, t time.Time) bool {
+	h, ok := q.hours(q.exchangeFor(symbol))
+	return !ok || h.openAt(t)
+}
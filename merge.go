@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+)
+
+type resolution int
+
+const (
+	keepMine resolution = iota
+	takeTheirs
+	keepBoth
+)
+
+func (r resolution) String() string {
+	switch r {
+	case takeTheirs:
+		return "take theirs"
+	case keepBoth:
+		return "keep both"
+	default:
+		return "keep mine"
+	}
+}
+
+// mergeConflict is a row present in both workbooks under the same key but
+// with different contents.
+type mergeConflict struct {
+	sheet        string
+	key          string
+	mine, theirs string
+	resolution   resolution
+	// allowBoth is set for sheets where duplicate keys are legitimate.
+	allowBoth bool
+	apply     func(resolution)
+}
+
+type mergeStats struct {
+	added, duplicates int
+}
+
+// mergeRows appends rows from theirs that mine lacks into merged. Rows are
+// paired as in pairRows, so repeated expenses like a monthly "Gym" line up
+// one to one. Identical rows are skipped and differing ones become
+// conflicts, resolved later by calling their apply func.
+func mergeRows[T any](sheet string, merged *[]T, theirs []T, key func(T) string, render func(T) string, allowBoth bool) (mergeStats, []*mergeConflict) {
+	pairs, _, onlyTheirs := pairRows(*merged, theirs, key)
+
+	var stats mergeStats
+	var conflicts []*mergeConflict
+	for _, p := range pairs {
+		i, row := p[0], theirs[p[1]]
+		mine := (*merged)[i]
+		if reflect.DeepEqual(mine, row) {
+			stats.duplicates++
+			continue
+		}
+		conflicts = append(conflicts, &mergeConflict{
+			sheet:     sheet,
+			key:       key(mine),
+			mine:      render(mine),
+			theirs:    render(row),
+			allowBoth: allowBoth,
+			apply: func(r resolution) {
+				switch r {
+				case takeTheirs:
+					(*merged)[i] = row
+				case keepBoth:
+					*merged = append(*merged, row)
+				}
+			},
+		})
+	}
+	for _, j := range onlyTheirs {
+		*merged = append(*merged, theirs[j])
+		stats.added++
+	}
+	return stats, conflicts
+}
+
+// mergeDatasets merges theirs into mine, printing per-sheet statistics, and
+// returns the merged dataset along with the conflicts still to resolve.
+func mergeDatasets(mine, theirs Dataset) (*Dataset, []*mergeConflict) {
+	merged := &Dataset{
+		Expenses:  append([]Expense(nil), mine.Expenses...),
+		Stonks:    append([]Stonk(nil), mine.Stonks...),
+		WatchList: append([]WatchItem(nil), mine.WatchList...),
+		Revision:  mine.Revision,
+	}
+
+	var conflicts []*mergeConflict
+	report := func(sheet string, stats mergeStats, c []*mergeConflict) {
+		fmt.Printf("  %-10s %3d added, %3d duplicates skipped, %3d conflicts\n",
+			sheet, stats.added, stats.duplicates, len(c))
+		conflicts = append(conflicts, c...)
+	}
+
+	stats, c := mergeRows("Expenses", &merged.Expenses, theirs.Expenses, expenseKey, expenseSummary, true)
+	report("Expenses", stats, c)
+	stats, c = mergeRows("Stonks", &merged.Stonks, theirs.Stonks, stonkKey, stonkSummary, false)
+	report("Stonks", stats, c)
+	stats, c = mergeRows("WatchList", &merged.WatchList, theirs.WatchList, watchKey, watchSummary, false)
+	report("WatchList", stats, c)
+
+	return merged, conflicts
+}
+
+// storeForPath picks the backend from the file extension, or the prefix of
+// paths that name no file.
+func storeForPath(path string) (Store, error) {
+	if isMemoryPath(path) {
+		return openStore("memory", path)
+	}
+	if isSheetsPath(path) {
+		return openStore("gsheets", path)
+	}
+	backend := strings.TrimPrefix(filepath.Ext(path), ".")
+	if _, ok := backends[backend]; !ok {
+		backend = "xlsx"
+	}
+	return openStore(backend, path)
+}
+
+// readOnlyStoreForPath is storeForPath for files that are only read, such
+// as the other side of a merge or a snapshot, which must not be upgraded
+// in place.
+func readOnlyStoreForPath(path string) (Store, error) {
+	s, err := storeForPath(path)
+	if xs, ok := s.(*excelStore); ok {
+		xs.readOnly = true
+	}
+	return s, err
+}
+
+func runMerge(args []string) error {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "workbook to merge into")
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tet merge [flags] other.xlsx")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("merge: expected exactly one workbook to merge from")
+	}
+	other := flags.Arg(0)
+
+	dest, err := storeForPath(*file)
+	if err != nil {
+		return err
+	}
+	source, err := readOnlyStoreForPath(other)
+	if err != nil {
+		return err
+	}
+	mine, err := dest.Load()
+	if err != nil {
+		return fmt.Errorf("merge: reading %s: %w", *file, err)
+	}
+	theirs, err := source.Load()
+	if err != nil {
+		return fmt.Errorf("merge: reading %s: %w", other, err)
+	}
+
+	fmt.Printf("Merging %s into %s\n", other, *file)
+	merged, conflicts := mergeDatasets(mine, theirs)
+	if *dryRun {
+		return nil
+	}
+
+	if len(conflicts) > 0 {
+		review := &mergeReview{conflicts: conflicts}
+		if _, err := tea.NewProgram(review).Run(); err != nil {
+			return err
+		}
+		if !review.confirmed {
+			fmt.Println("Merge aborted, nothing written.")
+			return nil
+		}
+		for _, c := range conflicts {
+			c.apply(c.resolution)
+		}
+	}
+
+	if err := dest.Save(*merged); err != nil {
+		return fmt.Errorf("merge: writing %s: %w", *file, err)
+	}
+	fmt.Printf("Wrote %s\n", *file)
+	return nil
+}
+
+// mergeReview is the conflict review screen shown before a merge is
+// written.
+type mergeReview struct {
+	conflicts []*mergeConflict
+	selected  int
+	confirmed bool
+}
+
+func (r *mergeReview) Init() tea.Cmd { return nil }
+
+func (r *mergeReview) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return r, nil
+	}
+	current := r.conflicts[r.selected]
+	switch key.String() {
+	case "q", "esc", "ctrl+c":
+		return r, tea.Quit
+	case "up":
+		if r.selected > 0 {
+			r.selected--
+		}
+	case "down":
+		if r.selected < len(r.conflicts)-1 {
+			r.selected++
+		}
+	case "m":
+		current.resolution = keepMine
+	case "t":
+		current.resolution = takeTheirs
+	case "b":
+		if current.allowBoth {
+			current.resolution = keepBoth
+		}
+	case "w", "enter":
+		r.confirmed = true
+		return r, tea.Quit
+	}
+	return r, nil
+}
+
+func (r *mergeReview) View() string {
+	headers := []string{"Sheet", "Key", "Mine", "Theirs", "Resolution"}
+	var rows [][]string
+	for _, c := range r.conflicts {
+		rows = append(rows, []string{c.sheet, c.key, c.mine, c.theirs, c.resolution.String()})
+	}
+
+	baseStyle := lipgloss.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	highlightStyle := baseStyle.
+		Background(lipgloss.Color("57")).
+		Foreground(lipgloss.Color("229")).
+		Bold(true)
+
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(rows...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			if row == r.selected {
+				return highlightStyle
+			}
+			return baseStyle
+		})
+
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString(screenTitleStyle.Render(fmt.Sprintf("Merge conflicts (%d)", len(r.conflicts))))
+	b.WriteString("\n")
+	b.WriteString(t.String())
+	b.WriteString("\nUse ↑/↓ to move, 'm' keep mine, 't' take theirs, 'b' keep both (expenses only).\n")
+	b.WriteString("Press 'w' or enter to write the merge, 'q' to abort.\n")
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/huh"
+)
+
+// paymentMethod is how an expense was paid, kept in the Method column next
+// to the account or card of its Payment column, so spending can be
+// reconciled against card statements method by method.
+type paymentMethod string
+
+const (
+	methodUnknown paymentMethod = ""
+	methodCash    paymentMethod = "Cash"
+	methodCard    paymentMethod = "Card"
+	// methodAccount is money paid straight from an account, by transfer or
+	// direct debit.
+	methodAccount paymentMethod = "Account"
+)
+
+var paymentMethods = []paymentMethod{methodCash, methodCard, methodAccount}
+
+// parsePaymentMethod reads the Method column, accepting the methods and
+// common words for them, such as "credit card" or "transfer", in any case.
+// Anything else is unknown.
+func parsePaymentMethod(s string) paymentMethod {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "cash":
+		return methodCash
+	case "card", "credit card", "debit card", "credit", "debit":
+		return methodCard
+	case "account", "bank", "transfer", "bank transfer", "direct debit":
+		return methodAccount
+	default:
+		return methodUnknown
+	}
+}
+
+// methodOptions are the choices of the Method select of the expense forms.
+func methodOptions() []huh.Option[paymentMethod] {
+	options := []huh.Option[paymentMethod]{huh.NewOption("Not set", methodUnknown)}
+	for _, method := range paymentMethods {
+		options = append(options, huh.NewOption(string(method), method))
+	}
+	return options
+}
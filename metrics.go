@@ -0,0 +1,121 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// timing accumulates the durations of one kind of operation.
+type timing struct {
+	count     int
+	last, max time.Duration
+	total     time.Duration
+}
+
+func (t *timing) avg() time.Duration {
+	if t.count == 0 {
+		return 0
+	}
+	return t.total / time.Duration(t.count)
+}
+
+// metricSet records how long loads, saves and renders take, for the debug
+// overlay and /debug/vars.
+type metricSet struct {
+	mu      sync.Mutex
+	timings map[string]*timing
+}
+
+var metrics = &metricSet{timings: make(map[string]*timing)}
+
+// track starts timing an operation; call the returned func when it ends:
+//
+//	defer metrics.track("load")()
+func (s *metricSet) track(name string) func() {
+	start := time.Now()
+	return func() { s.observe(name, time.Since(start)) }
+}
+
+func (s *metricSet) observe(name string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.timings[name]
+	if !ok {
+		t = &timing{}
+		s.timings[name] = t
+	}
+	t.count++
+	t.last = d
+	t.total += d
+	t.max = max(t.max, d)
+}
+
+// snapshot returns a copy of the timings, safe to read without the lock.
+func (s *metricSet) snapshot() map[string]timing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]timing, len(s.timings))
+	for name, t := range s.timings {
+		out[name] = *t
+	}
+	return out
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d.Microseconds())/1000)
+}
+
+var debugOverlayStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("238")).
+	Padding(0, 1).
+	MarginLeft(1)
+
+// overlay renders the timings as the debug overlay box.
+func (s *metricSet) overlay() string {
+	snap := s.snapshot()
+	names := make([]string, 0, len(snap))
+	for name := range snap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%-7s %6s %9s %9s %9s", "op", "count", "last", "avg", "max"))
+	for _, name := range names {
+		t := snap[name]
+		b.WriteString(fmt.Sprintf("\n%-7s %6d %9s %9s %9s", name, t.count, ms(t.last), ms(t.avg()), ms(t.max)))
+	}
+	return debugOverlayStyle.Render(b.String())
+}
+
+// startPprof serves net/http/pprof and the timings under /debug/vars on
+// addr in the background.
+func startPprof(addr string) {
+	expvar.Publish("timings", expvar.Func(func() any {
+		out := make(map[string]map[string]float64)
+		for name, t := range metrics.snapshot() {
+			out[name] = map[string]float64{
+				"count":   float64(t.count),
+				"last_ms": float64(t.last.Microseconds()) / 1000,
+				"avg_ms":  float64(t.avg().Microseconds()) / 1000,
+				"max_ms":  float64(t.max.Microseconds()) / 1000,
+			}
+		}
+		return out
+	}))
+	go func() {
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			log.Printf("pprof server: %v", err)
+		}
+	}()
+}
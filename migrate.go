@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// runMigrate copies the expenses, positions and watchlist, and a
+// workbook's other sheets, from one backend to another and verifies that
+// the destination holds the same number of rows afterwards.
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := flags.String("from", "xlsx", "source backend ("+backendNames()+")")
+	to := flags.String("to", "", "destination backend ("+backendNames()+")")
+	src := flags.String("src", "", "source path (defaults to the backend's default file)")
+	dst := flags.String("dst", "", "destination path (defaults to the backend's default file)")
+	encrypt := flags.Bool("encrypt", false, "encrypt the destination at rest (json backend only)")
+	flags.Parse(args)
+
+	if *to == "" {
+		return errors.New("migrate: --to is required")
+	}
+	if *src == "" {
+		*src = defaultPaths[*from]
+	}
+	if *dst == "" {
+		*dst = defaultPaths[*to]
+	}
+	if *src == *dst {
+		return fmt.Errorf("migrate: source and destination are both %s", *src)
+	}
+	if err := checkMissing(*dst); err != nil {
+		return err
+	}
+
+	source, err := openStore(*from, *src)
+	if err != nil {
+		return err
+	}
+	dest, err := openStore(*to, *dst)
+	if err != nil {
+		return err
+	}
+	if *encrypt {
+		enc, ok := dest.(encryptable)
+		if !ok {
+			return fmt.Errorf("migrate: the %s backend is not encrypted at rest: only JSON files are", *to)
+		}
+		enc.setEncrypted(true)
+	}
+
+	data, err := source.Load()
+	if err != nil {
+		return fmt.Errorf("migrate: reading %s: %w", *src, err)
+	}
+	// The destination is new, so its history starts over.
+	data.Revision = 0
+	if err := dest.Save(data); err != nil {
+		return fmt.Errorf("migrate: writing %s: %w", *dst, err)
+	}
+	copied, err := dest.Load()
+	if err != nil {
+		return fmt.Errorf("migrate: verifying %s: %w", *dst, err)
+	}
+
+	fmt.Printf("Migrated %s (%s) -> %s (%s)\n", *src, *from, *dst, *to)
+	return verifyCounts(data, copied)
+}
+
+// checkMissing refuses to migrate over existing data.
+func checkMissing(path string) error {
+	_, err := os.Stat(path)
+	if err == nil {
+		return fmt.Errorf("migrate: %s already exists, refusing to overwrite it", path)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// verifyCounts prints the per-sheet row counts of both datasets, the other
+// sheets' header rows included, and fails if any of them differ.
+func verifyCounts(want, got Dataset) error {
+	type count struct {
+		sheet     string
+		want, got int
+	}
+	counts := []count{
+		{"Expenses", len(want.Expenses), len(got.Expenses)},
+		{"Stonks", len(want.Stonks), len(got.Stonks)},
+		{"WatchList", len(want.WatchList), len(got.WatchList)},
+	}
+	for _, sheet := range sortedSheets(want.Sheets) {
+		counts = append(counts, count{sheet, len(want.Sheets[sheet]), len(got.Sheets[sheet])})
+	}
+
+	var mismatched bool
+	for _, c := range counts {
+		status := "ok"
+		if c.want != c.got {
+			status = "MISMATCH"
+			mismatched = true
+		}
+		fmt.Printf("  %-16s %5d -> %5d  %s\n", c.sheet, c.want, c.got, status)
+	}
+	if mismatched {
+		return errors.New("migrate: row counts differ between source and destination")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// addSheetRow writes a header and a row to sheet in the workbook at path,
+// adding the sheet if it has none.
+func addSheetRow(t *testing.T, path, sheet string) {
+	t.Helper()
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if idx, _ := f.GetSheetIndex(sheet); idx == -1 {
+		if _, err := f.NewSheet(sheet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for cell, value := range map[string]string{"A1": "Name", "A2": "kept"} {
+		if err := f.SetCellValue(sheet, cell, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateCopiesWorkbook(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "data.xlsx")
+	if err := generateWorkbook(src, fixtureOptions{rows: 40, seed: 3, months: 2}); err != nil {
+		t.Fatal(err)
+	}
+	addSheetRow(t, src, quotesSheet)
+	if err := runMigrate([]string{"--to", "json", "--src", src, "--dst", filepath.Join(dir, "data.json")}); err != nil {
+		t.Fatalf("migrating a workbook with only its expenses, positions and watchlist: %v", err)
+	}
+}
+
+// TestMigrateCarriesOtherSheets moves a workbook into SQLite and back and
+// expects its other sheets, the tracker's own and any added later, to
+// arrive as they were.
+func TestMigrateCarriesOtherSheets(t *testing.T) {
+	dir := t.TempDir()
+	src, db, back := filepath.Join(dir, "data.xlsx"), filepath.Join(dir, "data.sqlite"), filepath.Join(dir, "back.xlsx")
+	if err := generateWorkbook(src, fixtureOptions{rows: 40, seed: 3, months: 2}); err != nil {
+		t.Fatal(err)
+	}
+	addSheetRow(t, src, budgetsSheet)
+	addSheetRow(t, src, "Sheet Added Later")
+	if err := runMigrate([]string{"--to", "sqlite", "--src", src, "--dst", db}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigrate([]string{"--from", "sqlite", "--to", "xlsx", "--src", db, "--dst", back}); err != nil {
+		t.Fatal(err)
+	}
+	want, err := readOtherSheets(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readOtherSheets(back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sheet := range []string{budgetsSheet, "Sheet Added Later"} {
+		if !reflect.DeepEqual(got[sheet], want[sheet]) {
+			t.Errorf("%s arrived as\n%q\nwant\n%q", sheet, got[sheet], want[sheet])
+		}
+	}
+}
+
+func TestVerifyCountsChecksOtherSheets(t *testing.T) {
+	want := Dataset{Sheets: map[string][][]string{budgetsSheet: {{"Category", "Budget"}, {"Coffee", "40"}}}}
+	if err := verifyCounts(want, Dataset{}); err == nil {
+		t.Error("a destination without the Budgets rows passed the verification")
+	}
+	if err := verifyCounts(want, cloneDataset(want)); err != nil {
+		t.Errorf("a destination with every row failed the verification: %v", err)
+	}
+}
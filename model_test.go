@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestModel starts the TUI on a fresh memory store holding expenses, with
+// the Expenses screen open.
+func newTestModel(t *testing.T, expenses ...Expense) (*model, Store) {
+	t.Helper()
+	path := memoryPath + t.Name()
+	memoryStoresMu.Lock()
+	delete(memoryStores, path)
+	memoryStoresMu.Unlock()
+	s, err := openStore("memory", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(Dataset{Expenses: expenses}); err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel(path)
+	m.Update(tea.WindowSizeMsg{Width: 140, Height: 50})
+	press(m, "enter")
+	if m.currentScreen != screenExpenses {
+		t.Fatalf("enter on the menu opened screen %d, want the Expenses screen", m.currentScreen)
+	}
+	return m, s
+}
+
+// keyMsg is the message Bubble Tea sends for key, named as msg.String()
+// names it.
+func keyMsg(key string) tea.KeyMsg {
+	switch key {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "up":
+		return tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	case " ":
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+// press sends keys to m one after the other, returning the command of the
+// last. Commands are not run: they may open forms on the terminal.
+func press(m *model, keys ...string) tea.Cmd {
+	var cmd tea.Cmd
+	for _, key := range keys {
+		_, cmd = m.Update(keyMsg(key))
+	}
+	return cmd
+}
+
+// stored waits for the queued saves and returns the expenses in s.
+func stored(t *testing.T, s Store) []Expense {
+	t.Helper()
+	if err := saves.flush(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data.Expenses
+}
+
+var testExpenses = []Expense{
+	{Name: "Rent", Amount: -800, Date: parseExpenseDate("2026-09-01"), Category: "Housing"},
+	{Name: "Coffee", Amount: -3.5, Date: parseExpenseDate("2026-09-02"), Category: "Coffee"},
+}
+
+func TestModelCopiesSelectedExpense(t *testing.T) {
+	m, s := newTestModel(t, testExpenses...)
+	press(m, "down")
+	selected := m.visibleExpenses()[m.selectedRow]
+
+	cmd := press(m, "y")
+	if cmd == nil {
+		t.Fatal("y returned no command")
+	}
+	m.Update(cmd())
+
+	got := stored(t, s)
+	if len(got) != len(testExpenses)+1 {
+		t.Fatalf("the store has %d expenses after copying, want %d", len(got), len(testExpenses)+1)
+	}
+	copied := got[len(got)-1]
+	if copied.Name != selected.Name || copied.Amount != selected.Amount || !copied.Date.Equal(today()) {
+		t.Errorf("copied %s, want %s dated today", expenseSummary(copied), expenseSummary(selected))
+	}
+	if m.editing {
+		t.Error("the model is still editing after the copy was saved")
+	}
+}
+
+func TestModelDeletesConfirmedExpense(t *testing.T) {
+	m, s := newTestModel(t, testExpenses...)
+	selected := m.visibleExpenses()[m.selectedRow]
+	if cmd := press(m, "d"); cmd == nil || !m.editing {
+		t.Fatal("d did not ask to confirm the deletion")
+	}
+
+	// Keeping it changes nothing.
+	m.Update(expenseDeletedMsg{index: -1, expense: selected})
+	if got := stored(t, s); len(got) != len(testExpenses) {
+		t.Fatalf("the store has %d expenses after keeping one, want %d", len(got), len(testExpenses))
+	}
+
+	press(m, "d")
+	i := slicesIndexExpense(m.expenses, selected)
+	m.Update(expenseDeletedMsg{index: i, expense: selected})
+	got := stored(t, s)
+	if len(got) != len(testExpenses)-1 {
+		t.Fatalf("the store has %d expenses after deleting one, want %d", len(got), len(testExpenses)-1)
+	}
+	if slicesIndexExpense(got, selected) >= 0 {
+		t.Errorf("%s is still stored after deleting it", expenseSummary(selected))
+	}
+}
+
+func slicesIndexExpense(expenses []Expense, e Expense) int {
+	for i, x := range expenses {
+		if expenseSummary(x) == expenseSummary(e) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestModelReadOnlyChangesNothing(t *testing.T) {
+	m, s := newTestModel(t, testExpenses...)
+	m.readOnly = true
+	for _, key := range []string{"y", "Y", "d", "x", "e", "n", " ", "M", "c", "+", "T", "i", "C", "P", "W"} {
+		if cmd := press(m, key); cmd != nil {
+			if msg := cmd(); msg != nil {
+				m.Update(msg)
+			}
+		}
+		if m.editing || m.quickAdding || m.pickingTemplate || len(m.marked) > 0 {
+			t.Errorf("%q started a change in a read-only session", key)
+			m.editing, m.quickAdding, m.pickingTemplate, m.marked = false, false, false, nil
+		}
+	}
+	if got := stored(t, s); len(got) != len(testExpenses) {
+		t.Errorf("the store has %d expenses after a read-only session, want %d", len(got), len(testExpenses))
+	}
+}
+
+func TestModelReloadsAfterSave(t *testing.T) {
+	m, s := newTestModel(t, testExpenses...)
+	// Another session saves to the same store.
+	data, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Expenses = append(data.Expenses, Expense{Name: "Books", Amount: -20, Date: parseExpenseDate("2026-09-03")})
+	if err := s.Save(data); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Update(loadSheetsCmd(m.path, sheetExpenses)())
+	if len(m.expenses) != len(data.Expenses) {
+		t.Fatalf("the model has %d expenses after reloading, want %d", len(m.expenses), len(data.Expenses))
+	}
+	press(m, "down", "down", "down")
+	if m.selectedRow != len(data.Expenses)-1 {
+		t.Errorf("the selection stopped at row %d, want the last, %d", m.selectedRow, len(data.Expenses)-1)
+	}
+}
+
+func TestModelCursorKeepsBudgetStatus(t *testing.T) {
+	useClock(t, time.Date(2026, 9, 20, 12, 0, 0, 0, time.Local))
+	m, _ := newTestModel(t, testExpenses...)
+	m.budgets = []budget{{Category: "Coffee", Limit: 2}}
+	m.updateExpensesTable()
+	if m.budgetWarning == "" || !overIn(m.budgetsOver, categoryKey("Coffee"), monthOf(today())) {
+		t.Fatalf("Coffee is not over budget: %q", m.budgetWarning)
+	}
+
+	// Moving the selection draws the table again without working out the
+	// budgets: ones changed behind its back are not seen until the data is.
+	m.budgets = nil
+	press(m, "down", "up", "end", "home")
+	if m.budgetWarning == "" || m.budgetsOver == nil {
+		t.Error("moving the selection worked out the budgets again")
+	}
+	m.updateExpensesTable()
+	if m.budgetWarning != "" {
+		t.Errorf("the budgets were not worked out again with the data: %q", m.budgetWarning)
+	}
+}
+
+func TestModelShowsUnreadableGoalsInErrorPanel(t *testing.T) {
+	m, _ := newTestModel(t, testExpenses...)
+	m.goals = []goal{{Name: "Holiday", Target: 1000, Saved: 200}}
+	failed := errors.New("Goals sheet: unreadable")
+	m.Update(excelDataMsg{sheets: sheetExpenses, expenses: testExpenses, otherErr: failed})
+	if m.failure == nil || m.failure.err != failed {
+		t.Errorf("the error panel is not open on the Goals sheet's error")
+	}
+	if len(m.goals) != 1 || m.goals[0].Name != "Holiday" {
+		t.Errorf("the goals last loaded were replaced by %+v", m.goals)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// monthPicker is an overlay for jumping straight to a month instead of
+// stepping through them one at a time: a grid of one year's months, with
+// the year changed by a key and every month one key away.
+type monthPicker struct {
+	open bool
+	// cursor is the month highlighted, the first of the month.
+	cursor time.Time
+}
+
+// monthKeys pick the months of the year shown, January to December, along
+// the number row.
+const monthKeys = "1234567890-="
+
+// monthPickerColumns is how many months each row of the grid holds.
+const monthPickerColumns = 4
+
+// show opens the picker on month.
+func (p *monthPicker) show(month time.Time) {
+	p.open = true
+	p.cursor = calendarMonth(month)
+}
+
+// update handles a key while the picker is open. It returns the month
+// picked, or zero while still picking or once the picker is closed
+// without one.
+func (p *monthPicker) update(key string) time.Time {
+	switch key {
+	case "esc", "q", "m":
+		p.open = false
+	case "enter":
+		p.open = false
+		return p.cursor
+	case "left":
+		p.cursor = p.cursor.AddDate(0, -1, 0)
+	case "right":
+		p.cursor = p.cursor.AddDate(0, 1, 0)
+	case "up":
+		p.cursor = p.cursor.AddDate(0, -monthPickerColumns, 0)
+	case "down":
+		p.cursor = p.cursor.AddDate(0, monthPickerColumns, 0)
+	case "[", "pgup":
+		p.cursor = p.cursor.AddDate(-1, 0, 0)
+	case "]", "pgdown":
+		p.cursor = p.cursor.AddDate(1, 0, 0)
+	case "t":
+		p.cursor = monthOf(today())
+	default:
+		if i := strings.Index(monthKeys, key); i >= 0 && len(key) == 1 {
+			p.open = false
+			return time.Date(p.cursor.Year(), time.Month(i+1), 1, 0, 0, 0, 0, time.UTC)
+		}
+	}
+	return time.Time{}
+}
+
+// view draws the year of the cursor, marking the month currently shown.
+func (p *monthPicker) view(current time.Time) string {
+	cursorStyle := lipgloss.NewStyle().Background(lipgloss.Color("57")).Foreground(lipgloss.Color("229")).Bold(true)
+	currentStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("170")).Underline(true)
+	plainStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+
+	var b strings.Builder
+	b.WriteString(lipgloss.NewStyle().Bold(true).Render("‹ " + strconv.Itoa(p.cursor.Year()) + " ›"))
+	b.WriteString("\n\n")
+	for month := time.January; month <= time.December; month++ {
+		day := time.Date(p.cursor.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+		label := " " + string(monthKeys[month-1]) + " " + day.Format("Jan") + " "
+		switch {
+		case day.Equal(p.cursor):
+			label = cursorStyle.Render(label)
+		case day.Equal(calendarMonth(current)):
+			label = currentStyle.Render(label)
+		default:
+			label = plainStyle.Render(label)
+		}
+		b.WriteString(label)
+		if int(month)%monthPickerColumns == 0 {
+			b.WriteString("\n")
+		} else {
+			b.WriteString(" ")
+		}
+	}
+	b.WriteString("\n←/→/↑/↓ move · [/] change year · enter go\n1–0, -, = pick a month · t this month · esc cancel")
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("238")).
+		Padding(0, 1).
+		Render(b.String()) + "\n"
+}
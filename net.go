@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// errOffline is returned in place of any network call in offline mode.
+var errOffline = errors.New("offline mode, network access is turned off")
+
+// offlineEnv turns on offline mode like the config setting and the
+// --offline flag.
+const offlineEnv = "TET_OFFLINE"
+
+// httpClient makes every network call. Requests go through the proxy in
+// $HTTPS_PROXY or $HTTP_PROXY unless $NO_PROXY exempts the host, and time
+// out so a flaky connection cannot hold up a refresh.
+var httpClient = &http.Client{
+	Timeout:   30 * time.Second,
+	Transport: proxyTransport(),
+}
+
+func proxyTransport() http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	return t
+}
+
+// doRequest sends req with httpClient, unless tet is offline.
+func doRequest(req *http.Request) (*http.Response, error) {
+	if cfg.Offline {
+		return nil, errOffline
+	}
+	return httpClient.Do(req)
+}
+
+// offlineFromEnv reports whether $TET_OFFLINE asks for offline mode.
+func offlineFromEnv() bool {
+	switch os.Getenv(offlineEnv) {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
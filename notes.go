@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// noteMarker follows the name of the expenses with a note in the expenses
+// table; enter shows the note below it.
+const noteMarker = " ✎"
+
+// cleanNote is a note as typed, with Windows line endings and the blank
+// lines around it dropped.
+func cleanNote(note string) string {
+	return strings.TrimSpace(strings.ReplaceAll(note, "\r\n", "\n"))
+}
+
+// expenseDetail lists the fields of e that are set, its note last, for
+// the panel enter opens below the expenses table.
+func expenseDetail(e Expense) string {
+	var buffer bytes.Buffer
+	buffer.WriteString(screenTitleStyle.Render("Expense: " + e.Name))
+	buffer.WriteString("\n")
+	field := func(name, value string) {
+		if value != "" {
+			buffer.WriteString(fmt.Sprintf("%-11s %s\n", name+":", value))
+		}
+	}
+	amount := expenseAmount(e)
+	if e.Kind == kindRefund {
+		amount += " (refund)"
+	}
+	field("Amount", amount)
+	field("Date", formatExpenseDate(e.Date))
+	field("Category", e.Category)
+	field("Tags", formatTags(e.Tags))
+	field("Payment", e.Payment)
+	field("Method", string(e.Method))
+	field("For", e.Member)
+	field("Return by", formatExpenseDate(e.ReturnBy))
+	if end := warrantyEnd(e); !end.IsZero() {
+		field("Warranty", fmt.Sprintf("%d months, until %s", e.WarrantyMonths, formatExpenseDate(end)))
+	}
+	if price := unitPrice(e); price > 0 {
+		field("Usage", fmt.Sprintf("%s at %s %s", formatUsage(e.Usage, e.UsageUnit), strconv.FormatFloat(price, 'f', 4, 64), usagePer(e.UsageUnit)))
+	}
+	field("Entered by", e.EnteredBy)
+	if e.Pending != "" {
+		field("Pending", "awaiting approval")
+	}
+	if e.Note != "" {
+		buffer.WriteString("Note:\n")
+		for _, line := range strings.Split(e.Note, "\n") {
+			buffer.WriteString("  " + line + "\n")
+		}
+	}
+	return buffer.String()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/huh"
+)
+
+// exampleData is what a new workbook can start with to show how each sheet
+// is laid out: a month of income and spending up to day, a refund, a
+// position and a watched symbol.
+func exampleData(day time.Time) Dataset {
+	month := calendarMonth(day)
+	date := func(d int) time.Time { return month.AddDate(0, 0, min(d, day.Day())-1) }
+	user := currentUser()
+	return Dataset{
+		Expenses: []Expense{
+			{Name: "Salary", Amount: 2500, Date: date(1), EnteredBy: user, Category: "Income"},
+			{Name: "Rent", Amount: -850, Date: date(1), EnteredBy: user, Category: "Home", Payment: "transfer", Method: methodAccount, Member: "Shared"},
+			{Name: "Groceries", Amount: -64.2, Date: date(3), EnteredBy: user, Category: "Food", Payment: "card", Method: methodCard, Member: "Shared"},
+			{Name: "Lunch", Amount: -12.3, Date: date(4), EnteredBy: user, Category: "Food", Tags: []string{"work"}, Payment: "card", Method: methodCard, Member: "Me"},
+			{Name: "Groceries", Amount: 8.5, Kind: kindRefund, Date: date(5), EnteredBy: user, Category: "Food", Payment: "card", Method: methodCard, Member: "Shared"},
+		},
+		Stonks: []Stonk{
+			{Symbol: "VWCE.DE", Quantity: 10, AvgPrice: 100, Price: 110, Currency: "EUR", Comment: "Example position"},
+		},
+		WatchList: []WatchItem{
+			{Symbol: "AAPL", TargetBuy: 150, TargetSell: 250, Note: "Example target"},
+		},
+	}
+}
+
+// firstRun offers to create the workbook at path when there is none yet,
+// instead of starting empty. It reports false when the user would rather
+// not, and tet quits without creating anything.
+func firstRun(path string) (bool, error) {
+	if !isWorkbook(path) {
+		return true, nil
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	create, examples := true, false
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewNote().Title("Welcome to tet").
+				Description(fmt.Sprintf("There is no workbook at %s yet. To open another, run tet --file <path>.", path)),
+			huh.NewConfirm().Title("Create it?").Value(&create),
+		),
+		huh.NewGroup(
+			huh.NewConfirm().Title("Add example rows to show how each sheet is laid out?").
+				Description("Delete them, or the whole rows in Excel, once you have your own.").
+				Value(&examples),
+		).WithHideFunc(func() bool { return !create }),
+	)
+	if err := form.Run(); err != nil {
+		return false, err
+	}
+	if !create {
+		return false, nil
+	}
+	var seed Dataset
+	if examples {
+		seed = exampleData(today())
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+	if err := createWorkbook(path, seed); err != nil {
+		return false, fmt.Errorf("creating %s: %w", path, err)
+	}
+	return true, nil
+}
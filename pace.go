@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// aheadOfPaceStyle marks budgets spent faster than the month is going by.
+var aheadOfPaceStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+
+// monthElapsed is the share of the month, or budgeting period, of day gone
+// by its end: 1 on the last day.
+func monthElapsed(day time.Time) float64 {
+	month := monthOf(day)
+	start, end := periodStart(month), periodStart(month.AddDate(0, 1, 0))
+	return (dateOf(day).Sub(start).Hours()/24 + 1) / (end.Sub(start).Hours() / 24)
+}
+
+// paceBar is a budget bar with a marker where spending at an even pace
+// would have got to by now.
+func paceBar(spent, available, elapsed float64) string {
+	bar := []rune(budgetBar(spent, available))
+	at := min(int(elapsed*budgetBarWidth), budgetBarWidth-1)
+	bar[at] = '│'
+	return string(bar)
+}
+
+// paceLine is how far through a budget spending is against the month,
+// coloured red when over budget and orange when ahead of pace.
+func paceLine(label string, spent, available, elapsed float64) string {
+	percent := "—"
+	if available > 0 {
+		percent = fmt.Sprintf("%.0f%%", spent/available*100)
+	}
+	line := fmt.Sprintf("%s  %4s spent", paceBar(spent, available, elapsed), percent)
+	switch {
+	case spent > available:
+		return overBudgetStyle.Render(label + line)
+	case available > 0 && spent/available > elapsed:
+		return aheadOfPaceStyle.Render(label + line)
+	}
+	return label + line
+}
+
+// spendingPace compares the spending against each budget this month, and
+// against all of them, with how much of the month has gone, for the menu.
+// It is empty without budgets.
+func spendingPace(budgets []budget, expenses []Expense, day time.Time) string {
+	statuses := budgetStatuses(budgets, expenses, monthOf(day))
+	if len(statuses) == 0 {
+		return ""
+	}
+	elapsed := monthElapsed(day)
+	categories := budgetCategories(budgets)
+	labels := make([]string, len(statuses))
+	width := len("All budgets")
+	var available, spent float64
+	for i, s := range statuses {
+		labels[i] = withIcon(s.icon(), s.Category)
+		width = max(width, lipgloss.Width(labels[i]))
+		if !nestedCategory(s.Category, categories) {
+			available += s.available()
+			spent += s.spent
+		}
+	}
+	pad := func(label string) string {
+		return "  " + label + strings.Repeat(" ", width-lipgloss.Width(label)) + "  "
+	}
+
+	var b strings.Builder
+	if available > 0 {
+		b.WriteString(fmt.Sprintf("You've spent %.0f%% of your budgets with %.0f%% of %s elapsed.\n",
+			spent/available*100, elapsed*100, monthOf(day).Format("January")))
+	} else {
+		b.WriteString(fmt.Sprintf("%.0f%% of %s has elapsed.\n", elapsed*100, monthOf(day).Format("January")))
+	}
+	b.WriteString(paceLine(pad("All budgets"), cfg.Rounding.round(spent), cfg.Rounding.round(available), elapsed) + "\n")
+	for i, s := range statuses {
+		b.WriteString(paceLine(pad(labels[i]), s.spent, s.available(), elapsed) + "\n")
+	}
+	b.WriteString("│ marks an even pace through the month.\n")
+	return b.String()
+}
+
+type dayTickMsg struct{}
+
+// dayTickCmd wakes the TUI at the next midnight, so that the spending pace
+// moves on with the day.
+func dayTickCmd() tea.Cmd {
+	return tea.Tick(untilMidnight(now()), func(time.Time) tea.Msg { return dayTickMsg{} })
+}
+
+// untilMidnight is how long after t the next day starts.
+func untilMidnight(t time.Time) time.Duration {
+	midnight := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	return midnight.Sub(t)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/huh"
+	"github.com/xuri/excelize/v2"
+)
+
+// passwordEnv holds the password of a password-protected workbook, for
+// commands and SSH sessions that cannot ask for it.
+const passwordEnv = "TET_WORKBOOK_PASSWORD"
+
+// workbookPassword opens password-protected workbooks, asked for when the
+// TUI starts on one and kept in memory only, so that every save encrypts
+// the workbook again with it.
+var workbookPassword = os.Getenv(passwordEnv)
+
+// encryptedHeader starts a password-protected workbook, which Excel keeps
+// in an OLE compound file instead of a plain zip.
+var encryptedHeader = []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}
+
+// isProtected reports whether the workbook at filename has a password.
+func isProtected(filename string) bool {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	header := make([]byte, len(encryptedHeader))
+	if _, err := file.Read(header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, encryptedHeader)
+}
+
+// openWorkbook opens the workbook at filename, with workbookPassword when
+// it is protected. Saving it keeps it protected, while workbooks without a
+// password are never given one.
+func openWorkbook(filename string) (*excelize.File, error) {
+	var opts excelize.Options
+	if isProtected(filename) {
+		if workbookPassword == "" {
+			return nil, fmt.Errorf("%s is password-protected: set $%s to its password", filename, passwordEnv)
+		}
+		opts.Password = workbookPassword
+	}
+	f, err := excelize.OpenFile(filename, opts)
+	if err != nil && opts.Password != "" {
+		// A wrong password decrypts to garbage, reported as whatever
+		// excelize makes of it.
+		return nil, fmt.Errorf("%s: %w", filename, excelize.ErrWorkbookPassword)
+	}
+	return f, err
+}
+
+// unlockWorkbook asks for the password of the workbook at path when it is
+// protected and $TET_WORKBOOK_PASSWORD does not open it.
+func unlockWorkbook(path string) error {
+	if !isWorkbook(path) || !isProtected(path) {
+		return nil
+	}
+	if f, err := openWorkbook(path); err == nil {
+		return f.Close()
+	}
+	password := ""
+	form := huh.NewForm(huh.NewGroup(
+		huh.NewInput().Title("Password").
+			Description(fmt.Sprintf("%s is password-protected.", path)).
+			EchoMode(huh.EchoModePassword).
+			Value(&password).
+			Validate(func(s string) error {
+				if s == "" {
+					return errors.New("enter the workbook's password")
+				}
+				workbookPassword = s
+				f, err := openWorkbook(path)
+				if err != nil {
+					return errors.New("wrong password")
+				}
+				return f.Close()
+			}),
+	))
+	return form.Run()
+}
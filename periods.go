@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// periodConfig anchors budgeting periods to a payday instead of the 1st
+// of the month.
+type periodConfig struct {
+	// StartDay is the day of the month periods start on, such as 25 for
+	// periods running from the 25th to the 24th; by default, and with 1,
+	// periods are calendar months.
+	StartDay int `json:"start_day"`
+}
+
+func (c periodConfig) validate() error {
+	if c.StartDay < 0 || c.StartDay > 28 {
+		return errors.New("period.start_day must be between 1 and 28")
+	}
+	return nil
+}
+
+// custom reports whether periods start on a day other than the 1st.
+func (c periodConfig) custom() bool {
+	return c.StartDay > 1
+}
+
+// periodStart is the first day of the period named month. A period is
+// named after the month holding most of its days, so periods starting on
+// the 25th begin in the month before the one they are named after.
+func periodStart(month time.Time) time.Time {
+	month = calendarMonth(month)
+	if !cfg.Period.custom() {
+		return month
+	}
+	if cfg.Period.StartDay > 15 {
+		month = month.AddDate(0, -1, 0)
+	}
+	return month.AddDate(0, 0, cfg.Period.StartDay-1)
+}
+
+// periodName names the period of month for titles, with the days it runs
+// between when periods are not calendar months: "October 2026 (25 Sep –
+// 24 Oct)".
+func periodName(month time.Time) string {
+	name := month.Format("January 2006")
+	if !cfg.Period.custom() {
+		return name
+	}
+	start := periodStart(month)
+	end := periodStart(month.AddDate(0, 1, 0)).AddDate(0, 0, -1)
+	return name + " (" + start.Format("2 Jan") + " – " + end.Format("2 Jan") + ")"
+}
+
+// calendarMonth is the first day of the calendar month of t. Months named
+// by monthOf are kept as they are, unlike monthOf, which would move them
+// into the period their first day falls in.
+func calendarMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
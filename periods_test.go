@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// usePeriods has the test budget by periods starting on startDay.
+func usePeriods(t *testing.T, startDay int) {
+	t.Helper()
+	old := cfg.Period
+	cfg.Period = periodConfig{StartDay: startDay}
+	t.Cleanup(func() { cfg.Period = old })
+}
+
+func TestMonthOf(t *testing.T) {
+	for _, tc := range []struct {
+		startDay    int
+		date, month string
+	}{
+		// Calendar months, by default and with 1.
+		{0, "2026-10-01", "2026-10"},
+		{0, "2026-10-31", "2026-10"},
+		{1, "2026-12-31", "2026-12"},
+		// Periods from the 25th are named after the month they end in.
+		{25, "2026-10-24", "2026-10"},
+		{25, "2026-10-25", "2026-11"},
+		{25, "2026-12-24", "2026-12"},
+		{25, "2026-12-25", "2027-01"},
+		{25, "2027-01-01", "2027-01"},
+		// Periods from the 15th or earlier after the month they start in.
+		{10, "2026-10-09", "2026-09"},
+		{10, "2026-10-10", "2026-10"},
+		{10, "2026-01-09", "2025-12"},
+		{15, "2026-10-14", "2026-09"},
+		{15, "2026-10-15", "2026-10"},
+		{16, "2026-10-15", "2026-10"},
+		{16, "2026-10-16", "2026-11"},
+		// The 28th, the latest start, in February and a leap year.
+		{28, "2026-02-27", "2026-02"},
+		{28, "2026-02-28", "2026-03"},
+		{28, "2024-02-29", "2024-03"},
+		{2, "2026-03-01", "2026-02"},
+	} {
+		usePeriods(t, tc.startDay)
+		if got := monthOf(parseExpenseDate(tc.date)).Format("2006-01"); got != tc.month {
+			t.Errorf("start day %d: %s counts towards %s, want %s", tc.startDay, tc.date, got, tc.month)
+		}
+	}
+}
+
+// TestPeriodsMeet checks that every period starts the day after the one
+// before it ends, for every start day over two years.
+func TestPeriodsMeet(t *testing.T) {
+	for day := 1; day <= 28; day++ {
+		usePeriods(t, day)
+		for m := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC); m.Year() < 2028; m = m.AddDate(0, 1, 0) {
+			start := periodStart(m)
+			if got := monthOf(start); !got.Equal(m) {
+				t.Errorf("start day %d: %s, the start of %s, counts towards %s", day, formatExpenseDate(start), m.Format("2006-01"), got.Format("2006-01"))
+			}
+			if got, prev := monthOf(start.AddDate(0, 0, -1)), m.AddDate(0, -1, 0); !got.Equal(prev) {
+				t.Errorf("start day %d: the day before %s counts towards %s, want %s", day, formatExpenseDate(start), got.Format("2006-01"), prev.Format("2006-01"))
+			}
+			if start.Day() != max(day, 1) {
+				t.Errorf("start day %d: %s starts on %s", day, m.Format("2006-01"), formatExpenseDate(start))
+			}
+		}
+	}
+}
+
+func TestPeriodName(t *testing.T) {
+	for _, tc := range []struct {
+		startDay int
+		month    string
+		want     string
+	}{
+		{0, "2026-10-01", "October 2026"},
+		{1, "2026-10-01", "October 2026"},
+		{25, "2026-10-01", "October 2026 (25 Sep – 24 Oct)"},
+		{25, "2027-01-01", "January 2027 (25 Dec – 24 Jan)"},
+		{10, "2026-12-01", "December 2026 (10 Dec – 9 Jan)"},
+		{28, "2026-03-01", "March 2026 (28 Feb – 27 Mar)"},
+	} {
+		usePeriods(t, tc.startDay)
+		if got := periodName(parseExpenseDate(tc.month)); got != tc.want {
+			t.Errorf("start day %d: %s is named %q, want %q", tc.startDay, tc.month, got, tc.want)
+		}
+	}
+}
+
+// TestSpendingByPeriod buckets spending either side of a period boundary,
+// the end of a calendar month falling inside a period.
+func TestSpendingByPeriod(t *testing.T) {
+	usePeriods(t, 25)
+	expense := func(date string, amount float64) Expense {
+		return Expense{Name: "Groceries", Category: "Food", Amount: amount, Date: parseExpenseDate(date)}
+	}
+	spending := monthlySpending([]Expense{
+		expense("2026-09-25", -10),
+		expense("2026-09-30", -20),
+		expense("2026-10-01", -40),
+		expense("2026-10-24", -80),
+		expense("2026-10-25", -160),
+	})[categoryOf(expense("", 0))]
+	for month, want := range map[string]float64{"2026-10": 150, "2026-11": 160} {
+		if got := spending[parseExpenseDate(month+"-01")]; got != want {
+			t.Errorf("spent %v in the %s period, want %v", got, month, want)
+		}
+	}
+	if len(spending) != 2 {
+		t.Errorf("spending fell in %d periods, want 2: %v", len(spending), spending)
+	}
+}
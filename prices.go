@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// pricesSheet holds daily closing prices, one row per symbol and day, for
+// charts and benchmarks. It is created by `tet backfill` and, like the
+// archive sheets, never read by the everyday load.
+const pricesSheet = "Prices"
+
+var pricesHeaders = []string{"Symbol", "Date", "Close"}
+
+// pricePoint is one close on the Prices sheet.
+type pricePoint struct {
+	Symbol string
+	Date   time.Time
+	Close  float64
+}
+
+func (p pricePoint) key() string { return symbolKey(p.Symbol) + "/" + formatExpenseDate(p.Date) }
+
+// chartEndpoint serves the daily history backfill fetches, as Yahoo
+// Finance's chart JSON.
+var chartEndpoint = "https://query1.finance.yahoo.com/v8/finance/chart/"
+
+// chartResult is the part of a chart response tet uses.
+type chartResult struct {
+	Meta struct {
+		GMTOffset          int64   `json:"gmtoffset"`
+		RegularMarketPrice float64 `json:"regularMarketPrice"`
+		ChartPreviousClose float64 `json:"chartPreviousClose"`
+	} `json:"meta"`
+	Timestamp  []int64 `json:"timestamp"`
+	Indicators struct {
+		Quote []struct {
+			Open  []*float64 `json:"open"`
+			Close []*float64 `json:"close"`
+		} `json:"quote"`
+	} `json:"indicators"`
+}
+
+// fetchChart requests the chart of symbol with query q.
+func fetchChart(symbol string, q url.Values) (chartResult, error) {
+	req, err := http.NewRequest(http.MethodGet, chartEndpoint+url.PathEscape(symbol)+"?"+q.Encode(), nil)
+	if err != nil {
+		return chartResult{}, err
+	}
+	// The endpoint turns away requests without a browser-like agent.
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; tet)")
+	resp, err := doRequest(req)
+	if err != nil {
+		return chartResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Chart struct {
+			Result []chartResult `json:"result"`
+			Error  *struct {
+				Description string `json:"description"`
+			} `json:"error"`
+		} `json:"chart"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&body); err != nil {
+		return chartResult{}, fmt.Errorf("%s: %s", symbol, resp.Status)
+	}
+	if body.Chart.Error != nil {
+		return chartResult{}, fmt.Errorf("%s: %s", symbol, body.Chart.Error.Description)
+	}
+	if len(body.Chart.Result) == 0 || len(body.Chart.Result[0].Indicators.Quote) == 0 {
+		return chartResult{}, fmt.Errorf("%s: no price history", symbol)
+	}
+	return body.Chart.Result[0], nil
+}
+
+// fetchCloses downloads the daily closes of symbol from from to to.
+func fetchCloses(symbol string, from, to time.Time) ([]pricePoint, error) {
+	result, err := fetchChart(symbol, url.Values{
+		"period1":  {strconv.FormatInt(from.Unix(), 10)},
+		"period2":  {strconv.FormatInt(to.Unix(), 10)},
+		"interval": {"1d"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("backfill: %w", err)
+	}
+	closes := result.Indicators.Quote[0].Close
+	var points []pricePoint
+	for i, ts := range result.Timestamp {
+		// Days the market was closed come back as nulls.
+		if i >= len(closes) || closes[i] == nil {
+			continue
+		}
+		// Timestamps are the session open; the exchange's offset keeps
+		// them on their trading day.
+		day := dateOf(time.Unix(ts+result.Meta.GMTOffset, 0).UTC())
+		points = append(points, pricePoint{Symbol: symbol, Date: day, Close: *closes[i]})
+	}
+	return points, nil
+}
+
+// readPriceSheet reads a sheet laid out like Prices, such as Prices or FX;
+// a workbook without it has no points.
+func readPriceSheet(f *excelize.File, sheet string) ([]pricePoint, error) {
+	if idx, _ := f.GetSheetIndex(sheet); idx == -1 {
+		return nil, nil
+	}
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+	var points []pricePoint
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) < 3 || row[0] == "" {
+			continue
+		}
+		p := pricePoint{Symbol: row[0], Date: parseExpenseDate(row[1])}
+		p.Close, _ = strconv.ParseFloat(row[2], 64)
+		if p.Date.IsZero() || p.Close == 0 {
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// storePriceSheet merges points into sheet of filename, replacing values
+// already there for the same symbol and day, and returns how many rows
+// were new.
+func storePriceSheet(filename, sheet string, headers []string, points []pricePoint) (int, error) {
+	if _, err := upgradeWorkbook(filename); err != nil {
+		return 0, err
+	}
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	existing, err := readPriceSheet(f, sheet)
+	if err != nil {
+		return 0, fmt.Errorf("%s sheet: %w", sheet, err)
+	}
+	merged := make(map[string]pricePoint, len(existing)+len(points))
+	for _, p := range existing {
+		merged[p.key()] = p
+	}
+	added := 0
+	for _, p := range points {
+		if _, ok := merged[p.key()]; !ok {
+			added++
+		}
+		merged[p.key()] = p
+	}
+	all := make([]pricePoint, 0, len(merged))
+	for _, p := range merged {
+		all = append(all, p)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if a, b := symbolKey(all[i].Symbol), symbolKey(all[j].Symbol); a != b {
+			return a < b
+		}
+		return all[i].Date.Before(all[j].Date)
+	})
+
+	// The sheet is rewritten whole, so it is simplest to start afresh.
+	if idx, _ := f.GetSheetIndex(sheet); idx != -1 {
+		if err := f.DeleteSheet(sheet); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := f.NewSheet(sheet); err != nil {
+		return 0, err
+	}
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return 0, err
+	}
+	header := make([]any, len(headers))
+	for i, h := range headers {
+		header[i] = h
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return 0, err
+	}
+	for i, p := range all {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := sw.SetRow(cell, []any{p.Symbol, formatExpenseDate(p.Date), p.Close}); err != nil {
+			return 0, err
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		return 0, err
+	}
+	return added, f.Save()
+}
+
+// runBackfill fetches a symbol's past daily closes into the Prices sheet,
+// for positions bought before tet was tracking them, or with --fx a
+// currency's exchange rates into the FX sheet.
+func runBackfill(args []string) error {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "workbook to store the prices in")
+	symbol := flags.String("symbol", "", "ticker to fetch, e.g. AAPL")
+	currency := flags.String("fx", "", "currency to fetch rates into the configured currency for, e.g. USD")
+	fromFlag := flags.String("from", "", "first month to fetch, as YYYY-MM")
+	toFlag := flags.String("to", "", "last month to fetch, as YYYY-MM (default this month)")
+	flags.Parse(args)
+
+	*symbol = strings.TrimSpace(*symbol)
+	*currency = strings.ToUpper(strings.TrimSpace(*currency))
+	if (*symbol == "") == (*currency == "") {
+		return errors.New("backfill: set one of --symbol or --fx")
+	}
+	from, err := time.Parse("2006-01", *fromFlag)
+	if err != nil {
+		return fmt.Errorf("backfill: --from must be YYYY-MM: %w", err)
+	}
+	to := time.Now().UTC()
+	if *toFlag != "" {
+		month, err := time.Parse("2006-01", *toFlag)
+		if err != nil {
+			return fmt.Errorf("backfill: --to must be YYYY-MM: %w", err)
+		}
+		to = month.AddDate(0, 1, 0)
+	}
+	if !from.Before(to) {
+		return errors.New("backfill: --from must be before --to")
+	}
+
+	sheet, headers, ticker, name := pricesSheet, pricesHeaders, *symbol, *symbol
+	if *currency != "" {
+		base := strings.ToUpper(cfg.Rounding.Currency)
+		if *currency == base {
+			return fmt.Errorf("backfill: %s is already the configured currency", base)
+		}
+		sheet, headers, ticker, name = fxSheet, fxHeaders, fxTicker(*currency, base), *currency
+	}
+	points, err := fetchCloses(ticker, from, to)
+	if err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		return fmt.Errorf("backfill: no closes for %s since %s", name, from.Format("January 2006"))
+	}
+	for i := range points {
+		points[i].Symbol = name
+	}
+	added, err := storePriceSheet(*file, sheet, headers, points)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Fetched %d closes for %s (%s to %s), %d new.\n", len(points), name,
+		formatExpenseDate(points[0].Date), formatExpenseDate(points[len(points)-1].Date), added)
+	return nil
+}
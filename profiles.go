@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// profileEnv picks a profile like --profile.
+const profileEnv = "TET_PROFILE"
+
+// profileArg takes --profile name, or --profile=name, from the front of
+// args, returning the rest.
+func profileArg(args []string) (name string, rest []string, ok bool) {
+	if len(args) == 0 {
+		return "", args, false
+	}
+	flag := strings.TrimLeft(args[0], "-")
+	if len(flag) == len(args[0]) || len(args[0])-len(flag) > 2 {
+		return "", args, false
+	}
+	if value, found := strings.CutPrefix(flag, "profile="); found {
+		return value, args[1:], true
+	}
+	if flag == "profile" && len(args) > 1 {
+		return args[1], args[2:], true
+	}
+	return "", args, false
+}
+
+// profileNames are the profiles in the config file, sorted.
+func (c config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateProfiles checks that every profile reads as settings and keeps
+// its data in a file of its own.
+func (c config) validateProfiles() error {
+	for _, name := range c.profileNames() {
+		var p struct {
+			File string `json:"file"`
+		}
+		if err := json.Unmarshal(c.Profiles[name], &p); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+		if p.File == "" {
+			return fmt.Errorf("profile %s: set the file it keeps its data in", name)
+		}
+	}
+	return nil
+}
+
+// applyProfile sets the named profile's settings over c. A profile holds
+// any settings of the config file, and those it leaves out, down to single
+// fields such as rounding.currency, are the ones set outside it.
+func (c *config) applyProfile(name string) error {
+	raw, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return fmt.Errorf("no profile %q, as none are set", name)
+		}
+		return fmt.Errorf("no profile %q (available: %s)", name, strings.Join(c.profileNames(), ", "))
+	}
+	profiles := c.Profiles
+	if err := json.Unmarshal(raw, c); err != nil {
+		return fmt.Errorf("profile %s: %w", name, err)
+	}
+	c.Profiles = profiles
+	c.profile = name
+	return nil
+}
+
+// profileChoices are the rows of the Profiles screen: the settings outside
+// any profile, as "", then every profile.
+func profileChoices() []string {
+	return append([]string{""}, cfg.profileNames()...)
+}
+
+func (m *model) updateProfiles(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	choices := profileChoices()
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "b", "esc":
+		m.currentScreen = screenMenu
+	case "up":
+		if m.profileRow > 0 {
+			m.profileRow--
+		}
+	case "down":
+		if m.profileRow < len(choices)-1 {
+			m.profileRow++
+		}
+	case "enter":
+		if len(choices) == 1 {
+			return m, nil
+		}
+		name := choices[m.profileRow]
+		if name == cfg.profile {
+			m.currentScreen = screenMenu
+			return m, nil
+		}
+		// The session ends and main starts another with the profile's
+		// settings and workbook.
+		m.nextProfile = &name
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *model) viewProfiles() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	buffer.WriteString(screenTitleStyle.Render("Profiles"))
+	buffer.WriteString("\n\n")
+	choices := profileChoices()
+	if len(choices) == 1 {
+		buffer.WriteString("No profiles are set. Add them under \"profiles\" in the config file, each with its own file and any other settings.\n")
+		buffer.WriteString("\nPress 'b' to go back.\n")
+		return buffer.String()
+	}
+	for i, name := range choices {
+		line := name
+		if name == "" {
+			line = "(no profile)"
+		}
+		switch {
+		case name == cfg.profile:
+			line += " — open, " + m.path
+		case name != "":
+			line += " — " + profileFile(name)
+		}
+		if i == m.profileRow {
+			buffer.WriteString(selectedItemStyle.Render("> "+line) + "\n")
+		} else {
+			buffer.WriteString(itemStyle.Render(line) + "\n")
+		}
+	}
+	buffer.WriteString("\nUse ↑/↓ to move, 'enter' to switch to the profile and open its workbook, 'b' to go back.\n")
+	return buffer.String()
+}
+
+// profileFile is the data file the named profile opens.
+func profileFile(name string) string {
+	var p struct {
+		File string `json:"file"`
+	}
+	json.Unmarshal(cfg.Profiles[name], &p)
+	return expandHome(p.File)
+}
+
+// selectCurrentProfile puts the Profiles screen's cursor on the profile in
+// use.
+func (m *model) selectCurrentProfile() {
+	m.profileRow = max(slices.Index(profileChoices(), cfg.profile), 0)
+}
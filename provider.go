@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// quoteProvider fetches quotes in batches, within the provider's rate limit.
+type quoteProvider struct {
+	name string
+	// batchSize is the most symbols one request may ask for.
+	batchSize int
+	// workers is how many requests may be in flight at once.
+	workers int
+	// interval is the least time between two requests, with up to burst
+	// sent back to back after a quiet spell.
+	interval time.Duration
+	burst    int
+	// fetchBatch requests one batch, returning the quotes it got keyed by
+	// symbolKey.
+	fetchBatch func(symbols []string) (map[string]quote, error)
+
+	once   sync.Once
+	tokens chan struct{}
+}
+
+// sparkEndpoint serves Yahoo Finance quotes for up to 20 symbols per
+// request.
+var sparkEndpoint = "https://query1.finance.yahoo.com/v8/finance/spark"
+
+var yahooQuotes = &quoteProvider{
+	name:       "Yahoo Finance",
+	batchSize:  20,
+	workers:    4,
+	interval:   500 * time.Millisecond,
+	burst:      4,
+	fetchBatch: fetchSpark,
+}
+
+// wait blocks until the rate limit allows another request.
+func (p *quoteProvider) wait() {
+	p.once.Do(func() {
+		p.tokens = make(chan struct{}, p.burst)
+		for i := 0; i < p.burst; i++ {
+			p.tokens <- struct{}{}
+		}
+		go func() {
+			for range time.Tick(p.interval) {
+				select {
+				case p.tokens <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	})
+	<-p.tokens
+}
+
+// fetch gets the quotes of symbols, batched over the provider's workers.
+// Symbols it got no quote for, including those of failed batches, are
+// returned in failed.
+func (p *quoteProvider) fetch(symbols []string) (quotes map[string]quote, failed []string) {
+	quotes = make(map[string]quote)
+	if len(symbols) == 0 {
+		return quotes, nil
+	}
+	batches := make(chan []string)
+	go func() {
+		for start := 0; start < len(symbols); start += p.batchSize {
+			batches <- symbols[start:min(start+p.batchSize, len(symbols))]
+		}
+		close(batches)
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				p.wait()
+				got, err := p.fetchBatch(batch)
+				mu.Lock()
+				for _, symbol := range batch {
+					if q, ok := got[symbolKey(symbol)]; ok && err == nil {
+						quotes[symbolKey(symbol)] = q
+					} else {
+						failed = append(failed, symbol)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	sort.Strings(failed)
+	return quotes, failed
+}
+
+// fetchSpark requests one batch from Yahoo's spark endpoint, which answers
+// with a chart per symbol.
+func fetchSpark(symbols []string) (map[string]quote, error) {
+	q := url.Values{"symbols": {strings.Join(symbols, ",")}, "range": {"1d"}, "interval": {"1d"}}
+	req, err := http.NewRequest(http.MethodGet, sparkEndpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; tet)")
+	resp, err := doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Spark struct {
+			Result []struct {
+				Symbol   string        `json:"symbol"`
+				Response []chartResult `json:"response"`
+			} `json:"result"`
+		} `json:"spark"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("quotes: %s", resp.Status)
+	}
+	quotes := make(map[string]quote)
+	for _, r := range body.Spark.Result {
+		if len(r.Response) == 0 || r.Response[0].Meta.RegularMarketPrice == 0 {
+			continue
+		}
+		chart := r.Response[0]
+		qt := quote{Price: chart.Meta.RegularMarketPrice, PrevClose: chart.Meta.ChartPreviousClose}
+		if len(chart.Indicators.Quote) > 0 {
+			if opens := chart.Indicators.Quote[0].Open; len(opens) > 0 && opens[0] != nil {
+				qt.Open = *opens[0]
+			}
+		}
+		quotes[symbolKey(r.Symbol)] = qt
+	}
+	return quotes, nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+)
+
+// queryShownRows caps the rows the Query screen shows; `tet query` prints
+// them all.
+const queryShownRows = 50
+
+// queryResult is what a query returned, every cell as text.
+type queryResult struct {
+	columns []string
+	rows    [][]string
+}
+
+// queryDatabase loads the data file at path into an in-memory SQLite
+// database, one table per table `tet dump --all` writes: expenses, stonks,
+// watchlist and the workbook's other sheets, with the same snake_case
+// columns. Dates are ISO text, numbers REAL and flags 0 or 1. The database
+// is a copy, so queries that change it change nothing else.
+func queryDatabase(path string) (*sql.DB, error) {
+	tables, err := dumpTables(path, true)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	// Every connection to :memory: opens a database of its own.
+	db.SetMaxOpenConns(1)
+	for _, t := range tables {
+		if err := createQueryTable(db, t); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%s table: %w", t.name, err)
+		}
+	}
+	return db, nil
+}
+
+func quoteIdent(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` }
+
+func createQueryTable(db *sql.DB, t dumpTable) error {
+	types := make([]any, len(t.columns))
+	cols := make([]string, len(t.columns))
+	marks := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		types[i] = t.columnType(i)
+		typ := "TEXT"
+		switch types[i].(type) {
+		case float64:
+			typ = "REAL"
+		case bool:
+			typ = "INTEGER"
+		}
+		cols[i] = quoteIdent(c) + " " + typ
+		marks[i] = "?"
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("CREATE TABLE " + quoteIdent(t.name) + " (" + strings.Join(cols, ", ") + ")"); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare("INSERT INTO " + quoteIdent(t.name) + " VALUES (" + strings.Join(marks, ", ") + ")")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, row := range t.rows {
+		args := make([]any, len(row))
+		for i, v := range row {
+			switch types[i].(type) {
+			case float64, bool:
+				args[i] = v
+			default:
+				if v != nil {
+					args[i] = dumpText(v)
+				}
+			}
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// queryCell formats a value a query returned. Floats are cut to 15
+// significant digits, so that sums such as 0.1 + 0.2 read 0.3.
+func queryCell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		rounded, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', 15, 64), 64)
+		return strconv.FormatFloat(rounded, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(dateLayout)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// runSQL runs query over the data file at path.
+func runSQL(path, query string) (queryResult, error) {
+	db, err := queryDatabase(path)
+	if err != nil {
+		return queryResult{}, err
+	}
+	defer db.Close()
+	rows, err := db.Query(query)
+	if err != nil {
+		return queryResult{}, err
+	}
+	defer rows.Close()
+	var r queryResult
+	if r.columns, err = rows.Columns(); err != nil {
+		return queryResult{}, err
+	}
+	values := make([]any, len(r.columns))
+	ptrs := make([]any, len(values))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return queryResult{}, err
+		}
+		cells := make([]string, len(values))
+		for i, v := range values {
+			cells[i] = queryCell(v)
+		}
+		r.rows = append(r.rows, cells)
+	}
+	return r, rows.Err()
+}
+
+func renderQueryResult(r queryResult, limit int) string {
+	baseStyle := lipgloss.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rows := r.rows
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(r.columns...).
+		Rows(rows...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			return baseStyle
+		})
+	s := t.String() + "\n"
+	switch {
+	case len(rows) < len(r.rows):
+		s += fmt.Sprintf("First %d of %d rows; tet query prints them all.\n", len(rows), len(r.rows))
+	case len(r.rows) == 1:
+		s += "1 row.\n"
+	default:
+		s += fmt.Sprintf("%d rows.\n", len(r.rows))
+	}
+	return s
+}
+
+type queryResultMsg struct {
+	result queryResult
+	err    error
+}
+
+func runSQLCmd(path, query string) tea.Cmd {
+	return func() tea.Msg {
+		r, err := runSQL(path, query)
+		return queryResultMsg{result: r, err: err}
+	}
+}
+
+func newQueryInput() textinput.Model {
+	in := textinput.New()
+	in.Placeholder = "SELECT type, sum(amount) FROM expenses GROUP BY type"
+	in.Prompt = "SQL> "
+	in.Width = 100
+	return in
+}
+
+func (m *model) updateQuery(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case queryResultMsg:
+		m.queryRunning = false
+		m.queryResult, m.queryErr = &msg.result, msg.err
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			m.queryInput.Blur()
+			m.currentScreen = screenMenu
+			return m, nil
+		case "enter":
+			query := strings.TrimSpace(m.queryInput.Value())
+			if query == "" || m.queryRunning {
+				return m, nil
+			}
+			m.queryRunning = true
+			return m, runSQLCmd(m.path, query)
+		}
+	}
+	var cmd tea.Cmd
+	m.queryInput, cmd = m.queryInput.Update(msg)
+	return m, cmd
+}
+
+func (m *model) viewQuery() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	buffer.WriteString(screenTitleStyle.Render("Query"))
+	buffer.WriteString("\n\n")
+	buffer.WriteString("Run SQL over a copy of the data: the expenses, stonks and watchlist tables and one per other sheet, such as prices, fx and history.\n\n")
+	buffer.WriteString(m.queryInput.View() + "\n\n")
+	switch {
+	case m.queryRunning:
+		buffer.WriteString("Running…\n")
+	case m.queryErr != nil:
+		buffer.WriteString(errorBannerStyle.Render(m.queryErr.Error()) + "\n")
+	case m.queryResult != nil:
+		buffer.WriteString(renderQueryResult(*m.queryResult, queryShownRows))
+	}
+	buffer.WriteString("\nPress enter to run the query, esc to go back.\n")
+	return buffer.String()
+}
+
+// runQuery runs a query from the command line, printing a table or CSV.
+func runQuery(args []string) error {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "data file to query")
+	format := flags.String("format", "table", "table or csv")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), `Usage: tet query [flags] "SELECT ..."`)
+		fmt.Fprintln(flags.Output(), "Tables: expenses, stonks, watchlist and one per other sheet of the workbook, as tet dump --all writes them.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("query: expected exactly one SQL statement")
+	}
+	if *format != "table" && *format != "csv" {
+		return fmt.Errorf("query: unknown format %q (available: table, csv)", *format)
+	}
+	r, err := runSQL(*file, flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	if *format == "table" {
+		fmt.Print(renderQueryResult(r, 0))
+		return nil
+	}
+	w := csv.NewWriter(os.Stdout)
+	w.Write(r.columns)
+	w.WriteAll(r.rows)
+	return w.Error()
+}
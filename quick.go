@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+)
+
+// quickOther is the category choice that asks for a name instead.
+const quickOther = "Other…"
+
+// quickCategories are the choices offered by `tet quick`: the Budgets
+// categories when the workbook has any, else the most frequent expense
+// names.
+func quickCategories(file string, expenses []Expense) []string {
+	if isWorkbook(file) {
+		if f, err := openWorkbook(file); err == nil {
+			budgets, _, _ := readBudgets(f)
+			f.Close()
+			if len(budgets) > 0 {
+				names := make([]string, len(budgets))
+				for i, b := range budgets {
+					names[i] = b.Category
+				}
+				return names
+			}
+		}
+	}
+	counts := make(map[string]int)
+	names := make(map[string]string)
+	for _, e := range expenses {
+		if e.Amount < 0 && strings.TrimSpace(e.Name) != "" {
+			key := payeeKey(e.Name)
+			counts[key]++
+			names[key] = strings.TrimSpace(e.Name)
+		}
+	}
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	var categories []string
+	for _, key := range keys[:min(len(keys), 8)] {
+		categories = append(categories, names[key])
+	}
+	return categories
+}
+
+// quickForm asks for one expense. Amounts are what was spent, so they are
+// entered as positive numbers.
+func quickForm(categories []string, closed map[time.Time]bool) (Expense, bool, error) {
+	category := quickOther
+	if len(categories) > 0 {
+		category = categories[0]
+	}
+	var name, amount string
+	date := formatExpenseDate(today())
+	another := true
+
+	options := huh.NewOptions(append(append([]string(nil), categories...), quickOther)...)
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().Title("What was it?").Options(options...).Value(&category),
+		),
+		huh.NewGroup(
+			huh.NewInput().Title("Name").Value(&name).Validate(func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return errors.New("enter a name")
+				}
+				return nil
+			}),
+		).WithHideFunc(func() bool { return category != quickOther }),
+		huh.NewGroup(
+			huh.NewInput().Title("How much?").Value(&amount).Validate(func(s string) error {
+				_, err := cfg.Rounding.parse(strings.TrimSpace(s))
+				return err
+			}),
+			huh.NewInput().Title("When? (YYYY-MM-DD)").Value(&date).Validate(func(s string) error {
+				if err := validateDate(s); err != nil {
+					return err
+				}
+				if d := parseExpenseDate(s); !d.IsZero() && closed[monthOf(d)] {
+					return fmt.Errorf("%s is closed", d.Format("January 2006"))
+				}
+				return nil
+			}),
+			huh.NewConfirm().Title("Add another after this one?").Value(&another),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return Expense{}, false, err
+	}
+	if category != quickOther {
+		name = category
+	}
+	amt, err := cfg.Rounding.parse(strings.TrimSpace(amount))
+	if err != nil {
+		return Expense{}, false, err
+	}
+	if amt > 0 {
+		amt = -amt
+	}
+	e := Expense{Name: strings.TrimSpace(name), Amount: amt, Date: parseExpenseDate(date), EnteredBy: currentUser()}
+	return e, another, nil
+}
+
+// runQuick is an entry-only mode for household members who log expenses
+// but should not browse or edit them: it only ever asks for new expenses
+// and adds them, shows nothing of the data but the categories to choose
+// from, and adds them pending when the book has an owner. An expense
+// typed as arguments, as parseQuickEntry reads them, is added alone
+// after a preview.
+func runQuick(args []string) error {
+	flags := flag.NewFlagSet("quick", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "data file to add expenses to")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tet quick [flags] [lunch 12.30 yesterday #food @visa]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	s, err := storeForPath(*file)
+	if err != nil {
+		return err
+	}
+	data, err := s.Load()
+	if err != nil {
+		return err
+	}
+	categories := quickCategories(*file, data.Expenses)
+	var closed map[time.Time]bool
+	if isWorkbook(*file) {
+		if f, err := openWorkbook(*file); err == nil {
+			closed = readClosedMonths(f)
+			f.Close()
+		}
+	}
+
+	if flags.NArg() > 0 {
+		e, err := parseQuickEntry(strings.Join(flags.Args(), " "), now())
+		if err != nil {
+			return fmt.Errorf("quick: %w", err)
+		}
+		if closed[monthOf(e.Date)] {
+			return fmt.Errorf("quick: %s is closed", e.Date.Format("January 2006"))
+		}
+		e.EnteredBy = currentUser()
+		add := true
+		err = huh.NewConfirm().Title("Add " + quickEntryPreview(e) + "?").Value(&add).Run()
+		if errors.Is(err, huh.ErrUserAborted) || (err == nil && !add) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return quickSave(s, e)
+	}
+
+	for {
+		e, another, err := quickForm(categories, closed)
+		if errors.Is(err, huh.ErrUserAborted) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := quickSave(s, e); err != nil {
+			return err
+		}
+		if !another {
+			return nil
+		}
+	}
+}
+
+// quickSave adds e to the store. It reloads first, so entries made
+// elsewhere meanwhile are kept rather than reported as a conflict.
+func quickSave(s Store, e Expense) error {
+	data, err := s.Load()
+	if err != nil {
+		return err
+	}
+	data.Expenses = applyEdit(data.Expenses, -1, e)
+	if err := s.Save(data); err != nil {
+		return err
+	}
+	added := fmt.Sprintf("Added %s %s.", e.Name, money(-e.Amount))
+	if needsApproval() {
+		added = fmt.Sprintf("Added %s %s for %s to approve.", e.Name, money(-e.Amount), cfg.Owner)
+	}
+	fmt.Println(added)
+	return nil
+}
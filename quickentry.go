@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// quickAmount matches the amount of a quick entry: a number with a dot or
+// comma before the decimals, a leading + marking income.
+var quickAmount = regexp.MustCompile(`^[+-]?[0-9]+([.,][0-9]+)?$`)
+
+// parseQuickEntry reads an expense typed on one line, such as
+// "lunch 12.30 yesterday #food #work @visa", with its words in any order:
+//
+//   - the first number is the amount spent, or received when it starts
+//     with +;
+//   - today, yesterday, a weekday (the last one, today included) or a
+//     YYYY-MM-DD date is when, today when there is none;
+//   - the first #word is the category and any further ones are tags;
+//   - an @word is what it was paid with;
+//   - the other words are the name, the category when there are none.
+func parseQuickEntry(line string, now time.Time) (Expense, error) {
+	e := Expense{Date: dateOf(now)}
+	var name []string
+	amount, dated := false, false
+	for _, word := range strings.Fields(line) {
+		lower := strings.ToLower(word)
+		switch {
+		case strings.HasPrefix(word, "#") && len(word) > 1:
+			if e.Category == "" {
+				e.Category = word[1:]
+			} else {
+				e.Tags = append(e.Tags, word[1:])
+			}
+		case strings.HasPrefix(word, "@") && len(word) > 1:
+			e.Payment = word[1:]
+			e.Method = parsePaymentMethod(e.Payment)
+		case !amount && quickAmount.MatchString(word):
+			amt, err := cfg.Rounding.parse(strings.Replace(word, ",", ".", 1))
+			if err != nil {
+				return Expense{}, err
+			}
+			if !strings.HasPrefix(word, "+") && amt > 0 {
+				amt = -amt
+			}
+			e.Amount, amount = amt, true
+		case !dated && !quickDate(lower, now).IsZero():
+			e.Date, dated = quickDate(lower, now), true
+		default:
+			name = append(name, word)
+		}
+	}
+	e.Name = strings.Join(name, " ")
+	if e.Name == "" {
+		e.Name = e.Category
+	}
+	switch {
+	case !amount:
+		return Expense{}, errors.New("no amount: type it as a number, such as 12.30")
+	case e.Name == "":
+		return Expense{}, errors.New("no name: type what it was, or a #category")
+	}
+	return e, nil
+}
+
+// quickDate reads a date word of a quick entry, zero when word is none.
+func quickDate(word string, now time.Time) time.Time {
+	today := dateOf(now)
+	switch word {
+	case "today":
+		return today
+	case "yesterday":
+		return today.AddDate(0, 0, -1)
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if name := strings.ToLower(d.String()); word == name || word == name[:3] {
+			return today.AddDate(0, 0, -((int(today.Weekday()) - int(d) + 7) % 7))
+		}
+	}
+	if t, err := time.Parse(dateLayout, word); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// parseTags reads the Tags column, tags separated by commas; nil when it
+// is blank.
+func parseTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func formatTags(tags []string) string {
+	return strings.Join(tags, ", ")
+}
+
+// quickEntryPreview spells out what a quick entry would add.
+func quickEntryPreview(e Expense) string {
+	s := e.Name + ", "
+	if e.Amount > 0 {
+		s += money(e.Amount) + " received on "
+	} else {
+		s += money(-e.Amount) + " spent on "
+	}
+	s += e.Date.Format("Mon 2 Jan 2006")
+	if e.Category != "" {
+		s += " · category " + e.Category
+	}
+	if len(e.Tags) > 0 {
+		s += " · tags " + formatTags(e.Tags)
+	}
+	if e.Payment != "" {
+		s += " · paid with " + e.Payment
+	}
+	return s
+}
+
+func newQuickInput() textinput.Model {
+	in := textinput.New()
+	in.Placeholder = "lunch 12.30 yesterday #food @visa"
+	in.Prompt = "+ "
+	in.Width = 60
+	return in
+}
+
+// updateQuickAdd handles keys while a quick entry is being typed on the
+// Expenses screen: enter adds it once it parses, esc drops it.
+func (m *model) updateQuickAdd(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.quickAdding = false
+		m.quickInput.Blur()
+		return m, nil
+	case "enter":
+		e, err := parseQuickEntry(m.quickInput.Value(), now())
+		if err != nil {
+			return m, nil
+		}
+		e.EnteredBy = currentUser()
+		m.quickAdding = false
+		m.quickInput.Blur()
+		m.editing = true
+		return m, func() tea.Msg { return expenseEditedMsg{index: -1, expense: e} }
+	}
+	var cmd tea.Cmd
+	m.quickInput, cmd = m.quickInput.Update(key)
+	return m, cmd
+}
+
+// viewQuickAdd is the quick entry line with a preview of what it adds.
+func (m *model) viewQuickAdd() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n" + m.quickInput.View() + "\n")
+	if strings.TrimSpace(m.quickInput.Value()) != "" {
+		if e, err := parseQuickEntry(m.quickInput.Value(), now()); err != nil {
+			buffer.WriteString(errorBannerStyle.Render(err.Error()) + "\n")
+		} else {
+			buffer.WriteString(fmt.Sprintf("Adds %s.\n", quickEntryPreview(e)))
+		}
+	}
+	buffer.WriteString("Press enter to add it, esc to cancel.\n")
+	return buffer.String()
+}
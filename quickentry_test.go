@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuickEntry(t *testing.T) {
+	// A Wednesday.
+	now := time.Date(2026, 10, 14, 18, 30, 0, 0, time.Local)
+	day := func(d int) string { return formatExpenseDate(time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)) }
+	for _, tc := range []struct {
+		line     string
+		name     string
+		amount   float64
+		date     string
+		category string
+		tags     []string
+		payment  string
+	}{
+		{line: "lunch 12.30", name: "lunch", amount: -12.3, date: day(14)},
+		{line: "lunch 12,30", name: "lunch", amount: -12.3, date: day(14)},
+		{line: "refund -5", name: "refund", amount: -5, date: day(14)},
+		{line: "+2500 salary", name: "salary", amount: 2500, date: day(14)},
+		{line: "bus 2 today", name: "bus", amount: -2, date: day(14)},
+		{line: "yesterday taxi 18", name: "taxi", amount: -18, date: day(13)},
+		{line: "wednesday cinema 9", name: "cinema", amount: -9, date: day(14)},
+		{line: "cinema 9 Mon", name: "cinema", amount: -9, date: day(12)},
+		{line: "thu cinema 9", name: "cinema", amount: -9, date: day(8)},
+		{line: "rent 900 2026-10-01", name: "rent", amount: -900, date: day(1)},
+		{line: "2 coffees 7", name: "coffees 7", amount: -2, date: day(14)},
+		{line: "today dinner yesterday 30", name: "dinner yesterday", amount: -30, date: day(14)},
+		{
+			line: "lunch 12.30 yesterday #food #work #team @visa", name: "lunch", amount: -12.3, date: day(13),
+			category: "food", tags: []string{"work", "team"}, payment: "visa",
+		},
+		{line: "#groceries 45.10", name: "groceries", amount: -45.1, date: day(14), category: "groceries"},
+		{line: "# @ 4 snack", name: "# @ snack", amount: -4, date: day(14)},
+	} {
+		t.Run(tc.line, func(t *testing.T) {
+			e, err := parseQuickEntry(tc.line, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []any{e.Name, e.Amount, formatExpenseDate(e.Date), e.Category, e.Tags, e.Payment, e.Method}
+			want := []any{tc.name, tc.amount, tc.date, tc.category, tc.tags, tc.payment, parsePaymentMethod(tc.payment)}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got  %q\nwant %q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseQuickEntryErrors(t *testing.T) {
+	now := time.Date(2026, 10, 14, 18, 30, 0, 0, time.Local)
+	for _, line := range []string{"", "lunch", "lunch yesterday #food", "12.30", "12.30 @visa today"} {
+		if e, err := parseQuickEntry(line, now); err == nil {
+			t.Errorf("%q read as %+v, want an error", line, e)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// quoteConfig turns on refreshing prices while the TUI runs.
+type quoteConfig struct {
+	// Refresh is how often quotes are fetched, e.g. "5m"; empty leaves
+	// prices to be edited in the workbook.
+	Refresh string `json:"refresh,omitempty"`
+	// Exchanges add to or override the built-in trading hours by name.
+	Exchanges map[string]exchangeHours `json:"exchanges,omitempty"`
+	// Symbols name the exchange of symbols not on the default one.
+	Symbols         map[string]string `json:"symbols,omitempty"`
+	DefaultExchange string            `json:"default_exchange,omitempty"`
+	// SaveToWorkbook keeps the fetched quotes and when they were fetched
+	// in a hidden Quotes sheet.
+	SaveToWorkbook bool `json:"save_to_workbook,omitempty"`
+}
+
+func (q quoteConfig) validate() error {
+	if q.Refresh != "" {
+		d, err := time.ParseDuration(q.Refresh)
+		if err != nil {
+			return fmt.Errorf("quotes: refresh: %w", err)
+		}
+		if d < time.Minute {
+			return fmt.Errorf("quotes: refresh must be at least a minute, not %s", d)
+		}
+	}
+	for name, h := range q.Exchanges {
+		if err := h.validate(); err != nil {
+			return fmt.Errorf("quotes: exchange %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// interval is the refresh period, zero when refreshing is off.
+func (q quoteConfig) interval() time.Duration {
+	d, _ := time.ParseDuration(q.Refresh)
+	return d
+}
+
+// quote is the latest trading data of a symbol. Open is zero when the
+// provider does not report it.
+type quote struct {
+	Price, Open, PrevClose float64
+}
+
+type quoteTickMsg struct{}
+
+func quoteTickCmd() tea.Cmd {
+	d := cfg.Quotes.interval()
+	if d == 0 || cfg.Offline {
+		return nil
+	}
+	return tea.Tick(d, func(time.Time) tea.Msg { return quoteTickMsg{} })
+}
+
+// quotesMsg carries the quotes of one refresh, keyed by symbolKey.
+type quotesMsg struct {
+	quotes  map[string]quote
+	fetched time.Time
+	// paused counts the symbols skipped because their market is closed.
+	paused   int
+	provider string
+	failed   []string
+}
+
+// quoteSymbols lists the distinct symbols of the loaded positions and
+// watchlist.
+func (m *model) quoteSymbols() []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	add := func(symbol string) {
+		if k := symbolKey(symbol); k != "" && !seen[k] {
+			seen[k] = true
+			symbols = append(symbols, strings.TrimSpace(symbol))
+		}
+	}
+	for _, s := range m.stonks {
+		add(s.Symbol)
+	}
+	for _, w := range m.watchList {
+		add(w.Symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// refreshQuotesCmd fetches the symbols whose market is open; the others
+// keep their last price rather than showing a flat change.
+func refreshQuotesCmd(symbols []string) tea.Cmd {
+	return func() tea.Msg {
+		msg := quotesMsg{fetched: time.Now()}
+		var open []string
+		for _, symbol := range symbols {
+			// Market hours follow the clock, so that a pretend weekend
+			// pauses them too.
+			if cfg.Quotes.marketOpen(symbol, now()) {
+				open = append(open, symbol)
+			} else {
+				msg.paused++
+			}
+		}
+		msg.provider = yahooQuotes.name
+		msg.quotes, msg.failed = yahooQuotes.fetch(open)
+		return msg
+	}
+}
+
+// applyQuotes updates prices from msg, reporting whether any changed.
+func (m *model) applyQuotes(msg quotesMsg) bool {
+	changed := false
+	if m.quoteCache == nil {
+		m.quoteCache = make(map[string]quoteRecord)
+	}
+	for k, q := range msg.quotes {
+		m.quoteCache[k] = quoteRecord{quote: q, Symbol: k, Fetched: msg.fetched}
+	}
+	for i := range m.stonks {
+		s := &m.stonks[i]
+		q, ok := msg.quotes[symbolKey(s.Symbol)]
+		if !ok {
+			continue
+		}
+		day := 0.0
+		if q.PrevClose > 0 {
+			day = cfg.Rounding.round((q.Price - q.PrevClose) / q.PrevClose * 100)
+		}
+		open := s.Open
+		if q.Open != 0 {
+			open = q.Open
+		}
+		if s.Price != q.Price || s.Open != open || s.DayChange != day {
+			s.Price, s.Open, s.DayChange = q.Price, open, day
+			changed = true
+		}
+	}
+	for i := range m.watchList {
+		w := &m.watchList[i]
+		if q, ok := msg.quotes[symbolKey(w.Symbol)]; ok && w.Price != q.Price {
+			w.Price = q.Price
+			changed = true
+		}
+	}
+	m.quotesPaused = msg.paused
+	if len(msg.failed) > 0 {
+		m.status = fmt.Sprintf("No quote from %s for %s", msg.provider, strings.Join(msg.failed, ", "))
+	}
+	return changed
+}
+
+// quotesLine says when prices are not being refreshed: offline, or on
+// hold for closed markets.
+func (m *model) quotesLine() string {
+	if cfg.Offline {
+		return offlineStyle.Render("Offline: prices are as last saved in the workbook.") + "\n"
+	}
+	if m.quotesPaused == 0 {
+		return ""
+	}
+	if m.quotesPaused == 1 {
+		return "Quotes paused for 1 symbol while its market is closed.\n"
+	}
+	return fmt.Sprintf("Quotes paused for %d symbols while their markets are closed.\n", m.quotesPaused)
+}
+
+// quotesSheet is the hidden sheet quotes are kept in when
+// quotes.save_to_workbook is set, so the workbook shows the prices the TUI
+// last fetched without it.
+const quotesSheet = "Quotes"
+
+var quotesHeaders = []string{"Symbol", "Price", "Open", "Previous Close", "Fetched"}
+
+// quoteRecord is a row of the Quotes sheet.
+type quoteRecord struct {
+	quote
+	Symbol  string
+	Fetched time.Time
+}
+
+// cachedQuotes are the rows to save in the Quotes sheet, nil when that is
+// turned off.
+func (m *model) cachedQuotes() []quoteRecord {
+	if !cfg.Quotes.SaveToWorkbook || len(m.quoteCache) == 0 {
+		return nil
+	}
+	records := make([]quoteRecord, 0, len(m.quoteCache))
+	for _, r := range m.quoteCache {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Symbol < records[j].Symbol })
+	return records
+}
+
+// writeQuotesSheet merges records into the Quotes sheet of filename,
+// keeping quotes of symbols this session did not fetch.
+func writeQuotesSheet(filename string, records []quoteRecord) error {
+	unlock, err := lockFile(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if idx, _ := f.GetSheetIndex(quotesSheet); idx == -1 {
+		if _, err := f.NewSheet(quotesSheet); err != nil {
+			return err
+		}
+		if err := f.SetSheetVisible(quotesSheet, false); err != nil {
+			return err
+		}
+	}
+	rows, err := f.GetRows(quotesSheet)
+	if err != nil {
+		return err
+	}
+	rowOf := make(map[string]int)
+	for i := 1; i < len(rows); i++ {
+		if len(rows[i]) > 0 {
+			rowOf[symbolKey(rows[i][0])] = i + 1
+		}
+	}
+	if err := f.SetSheetRow(quotesSheet, "A1", &quotesHeaders); err != nil {
+		return err
+	}
+	next := max(len(rows), 1) + 1
+	for _, r := range records {
+		row, ok := rowOf[symbolKey(r.Symbol)]
+		if !ok {
+			row = next
+			next++
+		}
+		values := []any{r.Symbol, r.Price, optionalCell(r.Open), optionalCell(r.PrevClose), r.Fetched.Format(time.RFC3339)}
+		if err := f.SetSheetRow(quotesSheet, fmt.Sprintf("A%d", row), &values); err != nil {
+			return err
+		}
+	}
+	return f.Save()
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// receiptsConfig hands receipt images dropped into a folder to an OCR
+// command, and stages what it reads as an expense for review.
+type receiptsConfig struct {
+	// Dir is the folder watched, relative to the data file's unless
+	// absolute; "receipts" by default.
+	Dir string `json:"dir,omitempty"`
+	// Command runs through the shell with $TET_RECEIPT set to the image,
+	// e.g. `ocr-receipt "$TET_RECEIPT"`, and prints a JSON object such as
+	// {"merchant": "Cafe Luna", "total": 12.30, "date": "2025-01-31"}.
+	// Receipts are not watched without one.
+	Command string `json:"command,omitempty"`
+}
+
+// Processed receipts are moved into these folders within the watched one:
+// staged ones so that they are not staged twice, failed ones so that they
+// are not retried until moved back.
+const (
+	receiptsDoneDir   = "processed"
+	receiptsFailedDir = "failed"
+)
+
+// receiptExtensions are the files handed to the command.
+var receiptExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".heic": true, ".webp": true, ".tif": true, ".tiff": true, ".pdf": true,
+}
+
+// pendingReceiptPrefix marks an expense read from a receipt, followed by
+// the receipt's path within the watched folder. It waits on the Review
+// screen like an expense awaiting the owner's approval.
+const pendingReceiptPrefix = "Receipt "
+
+// receiptsDir is the folder watched for the data file at path.
+func receiptsDir(path string) string {
+	dir := cfg.Receipts.Dir
+	if dir == "" {
+		dir = "receipts"
+	}
+	if filepath.IsAbs(dir) || isSheetsPath(path) || isMemoryPath(path) {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(path), dir)
+}
+
+// receiptReading is what the OCR command prints. Total may be a number or
+// text such as "12,30".
+type receiptReading struct {
+	Merchant string `json:"merchant"`
+	Total    any    `json:"total"`
+	Date     string `json:"date"`
+}
+
+// expense is the staged expense a reading becomes: the merchant as its
+// name, the total as spent and, unless it cannot be read, the date.
+func (r receiptReading) expense(receipt string) (Expense, error) {
+	var total float64
+	switch t := r.Total.(type) {
+	case float64:
+		total = t
+	case string:
+		var err error
+		if total, err = strconv.ParseFloat(strings.Replace(strings.TrimSpace(t), ",", ".", 1), 64); err != nil {
+			return Expense{}, fmt.Errorf("total %q is not a number", t)
+		}
+	default:
+		return Expense{}, errors.New("no total")
+	}
+	name := strings.TrimSpace(r.Merchant)
+	if name == "" {
+		name = "Receipt"
+	}
+	e := Expense{
+		Name:      name,
+		Amount:    -cfg.Rounding.round(math.Abs(total)),
+		Date:      parseExpenseDate(r.Date),
+		EnteredBy: currentUser(),
+		Pending:   pendingReceiptPrefix + receipt,
+	}
+	if e.Date.IsZero() {
+		e.Date = today()
+	}
+	return e, nil
+}
+
+func runReceiptCommand(command, image string) (receiptReading, error) {
+	cmd := shellCommand(command)
+	cmd.Env = append(os.Environ(), "TET_RECEIPT="+image)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return receiptReading{}, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var r receiptReading
+	if err := json.Unmarshal(out, &r); err != nil {
+		return receiptReading{}, fmt.Errorf("reading its output: %w", err)
+	}
+	return r, nil
+}
+
+// receiptStagedMsg is a receipt read into an expense, or the error that
+// kept it from being read.
+type receiptStagedMsg struct {
+	file    string
+	expense Expense
+	err     error
+}
+
+// nextReceipt returns the first receipt waiting in dir, or "".
+func nextReceipt(dir string) string {
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && receiptExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			return filepath.Join(dir, entry.Name())
+		}
+	}
+	return ""
+}
+
+// stageReceipt runs the OCR command on file and moves it out of the way,
+// into the processed or failed folder.
+func stageReceipt(dir, file string) receiptStagedMsg {
+	msg := receiptStagedMsg{file: filepath.Base(file)}
+	reading, err := runReceiptCommand(cfg.Receipts.Command, file)
+	if err == nil {
+		msg.expense, err = reading.expense(filepath.Join(receiptsDoneDir, msg.file))
+	}
+	moveTo := receiptsDoneDir
+	if err != nil {
+		msg.err = fmt.Errorf("receipt %s: %w; moved to %s", msg.file, err, receiptsFailedDir)
+		moveTo = receiptsFailedDir
+	}
+	err = os.MkdirAll(filepath.Join(dir, moveTo), 0o755)
+	if err == nil {
+		err = os.Rename(file, filepath.Join(dir, moveTo, msg.file))
+	}
+	if err != nil {
+		// Left where it is, it would be read again and again, so the
+		// folder is no longer watched.
+		return receiptStagedMsg{err: fmt.Errorf("receipts: %w", err)}
+	}
+	return msg
+}
+
+// watchReceiptsCmd stages the next receipt in the folder, waiting for one
+// to be dropped in when there is none. Receipts already there when tet
+// starts are staged first.
+func watchReceiptsCmd(path string) tea.Cmd {
+	if cfg.Receipts.Command == "" {
+		return nil
+	}
+	dir := receiptsDir(path)
+	stop := stopping
+	return func() tea.Msg {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return receiptStagedMsg{err: fmt.Errorf("receipts: %w", err)}
+		}
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return receiptStagedMsg{err: fmt.Errorf("receipts: %w", err)}
+		}
+		defer watcher.Close()
+		if err := watcher.Add(dir); err != nil {
+			return receiptStagedMsg{err: fmt.Errorf("receipts: %w", err)}
+		}
+		for {
+			if file := nextReceipt(dir); file != "" {
+				// Give whatever is copying it in time to finish.
+				clk.Sleep(500 * time.Millisecond)
+				return stageReceipt(dir, file)
+			}
+			select {
+			case <-watcher.Events:
+			case err := <-watcher.Errors:
+				return receiptStagedMsg{err: fmt.Errorf("receipts: %w", err)}
+			case <-stop:
+				return nil
+			}
+		}
+	}
+}
+
+// stageReceiptExpense adds the expense read from a receipt for review and
+// waits for the next receipt.
+func (m *model) stageReceiptExpense(msg receiptStagedMsg) (tea.Model, tea.Cmd) {
+	next := watchReceiptsCmd(m.path)
+	if msg.err != nil {
+		m.status = msg.err.Error()
+		if msg.file == "" {
+			// The folder cannot be watched or receipts moved out of it;
+			// retrying would fail the same way.
+			return m, nil
+		}
+		return m, next
+	}
+	m.expenses = append(m.expenses, msg.expense)
+	m.updateExpensesTable()
+	e := msg.expense
+	m.status = fmt.Sprintf("Read receipt %s: %s %s on %s. Review it on the Review screen.", msg.file, e.Name, money(-e.Amount), formatExpenseDate(e.Date))
+	return m, tea.Batch(next, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/huh"
+)
+
+// maxRecent is how many workbooks the startup picker remembers.
+const maxRecent = 10
+
+// workspace is what the TUI restores when a workbook is reopened.
+type workspace struct {
+	Path string `json:"path"`
+	// Screen is the menu entry that was open, empty for the menu itself.
+	Screen      string    `json:"screen,omitempty"`
+	SelectedRow int       `json:"selected_row,omitempty"`
+	Opened      time.Time `json:"opened"`
+}
+
+// recentPath is recent.json, kept beside the config file.
+func recentPath() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "recent.json"), nil
+}
+
+// loadRecent returns the remembered workspaces, most recent first.
+func loadRecent() ([]workspace, error) {
+	path, err := recentPath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var recent []workspace
+	if err := json.Unmarshal(b, &recent); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return recent, nil
+}
+
+// rememberWorkspace moves ws to the front of the recent list.
+func rememberWorkspace(ws workspace) error {
+	path, err := recentPath()
+	if err != nil {
+		return err
+	}
+	recent, err := loadRecent()
+	if err != nil {
+		return err
+	}
+	list := []workspace{ws}
+	for _, r := range recent {
+		if r.Path != ws.Path && len(list) < maxRecent {
+			list = append(list, r)
+		}
+	}
+	b, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(b, '\n'))
+}
+
+// pickWorkspace chooses the workbook to open. Recent workbooks that still
+// exist are offered in a picker alongside the default one; with nothing
+// else to choose from the default opens straight away.
+func pickWorkspace(defaultPath string) (workspace, error) {
+	abs, err := filepath.Abs(defaultPath)
+	if err != nil {
+		return workspace{}, err
+	}
+	recent, err := loadRecent()
+	if err != nil {
+		return workspace{}, err
+	}
+
+	var choices []workspace
+	hasDefault := false
+	for _, ws := range recent {
+		if _, err := os.Stat(ws.Path); err != nil {
+			continue
+		}
+		hasDefault = hasDefault || ws.Path == abs
+		choices = append(choices, ws)
+	}
+	if !hasDefault {
+		choices = append(choices, workspace{Path: abs})
+	}
+	if len(choices) == 1 {
+		return choices[0], nil
+	}
+
+	options := make([]huh.Option[int], len(choices))
+	for i, ws := range choices {
+		options[i] = huh.NewOption(ws.label(), i)
+	}
+	var picked int
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().Title("Open workbook").Options(options...).Value(&picked),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return workspace{}, err
+	}
+	return choices[picked], nil
+}
+
+func (ws workspace) label() string {
+	s := ws.Path
+	if ws.Screen != "" {
+		s += fmt.Sprintf(" — %s, row %d", ws.Screen, ws.SelectedRow+1)
+	}
+	if !ws.Opened.IsZero() {
+		s += " · " + ws.Opened.Format("2006-01-02 15:04")
+	}
+	return s
+}
+
+// workspace captures what is open, to be restored next time.
+func (m *model) workspace() workspace {
+	ws := workspace{Path: m.path, SelectedRow: m.selectedRow, Opened: time.Now()}
+	switch m.currentScreen {
+	case screenExpenses:
+		ws.Screen = "Expenses"
+	case screenStonks:
+		ws.Screen = "Stonks"
+	case screenWatchlist:
+		ws.Screen = "Watchlist"
+	}
+	return ws
+}
+
+// restore reopens the screen and selection saved in ws. Sheets that screen
+// needs are loaded by Init.
+func (m *model) restore(ws workspace) {
+	switch ws.Screen {
+	case "Expenses":
+		m.currentScreen = screenExpenses
+	case "Stonks":
+		m.currentScreen = screenStonks
+	case "Watchlist":
+		m.currentScreen = screenWatchlist
+	}
+	if ws.SelectedRow > 0 && ws.SelectedRow < len(m.expenses) {
+		m.selectedRow = ws.SelectedRow
+		m.updateExpensesTable()
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// expenseKind says how an expense row counts towards the totals. Amounts
+// follow the sheet's convention of income positive and spending negative.
+type expenseKind string
+
+const (
+	kindExpense expenseKind = ""
+	// kindRefund marks refunds and reimbursements: money coming back for
+	// earlier spending, which reduces what was spent instead of counting
+	// as income.
+	kindRefund expenseKind = "Refund"
+)
+
+// parseExpenseKind reads the Type column, accepting "refund" and
+// "reimbursement" in any case.
+func parseExpenseKind(s string) expenseKind {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "refund", "reimbursement":
+		return kindRefund
+	default:
+		return kindExpense
+	}
+}
+
+// expenseTotals splits a list of expenses into what came in, what went out
+// and what was given back.
+type expenseTotals struct {
+	income float64
+	// spent is the sum of the negative, non-refund rows.
+	spent float64
+	// refunds is the sum of refund rows, always positive whichever sign
+	// they were entered with.
+	refunds float64
+}
+
+func totalsOf(expenses []Expense) expenseTotals {
+	var t expenseTotals
+	for _, e := range expenses {
+		switch {
+		case e.Pending != "":
+			// Not approved yet.
+		case e.Kind == kindRefund:
+			t.refunds += math.Abs(e.Amount)
+		case e.Amount < 0:
+			t.spent += e.Amount
+		default:
+			t.income += e.Amount
+		}
+	}
+	t.income = cfg.Rounding.round(t.income)
+	t.spent = cfg.Rounding.round(t.spent)
+	t.refunds = cfg.Rounding.round(t.refunds)
+	return t
+}
+
+// netSpend is what was spent once refunds are taken off.
+func (t expenseTotals) netSpend() float64 {
+	return cfg.Rounding.round(t.spent + t.refunds)
+}
+
+func (t expenseTotals) String() string {
+	return fmt.Sprintf("Income %s · Spent %s · Refunds %s · Net spend %s",
+		money(t.income), money(t.spent), money(t.refunds), money(t.netSpend()))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type roundingMode string
+
+const (
+	// roundHalfUp rounds halves away from zero: 0.125 -> 0.13.
+	roundHalfUp roundingMode = "half-up"
+	// roundHalfEven is banker's rounding, halves go to the even digit:
+	// 0.125 -> 0.12.
+	roundHalfEven roundingMode = "half-even"
+)
+
+// currencyDecimals lists the currencies whose minor unit is not cents.
+var currencyDecimals = map[string]int{
+	"JPY": 0, "KRW": 0, "ISK": 0, "CLP": 0, "VND": 0,
+	"BHD": 3, "KWD": 3, "OMR": 3, "TND": 3,
+}
+
+// roundingPolicy decides how amounts are rounded when they are parsed,
+// summed, shown and written to the workbook.
+type roundingPolicy struct {
+	Mode roundingMode `json:"mode"`
+	// Currency is the currency amounts are kept in.
+	Currency string `json:"currency"`
+	// Decimals overrides the number of decimals of a currency, keyed by
+	// its ISO code.
+	Decimals map[string]int `json:"decimals,omitempty"`
+}
+
+func (p roundingPolicy) validate() error {
+	switch p.Mode {
+	case roundHalfUp, roundHalfEven:
+	default:
+		return fmt.Errorf("rounding mode %q is not %q or %q", p.Mode, roundHalfUp, roundHalfEven)
+	}
+	for code, d := range p.Decimals {
+		if d < 0 || d > 8 {
+			return fmt.Errorf("%s: decimals must be between 0 and 8", code)
+		}
+	}
+	return nil
+}
+
+// decimals is the number of decimals amounts in the policy's currency have.
+func (p roundingPolicy) decimals() int {
+	code := strings.ToUpper(p.Currency)
+	if d, ok := p.Decimals[code]; ok {
+		return d
+	}
+	if d, ok := currencyDecimals[code]; ok {
+		return d
+	}
+	return 2
+}
+
+// round rounds x to the currency's decimals. It works on the shortest
+// decimal form of x, so 2.675 rounds half-up to 2.68 even though the
+// nearest float is slightly below it.
+func (p roundingPolicy) round(x float64) float64 {
+	s := strconv.FormatFloat(x, 'f', -1, 64)
+	if dot := strings.IndexByte(s, '.'); dot == -1 || len(s)-dot-1 <= p.decimals() {
+		return x
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return x
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.decimals())), nil)
+	r.Mul(r, new(big.Rat).SetInt(scale))
+
+	num, den := r.Num(), r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// Compare twice the remainder's magnitude with the denominator to
+	// find out whether x was below, at or above the halfway point.
+	half := new(big.Int).Abs(rem)
+	half.Lsh(half, 1)
+	step := big.NewInt(int64(num.Sign()))
+	switch half.Cmp(den) {
+	case 1:
+		q.Add(q, step)
+	case 0:
+		if p.Mode == roundHalfUp || q.Bit(0) == 1 {
+			q.Add(q, step)
+		}
+	}
+	out, _ := new(big.Rat).SetFrac(q, scale).Float64()
+	return out
+}
+
+// format renders x rounded to the currency's decimals.
+func (p roundingPolicy) format(x float64) string {
+	return strconv.FormatFloat(p.round(x), 'f', p.decimals(), 64)
+}
+
+// numFmt is the Excel number format showing the currency's decimals.
+func (p roundingPolicy) numFmt() string {
+	if d := p.decimals(); d > 0 {
+		return "0." + strings.Repeat("0", d)
+	}
+	return "0"
+}
+
+// parse reads a user-entered amount and rounds it.
+func (p roundingPolicy) parse(s string) (float64, error) {
+	x, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, err
+	}
+	return p.round(x), nil
+}
+
+// parseAmountCell reads the Amount cell of a row: a number as tet writes
+// it, or text in the layouts spreadsheets kept in other locales store it
+// in, such as "-1.234,56", "1 234,56" or "1,234.56". A blank cell is zero;
+// anything else is an error rather than a silent zero.
+func parseAmountCell(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if x, err := strconv.ParseFloat(s, 64); err == nil {
+		return x, nil
+	}
+	text := strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", "'", "").Replace(s)
+	comma, dot := strings.LastIndex(text, ","), strings.LastIndex(text, ".")
+	switch {
+	case comma > dot:
+		// The comma is the decimal separator, and dots group thousands.
+		text = strings.ReplaceAll(text[:comma], ".", "") + "." + text[comma+1:]
+	case comma >= 0:
+		text = strings.ReplaceAll(text, ",", "")
+	}
+	x, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q is not a number", s)
+	}
+	return x, nil
+}
+
+// money formats an amount with the configured rounding policy.
+func money(x float64) string {
+	return cfg.Rounding.format(x)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	for _, tc := range []struct {
+		mode     roundingMode
+		currency string
+		x, want  float64
+	}{
+		// Below, at and above the halfway point, both ways.
+		{roundHalfUp, "EUR", 0.124, 0.12},
+		{roundHalfUp, "EUR", 0.125, 0.13},
+		{roundHalfUp, "EUR", 0.126, 0.13},
+		{roundHalfEven, "EUR", 0.124, 0.12},
+		{roundHalfEven, "EUR", 0.125, 0.12},
+		{roundHalfEven, "EUR", 0.135, 0.14},
+		{roundHalfEven, "EUR", 0.126, 0.13},
+		// The shortest decimal form counts, not the float below it.
+		{roundHalfUp, "EUR", 2.675, 2.68},
+		{roundHalfEven, "EUR", 2.675, 2.68},
+		{roundHalfEven, "EUR", 2.665, 2.66},
+		// Negative amounts round away from zero, or to the even digit.
+		{roundHalfUp, "EUR", -0.124, -0.12},
+		{roundHalfUp, "EUR", -0.125, -0.13},
+		{roundHalfUp, "EUR", -2.675, -2.68},
+		{roundHalfEven, "EUR", -0.125, -0.12},
+		{roundHalfEven, "EUR", -0.135, -0.14},
+		{roundHalfEven, "EUR", -0.126, -0.13},
+		{roundHalfUp, "EUR", -0.004, 0},
+		{roundHalfUp, "EUR", -0.005, -0.01},
+		{roundHalfEven, "EUR", -0.005, 0},
+		// Amounts already within the decimals are left alone.
+		{roundHalfEven, "EUR", 1.5, 1.5},
+		{roundHalfEven, "EUR", -900, -900},
+		{roundHalfEven, "EUR", 123456789.125, 123456789.12},
+		// Currencies with other minor units.
+		{roundHalfUp, "JPY", 1234.5, 1235},
+		{roundHalfEven, "JPY", 1234.5, 1234},
+		{roundHalfEven, "jpy", 1235.5, 1236},
+		{roundHalfUp, "JPY", -0.5, -1},
+		{roundHalfEven, "JPY", -0.5, 0},
+		{roundHalfUp, "KWD", 1.0005, 1.001},
+		{roundHalfEven, "KWD", 1.0005, 1},
+		{roundHalfEven, "KWD", -1.0015, -1.002},
+	} {
+		p := roundingPolicy{Mode: tc.mode, Currency: tc.currency}
+		if got := p.round(tc.x); got != tc.want {
+			t.Errorf("%s %s: round(%v) = %v, want %v", tc.mode, tc.currency, tc.x, got, tc.want)
+		}
+	}
+}
+
+func TestRoundingDecimals(t *testing.T) {
+	for _, tc := range []struct {
+		p      roundingPolicy
+		x      float64
+		format string
+		numFmt string
+	}{
+		{roundingPolicy{Mode: roundHalfEven, Currency: "EUR"}, -0.125, "-0.12", "0.00"},
+		{roundingPolicy{Mode: roundHalfUp, Currency: "EUR"}, -0.004, "0.00", "0.00"},
+		{roundingPolicy{Mode: roundHalfEven, Currency: "JPY"}, 1234.5, "1234", "0"},
+		{roundingPolicy{Mode: roundHalfUp, Currency: "BHD"}, 2.0005, "2.001", "0.000"},
+		{roundingPolicy{Mode: roundHalfEven, Currency: "EUR", Decimals: map[string]int{"EUR": 0}}, 2.5, "2", "0"},
+		{roundingPolicy{Mode: roundHalfUp, Currency: "JPY", Decimals: map[string]int{"JPY": 2}}, 0.125, "0.13", "0.00"},
+	} {
+		if got := tc.p.format(tc.x); got != tc.format {
+			t.Errorf("%+v: format(%v) = %q, want %q", tc.p, tc.x, got, tc.format)
+		}
+		if got := tc.p.numFmt(); got != tc.numFmt {
+			t.Errorf("%+v: numFmt() = %q, want %q", tc.p, got, tc.numFmt)
+		}
+	}
+}
+
+func TestRoundingPolicyValidate(t *testing.T) {
+	for _, p := range []roundingPolicy{
+		{Mode: "half-down"},
+		{Mode: roundHalfUp, Decimals: map[string]int{"EUR": -1}},
+		{Mode: roundHalfUp, Decimals: map[string]int{"EUR": 9}},
+	} {
+		if err := p.validate(); err == nil {
+			t.Errorf("%+v passed validation", p)
+		}
+	}
+	if err := (roundingPolicy{Mode: roundHalfEven, Decimals: map[string]int{"BTC": 8}}).validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseAmountCell(t *testing.T) {
+	for _, tc := range []struct {
+		cell string
+		want float64
+	}{
+		{"", 0},
+		{"  ", 0},
+		{"-12.5", -12.5},
+		{"1e3", 1000},
+		{"-1.234,56", -1234.56},
+		{"1 234,56", 1234.56},
+		{"1 234,5", 1234.5},
+		{"-1 234,50", -1234.5},
+		{"1'234.50", 1234.5},
+		{"1,234.56", 1234.56},
+		{"12,30", 12.3},
+		{"-0,5", -0.5},
+		// A lone dot is always the decimal separator.
+		{"1.234", 1.234},
+	} {
+		got, err := parseAmountCell(tc.cell)
+		if err != nil || math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("parseAmountCell(%q) = %v, %v; want %v", tc.cell, got, err, tc.want)
+		}
+	}
+	for _, cell := range []string{"n/a", "12-", "1,2,3", "€12"} {
+		if got, err := parseAmountCell(cell); err == nil {
+			t.Errorf("parseAmountCell(%q) = %v, want an error", cell, got)
+		}
+	}
+}
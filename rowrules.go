@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// rowRule styles the rows of the expenses table matching a condition, over
+// the table's own colours: When compares a field of the expense with a
+// value, such as `amount < -100` or `category == "Eating out"`.
+type rowRule struct {
+	When string `json:"when"`
+	// Color and Background are colour names, ANSI numbers such as "203"
+	// or hex codes such as "#ff5f5f".
+	Color      string `json:"color,omitempty"`
+	Background string `json:"background,omitempty"`
+	Bold       bool   `json:"bold,omitempty"`
+	Italic     bool   `json:"italic,omitempty"`
+	Underline  bool   `json:"underline,omitempty"`
+
+	// field, op and value are When taken apart by compile; number is the
+	// value of amount conditions.
+	field, op, value string
+	number           float64
+}
+
+// rowRules apply in order, so a later rule overrides the colours of an
+// earlier one where both match.
+type rowRules []rowRule
+
+// ruleFields are the fields rules can test, by name.
+var ruleFields = map[string]func(e Expense) string{
+	"name":     func(e Expense) string { return e.Name },
+	"amount":   func(e Expense) string { return strconv.FormatFloat(e.Amount, 'f', -1, 64) },
+	"date":     func(e Expense) string { return formatExpenseDate(e.Date) },
+	"category": func(e Expense) string { return e.Category },
+	"tags":     func(e Expense) string { return formatTags(e.Tags) },
+	"payment":  func(e Expense) string { return e.Payment },
+	"method":   func(e Expense) string { return string(e.Method) },
+	"member":   func(e Expense) string { return e.Member },
+	"by":       func(e Expense) string { return e.EnteredBy },
+	"type": func(e Expense) string {
+		if e.Kind == kindRefund {
+			return "refund"
+		}
+		return "expense"
+	},
+}
+
+// ruleOps are the comparisons of a rule, longest first so that ">=" is not
+// read as ">".
+var ruleOps = []string{"==", "!=", ">=", "<=", "contains", ">", "<", "="}
+
+// ruleColors names the colours rules can use by name.
+var ruleColors = map[string]string{
+	"black": "0", "red": "203", "green": "42", "yellow": "227", "orange": "214",
+	"blue": "33", "magenta": "170", "purple": "57", "cyan": "51", "white": "15",
+	"gray": "245", "grey": "245",
+}
+
+// compile takes the conditions apart, so that a mistake in one is
+// reported when the config is read rather than ignored on screen.
+func (r rowRules) compile() error {
+	for i := range r {
+		if err := r[i].compile(); err != nil {
+			return fmt.Errorf("row_rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (r *rowRule) compile() error {
+	s := strings.TrimSpace(r.When)
+	end := strings.IndexFunc(s, func(c rune) bool { return !unicode.IsLetter(c) })
+	if end < 0 {
+		end = len(s)
+	}
+	r.field = strings.ToLower(s[:end])
+	if _, ok := ruleFields[r.field]; !ok {
+		fields := make([]string, 0, len(ruleFields))
+		for f := range ruleFields {
+			fields = append(fields, f)
+		}
+		slices.Sort(fields)
+		return fmt.Errorf("when %q must start with one of %s", r.When, strings.Join(fields, ", "))
+	}
+	rest := strings.TrimSpace(s[end:])
+	r.op = ""
+	for _, op := range ruleOps {
+		if strings.HasPrefix(strings.ToLower(rest), op) {
+			r.op = op
+			break
+		}
+	}
+	if r.op == "" {
+		return fmt.Errorf("when %q needs one of ==, !=, >, >=, <, <= or contains after %s", r.When, r.field)
+	}
+	if r.op == "=" {
+		r.op = "=="
+		rest = rest[1:]
+	} else {
+		rest = rest[len(r.op):]
+	}
+	r.value = strings.TrimSpace(rest)
+	if strings.HasPrefix(r.value, `"`) {
+		v, err := strconv.Unquote(r.value)
+		if err != nil {
+			return fmt.Errorf("when %q: %w", r.When, err)
+		}
+		r.value = v
+	}
+	if r.field == "amount" {
+		if r.op == "contains" {
+			return fmt.Errorf("when %q: amounts are compared with ==, !=, >, >=, < or <=", r.When)
+		}
+		n, err := strconv.ParseFloat(r.value, 64)
+		if err != nil {
+			return fmt.Errorf("when %q: %q is not a number", r.When, r.value)
+		}
+		r.number = n
+	}
+	for _, c := range []string{r.Color, r.Background} {
+		if c != "" && ruleColor(c) == "" {
+			return fmt.Errorf("unknown colour %q; use a name such as red, an ANSI number or a #rrggbb code", c)
+		}
+	}
+	return nil
+}
+
+// ruleColor resolves a colour of a rule, empty when it is not one.
+func ruleColor(c string) string {
+	c = strings.ToLower(strings.TrimSpace(c))
+	if code, ok := ruleColors[c]; ok {
+		return code
+	}
+	if n, err := strconv.Atoi(c); err == nil && n >= 0 && n <= 255 {
+		return c
+	}
+	if len(c) == 7 && c[0] == '#' {
+		if _, err := strconv.ParseUint(c[1:], 16, 32); err == nil {
+			return c
+		}
+	}
+	return ""
+}
+
+// matches reports whether e meets the rule's condition. Text is compared
+// without regard to case, and tags match when any tag does.
+func (r rowRule) matches(e Expense) bool {
+	if r.field == "amount" {
+		switch r.op {
+		case "==":
+			return e.Amount == r.number
+		case "!=":
+			return e.Amount != r.number
+		case ">":
+			return e.Amount > r.number
+		case ">=":
+			return e.Amount >= r.number
+		case "<":
+			return e.Amount < r.number
+		case "<=":
+			return e.Amount <= r.number
+		}
+		return false
+	}
+	if r.field == "tags" && r.op != "contains" {
+		for _, t := range e.Tags {
+			if r.compare(t) {
+				return true
+			}
+		}
+		return r.op == "!=" && len(e.Tags) == 0
+	}
+	return r.compare(ruleFields[r.field](e))
+}
+
+// compare tests text against the rule's value. Dates compare in order, as
+// they are written year first.
+func (r rowRule) compare(text string) bool {
+	a, b := strings.ToLower(text), strings.ToLower(r.value)
+	switch r.op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "contains":
+		return strings.Contains(a, b)
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+// style applies the rules e matches over base.
+func (r rowRules) style(base lipgloss.Style, e Expense) lipgloss.Style {
+	for _, rule := range r {
+		if !rule.matches(e) {
+			continue
+		}
+		if rule.Color != "" {
+			base = base.Foreground(lipgloss.Color(ruleColor(rule.Color)))
+		}
+		if rule.Background != "" {
+			base = base.Background(lipgloss.Color(ruleColor(rule.Background)))
+		}
+		if rule.Bold {
+			base = base.Bold(true)
+		}
+		if rule.Italic {
+			base = base.Italic(true)
+		}
+		if rule.Underline {
+			base = base.Underline(true)
+		}
+	}
+	return base
+}
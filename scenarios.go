@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+	"github.com/xuri/excelize/v2"
+)
+
+// A budget scenario is another set of budgets to plan with, such as for a
+// new apartment or a car purchase, kept in a sheet laid out like the
+// Budgets sheet and named "Budgets (New Apartment)". The Budgets sheet
+// itself is the baseline scenario.
+const (
+	scenarioPrefix   = budgetsSheet + " ("
+	scenarioSuffix   = ")"
+	baselineScenario = "Baseline"
+)
+
+type budgetScenario struct {
+	Name    string
+	Budgets []budget
+}
+
+// scenarioName is the scenario a sheet holds, false when it holds none.
+func scenarioName(sheet string) (string, bool) {
+	name, ok := strings.CutPrefix(sheet, scenarioPrefix)
+	if !ok {
+		return "", false
+	}
+	name, ok = strings.CutSuffix(name, scenarioSuffix)
+	name = strings.TrimSpace(name)
+	return name, ok && name != ""
+}
+
+// readScenarios reads the baseline, when the workbook has a Budgets
+// sheet, and then every scenario sheet in the workbook's order.
+func readScenarios(f *excelize.File) ([]budgetScenario, error) {
+	var scenarios []budgetScenario
+	if budgets, found, err := readBudgets(f); err != nil {
+		return nil, fmt.Errorf("%s sheet: %w", budgetsSheet, err)
+	} else if found {
+		scenarios = append(scenarios, budgetScenario{Name: baselineScenario, Budgets: budgets})
+	}
+	for _, sheet := range f.GetSheetList() {
+		name, ok := scenarioName(sheet)
+		if !ok {
+			continue
+		}
+		budgets, _, err := readBudgetSheet(f, sheet)
+		if err != nil {
+			return nil, fmt.Errorf("%s sheet: %w", sheet, err)
+		}
+		scenarios = append(scenarios, budgetScenario{Name: name, Budgets: budgets})
+	}
+	return scenarios, nil
+}
+
+// scenarioProjection is how the year is expected to end under a scenario.
+type scenarioProjection struct {
+	Name string
+	// Budgeted is the scenario's budgets over a month.
+	Budgeted float64
+	// Monthly is the net of each month still to come: income less the
+	// scenario's budgets and the usual spending outside them.
+	Monthly float64
+	// YearEnd is the net of the whole year.
+	YearEnd float64
+}
+
+// projectScenarios projects the year of month under each scenario. The
+// months of the year before month count as they were; month and each one
+// after it to December spend the scenario's budgets in full, without
+// rollover, and everything else, income included, as it went on average
+// over the history months before month: twelve, or fewer when the
+// expenses start later. actual is the net of the months before month.
+func projectScenarios(scenarios []budgetScenario, expenses []Expense, month time.Time) (actual float64, history int, projections []scenarioProjection) {
+	spending := monthlySpending(expenses)
+	month = calendarMonth(month)
+	yearStart := time.Date(month.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	start := month
+	for _, byMonth := range spending {
+		for m, spent := range byMonth {
+			if !m.Before(yearStart) && m.Before(month) {
+				actual -= spent
+			}
+			if m.Before(start) {
+				start = m
+			}
+		}
+	}
+	start = later(start, month.AddDate(0, -12, 0))
+	for m := start; m.Before(month); m = m.AddDate(0, 1, 0) {
+		history++
+	}
+	remaining := float64(13 - int(month.Month()))
+	for _, s := range scenarios {
+		p := scenarioProjection{Name: s.Name}
+		// A subcategory's budget is part of its parent's, when that has
+		// one too.
+		categories := budgetCategories(s.Budgets)
+		for _, b := range s.Budgets {
+			if !nestedCategory(b.Category, categories) {
+				p.Budgeted += b.Limit
+			}
+		}
+		var other float64
+		for category, byMonth := range spending {
+			if slices.ContainsFunc(categories, func(c string) bool { return withinCategory(category, categoryKey(c)) }) {
+				continue
+			}
+			for m, spent := range byMonth {
+				if !m.Before(start) && m.Before(month) {
+					other += spent
+				}
+			}
+		}
+		if history > 0 {
+			other /= float64(history)
+		}
+		p.Budgeted = cfg.Rounding.round(p.Budgeted)
+		p.Monthly = cfg.Rounding.round(-(p.Budgeted + other))
+		p.YearEnd = cfg.Rounding.round(actual + remaining*p.Monthly)
+		projections = append(projections, p)
+	}
+	return cfg.Rounding.round(actual), history, projections
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// scenarioTable compares the projections side by side, each year-end
+// against the first scenario's.
+func scenarioTable(projections []scenarioProjection) string {
+	headers := []string{"Scenario", "Budgets / Month", "Net / Month", "Year-End", "vs " + projections[0].Name}
+	var data [][]string
+	for _, p := range projections {
+		data = append(data, []string{p.Name, money(p.Budgeted), money(p.Monthly), money(p.YearEnd),
+			money(cfg.Rounding.round(p.YearEnd - projections[0].YearEnd))})
+	}
+
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	aheadStyle := baseStyle.Foreground(lipgloss.Color("42"))
+	behindStyle := baseStyle.Foreground(lipgloss.Color("203"))
+
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			var v float64
+			switch col {
+			case 3:
+				v = projections[row].YearEnd
+			case 4:
+				v = projections[row].YearEnd - projections[0].YearEnd
+			}
+			switch {
+			case v > 0:
+				return aheadStyle
+			case v < 0:
+				return behindStyle
+			}
+			return rowStyle
+		})
+	return t.String()
+}
+
+// viewScenarios is the Budgets screen's comparison of the scenarios from
+// m.budgetMonth to the end of its year.
+func (m *model) viewScenarios() string {
+	if len(m.budgetScenarios) == 0 {
+		return fmt.Sprintf("No budget scenarios yet. Copy the %s sheet to one named like %q and change its budgets to compare the year's end under each.\n",
+			budgetsSheet, scenarioPrefix+"New Apartment"+scenarioSuffix)
+	}
+	actual, history, projections := projectScenarios(m.budgetScenarios, m.inBase(m.expenses), m.budgetMonth)
+	var b strings.Builder
+	b.WriteString(scenarioTable(projections))
+	b.WriteString("\n")
+	if m.budgetMonth.Month() != time.January {
+		b.WriteString(fmt.Sprintf("Net %s from January to %s, as it was.\n", money(actual), m.budgetMonth.AddDate(0, -1, 0).Format("January")))
+	}
+	b.WriteString(fmt.Sprintf("From %s to December, each scenario spends its budgets in full, and everything else, income included, goes as it did on average over the last %d months.\n",
+		m.budgetMonth.Format("January"), history))
+	return b.String()
+}
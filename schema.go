@@ -0,0 +1,479 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// metaSheet is a hidden sheet of key/value pairs describing the workbook
+// itself, such as its schema version.
+const metaSheet = "Meta"
+
+// schemaMigration upgrades a workbook from one layout to the next.
+type schemaMigration struct {
+	description string
+	apply       func(f *excelize.File) error
+}
+
+// migrations are applied in order; a workbook at version N has had the
+// first N applied. Only ever append to this list.
+var migrations = []schemaMigration{
+	{"create missing tracker sheets", addMissingSheets},
+	{"add the expense Type column", addExpenseType},
+	{"add the expense Date column", addExpenseDate},
+	{"add watchlist targets and make Qty numeric", addWatchTargets},
+	{"derive watchlist ownership from Stonks positions", linkOwnedPositions},
+	{"replace Stonks Change and Extra with position columns", addPositionColumns},
+	{"add the Stonks Open column", addOpenColumn},
+	{"add the Stonks Currency column", addCurrencyColumn},
+	{"add the expense Entered By column", addEnteredBy},
+	{"add the expense Pending column", addPending},
+	{"add the expense Category column", addCategory},
+	{"add the expense Tags column", addTags},
+	{"add the expense Payment column", addPayment},
+	{"add the expense Currency column", addExpenseCurrency},
+	{"add the expense Note column", addExpenseNote},
+	{"add the expense Method column", addExpenseMethod},
+	{"add the expense Member column", addExpenseMember},
+	{"add the expense Return By and Warranty columns", addExpenseGoods},
+	{"add the expense Usage column", addExpenseUsage},
+}
+
+// schemaVersion is the layout version this build reads and writes.
+var schemaVersion = len(migrations)
+
+func getMeta(f *excelize.File, key string) string {
+	rows, err := f.GetRows(metaSheet)
+	if err != nil {
+		return ""
+	}
+	for _, row := range rows {
+		if len(row) >= 2 && row[0] == key {
+			return row[1]
+		}
+	}
+	return ""
+}
+
+func setMeta(f *excelize.File, key, value string) error {
+	if idx, _ := f.GetSheetIndex(metaSheet); idx == -1 {
+		if _, err := f.NewSheet(metaSheet); err != nil {
+			return err
+		}
+		if err := f.SetSheetVisible(metaSheet, false); err != nil {
+			return err
+		}
+	}
+	rows, err := f.GetRows(metaSheet)
+	if err != nil {
+		return err
+	}
+	row := len(rows) + 1
+	for i, r := range rows {
+		if len(r) > 0 && r[0] == key {
+			row = i + 1
+			break
+		}
+	}
+	return f.SetSheetRow(metaSheet, fmt.Sprintf("A%d", row), &[]string{key, value})
+}
+
+// workbookVersion reports the schema version recorded in f; workbooks
+// created before versioning existed are version 0.
+func workbookVersion(f *excelize.File) int {
+	v, _ := strconv.Atoi(getMeta(f, "schema_version"))
+	return v
+}
+
+// upgradeWorkbook brings filename up to schemaVersion in place. The original
+// file is copied aside first and the backup path returned; it is empty when
+// the workbook was already current.
+func upgradeWorkbook(filename string) (string, error) {
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	version := workbookVersion(f)
+	if version > schemaVersion {
+		return "", fmt.Errorf("%s uses schema version %d, newer than this build supports (%d)",
+			filename, version, schemaVersion)
+	}
+	if version == schemaVersion {
+		return "", nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d-%s.bak", filename, version, now().Format("20060102-150405"))
+	if err := copyFile(filename, backup); err != nil {
+		return "", fmt.Errorf("backing up before upgrade: %w", err)
+	}
+	if err := applyMigrations(f); err != nil {
+		return backup, err
+	}
+	return backup, f.Save()
+}
+
+// applyMigrations upgrades the open workbook f in memory without saving it,
+// which is also how read-only copies such as snapshots are read.
+func applyMigrations(f *excelize.File) error {
+	version := workbookVersion(f)
+	if version > schemaVersion {
+		return fmt.Errorf("workbook uses schema version %d, newer than this build supports (%d)",
+			version, schemaVersion)
+	}
+	if version == schemaVersion {
+		return nil
+	}
+	for i := version; i < schemaVersion; i++ {
+		if err := migrations[i].apply(f); err != nil {
+			return fmt.Errorf("upgrade to v%d (%s): %w", i+1, migrations[i].description, err)
+		}
+	}
+	return setMeta(f, "schema_version", strconv.Itoa(schemaVersion))
+}
+
+func copyFile(src, dst string) error {
+	in, err := files.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := files.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// Snapshots are listed by modification time, which follows the clock.
+	t := now()
+	return files.Chtimes(dst, t, t)
+}
+
+// addMissingSheets is migration 1: older workbooks were hand-made and may
+// lack some of the tracker's sheets entirely.
+func addMissingSheets(f *excelize.File) error {
+	for _, name := range sheetNames {
+		if idx, _ := f.GetSheetIndex(name); idx != -1 {
+			continue
+		}
+		if _, err := f.NewSheet(name); err != nil {
+			return err
+		}
+		header := sheetHeaders[name]
+		if err := f.SetSheetRow(name, "A1", &header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasCurrentHeaders reports whether sheet already has this build's header
+// row, as sheets created by addMissingSheets do. Later migrations leave
+// such sheets alone.
+func hasCurrentHeaders(f *excelize.File, sheet string) bool {
+	rows, err := f.GetRows(sheet)
+	if err != nil || len(rows) == 0 {
+		return false
+	}
+	want := sheetHeaders[sheet]
+	if len(rows[0]) != len(want) {
+		return false
+	}
+	for i, h := range want {
+		if rows[0][i] != h {
+			return false
+		}
+	}
+	return true
+}
+
+// storedSheetName returns sheet as it is spelled in f. Sheet lookups ignore
+// case, but excelize only shifts cells on InsertCols and RemoveCol when the
+// name matches exactly, so workbooks with e.g. a "Watchlist" sheet need it.
+func storedSheetName(f *excelize.File, sheet string) string {
+	for _, name := range f.GetSheetList() {
+		if strings.EqualFold(name, sheet) {
+			return name
+		}
+	}
+	return sheet
+}
+
+// ensureColumn inserts a column headed header at col, shifting anything
+// already there (such as the expense total) to the right. Sheets created
+// with the current headers already have it and are left alone.
+func ensureColumn(f *excelize.File, sheet, col, header string) error {
+	sheet = storedSheetName(f, sheet)
+	if v, _ := f.GetCellValue(sheet, col+"1"); v == header {
+		return nil
+	}
+	if err := f.InsertCols(sheet, col, 1); err != nil {
+		return err
+	}
+	return f.SetCellValue(sheet, col+"1", header)
+}
+
+// addExpenseType is migration 2: a Type column marking refunds and
+// reimbursements, inserted before the total in C2:D2.
+func addExpenseType(f *excelize.File) error {
+	if hasCurrentHeaders(f, "Expenses") {
+		return nil
+	}
+	return ensureColumn(f, "Expenses", "C", "Type")
+}
+
+// addExpenseDate is migration 3: a Date column, left empty for existing
+// rows, which stay undated.
+func addExpenseDate(f *excelize.File) error {
+	if hasCurrentHeaders(f, "Expenses") {
+		return nil
+	}
+	return ensureColumn(f, "Expenses", "D", "Date")
+}
+
+// addWatchTargets is migration 4: target price, last price and note columns
+// for the watchlist. Qty used to be free text; text that is not a number
+// moves to the note so nothing is lost when Qty is saved as a number.
+func addWatchTargets(f *excelize.File) error {
+	if hasCurrentHeaders(f, "WatchList") {
+		return nil
+	}
+	for i, header := range []string{"Target Buy", "Target Sell", "Price", "Note"} {
+		col, _ := excelize.ColumnNumberToName(4 + i)
+		if err := ensureColumn(f, "WatchList", col, header); err != nil {
+			return err
+		}
+	}
+	rows, err := f.GetRows("WatchList")
+	if err != nil {
+		return err
+	}
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		if len(row) < 2 || row[1] == "" {
+			continue
+		}
+		note := ""
+		if len(row) > 6 {
+			note = row[6]
+		}
+		qty, note := parseQty(row[1], note)
+		if err := f.SetCellValue("WatchList", fmt.Sprintf("B%d", i+1), qty); err != nil {
+			return err
+		}
+		if err := f.SetCellValue("WatchList", fmt.Sprintf("G%d", i+1), note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkOwnedPositions is migration 5: the watchlist's Yes/No Owned column is
+// dropped, as ownership now follows the Stonks sheet. Items marked owned
+// without a position there get one, so none of them stop being owned.
+func linkOwnedPositions(f *excelize.File) error {
+	if hasCurrentHeaders(f, "WatchList") {
+		return nil
+	}
+	watch, err := f.GetRows("WatchList")
+	if err != nil {
+		return err
+	}
+	stonks, err := f.GetRows("Stonks")
+	if err != nil {
+		return err
+	}
+	held := make(map[string]bool)
+	for _, row := range stonks {
+		if len(row) > 0 {
+			held[symbolKey(row[0])] = true
+		}
+	}
+	next := len(stonks) + 1
+	for _, row := range watch[min(1, len(watch)):] {
+		if len(row) < 3 || row[2] != "Yes" || row[0] == "" || held[symbolKey(row[0])] {
+			continue
+		}
+		position := []any{row[0], 0, "Owned (moved from the watchlist)", 0}
+		if err := f.SetSheetRow("Stonks", fmt.Sprintf("A%d", next), &position); err != nil {
+			return err
+		}
+		held[symbolKey(row[0])] = true
+		next++
+	}
+	return f.RemoveCol(storedSheetName(f, "WatchList"), "C")
+}
+
+// addPositionColumns is migration 6: Stonks rows go from Symbol, Change,
+// Comment, Extra to explicit position columns. Change becomes Day Change %
+// and Extra, which had no defined meaning, is kept in the comment;
+// quantities and prices are left for the user to fill in.
+func addPositionColumns(f *excelize.File) error {
+	if hasCurrentHeaders(f, "Stonks") {
+		return nil
+	}
+	rows, err := f.GetRows("Stonks")
+	if err != nil {
+		return err
+	}
+	header := []string{"Symbol", "Quantity", "Avg Price", "Price", "Day Change %", "Total Return", "Comment"}
+	if err := f.SetSheetRow("Stonks", "A1", &header); err != nil {
+		return err
+	}
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		cell := func(i int) string {
+			if i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		change, comment := parseQty(cell(1), legacyExtra(cell(2), cell(3)))
+		values := []any{cell(0), nil, nil, nil, optionalCell(change), nil, comment}
+		if err := f.SetSheetRow("Stonks", fmt.Sprintf("A%d", i+1), &values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addOpenColumn is migration 7: the day's opening price, which intraday
+// change is measured from.
+func addOpenColumn(f *excelize.File) error {
+	if hasCurrentHeaders(f, "Stonks") {
+		return nil
+	}
+	return ensureColumn(f, "Stonks", "E", "Open")
+}
+
+// addCurrencyColumn is migration 8: the currency each position is quoted
+// in, blank for the configured one.
+func addCurrencyColumn(f *excelize.File) error {
+	if hasCurrentHeaders(f, "Stonks") {
+		return nil
+	}
+	return ensureColumn(f, "Stonks", "H", "Currency")
+}
+
+// addEnteredBy is migration 9: an Entered By column attributing expenses
+// in shared books, left empty for existing rows.
+func addEnteredBy(f *excelize.File) error {
+	return ensureExpenseColumn(f, "E", "Entered By")
+}
+
+// addPending is migration 10: a Pending column for changes awaiting the
+// owner's approval. Existing rows are approved.
+func addPending(f *excelize.File) error {
+	return ensureExpenseColumn(f, "F", "Pending")
+}
+
+// addCategory is migration 11: the budget category of each expense, blank
+// for existing rows, which count towards their name as before.
+func addCategory(f *excelize.File) error {
+	return ensureExpenseColumn(f, "G", "Category")
+}
+
+// addTags is migration 12: labels for each expense, comma-separated.
+func addTags(f *excelize.File) error {
+	return ensureExpenseColumn(f, "H", "Tags")
+}
+
+// addPayment is migration 13: what each expense was paid with.
+func addPayment(f *excelize.File) error {
+	return ensureExpenseColumn(f, "I", "Payment")
+}
+
+// addExpenseCurrency is migration 14: the currency of each expense, blank
+// for existing rows, which are in the configured one.
+func addExpenseCurrency(f *excelize.File) error {
+	return ensureExpenseColumn(f, "J", "Currency")
+}
+
+// addExpenseNote is migration 15: a note on each expense, blank for
+// existing rows.
+func addExpenseNote(f *excelize.File) error {
+	return ensureExpenseColumn(f, "K", "Note")
+}
+
+// addExpenseMethod is migration 16: how each expense was paid. Existing
+// rows whose Payment names a method, such as "card" or "cash", take it;
+// the rest are left blank.
+func addExpenseMethod(f *excelize.File) error {
+	if err := ensureExpenseColumn(f, "L", "Method"); err != nil {
+		return err
+	}
+	sheets := []string{"Expenses"}
+	for _, sheet := range f.GetSheetList() {
+		if strings.HasPrefix(sheet, archiveSheetPrefix) {
+			sheets = append(sheets, sheet)
+		}
+	}
+	for _, sheet := range sheets {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			return err
+		}
+		for i := 1; i < len(rows); i++ {
+			if len(rows[i]) <= 8 || (len(rows[i]) > 11 && rows[i][11] != "") {
+				continue
+			}
+			if method := parsePaymentMethod(rows[i][8]); method != methodUnknown {
+				cell, _ := excelize.CoordinatesToCellName(12, i+1)
+				if err := f.SetCellStr(sheet, cell, string(method)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addExpenseMember is migration 17: the household member each expense was
+// for, blank for existing rows.
+func addExpenseMember(f *excelize.File) error {
+	return ensureExpenseColumn(f, "M", "Member")
+}
+
+// addExpenseGoods is migration 18: the last day goods bought can be
+// returned and the months of their warranty, blank for existing rows.
+func addExpenseGoods(f *excelize.File) error {
+	if err := ensureExpenseColumn(f, "N", "Return By"); err != nil {
+		return err
+	}
+	return ensureExpenseColumn(f, "O", "Warranty")
+}
+
+// addExpenseUsage is migration 19: how much of a utility, such as kWh of
+// electricity, a bill was for, blank for existing rows.
+func addExpenseUsage(f *excelize.File) error {
+	return ensureExpenseColumn(f, "P", "Usage")
+}
+
+// ensureExpenseColumn adds a column to Expenses and, since they are laid
+// out like it, to the archive sheets.
+func ensureExpenseColumn(f *excelize.File, col, header string) error {
+	for _, sheet := range f.GetSheetList() {
+		if !strings.HasPrefix(sheet, archiveSheetPrefix) {
+			continue
+		}
+		if err := ensureColumn(f, sheet, col, header); err != nil {
+			return err
+		}
+	}
+	if hasCurrentHeaders(f, "Expenses") {
+		return nil
+	}
+	return ensureColumn(f, "Expenses", col, header)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// expenseSearch narrows the expenses table to the rows matching what was
+// typed after '/'. The zero value matches every row.
+type expenseSearch struct {
+	// text is the search as typed.
+	text string
+	// words must each be found in the name or category.
+	words []string
+	// low and high bound the amount spent or received, either way; high
+	// is +Inf when there is no upper bound.
+	low, high float64
+}
+
+var (
+	// searchRange matches an amount range such as 10-50 or 10..50.
+	searchRange = regexp.MustCompile(`^([0-9]+(?:[.,][0-9]+)?)(?:-|\.\.)([0-9]+(?:[.,][0-9]+)?)$`)
+	// searchBound matches an amount bound such as >20 or <=100.
+	searchBound = regexp.MustCompile(`^(>=|<=|>|<)([0-9]+(?:[.,][0-9]+)?)$`)
+)
+
+// parseSearch reads a search: amount ranges (10-50, 10..50) and bounds
+// (>20, <=100) on the amount, and any other words to look for in the
+// name or category, in any case.
+func parseSearch(text string) expenseSearch {
+	s := expenseSearch{text: strings.TrimSpace(text), high: math.Inf(1)}
+	amount := func(a string) float64 {
+		v, _ := cfg.Rounding.parse(strings.Replace(a, ",", ".", 1))
+		return v
+	}
+	for _, word := range strings.Fields(text) {
+		if m := searchRange.FindStringSubmatch(word); m != nil {
+			low, high := amount(m[1]), amount(m[2])
+			s.low, s.high = max(s.low, min(low, high)), min(s.high, max(low, high))
+			continue
+		}
+		if m := searchBound.FindStringSubmatch(word); m != nil {
+			switch v := amount(m[2]); m[1] {
+			case ">", ">=":
+				s.low = max(s.low, v)
+			case "<", "<=":
+				s.high = min(s.high, v)
+			}
+			continue
+		}
+		s.words = append(s.words, strings.ToLower(word))
+	}
+	return s
+}
+
+// active reports whether the search filters out anything.
+func (s expenseSearch) active() bool {
+	return s.text != ""
+}
+
+func (s expenseSearch) matches(e Expense) bool {
+	if !s.active() {
+		return true
+	}
+	if a := math.Abs(e.Amount); a < s.low || a > s.high {
+		return false
+	}
+	text := strings.ToLower(e.Name + " " + e.Category)
+	for _, w := range s.words {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func newSearchInput() textinput.Model {
+	in := textinput.New()
+	in.Placeholder = "coffee >5, rent 500-900"
+	in.Prompt = "/ "
+	in.Width = 60
+	return in
+}
+
+// startSearch opens the search line of the Expenses screen, with the
+// current search to refine.
+func (m *model) startSearch() (tea.Model, tea.Cmd) {
+	m.searching = true
+	m.searchInput.SetValue(m.search.text)
+	m.searchInput.CursorEnd()
+	return m, m.searchInput.Focus()
+}
+
+// updateSearch handles keys while a search is typed, narrowing the table
+// at each one: enter keeps the search, esc drops it.
+func (m *model) updateSearch(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "enter":
+		m.searching = false
+		m.searchInput.Blur()
+		return m, nil
+	case "esc":
+		m.searching = false
+		m.searchInput.Blur()
+		m.searchInput.SetValue("")
+		m.setSearch("")
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(key)
+	if m.searchInput.Value() != m.search.text {
+		m.setSearch(m.searchInput.Value())
+	}
+	return m, cmd
+}
+
+// setSearch filters the table by text, keeping the selected expense
+// selected while it is still shown.
+func (m *model) setSearch(text string) {
+	_, before := m.visibleRows()
+	selected := -1
+	if m.selectedRow < len(before) {
+		selected = before[m.selectedRow]
+	}
+	m.search = parseSearch(text)
+	_, after := m.visibleRows()
+	m.selectedRow = 0
+	for row, i := range after {
+		if selected >= 0 && i == selected {
+			m.selectedRow = row
+			break
+		}
+	}
+	m.updateExpensesTable()
+}
+
+// viewSearch is the search line and how many rows it matches.
+func (m *model) viewSearch() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n" + m.searchInput.View() + "\n")
+	if m.search.active() {
+		buffer.WriteString(fmt.Sprintf("%d expenses match.\n", len(m.visibleExpenses())))
+	}
+	buffer.WriteString("Type words of the name or category, and amounts as 10-50, >20 or <100. Press enter to keep the search, esc to clear it.\n")
+	return buffer.String()
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/crypto/ssh"
+)
+
+// runServe serves the TUI over SSH to spectators. Clients are
+// authenticated by public key, from the spectator keys file, and get a
+// live but read-only session: the editing forms run on the server's own
+// terminal, not the session's, so nobody can edit over SSH.
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", ":2222", "address to listen on")
+	hostKeyPath := flags.String("host-key", ".ssh/tet_host_ed25519", "host key, generated if missing")
+	spectatorKeys := flags.String("spectator-keys", "", "authorized_keys file of the read-only spectators allowed in")
+	file := flags.String("file", dataFile(), "data file to serve")
+	flags.Parse(args)
+
+	if *spectatorKeys == "" {
+		return errors.New("serve: --spectator-keys is required")
+	}
+	keys, err := loadAuthorizedKeys(*spectatorKeys)
+	if err != nil {
+		return err
+	}
+
+	hostKey, err := loadOrCreateHostKey(*hostKeyPath)
+	if err != nil {
+		return err
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if !keys[string(key.Marshal())] {
+				return nil, fmt.Errorf("unknown public key for %s", conn.User())
+			}
+			return &ssh.Permissions{}, nil
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	log.Printf("Serving tet over SSH on %s", listener.Addr())
+	auditSource = "SSH"
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go serveConn(conn, config, *file)
+	}
+}
+
+// loadAuthorizedKeys returns the set of keys in the authorized_keys file
+// at path.
+func loadAuthorizedKeys(path string) (map[string]bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	for len(bytes.TrimSpace(b)) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys[string(key.Marshal())] = true
+		b = rest
+	}
+	return keys, nil
+}
+
+func loadOrCreateHostKey(path string) (ssh.Signer, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(priv, "tet host key")
+		if err != nil {
+			return nil, err
+		}
+		b = pem.EncodeToMemory(block)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(b)
+}
+
+func serveConn(conn net.Conn, config *ssh.ServerConfig, path string) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		log.Printf("SSH handshake with %s failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	log.Printf("%s connected from %s", sconn.User(), sconn.RemoteAddr())
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, requests, err := newChan.Accept()
+		if err != nil {
+			continue
+		}
+		go serveSession(ch, requests, path)
+	}
+}
+
+// serveSession runs one TUI on the data file at path for an SSH session
+// channel, wired to the client's pty size.
+func serveSession(ch ssh.Channel, requests <-chan *ssh.Request, path string) {
+	defer ch.Close()
+
+	var p *tea.Program
+	var width, height int
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			var pty struct {
+				Term          string
+				Columns, Rows uint32
+				Width, Height uint32
+				Modes         string
+			}
+			if err := ssh.Unmarshal(req.Payload, &pty); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			width, height = int(pty.Columns), int(pty.Rows)
+		case "window-change":
+			var size struct {
+				Columns, Rows uint32
+				Width, Height uint32
+			}
+			if ssh.Unmarshal(req.Payload, &size) == nil && p != nil {
+				p.Send(tea.WindowSizeMsg{Width: int(size.Columns), Height: int(size.Rows)})
+			}
+		case "shell":
+			if p != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			m := initialModel(path)
+			// The forms would read and draw on the server's terminal.
+			m.readOnly = true
+			p = tea.NewProgram(m, tea.WithInput(ch), tea.WithOutput(ch), tea.WithoutSignalHandler())
+			go p.Send(tea.WindowSizeMsg{Width: width, Height: height})
+			go func() {
+				if _, err := p.Run(); err != nil {
+					log.Printf("session ended: %v", err)
+				}
+				ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+				ch.Close()
+			}()
+		default:
+			req.Reply(false, nil)
+		}
+	}
+	if p != nil {
+		p.Quit()
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// shareHeader heads the column of the Expenses sheet that, with
+// share_formula set, holds each expense's share of its month's spending.
+const shareHeader = "Share of Month"
+
+// isSpending reports whether e counts towards a month's spending: money
+// going out, approved and dated.
+func isSpending(e Expense) bool {
+	return e.Kind != kindRefund && e.Amount < 0 && e.Pending == "" && !e.Date.IsZero()
+}
+
+// monthSpending sums the spending of expenses by month, or budgeting
+// period, negative like the amounts.
+func monthSpending(expenses []Expense) map[time.Time]float64 {
+	spent := make(map[time.Time]float64)
+	for _, e := range expenses {
+		if isSpending(e) {
+			spent[monthOf(e.Date)] += e.Amount
+		}
+	}
+	return spent
+}
+
+// spendingShare is e's share of the spending of its month, as a fraction,
+// from the monthly sums of monthSpending. Only spending has one.
+func spendingShare(e Expense, spent map[time.Time]float64) (float64, bool) {
+	total := spent[monthOf(e.Date)]
+	if !isSpending(e) || total == 0 {
+		return 0, false
+	}
+	return e.Amount / total, true
+}
+
+// formatShare shows a share as a percentage with one decimal, as the
+// workbook's column does.
+func formatShare(share float64) string {
+	return strconv.FormatFloat(share*100, 'f', 1, 64) + "%"
+}
+
+// expenseShareColumn is the column of the shares, a column apart from the
+// expense total so the total is not read as one of them.
+func expenseShareColumn() string {
+	col, _ := excelize.ColumnNumberToName(len(sheetHeaders["Expenses"]) + 4)
+	return col
+}
+
+// writeExpenseShares fills the share column, with share_formula set, with
+// a formula per spending row dividing its amount by the sum of the month's
+// spending, its value computed here cached so it shows before Excel
+// recalculates; the other rows are left blank. As with the total, the
+// formulas are left out when expenses are in other currencies, writing the
+// shares converted here. Without share_formula a column written before is
+// cleared.
+func writeExpenseShares(f *excelize.File, expenses []Expense) error {
+	col := expenseShareColumn()
+	header, _ := f.GetCellValue("Expenses", col+"1")
+	if !cfg.ShareFormula && header != shareHeader {
+		return nil
+	}
+	rows, err := f.GetRows("Expenses")
+	if err != nil {
+		return err
+	}
+	last := max(len(rows), len(expenses)+1)
+	for row := 1; row <= last; row++ {
+		if err := f.SetCellValue("Expenses", col+strconv.Itoa(row), nil); err != nil {
+			return err
+		}
+	}
+	if !cfg.ShareFormula {
+		return nil
+	}
+	if err := f.SetCellValue("Expenses", col+"1", shareHeader); err != nil {
+		return err
+	}
+
+	base, _ := readRates(f).inBase(expenses)
+	spent := monthSpending(base)
+	formulas := !slices.ContainsFunc(expenses, func(e Expense) bool { return foreignCurrency(e.Currency) })
+	end := len(expenses) + 1
+	for i, e := range base {
+		share, ok := spendingShare(e, spent)
+		if !ok {
+			continue
+		}
+		cell := col + strconv.Itoa(i+2)
+		if err := f.SetCellValue("Expenses", cell, share); err != nil {
+			return err
+		}
+		if !formulas {
+			continue
+		}
+		// Dates are written YYYY-MM-DD, so the month's compare as text.
+		month := monthOf(e.Date)
+		from, to := formatExpenseDate(periodStart(month)), formatExpenseDate(periodStart(month.AddDate(0, 1, 0)))
+		formula := fmt.Sprintf(`B%d/SUMPRODUCT((D$2:D$%d>="%s")*(D$2:D$%d<"%s")*(C$2:C$%d<>"%s")*(F$2:F$%d="")*(B$2:B$%d<0)*B$2:B$%d)`,
+			i+2, end, from, end, to, end, kindRefund, end, end, end)
+		if err := f.SetCellFormula("Expenses", cell, formula); err != nil {
+			return err
+		}
+	}
+	format := "0.0%"
+	style, err := f.NewStyle(&excelize.Style{CustomNumFmt: &format})
+	if err != nil {
+		return err
+	}
+	return f.SetCellStyle("Expenses", col+"2", col+strconv.Itoa(end), style)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"sync"
+)
+
+// saveQueue keeps track of the saves the TUI has issued, so that quitting
+// finishes them rather than cutting one off: Bubble Tea neither waits for
+// the commands under way when it quits nor runs those still queued.
+type saveQueue struct {
+	mu   sync.Mutex
+	jobs []*saveJob
+}
+
+// saveJob is a save run once, by its command or by flush, whichever gets
+// to it first.
+type saveJob struct {
+	once sync.Once
+	done chan struct{}
+	run  func() error
+	err  error
+}
+
+// saves are the saves of the running session.
+var saves saveQueue
+
+// add queues run, returning the func that runs it, or waits for it when
+// flush already has, and returns its error.
+func (q *saveQueue) add(run func() error) func() error {
+	j := &saveJob{run: run, done: make(chan struct{})}
+	q.mu.Lock()
+	q.jobs = append(q.jobs, j)
+	q.mu.Unlock()
+	return func() error {
+		q.do(j)
+		return j.err
+	}
+}
+
+// run saves right away, with quitting waiting for it.
+func (q *saveQueue) run(run func() error) error {
+	return q.add(run)()
+}
+
+func (q *saveQueue) do(j *saveJob) {
+	j.once.Do(func() {
+		j.err = j.run()
+		q.mu.Lock()
+		q.jobs = slices.DeleteFunc(q.jobs, func(o *saveJob) bool { return o == j })
+		q.mu.Unlock()
+		close(j.done)
+	})
+	<-j.done
+}
+
+// pending counts the saves not yet done.
+func (q *saveQueue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// flush runs the saves not yet started, in the order they were issued, and
+// waits for those under way.
+func (q *saveQueue) flush() error {
+	q.mu.Lock()
+	jobs := slices.Clone(q.jobs)
+	q.mu.Unlock()
+	var errs []error
+	for _, j := range jobs {
+		q.do(j)
+		if j.err != nil {
+			errs = append(errs, j.err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopping is closed when the session quits, for the file watchers and
+// pollers it started to return; they take it when their command is made,
+// so that each only answers to its own session.
+var stopping = make(chan struct{})
+
+// endSession stops the watchers of the session that quit and makes ready
+// for the next, such as after switching profile.
+func endSession() {
+	close(stopping)
+	stopping = make(chan struct{})
+}
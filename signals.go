@@ -0,0 +1,51 @@
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// offlineFlag is set by --offline, which a reloaded config file cannot
+// turn off.
+var offlineFlag bool
+
+// reloadDataMsg asks for the workbook to be read again, sent on SIGUSR1 by
+// scripts such as a sync job that know it changed.
+type reloadDataMsg struct{}
+
+// reloadConfigMsg asks for the config file to be read again, sent on
+// SIGHUP.
+type reloadConfigMsg struct{}
+
+// reloadData reads every loaded sheet again, along with the archived rows
+// and budgets when they are shown.
+func (m *model) reloadData() tea.Cmd {
+	var archive, budgets tea.Cmd
+	if m.showArchive {
+		archive = loadArchiveCmd(m.path)
+	}
+	if m.currentScreen == screenBudgets {
+		budgets = loadBudgetsCmd(m.path)
+	}
+	return tea.Batch(m.reloadSheetsCmd(m.loaded), archive, budgets)
+}
+
+// reloadConfig swaps in the config file as it is now, keeping the one in
+// use when it no longer loads. The data is read again for the settings
+// applied as it is, such as rounding and category icons.
+func (m *model) reloadConfig() tea.Cmd {
+	c, err := loadConfig()
+	if err != nil {
+		m.status = "Kept the config in use: " + err.Error()
+		return nil
+	}
+	c.Offline = c.Offline || offlineFlag
+	// Quotes are refreshed on a tick that stops while they are turned off
+	// or offline, so it is started again when they no longer are.
+	ticking := quoteTickCmd() != nil
+	cfg = c
+	var ticks tea.Cmd
+	if !ticking {
+		ticks = quoteTickCmd()
+	}
+	m.stonksDirty, m.watchlistDirty = true, true
+	m.status = "Reloaded the config file."
+	return tea.Batch(m.reloadData(), ticks)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// notifySignals does nothing where there are no SIGUSR1 and SIGHUP.
+func notifySignals(p *tea.Program) {}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// notifySignals passes SIGUSR1 and SIGHUP on to p, as reloads of the data
+// and of the config file, until the session ends.
+func notifySignals(p *tea.Program) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR1, syscall.SIGHUP)
+	stop := stopping
+	go func() {
+		defer signal.Stop(sig)
+		for {
+			select {
+			case s := <-sig:
+				if s == syscall.SIGHUP {
+					p.Send(reloadConfigMsg{})
+				} else {
+					p.Send(reloadDataMsg{})
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
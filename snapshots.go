@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// listSnapshots returns the saved copies of filename, newest first: those
+// taken before upgrades and restores and those in the backups directory.
+func listSnapshots(filename string) []string {
+	matches, _ := files.Glob(filename + ".*.bak")
+	matches = append(matches, listAutoBackups(filename)...)
+	modTimes := make(map[string]time.Time, len(matches))
+	for _, m := range matches {
+		if info, err := files.Stat(m); err == nil {
+			modTimes[m] = info.ModTime()
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return modTimes[matches[i]].After(modTimes[matches[j]]) })
+	return matches
+}
+
+// snapshotDiffMsg carries the changes between a snapshot and the current
+// workbook.
+type snapshotDiffMsg struct {
+	snapshot string
+	changes  []rowChange
+}
+
+func diffSnapshotCmd(snapshot, current string) tea.Cmd {
+	return func() tea.Msg {
+		var data [2]Dataset
+		for i, path := range []string{snapshot, current} {
+			s, err := readOnlyStoreForPath(path)
+			if err != nil {
+				return errMsg{err}
+			}
+			if data[i], err = s.Load(); err != nil {
+				return errMsg{err}
+			}
+		}
+		return snapshotDiffMsg{snapshot: snapshot, changes: diffDatasets(data[0], data[1])}
+	}
+}
+
+// restoreSnapshotMsg carries the data of the snapshot to restore, or no
+// snapshot when the restore was cancelled.
+type restoreSnapshotMsg struct {
+	snapshot string
+	data     Dataset
+	err      error
+}
+
+// restoreSnapshotCmd asks before restoring the expenses, positions and
+// watchlist of snapshot, then loads them.
+func restoreSnapshotCmd(snapshot string) tea.Cmd {
+	var confirmed bool
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("Restore %s?", filepath.Base(snapshot))).
+				Description("The expenses, positions and watchlist are replaced with the snapshot's. The current ones are backed up first.").
+				Value(&confirmed),
+		),
+	)
+	return func() tea.Msg {
+		if err := form.Run(); err != nil || !confirmed {
+			return restoreSnapshotMsg{}
+		}
+		s, err := readOnlyStoreForPath(snapshot)
+		if err != nil {
+			return restoreSnapshotMsg{err: err}
+		}
+		data, err := s.Load()
+		if err != nil {
+			return restoreSnapshotMsg{err: fmt.Errorf("restore: %s: %w", filepath.Base(snapshot), err)}
+		}
+		return restoreSnapshotMsg{snapshot: snapshot, data: data}
+	}
+}
+
+func (m *model) updateSnapshots(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case snapshotDiffMsg:
+		m.snapshotDiff = &msg
+	case restoreSnapshotMsg:
+		switch {
+		case msg.err != nil:
+			m.status = msg.err.Error()
+		case msg.snapshot == "":
+			m.status = "Nothing was restored."
+		default:
+			// Saving takes a backup of the data being replaced, so the
+			// restore can itself be rolled back.
+			m.expenses, m.stonks, m.watchList = msg.data.Expenses, msg.data.Stonks, msg.data.WatchList
+			m.updateExpensesTable()
+			m.status = "Restored from " + filepath.Base(msg.snapshot) + "."
+			return m, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil)
+		}
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "b":
+			if m.snapshotDiff != nil {
+				m.snapshotDiff = nil
+				return m, nil
+			}
+			m.currentScreen = screenMenu
+		case "up":
+			if m.snapshotRow > 0 {
+				m.snapshotRow--
+			}
+		case "down":
+			if m.snapshotRow < len(m.snapshots)-1 {
+				m.snapshotRow++
+			}
+		case "enter":
+			if m.snapshotDiff == nil && len(m.snapshots) > 0 {
+				return m, diffSnapshotCmd(m.snapshots[m.snapshotRow], m.path)
+			}
+		case "r":
+			if m.snapshotDiff != nil || len(m.snapshots) == 0 {
+				return m, nil
+			}
+			if m.readOnly || needsApproval() {
+				m.status = "Only the owner of the book can restore a snapshot."
+				return m, nil
+			}
+			return m, restoreSnapshotCmd(m.snapshots[m.snapshotRow])
+		}
+	}
+	return m, nil
+}
+
+func (m *model) viewSnapshots() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	if m.snapshotDiff != nil {
+		buffer.WriteString(screenTitleStyle.Render("Changes since " + filepath.Base(m.snapshotDiff.snapshot)))
+		buffer.WriteString("\n\n")
+		for _, sheet := range sheetNames {
+			buffer.WriteString(sheet + "\n")
+			n := 0
+			for _, c := range m.snapshotDiff.changes {
+				if c.sheet == sheet {
+					buffer.WriteString("  " + c.String() + "\n")
+					n++
+				}
+			}
+			if n == 0 {
+				buffer.WriteString("  no changes\n")
+			}
+		}
+		buffer.WriteString("\nPress 'b' to go back to the snapshot list.\n")
+		return buffer.String()
+	}
+
+	buffer.WriteString(screenTitleStyle.Render("Snapshots"))
+	buffer.WriteString("\n\n")
+	if len(m.snapshots) == 0 {
+		buffer.WriteString(fmt.Sprintf("  No snapshots of %s yet.\n", filepath.Base(m.path)))
+	}
+	for i, s := range m.snapshots {
+		if i == m.snapshotRow {
+			buffer.WriteString(selectedItemStyle.Render("> "+filepath.Base(s)) + "\n")
+		} else {
+			buffer.WriteString(itemStyle.Render(filepath.Base(s)) + "\n")
+		}
+	}
+	buffer.WriteString("\nUse ↑/↓ to move, enter to diff against the current workbook, 'r' to restore, 'b' to go back.\n")
+	buffer.WriteString(m.statusLine())
+	return buffer.String()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// sortColumn is the column the expenses table is sorted by, with 's'.
+type sortColumn int
+
+const (
+	sortNone sortColumn = iota
+	sortDate
+	sortName
+	sortAmount
+)
+
+// expenseSort orders the rows of the expenses table. It only changes how
+// they are shown: the workbook keeps its own order until the sorted one
+// is saved with 'P'. The zero value keeps the workbook's order.
+type expenseSort struct {
+	column     sortColumn
+	descending bool
+}
+
+// next is the sort after s: by date, name and amount in turn, then the
+// workbook's order again, in the same direction.
+func (s expenseSort) next() expenseSort {
+	s.column = (s.column + 1) % (sortAmount + 1)
+	return s
+}
+
+// compare orders a and b, undated rows last either way. Names compare
+// in any case and amounts as shown, spending being negative.
+func (s expenseSort) compare(a, b Expense) int {
+	var c int
+	switch s.column {
+	case sortDate:
+		if a.Date.IsZero() != b.Date.IsZero() {
+			if a.Date.IsZero() {
+				return 1
+			}
+			return -1
+		}
+		c = a.Date.Compare(b.Date)
+	case sortName:
+		c = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	case sortAmount:
+		c = cmp.Compare(a.Amount, b.Amount)
+	}
+	if s.descending {
+		return -c
+	}
+	return c
+}
+
+// header is the name of a column of the expenses table with an arrow
+// when the table is sorted by it.
+func (s expenseSort) header(column sortColumn, name string) string {
+	switch {
+	case s.column != column || column == sortNone:
+		return name
+	case s.descending:
+		return name + " ▼"
+	}
+	return name + " ▲"
+}
+
+func (s expenseSort) String() string {
+	name := map[sortColumn]string{sortDate: "date", sortName: "name", sortAmount: "amount"}[s.column]
+	if s.descending {
+		return name + ", descending"
+	}
+	return name + ", ascending"
+}
+
+// sortRows sorts rows, and index with them, by s; rows that tie keep
+// their order.
+func sortRows(rows []Expense, index []int, s expenseSort) {
+	if s.column == sortNone {
+		return
+	}
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return s.compare(rows[a], rows[b]) })
+	sortedRows := make([]Expense, len(rows))
+	sortedIndex := make([]int, len(index))
+	for i, j := range order {
+		sortedRows[i], sortedIndex[i] = rows[j], index[j]
+	}
+	copy(rows, sortedRows)
+	copy(index, sortedIndex)
+}
+
+// sortSavedMsg answers saveSortForm with the order to save the expenses
+// in, sortNone when the user kept the workbook's.
+type sortSavedMsg struct {
+	sort expenseSort
+}
+
+// saveSortForm asks before saving the expenses in the order they are
+// sorted by.
+func (m *model) saveSortForm() (tea.Model, tea.Cmd) {
+	switch {
+	case m.sort.column == sortNone:
+		m.status = "The table is in the workbook's order; press 's' to sort it first."
+		return m, nil
+	case needsApproval():
+		m.status = fmt.Sprintf("Only %s can reorder the workbook.", cfg.Owner)
+		return m, nil
+	}
+	s := m.sort
+	var confirmed bool
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("Save the expenses sorted by %s?", s)).
+				Description("Every expense moves to its place in the workbook, which is backed up before saving.").
+				Affirmative("Save").
+				Negative("Keep").
+				Value(&confirmed),
+		),
+	)
+	m.editing = true
+	return m, func() tea.Msg {
+		if err := form.Run(); err != nil || !confirmed {
+			return sortSavedMsg{}
+		}
+		return sortSavedMsg{sort: s}
+	}
+}
+
+// sortSaved reorders the expenses as the user confirmed and saves them.
+func (m *model) sortSaved(msg sortSavedMsg) (tea.Model, tea.Cmd) {
+	m.editing = false
+	if msg.sort.column == sortNone {
+		m.status = "Kept the workbook's order."
+		return m, nil
+	}
+	m.expenses = slices.Clone(m.expenses)
+	slices.SortStableFunc(m.expenses, msg.sort.compare)
+	clear(m.marked)
+	m.status = fmt.Sprintf("Saved the expenses sorted by %s.", msg.sort)
+	m.updateExpensesTable()
+	return m, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil)
+}
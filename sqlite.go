@@ -0,0 +1,307 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	_ "modernc.org/sqlite" // pure Go driver, registered as "sqlite"
+)
+
+// sqliteMigrations create and evolve the schema of a SQLite data file, one
+// step per schema version, recorded in PRAGMA user_version. Like the
+// workbook migrations they are only ever appended to.
+var sqliteMigrations = []string{
+	// 1: the three sheets as tables, rows numbered in sheet order.
+	`CREATE TABLE expenses (
+		row    INTEGER PRIMARY KEY,
+		name   TEXT NOT NULL,
+		amount REAL NOT NULL,
+		type   TEXT NOT NULL DEFAULT '',
+		date   TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE stonks (
+		row        INTEGER PRIMARY KEY,
+		symbol     TEXT NOT NULL,
+		quantity   REAL NOT NULL DEFAULT 0,
+		avg_price  REAL NOT NULL DEFAULT 0,
+		price      REAL NOT NULL DEFAULT 0,
+		open       REAL NOT NULL DEFAULT 0,
+		day_change REAL NOT NULL DEFAULT 0,
+		currency   TEXT NOT NULL DEFAULT '',
+		comment    TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE watchlist (
+		row         INTEGER PRIMARY KEY,
+		symbol      TEXT NOT NULL,
+		qty         REAL NOT NULL DEFAULT 0,
+		target_buy  REAL NOT NULL DEFAULT 0,
+		target_sell REAL NOT NULL DEFAULT 0,
+		price       REAL NOT NULL DEFAULT 0,
+		note        TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE meta (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`,
+	// 2: who entered each expense.
+	`ALTER TABLE expenses ADD COLUMN entered_by TEXT NOT NULL DEFAULT '';`,
+	// 3: changes awaiting the owner's approval.
+	`ALTER TABLE expenses ADD COLUMN pending TEXT NOT NULL DEFAULT '';`,
+	// 4-6: category, tags and means of payment.
+	`ALTER TABLE expenses ADD COLUMN category TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE expenses ADD COLUMN tags TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE expenses ADD COLUMN payment TEXT NOT NULL DEFAULT '';`,
+	// 7: the currency of each expense.
+	`ALTER TABLE expenses ADD COLUMN currency TEXT NOT NULL DEFAULT '';`,
+	// 8: a note on each expense.
+	`ALTER TABLE expenses ADD COLUMN note TEXT NOT NULL DEFAULT '';`,
+	// 9: how each expense was paid.
+	`ALTER TABLE expenses ADD COLUMN method TEXT NOT NULL DEFAULT '';`,
+	// 10: the household member each expense was for.
+	`ALTER TABLE expenses ADD COLUMN member TEXT NOT NULL DEFAULT '';`,
+	// 11: the return window and warranty of goods.
+	`ALTER TABLE expenses ADD COLUMN return_by TEXT NOT NULL DEFAULT '';
+	ALTER TABLE expenses ADD COLUMN warranty_months INTEGER NOT NULL DEFAULT 0;`,
+	// 12: the usage of utilities billed.
+	`ALTER TABLE expenses ADD COLUMN usage REAL NOT NULL DEFAULT 0;
+	ALTER TABLE expenses ADD COLUMN usage_unit TEXT NOT NULL DEFAULT '';`,
+	// 13: the workbook's other sheets, each row's cells as a JSON array.
+	`CREATE TABLE sheet_rows (
+		sheet TEXT NOT NULL,
+		row   INTEGER NOT NULL,
+		cells TEXT NOT NULL,
+		PRIMARY KEY (sheet, row)
+	);`,
+}
+
+// sqliteStore keeps the Dataset in a SQLite database, created and migrated
+// to the current schema when it is opened.
+type sqliteStore struct {
+	path string
+}
+
+func newSQLiteStore(path string) (Store, error) {
+	return &sqliteStore{path: path}, nil
+}
+
+// open connects to the database, creating it and bringing its schema up to
+// date as needed.
+func (s *sqliteStore) open() (*sql.DB, error) {
+	db, err := sql.Open("sqlite", s.path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec("PRAGMA busy_timeout = 5000"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	return db, nil
+}
+
+// migrateSQLite applies the migrations db has not had yet, each in its own
+// transaction.
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("schema v%d is newer than this version of tet supports (v%d)", version, len(sqliteMigrations))
+	}
+	for v := version; v < len(sqliteMigrations); v++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[v]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrating to schema v%d: %w", v+1, err)
+		}
+		// PRAGMA does not take placeholders.
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", v+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlQuerier is what reading needs of a *sql.DB or *sql.Tx.
+type sqlQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func sqliteRevision(q sqlQuerier) (int, error) {
+	var value string
+	err := q.QueryRow("SELECT value FROM meta WHERE key = 'revision'").Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+func (s *sqliteStore) Load() (Dataset, error) {
+	db, err := s.open()
+	if err != nil {
+		return Dataset{}, err
+	}
+	defer db.Close()
+
+	var data Dataset
+	if data.Revision, err = sqliteRevision(db); err != nil {
+		return Dataset{}, err
+	}
+	if err := queryRows(db, "SELECT name, amount, type, date, entered_by, pending, category, tags, payment, currency, note, method, member, return_by, warranty_months, usage, usage_unit FROM expenses ORDER BY row", func(rows *sql.Rows) error {
+		var e Expense
+		var kind, date, tags, method, returnBy string
+		if err := rows.Scan(&e.Name, &e.Amount, &kind, &date, &e.EnteredBy, &e.Pending, &e.Category, &tags, &e.Payment, &e.Currency, &e.Note, &method, &e.Member, &returnBy, &e.WarrantyMonths, &e.Usage, &e.UsageUnit); err != nil {
+			return err
+		}
+		e.Tags = parseTags(tags)
+		e.Amount = cfg.Rounding.round(e.Amount)
+		e.Kind = parseExpenseKind(kind)
+		e.Date = parseExpenseDate(date)
+		e.Method = parsePaymentMethod(method)
+		e.ReturnBy = parseExpenseDate(returnBy)
+		data.Expenses = append(data.Expenses, e)
+		return nil
+	}); err != nil {
+		return Dataset{}, fmt.Errorf("%s: expenses: %w", s.path, err)
+	}
+	if err := queryRows(db, "SELECT symbol, quantity, avg_price, price, open, day_change, currency, comment FROM stonks ORDER BY row", func(rows *sql.Rows) error {
+		var st Stonk
+		if err := rows.Scan(&st.Symbol, &st.Quantity, &st.AvgPrice, &st.Price, &st.Open, &st.DayChange, &st.Currency, &st.Comment); err != nil {
+			return err
+		}
+		data.Stonks = append(data.Stonks, st)
+		return nil
+	}); err != nil {
+		return Dataset{}, fmt.Errorf("%s: stonks: %w", s.path, err)
+	}
+	if err := queryRows(db, "SELECT symbol, qty, target_buy, target_sell, price, note FROM watchlist ORDER BY row", func(rows *sql.Rows) error {
+		var w WatchItem
+		if err := rows.Scan(&w.Symbol, &w.Qty, &w.TargetBuy, &w.TargetSell, &w.Price, &w.Note); err != nil {
+			return err
+		}
+		data.WatchList = append(data.WatchList, w)
+		return nil
+	}); err != nil {
+		return Dataset{}, fmt.Errorf("%s: watchlist: %w", s.path, err)
+	}
+	if err := queryRows(db, "SELECT sheet, cells FROM sheet_rows ORDER BY sheet, row", func(rows *sql.Rows) error {
+		var sheet, cells string
+		if err := rows.Scan(&sheet, &cells); err != nil {
+			return err
+		}
+		var row []string
+		if err := json.Unmarshal([]byte(cells), &row); err != nil {
+			return fmt.Errorf("%s: %w", sheet, err)
+		}
+		if data.Sheets == nil {
+			data.Sheets = make(map[string][][]string)
+		}
+		data.Sheets[sheet] = append(data.Sheets[sheet], row)
+		return nil
+	}); err != nil {
+		return Dataset{}, fmt.Errorf("%s: sheet rows: %w", s.path, err)
+	}
+	return data, nil
+}
+
+func queryRows(q sqlQuerier, query string, scan func(*sql.Rows) error) error {
+	rows, err := q.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Save replaces every row in one transaction, so readers see either the
+// old data or the new, never a mix.
+func (s *sqliteStore) Save(data Dataset) error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := sqliteRevision(tx)
+	if err != nil {
+		return err
+	}
+	if current != data.Revision {
+		return &conflictError{path: s.path, loaded: data.Revision, current: current}
+	}
+	for _, table := range []string{"expenses", "stonks", "watchlist"} {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+	for i, e := range data.Expenses {
+		if _, err := tx.Exec("INSERT INTO expenses (row, name, amount, type, date, entered_by, pending, category, tags, payment, currency, note, method, member, return_by, warranty_months, usage, usage_unit) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			i+1, e.Name, e.Amount, string(e.Kind), formatExpenseDate(e.Date), e.EnteredBy, e.Pending, e.Category, formatTags(e.Tags), e.Payment, e.Currency, e.Note, string(e.Method), e.Member, formatExpenseDate(e.ReturnBy), e.WarrantyMonths, e.Usage, e.UsageUnit); err != nil {
+			return err
+		}
+	}
+	for i, st := range data.Stonks {
+		if _, err := tx.Exec("INSERT INTO stonks (row, symbol, quantity, avg_price, price, open, day_change, currency, comment) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			i+1, st.Symbol, st.Quantity, st.AvgPrice, st.Price, st.Open, st.DayChange, st.Currency, st.Comment); err != nil {
+			return err
+		}
+	}
+	for i, w := range data.WatchList {
+		if _, err := tx.Exec("INSERT INTO watchlist (row, symbol, qty, target_buy, target_sell, price, note) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			i+1, w.Symbol, w.Qty, w.TargetBuy, w.TargetSell, w.Price, w.Note); err != nil {
+			return err
+		}
+	}
+	for sheet, rows := range data.Sheets {
+		if _, err := tx.Exec("DELETE FROM sheet_rows WHERE sheet = ?", sheet); err != nil {
+			return err
+		}
+		for i, row := range rows {
+			cells, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec("INSERT INTO sheet_rows (sheet, row, cells) VALUES (?, ?, ?)", sheet, i+1, string(cells)); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO meta (key, value) VALUES ('revision', ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value",
+		strconv.Itoa(data.Revision+1)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
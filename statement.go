@@ -0,0 +1,524 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// statementTxn is a transaction of a bank statement.
+type statementTxn struct {
+	Date   time.Time
+	Amount float64
+	Payee  string
+	Memo   string
+	// Category is the QIF category, empty for transfers and in OFX.
+	Category string
+}
+
+// statementFormat is the format of the bank statement at path by its
+// extension, qif or ofx (QFX being Quicken's name for OFX), or empty for
+// other files.
+func statementFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".qif":
+		return "qif"
+	case ".ofx", ".qfx":
+		return "ofx"
+	}
+	return ""
+}
+
+func isStatement(path string) bool {
+	return statementFormat(path) != ""
+}
+
+// readStatement reads the transactions of the statement at path, a qif or
+// ofx file.
+func readStatement(path, format string) ([]statementTxn, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var txns []statementTxn
+	if format == "qif" {
+		txns, err = parseQIF(f)
+	} else {
+		txns, err = parseOFX(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("import: %s: %w", filepath.Base(path), err)
+	}
+	return txns, nil
+}
+
+// qifSections are the QIF account types holding transactions; lists of
+// categories, classes and the like are skipped.
+var qifSections = map[string]bool{"bank": true, "cash": true, "ccard": true, "oth a": true, "oth l": true}
+
+// parseQIF reads the transactions of a QIF file. Dates are read month
+// first, as Quicken writes them, unless one of them only makes sense day
+// first.
+func parseQIF(r io.Reader) ([]statementTxn, error) {
+	type record struct {
+		date, amount, payee, memo, category string
+	}
+	var records []record
+	var rec record
+	inSection := true
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" {
+			continue
+		}
+		value := strings.TrimSpace(text[1:])
+		switch text[0] {
+		case '!':
+			if kind, ok := strings.CutPrefix(strings.ToLower(value), "type:"); ok {
+				inSection = qifSections[strings.TrimSpace(kind)]
+			}
+		case '^':
+			if inSection && (rec.date != "" || rec.amount != "") {
+				records = append(records, rec)
+			}
+			rec = record{}
+		case 'D':
+			rec.date = value
+		case 'T', 'U':
+			rec.amount = value
+		case 'P':
+			rec.payee = value
+		case 'M':
+			rec.memo = value
+		case 'L':
+			rec.category = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	dayFirst := false
+	for _, rec := range records {
+		if first, _, ok := qifDateParts(rec.date); ok && first > 12 {
+			dayFirst = true
+			break
+		}
+	}
+	var txns []statementTxn
+	for i, rec := range records {
+		date, err := parseQIFDate(rec.date, dayFirst)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+		amount, err := parseStatementAmount(rec.amount)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: amount %q is not a number", i+1, rec.amount)
+		}
+		txn := statementTxn{Date: date, Amount: amount, Payee: rec.payee, Memo: rec.memo}
+		// Transfers name the other account in brackets.
+		if !strings.HasPrefix(rec.category, "[") {
+			txn.Category = rec.category
+		}
+		txns = append(txns, txn)
+	}
+	return txns, nil
+}
+
+// qifDateParts splits a QIF date such as 1/31/2025, 1/31'25 or 31.01.2025
+// into its numbers.
+func qifDateParts(s string) (first int, parts []int, ok bool) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '\'' || r == '-' || r == '.' })
+	if len(fields) != 3 {
+		return 0, nil, false
+	}
+	for _, f := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return 0, nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts[0], parts, true
+}
+
+func parseQIFDate(s string, dayFirst bool) (time.Time, error) {
+	_, parts, ok := qifDateParts(s)
+	if !ok {
+		return time.Time{}, fmt.Errorf("date %q is not a date", s)
+	}
+	year, month, day := parts[2], parts[0], parts[1]
+	if parts[0] > 31 {
+		year, month, day = parts[0], parts[1], parts[2]
+	} else if dayFirst {
+		month, day = parts[1], parts[0]
+	}
+	if year < 100 {
+		year += 2000
+		if year > today().Year()+1 {
+			year -= 100
+		}
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day || int(t.Month()) != month {
+		return time.Time{}, fmt.Errorf("date %q is not a date", s)
+	}
+	return t, nil
+}
+
+// parseStatementAmount reads an amount with either decimal separator,
+// whichever comes last.
+func parseStatementAmount(s string) (float64, error) {
+	return parseCSVAmount(s, strings.LastIndex(s, ",") > strings.LastIndex(s, "."))
+}
+
+// parseOFX reads the transactions of an OFX file, SGML (OFX 1) or XML
+// (OFX 2) alike: both are a run of tags, each followed by its value.
+func parseOFX(r io.Reader) ([]statementTxn, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var txns []statementTxn
+	var fields map[string]string
+	for rest := string(b); ; {
+		start := strings.IndexByte(rest, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '>')
+		if end < 0 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(rest[start+1 : start+end]))
+		rest = rest[start+end+1:]
+		value := rest
+		if next := strings.IndexByte(rest, '<'); next >= 0 {
+			value = rest[:next]
+		}
+		value = strings.TrimSpace(html.UnescapeString(value))
+
+		switch {
+		case tag == "STMTTRN":
+			fields = make(map[string]string)
+		case tag == "/STMTTRN" && fields != nil:
+			txn, err := ofxTxn(fields)
+			if err != nil {
+				return nil, fmt.Errorf("transaction %d: %w", len(txns)+1, err)
+			}
+			txns = append(txns, txn)
+			fields = nil
+		case fields != nil && !strings.HasPrefix(tag, "/"):
+			fields[tag] = value
+		}
+	}
+	if len(txns) == 0 && !bytes.Contains(bytes.ToUpper(b), []byte("<OFX>")) {
+		return nil, fmt.Errorf("not an OFX file")
+	}
+	return txns, nil
+}
+
+// ofxTxn reads the fields of an STMTTRN aggregate.
+func ofxTxn(fields map[string]string) (statementTxn, error) {
+	posted := fields["DTPOSTED"]
+	if len(posted) < 8 {
+		return statementTxn{}, fmt.Errorf("date %q is not a date", posted)
+	}
+	date, err := time.Parse("20060102", posted[:8])
+	if err != nil {
+		return statementTxn{}, fmt.Errorf("date %q is not a date", posted)
+	}
+	amount, err := parseStatementAmount(fields["TRNAMT"])
+	if err != nil {
+		return statementTxn{}, fmt.Errorf("amount %q is not a number", fields["TRNAMT"])
+	}
+	return statementTxn{Date: date, Amount: amount, Payee: fields["NAME"], Memo: fields["MEMO"]}, nil
+}
+
+// statementRow is a transaction under review, as the expense it imports.
+type statementRow struct {
+	expense Expense
+	skip    bool
+	// note says why the row starts skipped.
+	note string
+}
+
+// statementRows turns txns into expenses for review. Transactions dated in
+// closed months cannot be imported and are dropped, counted by dropped.
+// Those with the date and amount of an expense already in the book start
+// skipped, as the bank's copy of it.
+func statementRows(txns []statementTxn, existing []Expense, closed map[time.Time]bool) (rows []statementRow, dropped int) {
+	for _, txn := range txns {
+		date := dateOf(txn.Date)
+		if closed[monthOf(date)] {
+			dropped++
+			continue
+		}
+		name := strings.Join(strings.Fields(txn.Payee), " ")
+		if name == "" {
+			name = strings.Join(strings.Fields(txn.Memo), " ")
+		}
+		if name == "" {
+			name = "Bank transaction"
+		}
+		e := Expense{Name: name, Amount: txn.Amount, Date: date, Category: txn.Category, EnteredBy: currentUser()}
+		if needsApproval() {
+			e.Pending = pendingNew
+		}
+		row := statementRow{expense: e}
+		for _, x := range existing {
+			if x.Date.Equal(date) && x.Amount == txn.Amount {
+				row.skip = true
+				row.note = "already entered as " + x.Name + "?"
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, dropped
+}
+
+// statementPageSize is how many transactions the Statement screen shows
+// at once.
+const statementPageSize = 15
+
+// statementReview is the review of a bank statement before its
+// transactions are added: each is accepted, renamed or skipped.
+type statementReview struct {
+	file    string
+	rows    []statementRow
+	dropped int
+	cursor  int
+	// input holds the new name of the row being renamed.
+	input    textinput.Model
+	renaming bool
+}
+
+// accepted are the expenses of the rows not skipped.
+func (r *statementReview) accepted() []Expense {
+	var expenses []Expense
+	for _, row := range r.rows {
+		if !row.skip {
+			expenses = append(expenses, row.expense)
+		}
+	}
+	return expenses
+}
+
+// statementReadMsg carries the transactions read from a bank statement.
+type statementReadMsg struct {
+	file    string
+	rows    []statementRow
+	dropped int
+	err     error
+}
+
+// loadStatement reads the statement at path for review.
+func loadStatement(path string, existing []Expense, closed map[time.Time]bool) statementReadMsg {
+	txns, err := readStatement(path, statementFormat(path))
+	if err != nil {
+		return statementReadMsg{err: err}
+	}
+	rows, dropped := statementRows(txns, existing, closed)
+	return statementReadMsg{file: path, rows: rows, dropped: dropped}
+}
+
+// reviewStatement opens the Statement screen on the transactions read.
+func (m *model) reviewStatement(msg statementReadMsg) (tea.Model, tea.Cmd) {
+	m.editing = false
+	if msg.err != nil {
+		m.status = msg.err.Error()
+		return m, nil
+	}
+	if len(msg.rows) == 0 {
+		m.status = fmt.Sprintf("%s has no transactions to import", filepath.Base(msg.file))
+		if msg.dropped > 0 {
+			m.status += fmt.Sprintf("; %d are dated in closed months", msg.dropped)
+		}
+		m.status += "."
+		return m, nil
+	}
+	input := textinput.New()
+	input.Prompt = "Name: "
+	input.Width = 40
+	m.statement = statementReview{file: msg.file, rows: msg.rows, dropped: msg.dropped, input: input}
+	m.currentScreen = screenStatement
+	return m, nil
+}
+
+func (m *model) updateStatement(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := &m.statement
+	if r.renaming {
+		switch msg.String() {
+		case "esc":
+			r.renaming = false
+			r.input.Blur()
+		case "enter":
+			if name := strings.TrimSpace(r.input.Value()); name != "" {
+				r.rows[r.cursor].expense.Name = name
+				r.rows[r.cursor].skip = false
+			}
+			r.renaming = false
+			r.input.Blur()
+		default:
+			var cmd tea.Cmd
+			r.input, cmd = r.input.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "b":
+		m.statement = statementReview{}
+		m.currentScreen = screenExpenses
+		m.status = "Import cancelled; nothing was added."
+	case "up":
+		if r.cursor > 0 {
+			r.cursor--
+		}
+	case "down":
+		if r.cursor < len(r.rows)-1 {
+			r.cursor++
+		}
+	case " ", "x":
+		r.rows[r.cursor].skip = !r.rows[r.cursor].skip
+	case "a":
+		r.rows[r.cursor].skip = false
+		r.cursor = min(r.cursor+1, len(r.rows)-1)
+	case "s":
+		r.rows[r.cursor].skip = true
+		r.cursor = min(r.cursor+1, len(r.rows)-1)
+	case "A", "S":
+		for i := range r.rows {
+			r.rows[i].skip = msg.String() == "S"
+		}
+	case "r":
+		r.renaming = true
+		r.input.SetValue(r.rows[r.cursor].expense.Name)
+		r.input.CursorEnd()
+		return m, r.input.Focus()
+	case "enter":
+		added := r.accepted()
+		m.status = fmt.Sprintf("Imported %d of %d transactions from %s.", len(added), len(r.rows)+r.dropped, filepath.Base(r.file))
+		if r.dropped > 0 {
+			m.status += fmt.Sprintf(" %d dated in closed months were left out.", r.dropped)
+		}
+		m.statement = statementReview{}
+		m.currentScreen = screenExpenses
+		if len(added) == 0 {
+			return m, nil
+		}
+		m.expenses = append(m.expenses, added...)
+		m.updateExpensesTable()
+		return m, writeExcelCmd(m.path, m.revision, m.loaded, m.expenses, m.stonks, m.watchList, nil)
+	}
+	return m, nil
+}
+
+func (m *model) viewStatement() string {
+	r := &m.statement
+	var buffer bytes.Buffer
+	buffer.WriteString("\n")
+	buffer.WriteString(screenTitleStyle.Render("Import " + filepath.Base(r.file)))
+	buffer.WriteString("\n\n")
+	first := max(min(r.cursor-statementPageSize/2, len(r.rows)-statementPageSize), 0)
+	last := min(first+statementPageSize, len(r.rows))
+	for i := first; i < last; i++ {
+		row := r.rows[i]
+		mark := "✓"
+		if row.skip {
+			mark = "✗"
+		}
+		line := fmt.Sprintf("%s %s  %-32s %10s", mark, formatExpenseDate(row.expense.Date), row.expense.Name, money(row.expense.Amount))
+		if row.expense.Category != "" {
+			line += "  " + row.expense.Category
+		}
+		if row.skip && row.note != "" {
+			line += "  " + row.note
+		}
+		if i == r.cursor {
+			buffer.WriteString(selectedItemStyle.Render("> "+line) + "\n")
+		} else {
+			buffer.WriteString(itemStyle.Render(line) + "\n")
+		}
+	}
+	buffer.WriteString(fmt.Sprintf("\n%d of %d transactions will be imported.", len(r.accepted()), len(r.rows)))
+	if r.dropped > 0 {
+		buffer.WriteString(fmt.Sprintf(" %d dated in closed months are left out.", r.dropped))
+	}
+	buffer.WriteString("\n")
+	if r.renaming {
+		buffer.WriteString("\n" + r.input.View() + "\nPress enter to rename, esc to cancel.\n")
+		return buffer.String()
+	}
+	buffer.WriteString("\nUse ↑/↓ to move, 'a' to accept, 's' to skip, space to switch, 'r' to rename, 'A'/'S' to accept or skip all, enter to import the accepted ones, esc to cancel.\n")
+	return buffer.String()
+}
+
+// importStatement adds the transactions of a bank statement to the data
+// file without review, leaving out those that look already entered.
+func importStatement(file, path, format string, dryRun bool) error {
+	txns, err := readStatement(path, format)
+	if err != nil {
+		return err
+	}
+	s, err := storeForPath(file)
+	if err != nil {
+		return err
+	}
+	data, err := s.Load()
+	if err != nil {
+		return err
+	}
+	var closed map[time.Time]bool
+	if isWorkbook(file) {
+		if f, err := openWorkbook(file); err == nil {
+			closed = readClosedMonths(f)
+			f.Close()
+		}
+	}
+	rows, dropped := statementRows(txns, data.Expenses, closed)
+	review := statementReview{rows: rows}
+	added := review.accepted()
+	if !dryRun && len(added) > 0 {
+		data.Expenses = append(data.Expenses, added...)
+		if err := s.Save(data); err != nil {
+			return err
+		}
+	}
+	if dryRun {
+		fmt.Print("Dry run, nothing written. ")
+	}
+	fmt.Printf("Imported %d of %d transactions", len(added), len(txns))
+	if skipped := len(rows) - len(added); skipped > 0 {
+		fmt.Printf(", skipped %d that look already entered", skipped)
+	}
+	if dropped > 0 {
+		fmt.Printf(", left out %d dated in closed months", dropped)
+	}
+	fmt.Println(".")
+	if dryRun || len(added) == 0 {
+		return nil
+	}
+	return reportWithdrawalsMoved(file)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQIF(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	for _, tc := range []struct {
+		name string
+		qif  string
+		want []statementTxn
+	}{
+		{
+			name: "month first",
+			qif:  "!Type:Bank\nD1/31/2025\nT-12.50\nPCoffee Shop\nMMorning\nLFood\n^\n",
+			want: []statementTxn{{Date: date(2025, 1, 31), Amount: -12.5, Payee: "Coffee Shop", Memo: "Morning", Category: "Food"}},
+		},
+		{
+			name: "two-digit year after an apostrophe",
+			qif:  "!Type:CCard\nD2/ 3'25\nT-4.00\nPBakery\n^\n",
+			want: []statementTxn{{Date: date(2025, 2, 3), Amount: -4, Payee: "Bakery"}},
+		},
+		{
+			name: "day first when a date only makes sense so",
+			qif:  "!Type:Bank\nD05.02.2025\nT-1\nPFirst\n^\nD31.01.2025\nT-2\nPSecond\n^\n",
+			want: []statementTxn{
+				{Date: date(2025, 2, 5), Amount: -1, Payee: "First"},
+				{Date: date(2025, 1, 31), Amount: -2, Payee: "Second"},
+			},
+		},
+		{
+			name: "year first",
+			qif:  "!Type:Bank\nD2025-01-31\nT-3\nPShop\n^\n",
+			want: []statementTxn{{Date: date(2025, 1, 31), Amount: -3, Payee: "Shop"}},
+		},
+		{
+			name: "signs and separators",
+			qif: "!Type:Bank\nD1/1/2025\nT-1,234.56\nPRent\n^\nD1/2/2025\nT2.500,00\nPSalary\n^\n" +
+				"D1/3/2025\nU+7.25\nPRefund\n^\n",
+			want: []statementTxn{
+				{Date: date(2025, 1, 1), Amount: -1234.56, Payee: "Rent"},
+				{Date: date(2025, 1, 2), Amount: 2500, Payee: "Salary"},
+				{Date: date(2025, 1, 3), Amount: 7.25, Payee: "Refund"},
+			},
+		},
+		{
+			name: "a split is one transaction of its total",
+			qif: "!Type:Bank\nD3/1/2025\nT-15.00\nPSupermarket\nLGroceries\n" +
+				"SGroceries\n$-10.00\nEFood\nSHousehold\n$-5.00\n^\n",
+			want: []statementTxn{{Date: date(2025, 3, 1), Amount: -15, Payee: "Supermarket", Category: "Groceries"}},
+		},
+		{
+			name: "a transfer has no category",
+			qif:  "!Type:Bank\nD3/2/2025\nT-200\nPTo savings\nL[Savings]\n^\n",
+			want: []statementTxn{{Date: date(2025, 3, 2), Amount: -200, Payee: "To savings"}},
+		},
+		{
+			name: "lists are skipped",
+			qif:  "\ufeff!Type:Cat\nNFood\nE\n^\n!Type:Bank\r\nD3/3/2025\r\nT-1\r\nPKiosk\r\n^\r\n",
+			want: []statementTxn{{Date: date(2025, 3, 3), Amount: -1, Payee: "Kiosk"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseQIF(strings.NewReader(tc.qif))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseQIFErrors(t *testing.T) {
+	for name, qif := range map[string]string{
+		"no date":      "!Type:Bank\nDyesterday\nT-1\n^\n",
+		"no such date": "!Type:Bank\nD2/30/2025\nT-1\n^\n",
+		"no amount":    "!Type:Bank\nD1/1/2025\nTabc\n^\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if txns, err := parseQIF(strings.NewReader(qif)); err == nil {
+				t.Errorf("read %+v, want an error", txns)
+			}
+		})
+	}
+}
+
+func TestParseOFX(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	for _, tc := range []struct {
+		name string
+		ofx  string
+		want []statementTxn
+	}{
+		{
+			name: "SGML",
+			ofx: "OFXHEADER:100\nDATA:OFXSGML\n\n<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>\n" +
+				"<STMTTRN>\n<TRNTYPE>DEBIT\n<DTPOSTED>20250131120000[-5:EST]\n<TRNAMT>-12.50\n<NAME>Coffee Shop\n<MEMO>Card 1234\n</STMTTRN>\n" +
+				"<STMTTRN>\n<TRNTYPE>CREDIT\n<DTPOSTED>20250201\n<TRNAMT>2500.00\n<NAME>Salary\n</STMTTRN>\n" +
+				"</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>\n",
+			want: []statementTxn{
+				{Date: date(2025, 1, 31), Amount: -12.5, Payee: "Coffee Shop", Memo: "Card 1234"},
+				{Date: date(2025, 2, 1), Amount: 2500, Payee: "Salary"},
+			},
+		},
+		{
+			name: "XML",
+			ofx: `<?xml version="1.0"?><?OFX OFXHEADER="200"?><OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS><BANKTRANLIST>` +
+				`<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20250305</DTPOSTED><TRNAMT>-7,25</TRNAMT><NAME>Fish &amp; Chips</NAME></STMTTRN>` +
+				`<STMTTRN><TRNTYPE>TRANSFER</TRNTYPE><DTPOSTED>20250306</DTPOSTED><TRNAMT>-100</TRNAMT><NAME>To savings</NAME><MEMO>Transfer</MEMO></STMTTRN>` +
+				`</BANKTRANLIST></CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`,
+			want: []statementTxn{
+				{Date: date(2025, 3, 5), Amount: -7.25, Payee: "Fish & Chips"},
+				{Date: date(2025, 3, 6), Amount: -100, Payee: "To savings", Memo: "Transfer"},
+			},
+		},
+		{
+			name: "no transactions",
+			ofx:  "<OFX><BANKTRANLIST></BANKTRANLIST></OFX>",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseOFX(strings.NewReader(tc.ofx))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseOFXErrors(t *testing.T) {
+	for name, ofx := range map[string]string{
+		"not OFX":   "Date,Amount\n2025-01-31,-12.50\n",
+		"no date":   "<OFX><STMTTRN><DTPOSTED>2025<TRNAMT>-1</STMTTRN></OFX>",
+		"bad date":  "<OFX><STMTTRN><DTPOSTED>20251340<TRNAMT>-1</STMTTRN></OFX>",
+		"no amount": "<OFX><STMTTRN><DTPOSTED>20250131<TRNAMT>n/a</STMTTRN></OFX>",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if txns, err := parseOFX(strings.NewReader(ofx)); err == nil {
+				t.Errorf("read %+v, want an error", txns)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+)
+
+// UnmarshalJSON also reads data files from before positions had explicit
+// columns: the old Change becomes DayChange and a non-zero Extra is kept in
+// the comment.
+func (s *Stonk) UnmarshalJSON(b []byte) error {
+	type plain Stonk
+	aux := struct {
+		*plain
+		Change *float64
+		Extra  float64
+	}{plain: (*plain)(s)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if aux.Change != nil {
+		s.DayChange = *aux.Change
+	}
+	if aux.Extra != 0 {
+		s.Comment = legacyExtra(s.Comment, strconv.FormatFloat(aux.Extra, 'f', -1, 64))
+	}
+	return nil
+}
+
+// legacyExtra appends the old opaque Extra value to comment, as nothing
+// says which of the new columns it belongs in.
+func legacyExtra(comment, extra string) string {
+	extra = strings.TrimSpace(extra)
+	if extra == "" || extra == "0" {
+		return comment
+	}
+	if comment != "" {
+		comment += "; "
+	}
+	return comment + "extra " + extra
+}
+
+func (s Stonk) costBasis() float64   { return s.Quantity * s.AvgPrice }
+func (s Stonk) marketValue() float64 { return s.Quantity * s.Price }
+
+// totalReturn is the gain on the position since it was bought, in money
+// and as a fraction of its cost. ok is false without a quantity, an
+// average price and a current price.
+func (s Stonk) totalReturn() (gain, pct float64, ok bool) {
+	if s.Quantity == 0 || s.AvgPrice <= 0 || s.Price <= 0 {
+		return 0, 0, false
+	}
+	gain = s.marketValue() - s.costBasis()
+	return gain, gain / s.costBasis(), true
+}
+
+// totalReturnCell is the Total Return column, written for readers of the
+// workbook; it is derived again on load rather than read back.
+func (s Stonk) totalReturnCell() any {
+	gain, _, ok := s.totalReturn()
+	if !ok {
+		return nil
+	}
+	return cfg.Rounding.round(gain)
+}
+
+// intradayChange is the change since the day's open, in percent.
+func (s Stonk) intradayChange() (pct float64, ok bool) {
+	if s.Open <= 0 || s.Price <= 0 {
+		return 0, false
+	}
+	return (s.Price - s.Open) / s.Open * 100, true
+}
+
+// changeMetric is the change the Stonks screen shows for each position.
+type changeMetric int
+
+const (
+	metricIntraday changeMetric = iota
+	metricDaily
+	metricTotalReturn
+	numChangeMetrics
+)
+
+func (c changeMetric) String() string {
+	switch c {
+	case metricIntraday:
+		return "Intraday"
+	case metricDaily:
+		return "Daily"
+	default:
+		return "Total Return"
+	}
+}
+
+func (c changeMetric) next() changeMetric {
+	return (c + 1) % numChangeMetrics
+}
+
+// change returns the metric for s as a signed number, for coloring, and
+// its cell text; the text is empty when s lacks the prices it needs.
+func (c changeMetric) change(s Stonk) (float64, string) {
+	switch c {
+	case metricIntraday:
+		if pct, ok := s.intradayChange(); ok {
+			return pct, formatPercent(pct)
+		}
+	case metricDaily:
+		if s.DayChange != 0 {
+			return s.DayChange, formatPercent(s.DayChange)
+		}
+	default:
+		if gain, pct, ok := s.totalReturn(); ok {
+			return gain, fmt.Sprintf("%s (%s)", money(gain), formatPercent(pct*100))
+		}
+	}
+	return 0, ""
+}
+
+func formatPercent(x float64) string {
+	return fmt.Sprintf("%+.2f%%", x)
+}
+
+func (m *model) updateStonksTable() {
+	m.stonksDirty = false
+	metric := m.stonksMetric
+	headers := []string{"Symbol", "Qty", "Avg Price", "Price", "▸ " + metric.String(), "Comment"}
+
+	var data [][]string
+	var cost, gains float64
+	for _, s := range m.stonks {
+		_, change := metric.change(s)
+		if gain, _, ok := s.totalReturn(); ok {
+			cost += s.costBasis()
+			gains += gain
+		}
+		data = append(data, []string{
+			s.Symbol, formatOptional(s.Quantity), formatOptional(s.AvgPrice), formatOptional(s.Price),
+			change, s.Comment,
+		})
+	}
+
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	upStyle := baseStyle.Foreground(lipgloss.Color("42"))
+	downStyle := baseStyle.Foreground(lipgloss.Color("203"))
+
+	t := windowedTable{
+		headers:  headers,
+		rows:     data,
+		frozen:   1,
+		selected: -1,
+		style: func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			if col == 4 {
+				switch change, _ := metric.change(m.stonks[row]); {
+				case change > 0:
+					return upStyle
+				case change < 0:
+					return downStyle
+				}
+			}
+			if row%2 == 0 {
+				return rowStyle.Foreground(lipgloss.Color("245"))
+			}
+			return rowStyle
+		},
+	}
+	m.stonksTable = m.stonksWindow.render(t, m.width, m.tableHeight(stonksChrome))
+	if cost > 0 {
+		m.stonksTable += fmt.Sprintf("\nTotal return: %s (%s)\n", money(gains), formatPercent(gains/cost*100))
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Dataset is everything the tracker persists, independent of the backend
+// it is stored in.
+type Dataset struct {
+	Expenses  []Expense   `json:"expenses"`
+	Stonks    []Stonk     `json:"stonks"`
+	WatchList []WatchItem `json:"watchList"`
+	// Sheets are the workbook's other sheets, such as Budgets, Accounts or
+	// the archives, by name: the header row and the rows below it, as the
+	// cells' raw values. A nil map leaves the stored ones as they are;
+	// otherwise each sheet in it replaces the one of the same name.
+	Sheets map[string][][]string `json:"sheets,omitempty"`
+	// Revision counts saves; a save based on an older revision than the
+	// one stored is a conflict.
+	Revision int `json:"revision"`
+}
+
+// Store loads and saves a Dataset to a storage backend. It has one Save
+// for every sheet rather than a save per sheet: the revision is checked
+// and bumped once per save, and SQLite writes it in one transaction, so
+// that readers never see the expenses of one save with the positions of
+// another. Workbooks and spreadsheets leave a sheet passed as nil as it
+// is, which is how the TUI saves only the sheets it has loaded.
+type Store interface {
+	Load() (Dataset, error)
+	Save(Dataset) error
+}
+
+// backends maps a backend name to the constructor for its Store.
+var backends = map[string]func(path string) (Store, error){
+	"xlsx":    newExcelStore,
+	"json":    newJSONStore,
+	"sqlite":  newSQLiteStore,
+	"memory":  newMemoryStore,
+	"gsheets": newSheetsStore,
+}
+
+// defaultPaths is where each backend keeps its data when no path is given.
+var defaultPaths = map[string]string{
+	"xlsx":   "data.xlsx",
+	"json":   "data.json",
+	"sqlite": "data.sqlite",
+	"memory": memoryPath,
+	// The spreadsheet in the config file.
+	"gsheets": sheetsPathPrefix,
+}
+
+func backendNames() string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// openStore returns the Store for backend at path, falling back to the
+// backend's default path when path is empty.
+func openStore(backend, path string) (Store, error) {
+	newStore, ok := backends[backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q (available: %s)", backend, backendNames())
+	}
+	if path == "" {
+		path = defaultPaths[backend]
+	}
+	return newStore(path)
+}
+
+// memoryPath names the data kept by a memoryStore. Other names starting
+// with it, such as ":memory:demo", are kept apart from it.
+const memoryPath = ":memory:"
+
+func isMemoryPath(path string) bool { return strings.HasPrefix(path, memoryPath) }
+
+// memoryStore keeps the Dataset in memory for as long as the process runs,
+// for scratch sessions and for driving the model without a data file.
+// Every store opened on the same path shares the data.
+type memoryStore struct {
+	path string
+	mu   sync.Mutex
+	data Dataset
+}
+
+var (
+	memoryStoresMu sync.Mutex
+	memoryStores   = make(map[string]*memoryStore)
+)
+
+func newMemoryStore(path string) (Store, error) {
+	memoryStoresMu.Lock()
+	defer memoryStoresMu.Unlock()
+	s, ok := memoryStores[path]
+	if !ok {
+		s = &memoryStore{path: path}
+		memoryStores[path] = s
+	}
+	return s, nil
+}
+
+// Load returns a copy of the data, which callers are free to change.
+func (s *memoryStore) Load() (Dataset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return cloneDataset(s.data), nil
+}
+
+func (s *memoryStore) Save(data Dataset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data.Revision != s.data.Revision {
+		return &conflictError{path: s.path, loaded: data.Revision, current: s.data.Revision}
+	}
+	sheets := mergeSheets(s.data.Sheets, data.Sheets)
+	s.data = cloneDataset(data)
+	s.data.Sheets = sheets
+	s.data.Revision++
+	return nil
+}
+
+func cloneDataset(data Dataset) Dataset {
+	data.Expenses = append([]Expense(nil), data.Expenses...)
+	data.Stonks = append([]Stonk(nil), data.Stonks...)
+	data.WatchList = append([]WatchItem(nil), data.WatchList...)
+	if data.Sheets != nil {
+		sheets := make(map[string][][]string, len(data.Sheets))
+		for name, rows := range data.Sheets {
+			sheets[name] = append([][]string(nil), rows...)
+		}
+		data.Sheets = sheets
+	}
+	return data
+}
+
+// mergeSheets returns current with the sheets of saved in place of those
+// of the same name.
+func mergeSheets(current, saved map[string][][]string) map[string][][]string {
+	if saved == nil {
+		return current
+	}
+	merged := make(map[string][][]string, len(current)+len(saved))
+	for name, rows := range current {
+		merged[name] = rows
+	}
+	for name, rows := range saved {
+		merged[name] = rows
+	}
+	return merged
+}
+
+// jsonStore keeps the Dataset in a single indented JSON file, optionally
+// encrypted at rest.
+type jsonStore struct {
+	path string
+	// encrypt is set once the file is found encrypted, so that it stays
+	// so, or by setEncrypted.
+	encrypt bool
+	// decrypt is set by setEncrypted to store an encrypted file as
+	// plaintext again.
+	decrypt bool
+}
+
+func (s *jsonStore) setEncrypted(on bool) { s.encrypt, s.decrypt = on, !on }
+
+func newJSONStore(path string) (Store, error) {
+	return &jsonStore{path: path}, nil
+}
+
+func (s *jsonStore) Load() (Dataset, error) {
+	data, encrypted, err := s.read()
+	if encrypted && !s.decrypt {
+		s.encrypt = true
+	}
+	return data, err
+}
+
+// read parses the file, reporting whether it was encrypted at rest.
+func (s *jsonStore) read() (Dataset, bool, error) {
+	var data Dataset
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return data, false, err
+	}
+	encrypted := isEncrypted(b)
+	if encrypted {
+		if b, err = decryptData(b); err != nil {
+			return data, true, fmt.Errorf("%s: %w", s.path, err)
+		}
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return data, encrypted, fmt.Errorf("%s: %w", s.path, err)
+	}
+	linkLegacyOwned(&data)
+	return data, encrypted, nil
+}
+
+func (s *jsonStore) Save(data Dataset) error {
+	unlock, err := lockFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var current Dataset
+	if _, err := os.Stat(s.path); err == nil {
+		var encrypted bool
+		current, encrypted, err = s.read()
+		if err != nil {
+			return err
+		}
+		// Stores opened for one save, like the TUI's, have not loaded the
+		// file to know.
+		if encrypted && !s.decrypt {
+			s.encrypt = true
+		}
+		if current.Revision != data.Revision {
+			return &conflictError{path: s.path, loaded: data.Revision, current: current.Revision}
+		}
+	}
+	data.Revision++
+	data.Sheets = mergeSheets(current.Sheets, data.Sheets)
+
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if s.encrypt {
+		if b, err = encryptData(b); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+// writeFileAtomic writes b next to path and renames it into place, so a
+// crash mid-write never leaves a truncated data file behind.
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fixtureRow is what a generated expense should read back as, whichever
+// store it went through and however messily it was written.
+func fixtureRow(e Expense) string {
+	return strings.Join([]string{formatExpenseDate(e.Date), strings.TrimSpace(e.Name), money(e.Amount), string(e.Kind),
+		e.Category, formatTags(e.Tags), e.EnteredBy}, "|")
+}
+
+func fixtureRows(expenses []Expense) []string {
+	rows := make([]string, len(expenses))
+	for i, e := range expenses {
+		rows[i] = fixtureRow(e)
+	}
+	return rows
+}
+
+func TestStoresRoundTripFixture(t *testing.T) {
+	want := generateFixture(fixtureOptions{rows: 120, seed: 7, months: 6})
+	want.Sheets = map[string][][]string{
+		budgetsSheet:   {{"Category", "Budget", "Rollover"}, {"Coffee", "40", "Yes"}, {"Books", "12.5"}},
+		"Archive 2025": {{"Expense", "Amount"}, {"Rent", "-900"}, {"Code", "007"}},
+	}
+	dir := t.TempDir()
+	for backend, path := range map[string]string{
+		"xlsx":   filepath.Join(dir, "data.xlsx"),
+		"json":   filepath.Join(dir, "data.json"),
+		"sqlite": filepath.Join(dir, "data.sqlite"),
+		"memory": memoryPath + t.Name(),
+	} {
+		t.Run(backend, func(t *testing.T) {
+			s, err := openStore(backend, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Save(want); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			got, err := s.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if g, w := fixtureRows(got.Expenses), fixtureRows(want.Expenses); !reflect.DeepEqual(g, w) {
+				t.Errorf("expenses differ after the round trip:\n got %q\nwant %q", g, w)
+			}
+			if !reflect.DeepEqual(got.Stonks, want.Stonks) {
+				t.Errorf("stonks differ after the round trip:\n got %+v\nwant %+v", got.Stonks, want.Stonks)
+			}
+			if !reflect.DeepEqual(got.WatchList, want.WatchList) {
+				t.Errorf("watchlist differs after the round trip:\n got %+v\nwant %+v", got.WatchList, want.WatchList)
+			}
+			for sheet, rows := range want.Sheets {
+				if !reflect.DeepEqual(got.Sheets[sheet], rows) {
+					t.Errorf("%s differs after the round trip:\n got %q\nwant %q", sheet, got.Sheets[sheet], rows)
+				}
+			}
+
+			// A second save from what was loaded is not a conflict and
+			// changes nothing.
+			if err := s.Save(got); err != nil {
+				t.Fatalf("second Save: %v", err)
+			}
+			again, err := s.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fixtureRows(again.Expenses), fixtureRows(got.Expenses)) {
+				t.Error("expenses changed on saving them again")
+			}
+		})
+	}
+}
+
+func TestMessyWorkbooksLoad(t *testing.T) {
+	opts := fixtureOptions{rows: 150, seed: 11, months: 6}
+	want := fixtureRows(generateFixture(opts).Expenses)
+	for name, mess := range messNames {
+		t.Run(name, func(t *testing.T) {
+			opts := opts
+			opts.mess = mess
+			path := filepath.Join(t.TempDir(), "messy.xlsx")
+			if err := generateWorkbook(path, opts); err != nil {
+				t.Fatal(err)
+			}
+			got, err := (&excelStore{path: path}).Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			rows, want := fixtureRows(got.Expenses), want
+			if mess == messColumns {
+				// Some rows stop short of their date and type, and
+				// none has the columns added later, so only what every
+				// row keeps is compared.
+				short := func(expenses []Expense) []string {
+					rows := make([]string, len(expenses))
+					for i, e := range expenses {
+						rows[i] = fixtureRow(Expense{Name: e.Name, Amount: e.Amount})
+					}
+					return rows
+				}
+				rows, want = short(got.Expenses), short(generateFixture(opts).Expenses)
+				f, err := openWorkbook(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				if v := workbookVersion(f); v != schemaVersion {
+					t.Errorf("the workbook is at schema v%d after loading, want v%d", v, schemaVersion)
+				}
+			}
+			if !reflect.DeepEqual(rows, want) {
+				for i := range min(len(rows), len(want)) {
+					if rows[i] != want[i] {
+						t.Errorf("row %d: got %q, want %q", i+2, rows[i], want[i])
+					}
+				}
+				if len(rows) != len(want) {
+					t.Errorf("got %d expenses, want %d", len(rows), len(want))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+)
+
+// tableWindow is the part of a table shown when it is taller or wider than
+// the terminal: the header row always, rows from rowOffset on, and after
+// the frozen columns, which stay put, the columns from colOffset on.
+type tableWindow struct {
+	rowOffset int
+	colOffset int
+	// page is how many rows were shown when the table was last drawn,
+	// which pgup and pgdown move by.
+	page int
+}
+
+// windowedTable is a table to draw within a tableWindow.
+type windowedTable struct {
+	headers []string
+	rows    [][]string
+	// style is the table's StyleFunc, called with the row and column of
+	// the whole table.
+	style func(row, col int) lipgloss.Style
+	// frozen is how many leading columns stay in view when paging
+	// sideways, so that rows can still be told apart.
+	frozen int
+	// selected is the row kept in view, or -1 to scroll freely.
+	selected int
+	// fill is the width the table is stretched to when it fits, if any.
+	fill int
+}
+
+// scrollRows moves the window by n rows, for tables without a selection.
+func (w *tableWindow) scrollRows(n int) {
+	w.rowOffset = max(w.rowOffset+n, 0)
+}
+
+// scrollColumns pages the window sideways by n columns.
+func (w *tableWindow) scrollColumns(n int) {
+	w.colOffset = max(w.colOffset+n, 0)
+}
+
+// cellWidth is how wide a cell is drawn: its text and a space either side.
+func cellWidth(s string) int {
+	return lipgloss.Width(s) + 2
+}
+
+// render draws t showing at most height rows and fitting within width
+// columns of the terminal, either being zero for no limit. The window is
+// moved to keep the selected row in view and clamped to the table, so
+// scrolling past either end stops there. A line under the table says which
+// rows and columns are shown when some are not.
+func (w *tableWindow) render(t windowedTable, width, height int) string {
+	if t.selected >= 0 && height > 0 {
+		if t.selected < w.rowOffset {
+			w.rowOffset = t.selected
+		} else if t.selected >= w.rowOffset+height {
+			w.rowOffset = t.selected - height + 1
+		}
+	}
+	lastRow := len(t.rows)
+	if height > 0 && len(t.rows) > height {
+		w.rowOffset = min(w.rowOffset, len(t.rows)-height)
+		lastRow = w.rowOffset + height
+	} else {
+		w.rowOffset = 0
+	}
+	w.page = lastRow - w.rowOffset
+
+	widths := make([]int, len(t.headers))
+	for c, h := range t.headers {
+		widths[c] = cellWidth(h)
+		for _, row := range t.rows {
+			if c < len(row) {
+				widths[c] = max(widths[c], cellWidth(row[c]))
+			}
+		}
+	}
+	columns := w.columns(widths, min(t.frozen, len(widths)), width)
+
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = t.headers[c]
+	}
+	var rows [][]string
+	for _, row := range t.rows[w.rowOffset:lastRow] {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			if c < len(row) {
+				cells[i] = row[c]
+			}
+		}
+		rows = append(rows, cells)
+	}
+
+	rowOffset := w.rowOffset
+	table := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(lipgloss.NewRenderer(os.Stdout).NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(rows...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return t.style(row, columns[col])
+			}
+			return t.style(row+rowOffset, columns[col])
+		})
+	s := table.String()
+	// Only stretch: a Width narrower than the table would shrink it instead.
+	if len(columns) == len(widths) && t.fill > lipgloss.Width(s) && (width == 0 || t.fill <= width) {
+		s = table.Width(t.fill).String()
+	}
+
+	var hint string
+	if lastRow-w.rowOffset < len(t.rows) {
+		hint = fmt.Sprintf("Rows %d–%d of %d (pgup/pgdn)", w.rowOffset+1, lastRow, len(t.rows))
+	}
+	if len(columns) < len(widths) {
+		if hint != "" {
+			hint += " · "
+		}
+		first, last := columns[min(t.frozen, len(columns)-1)]+1, columns[len(columns)-1]+1
+		if first == last {
+			hint += fmt.Sprintf("column %d of %d (←/→)", first, len(widths))
+		} else {
+			hint += fmt.Sprintf("columns %d–%d of %d (←/→)", first, last, len(widths))
+		}
+	}
+	if hint != "" {
+		s += "\n" + hint
+	}
+	return s
+}
+
+// columns picks the columns to show of a table whose columns are widths
+// wide: the frozen ones, then as many from colOffset on as fit in width,
+// at least one. colOffset is clamped so that the last page is full.
+func (w *tableWindow) columns(widths []int, frozen, width int) []int {
+	all := make([]int, len(widths))
+	for i := range all {
+		all[i] = i
+	}
+	// One border left of every column and one closing the table.
+	used := 1
+	for _, cw := range widths {
+		used += cw + 1
+	}
+	if width == 0 || used <= width || frozen >= len(widths) {
+		w.colOffset = 0
+		return all
+	}
+
+	used = 1
+	for _, cw := range widths[:frozen] {
+		used += cw + 1
+	}
+	// The furthest offset that still fills the page.
+	last, room := len(widths)-1, width-used-widths[len(widths)-1]-1
+	for last > frozen && room-widths[last-1]-1 >= 0 {
+		last--
+		room -= widths[last] + 1
+	}
+	w.colOffset = min(w.colOffset, last-frozen)
+
+	columns := all[:frozen:frozen]
+	for c := frozen + w.colOffset; c < len(widths); c++ {
+		if used+widths[c]+1 > width && len(columns) > frozen {
+			break
+		}
+		columns = append(columns, c)
+		used += widths[c] + 1
+	}
+	return columns
+}
+
+// The lines each table's screen takes besides the table's rows, so that
+// the header row stays on screen when the rows are scrolled.
+const (
+	expensesChrome  = 26
+	stonksChrome    = 18
+	watchlistChrome = 16
+)
+
+// screenLines is how many lines of a terminal width columns wide s takes,
+// its long lines wrapping.
+func screenLines(s string, width int) int {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if s == "" {
+		return 0
+	}
+	if width == 0 {
+		return len(lines)
+	}
+	n := 0
+	for _, line := range lines {
+		n += max((lipgloss.Width(line)+width-1)/width, 1)
+	}
+	return n
+}
+
+// pageTable moves the table on screen a page up or down, or to its first
+// or last row, by key: the selection on the expenses screen, which the
+// window follows, and the window itself on the others.
+func (m *model) pageTable(w *tableWindow, key string) {
+	page := max(w.page, 1)
+	if m.currentScreen != screenExpenses {
+		switch key {
+		case "pgup":
+			w.scrollRows(-page)
+		case "pgdown":
+			w.scrollRows(page)
+		case "home":
+			w.rowOffset = 0
+		case "end":
+			// Clamped to the last page when drawn.
+			w.rowOffset = math.MaxInt32
+		}
+		m.redrawTable()
+		return
+	}
+	last := len(m.visibleExpenses()) - 1
+	switch key {
+	case "pgup":
+		m.selectedRow -= page
+	case "pgdown":
+		m.selectedRow += page
+	case "home":
+		m.selectedRow = 0
+	case "end":
+		m.selectedRow = last
+	}
+	m.selectedRow = max(min(m.selectedRow, last), 0)
+	m.redrawExpensesTable()
+}
+
+// tableHeight is how many rows of a table fit the terminal next to chrome
+// lines of the rest of the screen, or zero when its size is not known.
+func (m *model) tableHeight(chrome int) int {
+	if m.height == 0 {
+		return 0
+	}
+	return max(m.height-chrome, 3)
+}
+
+// tableWindow returns the window of the table on the current screen, or
+// nil on screens without one.
+func (m *model) tableWindow() *tableWindow {
+	switch m.currentScreen {
+	case screenExpenses:
+		return &m.expensesWindow
+	case screenStonks:
+		return &m.stonksWindow
+	case screenWatchlist:
+		return &m.watchlistWindow
+	}
+	return nil
+}
+
+// redrawTable rebuilds the table on the current screen.
+func (m *model) redrawTable() {
+	switch m.currentScreen {
+	case screenExpenses:
+		m.updateExpensesTable()
+	case screenStonks:
+		m.updateStonksTable()
+	case screenWatchlist:
+		m.updateWatchlistTable()
+	}
+}
+
+// resize records the terminal's size and fits the tables to it, the one
+// on screen straight away and the others when they are next shown.
+func (m *model) resize(msg tea.WindowSizeMsg) {
+	m.width, m.height = msg.Width, msg.Height
+	m.expensesDirty, m.stonksDirty, m.watchlistDirty = true, true, true
+	m.redrawTable()
+}
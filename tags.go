@@ -0,0 +1,38 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// hasTag reports whether e is tagged tag, in any case.
+func hasTag(e Expense, tag string) bool {
+	return slices.ContainsFunc(e.Tags, func(t string) bool { return strings.EqualFold(t, tag) })
+}
+
+// tagNames lists the tags of expenses, each once, in alphabetical order.
+func tagNames(expenses []Expense) []string {
+	var tags []string
+	for _, e := range expenses {
+		for _, t := range e.Tags {
+			if !slices.ContainsFunc(tags, func(s string) bool { return strings.EqualFold(s, t) }) {
+				tags = append(tags, t)
+			}
+		}
+	}
+	slices.SortFunc(tags, func(a, b string) int { return strings.Compare(strings.ToLower(a), strings.ToLower(b)) })
+	return tags
+}
+
+// nextTag is the tag filter after current: each tag in turn, then every
+// expense again.
+func nextTag(current string, tags []string) string {
+	i := slices.IndexFunc(tags, func(t string) bool { return strings.EqualFold(t, current) })
+	if current != "" && i < 0 {
+		return ""
+	}
+	if i+1 < len(tags) {
+		return tags[i+1]
+	}
+	return ""
+}
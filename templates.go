@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Templates are quick entries kept in the config file for the expenses
+// entered every month, such as "Rent 800 #Housing @bank". On the Expenses
+// screen 'T' lists them and a number key adds one, dated today unless the
+// template names a day.
+
+// maxTemplates is how many templates the number keys reach.
+const maxTemplates = 9
+
+// validateTemplates checks the templates list of the config file: each
+// must read as a quick entry.
+func validateTemplates(templates []string) error {
+	if len(templates) > maxTemplates {
+		return fmt.Errorf("templates lists %d entries; 'T' reaches the first %d", len(templates), maxTemplates)
+	}
+	for _, t := range templates {
+		if _, err := parseQuickEntry(t, now()); err != nil {
+			return fmt.Errorf("template %q: %w", t, err)
+		}
+	}
+	return nil
+}
+
+// templateKey is the index of the template a key picks, or -1.
+func templateKey(key string) int {
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 1 || i > len(cfg.Templates) {
+		return -1
+	}
+	return i - 1
+}
+
+// updateTemplates handles keys while the templates are listed on the
+// Expenses screen: a template's number adds it, esc closes the list.
+func (m *model) updateTemplates(key tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "T":
+		m.pickingTemplate = false
+		return m, nil
+	}
+	i := templateKey(key.String())
+	if i < 0 {
+		return m, nil
+	}
+	m.pickingTemplate = false
+	e, err := parseQuickEntry(cfg.Templates[i], now())
+	if err != nil {
+		m.status = err.Error()
+		return m, nil
+	}
+	e.EnteredBy = currentUser()
+	m.editing = true
+	return m, func() tea.Msg { return expenseEditedMsg{index: -1, expense: e} }
+}
+
+// viewTemplates lists the templates with what each adds.
+func (m *model) viewTemplates() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("\nAdd from a template:\n")
+	for i, t := range cfg.Templates {
+		if e, err := parseQuickEntry(t, now()); err == nil {
+			buffer.WriteString(fmt.Sprintf("  %d  %s\n", i+1, quickEntryPreview(e)))
+		}
+	}
+	buffer.WriteString("Press a number to add it, esc to cancel.\n")
+	return buffer.String()
+}
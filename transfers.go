@@ -0,0 +1,372 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+	"github.com/xuri/excelize/v2"
+)
+
+// transfersSheet records money moved between the accounts of the Accounts
+// sheet, such as a deposit into savings or a currency exchange. Transfers
+// are not spending, so they stay out of the Expenses sheet and, like the
+// accounts, are edited in the workbook.
+const transfersSheet = "Transfers"
+
+var transfersHeaders = []string{"Date", "From", "Amount", "To", "Received"}
+
+// accountTransfer is a row of the Transfers sheet: Amount leaves From, in
+// its currency, and Received arrives in To, in its own. Either may be left
+// blank, or name no account, for money from or to outside the accounts,
+// such as cash withdrawn from a bank that is not followed.
+type accountTransfer struct {
+	Date     time.Time
+	From, To string
+	Amount   float64
+	// Received may be left blank: it is then Amount between accounts in
+	// the same currency, or Amount converted at the rate of the day.
+	Received float64
+}
+
+// readTransfers reads the Transfers sheet, skipping rows without a date,
+// either account or an amount. A workbook without one has no transfers.
+func readTransfers(f *excelize.File) ([]accountTransfer, error) {
+	if idx, _ := f.GetSheetIndex(transfersSheet); idx == -1 {
+		return nil, nil
+	}
+	rows, err := f.GetRows(transfersSheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+	var transfers []accountTransfer
+	for i := 1; i < len(rows); i++ {
+		row := append(rows[i], make([]string, len(transfersHeaders))...)
+		t := accountTransfer{
+			Date: parseExpenseDate(row[0]),
+			From: strings.TrimSpace(row[1]),
+			To:   strings.TrimSpace(row[3]),
+		}
+		amount, _ := strconv.ParseFloat(strings.TrimSpace(row[2]), 64)
+		received, _ := strconv.ParseFloat(strings.TrimSpace(row[4]), 64)
+		t.Amount, t.Received = cfg.Rounding.round(amount), cfg.Rounding.round(received)
+		if t.Date.IsZero() || (t.From == "" && t.To == "") || t.Amount <= 0 {
+			continue
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, nil
+}
+
+// accountNamed returns the index in accounts of the account called name,
+// matched case-insensitively, or -1.
+func accountNamed(accounts []account, name string) int {
+	return slices.IndexFunc(accounts, func(a account) bool { return payeeKey(a.Name) == payeeKey(name) })
+}
+
+// accountBook is the accounts worked through their expenses and transfers
+// in date order. Each balance is kept in its account's currency along with
+// what it cost in the configured one, at the average rate it was bought
+// at, so that exchanging it for another currency realizes the gain or loss
+// made on the rate since.
+type accountBook struct {
+	// rows is the running balance after each expense.
+	rows []runningBalance
+	// balances are the accounts' balances after everything, and costs
+	// what those balances cost in the configured currency.
+	balances, costs []float64
+	// gains is the FX gain each transfer realized in the configured
+	// currency: zero unless it moved money out of another currency.
+	gains []float64
+	// moves lists what moved each account after its As Of day, in the
+	// account's currency and in date order.
+	moves [][]accountMove
+	// missing lists the currencies without rates, which count as zero.
+	missing []string
+}
+
+// accountMove is an expense or transfer moving an account.
+type accountMove struct {
+	date   time.Time
+	amount float64
+	// transfer is set for transfers, clear for expenses.
+	transfer bool
+}
+
+// bookAccounts works accounts through rows and transfers: rows dated the
+// same day in the order they are listed, after that day's transfers.
+// Pending and undated rows, and rows and transfers dated before an
+// account's As Of day, move no account; a transfer moves only the
+// accounts it names that are listed.
+func bookAccounts(accounts []account, transfers []accountTransfer, rows []Expense, rates fxRates) accountBook {
+	book := accountBook{
+		rows:     make([]runningBalance, len(rows)),
+		balances: make([]float64, len(accounts)),
+		costs:    make([]float64, len(accounts)),
+		gains:    make([]float64, len(transfers)),
+		moves:    make([][]accountMove, len(accounts)),
+	}
+	rate := func(currency string, day time.Time) float64 {
+		r, ok := rates.rate(currency, day)
+		if !ok && !slices.Contains(book.missing, currency) {
+			book.missing = append(book.missing, currency)
+		}
+		return r
+	}
+	// convert is x in currency from in currency to, at the rates of day.
+	convert := func(x float64, from, to string, day time.Time) float64 {
+		if from == to {
+			return x
+		}
+		r := rate(to, day)
+		if r == 0 {
+			return 0
+		}
+		return cfg.Rounding.round(x * rate(from, day) / r)
+	}
+	// costOf is what x of account a cost: at the average rate of its
+	// balance, or the rate of day while it holds none.
+	costOf := func(a int, x float64, day time.Time) float64 {
+		switch {
+		case accounts[a].Currency == "":
+			return x
+		case book.balances[a] > 0:
+			return x * book.costs[a] / book.balances[a]
+		}
+		return x * rate(accounts[a].Currency, day)
+	}
+	move := func(a int, day time.Time, x, cost float64, transfer bool) {
+		book.balances[a] = cfg.Rounding.round(book.balances[a] + x)
+		book.costs[a] = cfg.Rounding.round(book.costs[a] + cost)
+		book.moves[a] = append(book.moves[a], accountMove{date: day, amount: x, transfer: transfer})
+	}
+
+	for i, a := range accounts {
+		book.balances[i] = a.Opening
+		book.costs[i] = cfg.Rounding.round(a.Opening * rate(a.Currency, a.AsOf))
+	}
+	// events are the expenses (row >= 0) and transfers (transfer >= 0) to
+	// book, in order.
+	type event struct {
+		date          time.Time
+		row, transfer int
+	}
+	var events []event
+	for j, t := range transfers {
+		if accountNamed(accounts, t.From) >= 0 || accountNamed(accounts, t.To) >= 0 {
+			events = append(events, event{date: t.Date, row: -1, transfer: j})
+		}
+	}
+	for i, e := range rows {
+		book.rows[i].account = -1
+		a := accountOf(accounts, e)
+		if a < 0 || e.Pending != "" || e.Date.IsZero() || e.Date.Before(accounts[a].AsOf) {
+			continue
+		}
+		book.rows[i].account = a
+		events = append(events, event{date: e.Date, row: i, transfer: -1})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].date.Before(events[j].date) })
+
+	for _, ev := range events {
+		if ev.row >= 0 {
+			e := rows[ev.row]
+			a := book.rows[ev.row].account
+			currency := accounts[a].Currency
+			x := convert(e.Amount, baseOrCurrency(e.Currency), currency, e.Date)
+			cost := x * rate(currency, e.Date)
+			if x < 0 {
+				cost = costOf(a, x, e.Date)
+			}
+			move(a, e.Date, x, cost, false)
+			book.rows[ev.row].balance = book.balances[a]
+			continue
+		}
+		t := transfers[ev.transfer]
+		from, to := accountNamed(accounts, t.From), accountNamed(accounts, t.To)
+		// Money from or to outside the accounts is taken to be in the
+		// currency of the account it comes from or goes to.
+		var fromCurrency, toCurrency string
+		switch {
+		case from < 0:
+			fromCurrency, toCurrency = accounts[to].Currency, accounts[to].Currency
+		case to < 0:
+			fromCurrency, toCurrency = accounts[from].Currency, accounts[from].Currency
+		default:
+			fromCurrency, toCurrency = accounts[from].Currency, accounts[to].Currency
+		}
+		received := t.Received
+		if received == 0 {
+			received = convert(t.Amount, fromCurrency, toCurrency, t.Date)
+		}
+		var cost float64
+		if from < 0 || t.Date.Before(accounts[from].AsOf) {
+			cost = t.Amount * rate(fromCurrency, t.Date)
+		} else {
+			cost = costOf(from, t.Amount, t.Date)
+			move(from, t.Date, -t.Amount, -cost, true)
+		}
+		if fromCurrency != "" && fromCurrency != toCurrency {
+			value := received * rate(toCurrency, t.Date)
+			book.gains[ev.transfer] = cfg.Rounding.round(value - cost)
+			cost = value
+		}
+		if to >= 0 && !t.Date.Before(accounts[to].AsOf) {
+			move(to, t.Date, received, cost, true)
+		}
+	}
+	slices.Sort(book.missing)
+	return book
+}
+
+// values are the accounts' balances revalued in the configured currency
+// at the latest rates.
+func (b accountBook) values(accounts []account, rates fxRates) []float64 {
+	values := make([]float64, len(accounts))
+	for i, a := range accounts {
+		r, _ := rates.rate(a.Currency, today())
+		values[i] = cfg.Rounding.round(b.balances[i] * r)
+	}
+	return values
+}
+
+// unrealized is what revaluing the accounts at values gains over what
+// their balances cost.
+func (b accountBook) unrealized(values []float64) float64 {
+	var gain float64
+	for i, v := range values {
+		gain += v - b.costs[i]
+	}
+	return cfg.Rounding.round(gain)
+}
+
+// realized is the FX gain realized by every transfer.
+func (b accountBook) realized() float64 {
+	var gain float64
+	for _, g := range b.gains {
+		gain += g
+	}
+	return cfg.Rounding.round(gain)
+}
+
+// currenciesWithoutRates lists the currencies of accounts that rates has
+// none for.
+func currenciesWithoutRates(accounts []account, rates fxRates) []string {
+	var missing []string
+	for _, a := range accounts {
+		if _, ok := rates.rate(a.Currency, today()); !ok && !slices.Contains(missing, a.Currency) {
+			missing = append(missing, a.Currency)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// accountsTable lists each account's balance, its value at the latest
+// rates, what it cost and the gain between them, with the net worth they
+// add up to.
+func accountsTable(accounts []account, book accountBook, values []float64) string {
+	headers := []string{"Account", "Balance", "Value", "Cost", "Unrealized"}
+	var data [][]string
+	var worth, cost float64
+	for i, a := range accounts {
+		data = append(data, []string{a.Name, a.amount(book.balances[i]), money(values[i]), money(book.costs[i]), money(values[i] - book.costs[i])})
+		worth += values[i]
+		cost += book.costs[i]
+	}
+	data = append(data, []string{"Net worth", "", money(cfg.Rounding.round(worth)), money(cfg.Rounding.round(cost)), money(book.unrealized(values))})
+	return reportTable(headers, data)
+}
+
+// transfersTable lists the transfers between accounts with the FX gain
+// each realized, and their total.
+func transfersTable(accounts []account, transfers []accountTransfer, book accountBook) string {
+	headers := []string{"Date", "From", "Amount", "To", "Realized gain"}
+	var data [][]string
+	for j, t := range transfers {
+		amount := money(t.Amount)
+		if a := accountNamed(accounts, t.From); a >= 0 {
+			amount = accounts[a].amount(t.Amount)
+		}
+		data = append(data, []string{formatExpenseDate(t.Date), t.From, amount, t.To, money(book.gains[j])})
+	}
+	data = append(data, []string{"Total", "", "", "", money(book.realized())})
+	return reportTable(headers, data)
+}
+
+// reportTable draws a table whose last row is a total, in bold.
+func reportTable(headers []string, data [][]string) string {
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			switch {
+			case row == ltable.HeaderRow:
+				return headerStyle
+			case row == len(data)-1:
+				return rowStyle.Bold(true)
+			}
+			return rowStyle
+		})
+	return t.String()
+}
+
+// runAccounts prints the balance of every account, revalued at the latest
+// rates into the net worth, and the FX gains realized by transfers.
+func runAccounts(args []string) error {
+	flags := flag.NewFlagSet("accounts", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "workbook with the Accounts sheet")
+	flags.Parse(args)
+
+	if !isWorkbook(*file) {
+		return fmt.Errorf("accounts: %s is not an .xlsx workbook", *file)
+	}
+	f, err := openWorkbook(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	accounts := readAccounts(f)
+	if len(accounts) == 0 {
+		return fmt.Errorf("accounts: %s has no accounts; add an %s sheet with %s columns", *file, accountsSheet, strings.Join(accountsHeaders, ", "))
+	}
+	expenses, err := readExpenses(f)
+	if err != nil {
+		return err
+	}
+	transfers, err := readTransfers(f)
+	if err != nil {
+		return err
+	}
+	rates := readRates(f)
+	book := bookAccounts(accounts, transfers, expenses, rates)
+	fmt.Println(accountsTable(accounts, book, book.values(accounts, rates)))
+	if len(transfers) > 0 {
+		fmt.Println(transfersTable(accounts, transfers, book))
+	}
+	for _, t := range transfers {
+		for _, name := range []string{t.From, t.To} {
+			if name != "" && accountNamed(accounts, name) < 0 {
+				fmt.Printf("The transfer of %s moves no account for %s, which is not in the %s sheet.\n", formatExpenseDate(t.Date), name, accountsSheet)
+			}
+		}
+	}
+	if len(book.missing) > 0 {
+		return errors.New("accounts: no rates for " + strings.Join(book.missing, ", ") + "; `tet backfill --fx` fetches them")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestFXGains exchanges euros for dollars, spends some and exchanges some
+// back at better rates, and expects the gain made on the dollars exchanged
+// back to be realized and the gain on those still held to be unrealized.
+func TestFXGains(t *testing.T) {
+	useClock(t, time.Date(2026, 3, 15, 12, 0, 0, 0, time.Local))
+	currency := cfg.Rounding.Currency
+	cfg.Rounding.Currency = "EUR"
+	t.Cleanup(func() { cfg.Rounding.Currency = currency })
+
+	day := parseExpenseDate
+	rates := seriesBySymbol([]pricePoint{
+		{Symbol: "USD", Date: day("2026-01-01"), Close: 0.90},
+		{Symbol: "USD", Date: day("2026-02-01"), Close: 0.95},
+		{Symbol: "USD", Date: day("2026-03-01"), Close: 1.00},
+	})
+	accounts := []account{
+		{Name: "Checking", Opening: 1000, AsOf: day("2026-01-01")},
+		{Name: "Dollars", AsOf: day("2026-01-01"), Currency: "USD"},
+	}
+	transfers := []accountTransfer{
+		// 450 EUR buy 500 USD at 0.90: nothing is realized leaving euros.
+		{Date: day("2026-01-10"), From: "Checking", To: "Dollars", Amount: 450},
+		// 200 USD, which cost 180 EUR, fetch 190 EUR at 0.95.
+		{Date: day("2026-02-10"), From: "dollars", To: "Checking", Amount: 200},
+		// 100 USD, which cost 90 EUR, fetch 98 EUR after the bank's fee.
+		{Date: day("2026-03-05"), From: "Dollars", To: "Checking", Amount: 100, Received: 98},
+		// 50 USD, which cost 45 EUR, leave for an account not listed
+		// without realizing anything.
+		{Date: day("2026-03-06"), From: "Dollars", To: "Brokerage", Amount: 50},
+	}
+	rows := []Expense{
+		{Name: "Groceries", Amount: -40, Date: day("2026-02-15"), Payment: "Checking"},
+		// 30 USD of dollars that cost 0.90 EUR each.
+		{Name: "Hotel", Amount: -30, Currency: "USD", Date: day("2026-02-20"), Payment: "Dollars"},
+		{Name: "Pending", Amount: -5, Date: day("2026-02-21"), Payment: "Dollars", Pending: pendingNew},
+	}
+
+	book := bookAccounts(accounts, transfers, rows, rates)
+	if want := []float64{0, 10, 8, 0}; !reflect.DeepEqual(book.gains, want) {
+		t.Errorf("realized gains by transfer %v, want %v", book.gains, want)
+	}
+	if got := book.realized(); got != 18 {
+		t.Errorf("realized %v in all, want 18", got)
+	}
+	if want := []float64{798, 120}; !reflect.DeepEqual(book.balances, want) {
+		t.Errorf("balances %v, want %v", book.balances, want)
+	}
+	if want := []float64{798, 108}; !reflect.DeepEqual(book.costs, want) {
+		t.Errorf("costs %v, want %v", book.costs, want)
+	}
+	values := book.values(accounts, rates)
+	if want := []float64{798, 120}; !reflect.DeepEqual(values, want) {
+		t.Errorf("values at the latest rates %v, want %v", values, want)
+	}
+	// Only the 120 USD still held, bought for 108 EUR, have gained.
+	if got := book.unrealized(values); got != 12 {
+		t.Errorf("unrealized %v, want 12", got)
+	}
+	want := []runningBalance{{account: 0, balance: 700}, {account: 1, balance: 270}, {account: -1}}
+	if !reflect.DeepEqual(book.rows, want) {
+		t.Errorf("running balances %+v, want %+v", book.rows, want)
+	}
+	if len(book.missing) > 0 {
+		t.Errorf("no rates for %v", book.missing)
+	}
+}
+
+// TestFXGainsWithoutRates counts an account in a currency without rates as
+// zero and names the currency, rather than inventing a gain.
+func TestFXGainsWithoutRates(t *testing.T) {
+	useClock(t, time.Date(2026, 3, 15, 12, 0, 0, 0, time.Local))
+	currency := cfg.Rounding.Currency
+	cfg.Rounding.Currency = "EUR"
+	t.Cleanup(func() { cfg.Rounding.Currency = currency })
+
+	accounts := []account{
+		{Name: "Checking", Opening: 100},
+		{Name: "Francs", Opening: 50, Currency: "CHF"},
+	}
+	transfers := []accountTransfer{{Date: parseExpenseDate("2026-02-01"), From: "Francs", To: "Checking", Amount: 20, Received: 21}}
+	book := bookAccounts(accounts, transfers, nil, fxRates{})
+	if want := []string{"CHF"}; !reflect.DeepEqual(book.missing, want) {
+		t.Errorf("missing rates for %v, want %v", book.missing, want)
+	}
+	if want := []float64{121, 30}; !reflect.DeepEqual(book.balances, want) {
+		t.Errorf("balances %v, want %v", book.balances, want)
+	}
+	// The francs cost nothing without rates, so the 21 EUR they fetched
+	// are all gain.
+	if got := book.realized(); got != 21 {
+		t.Errorf("realized %v, want 21", got)
+	}
+	if got := book.unrealized(book.values(accounts, fxRates{})); got != 0 {
+		t.Errorf("unrealized %v on francs valued at zero, want 0", got)
+	}
+}
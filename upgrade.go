@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// runUpgrade migrates a workbook to the current schema without starting
+// the TUI.
+func runUpgrade(args []string) error {
+	flags := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "workbook to upgrade")
+	flags.Parse(args)
+
+	backup, err := upgradeWorkbook(*file)
+	if err != nil {
+		return err
+	}
+	if backup == "" {
+		fmt.Printf("%s is already at schema v%d\n", *file, schemaVersion)
+		return nil
+	}
+	fmt.Printf("Upgraded %s to schema v%d (backup saved as %s)\n", *file, schemaVersion, backup)
+	return nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+)
+
+// usagePattern reads a utility's usage as typed in the Usage column, a
+// quantity and its unit, such as "320 kWh" or "12.5 m3".
+var usagePattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*(.*)$`)
+
+// usageUnits are the usual spellings of units, written the same way
+// whatever their case so a utility's months compare.
+var usageUnits = map[string]string{
+	"kwh": "kWh",
+	"mwh": "MWh",
+	"m3":  "m³",
+	"m³":  "m³",
+	"l":   "L",
+	"gb":  "GB",
+}
+
+// parseUsage reads the Usage column or the usage typed in the expense
+// forms; anything it cannot read is no usage.
+func parseUsage(s string) (float64, string) {
+	match := usagePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, ""
+	}
+	quantity, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || quantity <= 0 {
+		return 0, ""
+	}
+	unit := strings.TrimSpace(match[2])
+	if known, ok := usageUnits[strings.ToLower(unit)]; ok {
+		unit = known
+	}
+	return quantity, unit
+}
+
+// formatUsage writes a usage as the Usage column and the forms show it,
+// blank for none.
+func formatUsage(quantity float64, unit string) string {
+	if quantity <= 0 {
+		return ""
+	}
+	return strings.TrimSpace(strconv.FormatFloat(quantity, 'f', -1, 64) + " " + unit)
+}
+
+// validateUsage checks the usage typed in the expense forms.
+func validateUsage(s string) error {
+	if q, _ := parseUsage(s); strings.TrimSpace(s) != "" && q == 0 {
+		return errors.New("enter the usage as a quantity and unit, such as 320 kWh, or leave it blank")
+	}
+	return nil
+}
+
+// usagePer names the unit a price is per, such as "per kWh".
+func usagePer(unit string) string {
+	if unit == "" {
+		return "per unit"
+	}
+	return "per " + unit
+}
+
+// unitPrice is what e cost per unit of its usage, zero without one.
+func unitPrice(e Expense) float64 {
+	if e.Usage <= 0 {
+		return 0
+	}
+	return -e.Amount / e.Usage
+}
+
+// usageMonth is what a utility used and cost in a month.
+type usageMonth struct {
+	month time.Time
+	usage float64
+	cost  float64
+}
+
+func (u usageMonth) price() float64 {
+	return u.cost / u.usage
+}
+
+// utilityUsage is the months of a utility, by the expense's name and unit
+// of usage, in date order.
+type utilityUsage struct {
+	name   string
+	unit   string
+	months []usageMonth
+}
+
+// utilitiesOf sums the usage and cost of the expenses that record usage by
+// utility and month. A utility billed twice in a month adds up.
+func utilitiesOf(expenses []Expense) []utilityUsage {
+	var utilities []utilityUsage
+	for _, e := range expenses {
+		if e.Usage <= 0 || !isSpending(e) {
+			continue
+		}
+		i := slices.IndexFunc(utilities, func(u utilityUsage) bool {
+			return payeeKey(u.name) == payeeKey(e.Name) && u.unit == e.UsageUnit
+		})
+		if i < 0 {
+			utilities = append(utilities, utilityUsage{name: strings.TrimSpace(e.Name), unit: e.UsageUnit})
+			i = len(utilities) - 1
+		}
+		u := &utilities[i]
+		month := monthOf(e.Date)
+		j := slices.IndexFunc(u.months, func(m usageMonth) bool { return m.month.Equal(month) })
+		if j < 0 {
+			u.months = append(u.months, usageMonth{month: month})
+			j = len(u.months) - 1
+		}
+		u.months[j].usage += e.Usage
+		u.months[j].cost += -e.Amount
+	}
+	for _, u := range utilities {
+		slices.SortFunc(u.months, func(a, b usageMonth) int { return a.month.Compare(b.month) })
+	}
+	slices.SortStableFunc(utilities, func(a, b utilityUsage) int { return strings.Compare(payeeKey(a.name), payeeKey(b.name)) })
+	return utilities
+}
+
+// costChange splits the change in cost from prev to cur into what using
+// more or less and paying more or less per unit account for: the change
+// in usage at the old price, and the change in price on the new usage.
+func costChange(prev, cur usageMonth) (usage, price float64) {
+	return (cur.usage - prev.usage) * prev.price(), (cur.price() - prev.price()) * cur.usage
+}
+
+// usageTable lays out the months of u with the unit price and, from the
+// second month, what of the change in cost came from usage and what from
+// the price.
+func usageTable(u utilityUsage) string {
+	headers := []string{"Month", "Usage", "Cost", "Price " + usagePer(u.unit), "Change", "From usage", "From price"}
+	var data [][]string
+	for i, m := range u.months {
+		row := []string{m.month.Format("Jan 2006"), formatUsage(m.usage, u.unit), money(m.cost), strconv.FormatFloat(m.price(), 'f', 4, 64), "", "", ""}
+		if i > 0 {
+			prev := u.months[i-1]
+			usage, price := costChange(prev, m)
+			row[4], row[5], row[6] = signedMoney(m.cost-prev.cost), signedMoney(usage), signedMoney(price)
+		}
+		data = append(data, row)
+	}
+
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(headers...).
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			return rowStyle
+		})
+	return t.String()
+}
+
+// signedMoney is a change in cost, a plus sign on increases.
+func signedMoney(x float64) string {
+	x = cfg.Rounding.round(x)
+	if x > 0 {
+		return "+" + money(x)
+	}
+	return money(x)
+}
+
+// usageSummary sums up the change of u over its months: how the unit
+// price and the usage moved from the first month to the last.
+func usageSummary(u utilityUsage) string {
+	if len(u.months) < 2 {
+		return ""
+	}
+	first, last := u.months[0], u.months[len(u.months)-1]
+	var usage, price float64
+	for i := 1; i < len(u.months); i++ {
+		du, dp := costChange(u.months[i-1], u.months[i])
+		usage += du
+		price += dp
+	}
+	return fmt.Sprintf("From %s to %s the price %s %s and usage %s; of the %s change in cost, %s came from usage and %s from the price.\n",
+		first.month.Format("Jan 2006"), last.month.Format("Jan 2006"), usagePer(u.unit),
+		movement(first.price(), last.price()), movement(first.usage, last.usage),
+		signedMoney(last.cost-first.cost), signedMoney(usage), signedMoney(price))
+}
+
+// movement says how to differs from from, such as "went up 4.2%".
+func movement(from, to float64) string {
+	change := (to - from) / from * 100
+	switch {
+	case change >= 0.05:
+		return fmt.Sprintf("went up %.1f%%", change)
+	case change <= -0.05:
+		return fmt.Sprintf("went down %.1f%%", -change)
+	}
+	return "held steady"
+}
+
+// runUsage prints, for each utility whose expenses record usage, its usage,
+// cost and unit price by month, splitting each change in cost between
+// usage and price.
+func runUsage(args []string) error {
+	flags := flag.NewFlagSet("usage", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "workbook with the expenses")
+	name := flags.String("name", "", "only the utility by this expense name")
+	flags.Parse(args)
+
+	if !isWorkbook(*file) {
+		return fmt.Errorf("usage: %s is not an .xlsx workbook", *file)
+	}
+	f, err := openWorkbook(*file)
+	if err != nil {
+		return err
+	}
+	expenses, err := readExpenses(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	expenses, _ = readRates(f).inBase(expenses)
+	f.Close()
+
+	utilities := utilitiesOf(expenses)
+	if *name != "" {
+		utilities = slices.DeleteFunc(utilities, func(u utilityUsage) bool { return payeeKey(u.name) != payeeKey(*name) })
+	}
+	if len(utilities) == 0 {
+		return fmt.Errorf("usage: %s has no expenses with usage recorded", *file)
+	}
+	for i, u := range utilities {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(u.name)
+		fmt.Println(usageTable(u))
+		fmt.Print(usageSummary(u))
+	}
+	return nil
+}
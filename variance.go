@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	ltable "github.com/charmbracelet/lipgloss/table"
+	"github.com/xuri/excelize/v2"
+)
+
+// varianceConfig sets when a budget is shown as nearly spent in the
+// variance report.
+type varianceConfig struct {
+	// WarnPercent is how little of its budget, in percent, a category may
+	// have left before it is flagged; 10 by default.
+	WarnPercent float64 `json:"warn_percent"`
+}
+
+func (c varianceConfig) validate() error {
+	if c.WarnPercent < 0 || c.WarnPercent > 100 {
+		return errors.New("variance.warn_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// varianceRow compares a category's budget for a month with what it
+// actually spent.
+type varianceRow struct {
+	Category string
+	// Icon is shown beside the category on screen.
+	Icon string
+	// Planned is the budget available in the month, rollover included.
+	Planned float64
+	Actual  float64
+}
+
+// variance is what was left unspent, negative when over budget.
+func (r varianceRow) variance() float64 {
+	return cfg.Rounding.round(r.Planned - r.Actual)
+}
+
+// percent is the variance as a share of the budget, false when there was
+// no budget to compare with.
+func (r varianceRow) percent() (float64, bool) {
+	if r.Planned <= 0 {
+		return 0, false
+	}
+	return math.Round(r.variance()/r.Planned*1000) / 10, true
+}
+
+// varianceLevel grades a row: over budget, within warn_percent of it, or
+// comfortably under it.
+type varianceLevel int
+
+const (
+	varianceUnder varianceLevel = iota
+	varianceNear
+	varianceOver
+)
+
+func (r varianceRow) level() varianceLevel {
+	if r.variance() < 0 {
+		return varianceOver
+	}
+	if pct, ok := r.percent(); ok && pct < cfg.Variance.WarnPercent {
+		return varianceNear
+	}
+	return varianceUnder
+}
+
+// budgetVariance compares every budget with the month's spending.
+func budgetVariance(budgets []budget, expenses []Expense, month time.Time) []varianceRow {
+	statuses := budgetStatuses(budgets, expenses, month)
+	rows := make([]varianceRow, 0, len(statuses))
+	for _, s := range statuses {
+		rows = append(rows, varianceRow{Category: s.Category, Icon: s.icon(), Planned: s.available(), Actual: s.spent})
+	}
+	return rows
+}
+
+// varianceTotal sums rows into one for the whole budget, leaving out
+// subcategories whose parent has a row of its own.
+func varianceTotal(rows []varianceRow) varianceRow {
+	total := varianceRow{Category: "Total"}
+	categories := make([]string, len(rows))
+	for i, r := range rows {
+		categories[i] = r.Category
+	}
+	for _, r := range rows {
+		if nestedCategory(r.Category, categories) {
+			continue
+		}
+		total.Planned += r.Planned
+		total.Actual += r.Actual
+	}
+	total.Planned = cfg.Rounding.round(total.Planned)
+	total.Actual = cfg.Rounding.round(total.Actual)
+	return total
+}
+
+var varianceHeaders = []string{"Category", "Planned", "Actual", "Variance", "Variance %"}
+
+func formatVariancePercent(r varianceRow) string {
+	pct, ok := r.percent()
+	if !ok {
+		return "—"
+	}
+	return strconv.FormatFloat(pct, 'f', 1, 64) + "%"
+}
+
+func varianceTable(rows []varianceRow) string {
+	all := append(slices.Clip(rows), varianceTotal(rows))
+	var data [][]string
+	for _, r := range all {
+		data = append(data, []string{withIcon(r.Icon, r.Category), money(r.Planned), money(r.Actual), money(r.variance()), formatVariancePercent(r)})
+	}
+
+	re := lipgloss.NewRenderer(os.Stdout)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Foreground(lipgloss.Color("252")).Bold(true)
+	rowStyle := baseStyle.Foreground(lipgloss.Color("252"))
+	levelStyles := map[varianceLevel]lipgloss.Style{
+		varianceUnder: baseStyle.Foreground(lipgloss.Color("42")),
+		varianceNear:  baseStyle.Foreground(lipgloss.Color("214")),
+		varianceOver:  baseStyle.Foreground(lipgloss.Color("203")),
+	}
+
+	t := ltable.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.Color("238"))).
+		Headers(varianceHeaders...).
+		Rows(data...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == ltable.HeaderRow {
+				return headerStyle
+			}
+			style := rowStyle
+			if col >= 3 {
+				style = levelStyles[all[row].level()]
+			}
+			if row == len(all)-1 {
+				style = style.Bold(true)
+			}
+			return style
+		})
+	return t.String()
+}
+
+// varianceLegend explains the colours of varianceTable.
+func varianceLegend() string {
+	return fmt.Sprintf("Green: under budget by %g%% or more · orange: less than %g%% of the budget left · red: over budget.\n",
+		cfg.Variance.WarnPercent, cfg.Variance.WarnPercent)
+}
+
+// varianceFile is where the TUI exports month's variance report, beside
+// the workbook.
+func varianceFile(path string, month time.Time) string {
+	return filepath.Join(filepath.Dir(path), "variance-"+month.Format(monthLayout)+".xlsx")
+}
+
+// exportVariance writes the report to path, a workbook with the rows
+// coloured as on screen or, for a .csv path, plain CSV.
+func exportVariance(path string, month time.Time, rows []varianceRow) error {
+	all := append(slices.Clip(rows), varianceTotal(rows))
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		w := csv.NewWriter(file)
+		w.Write(varianceHeaders)
+		for _, r := range all {
+			pct := ""
+			if p, ok := r.percent(); ok {
+				pct = strconv.FormatFloat(p, 'f', -1, 64)
+			}
+			w.Write([]string{r.Category, strconv.FormatFloat(r.Planned, 'f', -1, 64), strconv.FormatFloat(r.Actual, 'f', -1, 64),
+				strconv.FormatFloat(r.variance(), 'f', -1, 64), pct})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Variance " + month.Format(monthLayout)
+	if err := f.SetSheetName(f.GetSheetName(0), sheet); err != nil {
+		return err
+	}
+	if err := f.SetSheetRow(sheet, "A1", &varianceHeaders); err != nil {
+		return err
+	}
+	header, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"25A065"}},
+	})
+	if err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(sheet, "A1", "E1", header); err != nil {
+		return err
+	}
+	numFmt := cfg.Rounding.numFmt()
+	levelColors := map[varianceLevel]string{varianceUnder: "1E8449", varianceNear: "D68910", varianceOver: "C0392B"}
+	for i, r := range all {
+		row := i + 2
+		values := []any{r.Category, r.Planned, r.Actual, r.variance(), nil}
+		if pct, ok := r.percent(); ok {
+			values[4] = pct / 100
+		}
+		if err := f.SetSheetRow(sheet, fmt.Sprintf("A%d", row), &values); err != nil {
+			return err
+		}
+		total := i == len(all)-1
+		amount, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: total}, CustomNumFmt: &numFmt})
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("C%d", row), amount); err != nil {
+			return err
+		}
+		font := &excelize.Font{Bold: total, Color: levelColors[r.level()]}
+		variance, err := f.NewStyle(&excelize.Style{Font: font, CustomNumFmt: &numFmt})
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(sheet, fmt.Sprintf("D%d", row), fmt.Sprintf("D%d", row), variance); err != nil {
+			return err
+		}
+		percent, err := f.NewStyle(&excelize.Style{Font: font, NumFmt: 10})
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(sheet, fmt.Sprintf("E%d", row), fmt.Sprintf("E%d", row), percent); err != nil {
+			return err
+		}
+	}
+	if err := f.SetColWidth(sheet, "A", "A", 24); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "B", "E", 12); err != nil {
+		return err
+	}
+	return f.SaveAs(path)
+}
+
+// exportVarianceCmd exports the variance report of the month shown on the
+// Budgets screen.
+func (m *model) exportVarianceCmd() tea.Cmd {
+	if len(m.budgets) == 0 {
+		return nil
+	}
+	month := m.budgetMonth
+	rows := budgetVariance(m.budgets, m.inBase(m.expenses), month)
+	file := varianceFile(m.path, month)
+	return func() tea.Msg {
+		return sheetExportedMsg{file: file, err: exportVariance(file, month, rows)}
+	}
+}
+
+// readVariance reads the budgets and expenses of the workbook at filename
+// and compares them for month.
+func readVariance(filename string, month time.Time) ([]varianceRow, error) {
+	f, err := openWorkbook(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	budgets, found, err := readBudgets(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s sheet: %w", budgetsSheet, err)
+	}
+	if !found || len(budgets) == 0 {
+		return nil, nil
+	}
+	expenses, err := readExpenses(f)
+	if err != nil {
+		return nil, err
+	}
+	expenses, _ = readRates(f).inBase(expenses)
+	return budgetVariance(budgets, expenses, month), nil
+}
+
+// runVariance prints a month's budget variance report, or exports it.
+func runVariance(args []string) error {
+	flags := flag.NewFlagSet("variance", flag.ExitOnError)
+	file := flags.String("file", dataFile(), "workbook with the Budgets sheet")
+	monthFlag := flags.String("month", monthOf(today()).AddDate(0, -1, 0).Format(monthLayout), "month to report, as YYYY-MM")
+	out := flags.String("out", "", "write the report to this .xlsx or .csv file instead of printing it")
+	flags.Parse(args)
+
+	month, err := time.Parse(monthLayout, *monthFlag)
+	if err != nil {
+		return fmt.Errorf("variance: --month must be YYYY-MM: %w", err)
+	}
+	if !isWorkbook(*file) {
+		return fmt.Errorf("variance: %s is not an .xlsx workbook", *file)
+	}
+	rows, err := readVariance(*file, month)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("variance: %s has no budgets; add a %s sheet with %s columns", *file, budgetsSheet, strings.Join(budgetsHeaders, ", "))
+	}
+	if *out != "" {
+		if err := exportVariance(*out, month, rows); err != nil {
+			return err
+		}
+		fmt.Printf("Exported the variance report for %s to %s.\n", month.Format("January 2006"), *out)
+		return nil
+	}
+	fmt.Println(month.Format("January 2006"))
+	fmt.Println(varianceTable(rows))
+	fmt.Print(varianceLegend())
+	return nil
+}